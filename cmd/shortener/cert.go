@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/Schalure/urlalias/internal/app/tlsconfig"
+)
+
+const certUsage = `Usage: shortener cert [flags]
+
+Generate self-signed certificate and key for local development with HTTPS:
+	shortener cert -host localhost,127.0.0.1
+	shortener -s -tls-cert cert.pem -tls-key key.pem
+
+Flags:
+`
+
+// ------------------------------------------------------------
+//
+//	Run "cert" subcommand with its arguments
+func runCert(args []string) error {
+
+	flags := flag.NewFlagSet("cert", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), certUsage)
+		flags.PrintDefaults()
+	}
+	hosts := flags.String("host", "localhost,127.0.0.1,::1", "comma-separated DNS names and IP addresses of the certificate")
+	certFile := flags.String("cert", "cert.pem", "file of PEM certificate")
+	keyFile := flags.String("key", "key.pem", "file of PEM private key")
+	validFor := flags.Duration("valid-for", tlsconfig.SelfSignedValidForDefault, "lifetime of the certificate")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var hostList []string
+	for _, host := range strings.Split(*hosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hostList = append(hostList, host)
+		}
+	}
+
+	if err := tlsconfig.WriteSelfSigned(*certFile, *keyFile, hostList, *validFor); err != nil {
+		return err
+	}
+	fmt.Printf("certificate: %s\nkey: %s\n", *certFile, *keyFile)
+	return nil
+}
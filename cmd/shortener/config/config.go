@@ -1,25 +1,87 @@
-// Package config describes the configuration for github.com/Schalure/urlalias
+// Package config describes the configuration for github.com/Schalure/urlalias.
+// Every setting has a default value, which can be overridden by the configuration file,
+// then by environment variables, then by flags
 package config
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
+	"net/netip"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/jwtauth"
+	"github.com/Schalure/urlalias/internal/app/server"
+	"github.com/Schalure/urlalias/internal/app/storage/cachestor"
+	"github.com/Schalure/urlalias/internal/app/tlsconfig"
+	"github.com/Schalure/urlalias/internal/app/tracing"
 )
 
 // ------------------------------------------------------------
 //
 //	Application constants
 const (
-	AppName            = string("github.com/Schalure/urlalias") //	Application name
-	hostEnvKey         = string("SERVER_ADDRESS")               //	key for "host" in environment variables
-	baseURLEnvKey      = string("BASE_URL")                     //	key for "baseURL" in environment variables
-	storageFileEnvKey  = string("FILE_STORAGE_PATH")            //	key for "storageFile" in environment variables
-	dbConnectionEnvKey = string("DATABASE_DSN")                 //	key for "dbConnection in environment variables
+	AppName                = string("github.com/Schalure/urlalias")   //	Application name
+	configFileEnvKey       = string("CONFIG")                         //	key for configuration file in environment variables
+	configFileFlag         = string("c")                              //	flag of configuration file
+	logToFileEnvKey        = string("LOG_TO_FILE")                    //	key for "logToFile" in environment variables
+	logLevelEnvKey         = string("LOG_LEVEL")                      //	key for "logLevel" in environment variables
+	hostEnvKey             = string("SERVER_ADDRESS")                 //	key for "host" in environment variables
+	baseURLEnvKey          = string("BASE_URL")                       //	key for "baseURL" in environment variables
+	storageFileEnvKey      = string("FILE_STORAGE_PATH")              //	key for "storageFile" in environment variables
+	dbConnectionEnvKey     = string("DATABASE_DSN")                   //	key for "dbConnection in environment variables
+	aliasCharsetEnvKey     = string("ALIAS_CHARSET")                  //	key for "aliasCharset" in environment variables
+	aliasMinLenEnvKey      = string("ALIAS_MIN_LEN")                  //	key for "aliasMinLen" in environment variables
+	aliasMaxLenEnvKey      = string("ALIAS_MAX_LEN")                  //	key for "aliasMaxLen" in environment variables
+	expireIntervalEnvKey   = string("EXPIRE_INTERVAL")                //	key for "expireInterval" in environment variables
+	grpcAddressEnvKey      = string("GRPC_ADDRESS")                   //	key for "grpcAddress" in environment variables
+	metricsAddressEnvKey   = string("METRICS_ADDRESS")                //	key for "metricsAddress" in environment variables
+	shutdownTimeoutEnvKey  = string("SHUTDOWN_TIMEOUT")               //	key for "shutdownTimeout" in environment variables
+	fileFsyncEnvKey        = string("FILE_STORAGE_FSYNC")             //	key for "fileFsync" in environment variables
+	fileCompactEnvKey      = string("FILE_STORAGE_COMPACT_THRESHOLD") //	key for "fileCompactThreshold" in environment variables
+	keyGeneratorEnvKey     = string("KEY_GENERATOR")                  //	key for "keyGenerator" in environment variables
+	keyLengthEnvKey        = string("KEY_LENGTH")                     //	key for "keyLength" in environment variables
+	keySaltEnvKey          = string("KEY_SALT")                       //	key for "keySalt" in environment variables
+	keyRangeSizeEnvKey     = string("KEY_RANGE_SIZE")                 //	key for "keyRangeSize" in environment variables
+	cacheSizeEnvKey        = string("CACHE_SIZE")                     //	key for "cacheSize" in environment variables
+	cacheTTLEnvKey         = string("CACHE_TTL")                      //	key for "cacheTTL" in environment variables
+	cacheNegativeTTLEnvKey = string("CACHE_NEGATIVE_TTL")             //	key for "cacheNegativeTTL" in environment variables
+	tracingExporterEnvKey  = string("TRACING_EXPORTER")               //	key for "tracingExporter" in environment variables
+	tracingFileEnvKey      = string("TRACING_FILE")                   //	key for "tracingFile" in environment variables
+	jwtSecretEnvKey        = string("JWT_SECRET")                     //	key for "jwtSecret" in environment variables
+	jwtKeysFileEnvKey      = string("JWT_KEYS_FILE")                  //	key for "jwtKeysFile" in environment variables
+	jwtSecretFileEnvKey    = string("JWT_SECRET_FILE")                //	key for "jwtSecretFile" in environment variables
+	jwtTokenExpEnvKey      = string("JWT_TOKEN_EXP")                  //	key for "jwtTokenExp" in environment variables
+	cookieHTTPOnlyEnvKey   = string("COOKIE_HTTP_ONLY")               //	key for "cookieHTTPOnly" in environment variables
+	cookieSecureEnvKey     = string("COOKIE_SECURE")                  //	key for "cookieSecure" in environment variables
+	cookieSameSiteEnvKey   = string("COOKIE_SAME_SITE")               //	key for "cookieSameSite" in environment variables
+	cookieDomainEnvKey     = string("COOKIE_DOMAIN")                  //	key for "cookieDomain" in environment variables
+	adminUserIDsEnvKey     = string("ADMIN_USER_IDS")                 //	key for "adminUserIDs" in environment variables
+	compressMinSizeEnvKey  = string("COMPRESS_MIN_SIZE")              //	key for "compressMinSize" in environment variables
+	compressLevelsEnvKey   = string("COMPRESS_LEVELS")                //	key for "compressLevels" in environment variables
+	enableHTTPSEnvKey      = string("ENABLE_HTTPS")                   //	key for "enableHTTPS" in environment variables
+	tlsCertFileEnvKey      = string("TLS_CERT_FILE")                  //	key for "tls.CertFile" in environment variables
+	tlsKeyFileEnvKey       = string("TLS_KEY_FILE")                   //	key for "tls.KeyFile" in environment variables
+	tlsMinVersionEnvKey    = string("TLS_MIN_VERSION")                //	key for "tls.MinVersion" in environment variables
+	tlsCipherPolicyEnvKey  = string("TLS_CIPHER_POLICY")              //	key for "tls.CipherPolicy" in environment variables
+	tlsClientAuthEnvKey    = string("TLS_CLIENT_AUTH")                //	key for "tls.ClientAuth" in environment variables
+	tlsClientCAFileEnvKey  = string("TLS_CLIENT_CA_FILE")             //	key for "tls.ClientCAFile" in environment variables
+	httpsRedirectEnvKey    = string("HTTPS_REDIRECT_ADDRESS")         //	key for "httpsRedirectAddress" in environment variables
+	trustedProxiesEnvKey   = string("TRUSTED_PROXIES")                //	key for "trustedProxies" in environment variables
 )
 
 // StorageType - enumeration type for Storage
@@ -46,53 +108,474 @@ const (
 	aliasesFileDefault = "/tmp/short-url-db.json"        //	Default file name of URLs storage
 	usersFileDefault   = "/tmp/users-db.json"
 	logToFileDefault   = false //	How to save log default value
+
+	aliasCharsetDefault = aliasmaker.AliasCharsetDefault //	Characters allowed in custom aliases
+	aliasMinLenDefault  = aliasmaker.AliasMinLenDefault  //	Minimum length of custom alias
+	aliasMaxLenDefault  = aliasmaker.AliasMaxLenDefault  //	Maximum length of custom alias
+	aliasMaxLenLimit    = 64                             //	Size of short key column in storage
+
+	expireIntervalDefault = aliasmaker.ExpireIntervalDefault //	Period of marking expired aliases
+
+	keyGeneratorDefault = aliasmaker.KeyGeneratorSequential //	Strategy of short key generation
+	keyLengthDefault    = aliasmaker.KeyLenDefault          //	Length of generated short keys
+	keyRangeSizeDefault = aliasmaker.KeyRangeSizeDefault    //	Count of keys an instance reserves in storage at once
+
+	shutdownTimeoutDefault = 10 * time.Second //	Time to finish in-flight requests on shutdown
+
+	fileFsyncDefault            = false   //	Flush every write of file storage to the disk
+	fileCompactThresholdDefault = 4 << 20 //	Size of garbage in aliases file which triggers compaction
+
+	cacheSizeDefault        = 0                            //	Count of cached redirect lookups, 0 - cache is disabled
+	cacheTTLDefault         = cachestor.TTLDefault         //	How long a found alias is cached
+	cacheNegativeTTLDefault = cachestor.NegativeTTLDefault //	How long a short key which is not found is cached
+
+	tracingExporterDefault = tracing.ExporterNone       //	Where spans are written, by default they are not recorded
+	tracingFileDefault     = "/tmp/urlalias-spans.json" //	File of spans for "file" exporter
+
+	jwtTokenExpDefault    = jwtauth.TokenExpDefault //	Lifetime of tokens
+	cookieHTTPOnlyDefault = true                    //	Token cookie is not available to scripts
+	cookieSecureDefault   = false                   //	Token cookie is sent over HTTPS only
+	cookieSameSiteDefault = "lax"                   //	Token cookie is not sent with cross-site subrequests
+
+	compressMinSizeDefault = server.CompressMinSizeDefault //	Responses shorter than this size in bytes are not compressed
+
+	enableHTTPSDefault     = false                         //	Server serves plain HTTP
+	tlsMinVersionDefault   = tlsconfig.VersionDefault      //	Minimum TLS version of HTTPS server
+	tlsCipherPolicyDefault = tlsconfig.CipherPolicyDefault //	Cipher suites of HTTPS server
+	tlsClientAuthDefault   = tlsconfig.ClientAuthNone      //	Client certificates are not requested
 )
 
 // ------------------------------------------------------------
 //
 //	Struct of configuration vars
 type Configuration struct {
-	host    string //	Server addres
-	baseURL string //	Base URL for create alias
+	configFile string            //	configuration file, empty - it is not used
+	values     map[string]string //	values of settings which are set by the file, environment or flags, by keys of the file
+
+	host        string //	Server addres
+	baseURL     string //	Base URL for create alias
+	grpcAddress string //	gRPC server addres, empty - gRPC server is disabled
+
+	trustedProxies []netip.Prefix //	proxies whose "X-Forwarded-For" header is trusted, empty - the header is ignored
+
+	metricsAddress string //	metrics server addres, empty - metrics are disabled
+
+	enableHTTPS          bool               //	server serves HTTPS instead of HTTP
+	tls                  tlsconfig.Settings //	certificate, key, minimum version, cipher suites and client certificates of HTTPS server
+	httpsRedirectAddress string             //	addres of HTTP listener which redirects to HTTPS, empty - it is disabled
+
+	tracingExporter string //	where spans are written: none, stdout or file
+	tracingFile     string //	file of spans for "file" exporter
+
+	jwtSecret     string        //	HS256 secret of tokens, it is used if key set file is not set
+	jwtKeysFile   string        //	file of key set of tokens
+	jwtSecretFile string        //	file of HS256 secret which is generated if the file does not exist, it is used if neither key set file nor secret is set
+	jwtTokenExp   time.Duration //	lifetime of tokens
+
+	cookieHTTPOnly bool          //	token cookie is not available to scripts
+	cookieSecure   bool          //	token cookie is sent over HTTPS only
+	cookieSameSite http.SameSite //	token cookie is not sent with cross-site requests
+	cookieDomain   string        //	token cookie is sent to subdomains of the domain, empty - to the host only
+
+	adminUserIDs []uint64 //	IDs of registered users with the admin role
+
+	compressMinSize int                         //	responses shorter than this size in bytes are not compressed
+	compressLevels  map[server.CompressType]int //	compression levels by type, the default level of the type if it is missing
 
 	aliasesFile  string // File name of URLs storage
 	usersFile    string
+	clicksFile   string
+	keysFile     string //	File name of key ranges storage
+	apiKeysFile  string //	File name of API keys storage
 	dbConnection string
 
+	fileFsync            bool  //	flush every write of file storage to the disk
+	fileCompactThreshold int64 //	size of garbage in aliases file which triggers compaction, 0 - never
+
 	storageType StorageType
 
-	logToFile bool //	true - save log to file, false - print log to console
+	cacheSize        int           //	count of cached redirect lookups, 0 - cache is disabled
+	cacheTTL         time.Duration //	how long a found alias is cached
+	cacheNegativeTTL time.Duration //	how long a short key which is not found is cached, 0 - misses are not cached
+
+	logToFile bool   //	true - save log to file, false - print log to console
+	logLevel  string //	minimum level of log messages
+
+	aliasCharset string //	Characters allowed in custom aliases
+	aliasMinLen  int    //	Minimum length of custom alias
+	aliasMaxLen  int    //	Maximum length of custom alias
+
+	expireInterval time.Duration //	Period of marking expired aliases
+
+	keyGenerator string //	Strategy of short key generation
+	keyLength    int    //	Length of generated short keys
+	keySalt      string //	Secret of obfuscated key generator
+	keyRangeSize uint64 //	Count of keys an instance reserves in storage at once
+
+	shutdownTimeout time.Duration //	Time to finish in-flight requests on shutdown
 }
 
-// Common config variable
-var config *Configuration
+// ------------------------------------------------------------
+//
+//	Setting of configuration. It is read from the configuration file by key,
+//	from environment variable env and from flag, the empty ones are not used
+type setting struct {
+	key       string //	key in the configuration file
+	env       string //	environment variable
+	flag      string //	command line flag
+	usage     string //	description of the flag
+	isBool    bool   //	the flag can be set without value
+	flagEmpty string //	value of the flag which is set to empty string
+	reload    bool   //	the setting can be changed while the service is running
+	set       func(c *Configuration, value string) error
+}
+
+// settings are all settings of configuration
+var settings = []setting{
+	{key: "server_address", env: hostEnvKey, flag: "a", usage: "Server IP addres and port for server starting.\n\tFor example: 192.168.1.2:80",
+		set: func(c *Configuration, v string) error {
+			if err := checkServerAddres(v); err != nil {
+				return err
+			}
+			c.host = v
+			return nil
+		}},
+	{key: "base_url", env: baseURLEnvKey, flag: "b", usage: "Response base addres for alias URL.\n\tFor example: http://192.168.1.2",
+		set: func(c *Configuration, v string) error {
+			if err := checkBaseURL(v); err != nil {
+				return err
+			}
+			c.baseURL = v
+			return nil
+		}},
+	{key: "enable_https", env: enableHTTPSEnvKey, flag: "s", isBool: true, usage: "Serve HTTPS instead of HTTP, certificate and key files must be set",
+		set: func(c *Configuration, v string) (err error) {
+			c.enableHTTPS, err = parseBool(v, c.enableHTTPS)
+			return err
+		}},
+	{key: "tls_cert_file", env: tlsCertFileEnvKey, flag: "tls-cert", usage: "PEM file of HTTPS server certificate",
+		set: func(c *Configuration, v string) error {
+			c.tls.CertFile = v
+			return nil
+		}},
+	{key: "tls_key_file", env: tlsKeyFileEnvKey, flag: "tls-key", usage: "PEM file of HTTPS server private key",
+		set: func(c *Configuration, v string) error {
+			c.tls.KeyFile = v
+			return nil
+		}},
+	{key: "tls_min_version", env: tlsMinVersionEnvKey, flag: "tls-min-version", usage: "Minimum TLS version of HTTPS server: 1.2 or 1.3",
+		set: func(c *Configuration, v string) error {
+			if _, err := tlsconfig.ParseVersion(v); err != nil {
+				return err
+			}
+			c.tls.MinVersion = v
+			return nil
+		}},
+	{key: "tls_cipher_policy", env: tlsCipherPolicyEnvKey, flag: "tls-cipher-policy", usage: "TLS 1.2 cipher suites of HTTPS server: default or modern (ECDHE with AEAD ciphers only)",
+		set: func(c *Configuration, v string) error {
+			if err := tlsconfig.CheckCipherPolicy(v); err != nil {
+				return err
+			}
+			c.tls.CipherPolicy = v
+			return nil
+		}},
+	{key: "tls_client_auth", env: tlsClientAuthEnvKey, flag: "tls-client-auth", usage: "Verification of client certificates (mTLS): none, optional or require",
+		set: func(c *Configuration, v string) error {
+			if _, err := tlsconfig.ParseClientAuth(v); err != nil {
+				return err
+			}
+			c.tls.ClientAuth = v
+			return nil
+		}},
+	{key: "tls_client_ca_file", env: tlsClientCAFileEnvKey, flag: "tls-client-ca", usage: "PEM file of CA certificates which sign client certificates",
+		set: func(c *Configuration, v string) error {
+			c.tls.ClientCAFile = v
+			return nil
+		}},
+	{key: "https_redirect_address", env: httpsRedirectEnvKey, flag: "https-redirect-address", usage: "IP addres and port of HTTP listener which redirects to HTTPS. It is disabled if empty.\n\tFor example: 192.168.1.2:80",
+		set: func(c *Configuration, v string) error {
+			if v != "" {
+				if err := checkServerAddres(v); err != nil {
+					return err
+				}
+			}
+			c.httpsRedirectAddress = v
+			return nil
+		}},
+	{key: "trusted_proxies", env: trustedProxiesEnvKey, flag: "trusted-proxies", usage: "Comma separated IP addresses or networks of proxies whose X-Forwarded-For header gives the client address.\n\tThe header is ignored if empty. For example: 10.0.0.1,192.168.0.0/16",
+		set: func(c *Configuration, v string) error {
+			proxies, err := server.ParseTrustedProxies(parseList(v))
+			if err != nil {
+				return err
+			}
+			c.trustedProxies = proxies
+			return nil
+		}},
+	{key: "grpc_address", env: grpcAddressEnvKey, flag: "g", usage: "gRPC server IP addres and port. gRPC server is disabled if empty.\n\tFor example: 192.168.1.2:3200",
+		set: func(c *Configuration, v string) error {
+			if v != "" {
+				if err := checkServerAddres(v); err != nil {
+					return err
+				}
+			}
+			c.grpcAddress = v
+			return nil
+		}},
+	{key: "metrics_address", env: metricsAddressEnvKey, flag: "metrics-address", usage: "Metrics server IP addres and port. Metrics are disabled if empty.\n\tFor example: 127.0.0.1:9090",
+		set: func(c *Configuration, v string) error {
+			if v != "" {
+				if err := checkServerAddres(v); err != nil {
+					return err
+				}
+			}
+			c.metricsAddress = v
+			return nil
+		}},
+	{key: "tracing_exporter", env: tracingExporterEnvKey, flag: "tracing-exporter", usage: "Where spans are written: none, stdout or file",
+		set: func(c *Configuration, v string) error {
+			if err := checkTracingExporter(v); err != nil {
+				return err
+			}
+			c.tracingExporter = v
+			return nil
+		}},
+	{key: "tracing_file", env: tracingFileEnvKey, flag: "tracing-file", usage: "File of spans for \"file\" exporter",
+		set: func(c *Configuration, v string) error {
+			if v != "" {
+				c.tracingFile = v
+			}
+			return nil
+		}},
+	{key: "jwt_secret", reload: true, env: jwtSecretEnvKey, flag: "jwt-secret", usage: "HS256 secret of tokens, at least 32 bytes. It is used if key set file is not set.\n\tPrefer environment variable JWT_SECRET, flags are visible to other users",
+		set: func(c *Configuration, v string) error {
+			c.jwtSecret = v
+			return nil
+		}},
+	{key: "jwt_keys_file", reload: true, env: jwtKeysFileEnvKey, flag: "jwt-keys-file", usage: "JSON file of key set of tokens",
+		set: func(c *Configuration, v string) error {
+			c.jwtKeysFile = v
+			return nil
+		}},
+	{key: "jwt_secret_file", env: jwtSecretFileEnvKey, flag: "jwt-secret-file", usage: "File of HS256 secret of tokens, the secret is generated if the file does not exist.\n\tIt is used if neither key set file nor secret is set. Default: next to the storage file",
+		set: func(c *Configuration, v string) error {
+			c.jwtSecretFile = v
+			return nil
+		}},
+	{key: "jwt_token_exp", reload: true, env: jwtTokenExpEnvKey, flag: "jwt-token-exp", usage: "Lifetime of tokens.\n\tFor example: 24h",
+		set: func(c *Configuration, v string) (err error) {
+			c.jwtTokenExp, err = parseDuration(v, c.jwtTokenExp, false)
+			return err
+		}},
+	{key: "cookie_http_only", reload: true, env: cookieHTTPOnlyEnvKey, flag: "cookie-http-only", isBool: true, usage: "Token cookie is not available to scripts",
+		set: func(c *Configuration, v string) (err error) {
+			c.cookieHTTPOnly, err = parseBool(v, c.cookieHTTPOnly)
+			return err
+		}},
+	{key: "cookie_secure", reload: true, env: cookieSecureEnvKey, flag: "cookie-secure", isBool: true, usage: "Token cookie is sent over HTTPS only",
+		set: func(c *Configuration, v string) (err error) {
+			c.cookieSecure, err = parseBool(v, c.cookieSecure)
+			return err
+		}},
+	{key: "cookie_same_site", reload: true, env: cookieSameSiteEnvKey, flag: "cookie-same-site", usage: "SameSite attribute of token cookie: lax, strict, none or default",
+		set: func(c *Configuration, v string) error {
+			sameSite, err := parseSameSite(v)
+			if err != nil {
+				return err
+			}
+			c.cookieSameSite = sameSite
+			return nil
+		}},
+	{key: "cookie_domain", reload: true, env: cookieDomainEnvKey, flag: "cookie-domain", usage: "Domain attribute of token cookie, empty - the cookie is sent to the host only",
+		set: func(c *Configuration, v string) error {
+			c.cookieDomain = v
+			return nil
+		}},
+	{key: "admin_user_ids", reload: true, env: adminUserIDsEnvKey, flag: "admin-user-ids", usage: "Comma separated IDs of registered users with the admin role.\n\tFor example: 1,42",
+		set: func(c *Configuration, v string) (err error) {
+			c.adminUserIDs, err = parseUserIDs(v)
+			return err
+		}},
+	{key: "compress_min_size", reload: true, env: compressMinSizeEnvKey, flag: "compress-min-size", usage: "Responses shorter than this size in bytes are not compressed",
+		set: func(c *Configuration, v string) error {
+			n, err := parseInt(v, 0)
+			if err == nil {
+				c.compressMinSize = int(n)
+			}
+			return err
+		}},
+	{key: "compress_levels", reload: true, env: compressLevelsEnvKey, flag: "compress-levels", usage: "Comma separated compression levels by type: gzip and deflate 1-9, br 0-11, zstd 1-22.\n\tFor example: gzip=6,br=4",
+		set: func(c *Configuration, v string) error {
+			levels, err := parseCompressLevels(v)
+			if err == nil {
+				c.compressLevels = levels
+			}
+			return err
+		}},
+	{key: "log_level", reload: true, env: logLevelEnvKey, flag: "log-level", usage: "Minimum level of log messages: debug, info, warn or error",
+		set: func(c *Configuration, v string) error {
+			if err := zaplogger.CheckLevel(v); err != nil {
+				return err
+			}
+			c.logLevel = v
+			return nil
+		}},
+	{key: "log_to_file", env: logToFileEnvKey, flag: "l", isBool: true, usage: "Variant of logger: true - save log to file, false - print log to console",
+		set: func(c *Configuration, v string) (err error) {
+			c.logToFile, err = parseBool(v, c.logToFile)
+			return err
+		}},
+	{key: "file_storage_path", env: storageFileEnvKey, flag: "f", flagEmpty: aliasesFileDefault, usage: "File name of URLs storage. Specify the full name of the file",
+		set: func(c *Configuration, v string) error {
+			c.aliasesFile = v
+			c.usersFile = v + "-users"
+			c.clicksFile = v + "-clicks"
+			c.keysFile = v + "-keys"
+			c.apiKeysFile = v + "-apikeys"
+			return nil
+		}},
+	{key: "database_dsn", env: dbConnectionEnvKey, flag: "d", usage: "data base connection string",
+		set: func(c *Configuration, v string) error {
+			c.dbConnection = v
+			return nil
+		}},
+	{key: "file_storage_fsync", env: fileFsyncEnvKey, flag: "file-fsync", isBool: true, usage: "Flush every write of file storage to the disk",
+		set: func(c *Configuration, v string) (err error) {
+			c.fileFsync, err = parseBool(v, c.fileFsync)
+			return err
+		}},
+	{key: "file_storage_compact_threshold", env: fileCompactEnvKey, flag: "file-compact-threshold", usage: "Size in bytes of deleted and superseded records in file storage which triggers compaction, 0 - never",
+		set: func(c *Configuration, v string) error {
+			n, err := parseInt(v, 0)
+			if err == nil {
+				c.fileCompactThreshold = n
+			}
+			return err
+		}},
+	{key: "cache_size", env: cacheSizeEnvKey, flag: "cache-size", usage: "Count of cached redirect lookups, 0 - cache is disabled",
+		set: func(c *Configuration, v string) error {
+			n, err := parseInt(v, 0)
+			if err == nil {
+				c.cacheSize = int(n)
+			}
+			return err
+		}},
+	{key: "cache_ttl", env: cacheTTLEnvKey, flag: "cache-ttl", usage: "How long a found alias is cached.\n\tFor example: 1m",
+		set: func(c *Configuration, v string) (err error) {
+			c.cacheTTL, err = parseDuration(v, c.cacheTTL, false)
+			return err
+		}},
+	{key: "cache_negative_ttl", env: cacheNegativeTTLEnvKey, flag: "cache-negative-ttl", usage: "How long a short key which is not found is cached, 0 - misses are not cached.\n\tFor example: 10s",
+		set: func(c *Configuration, v string) (err error) {
+			c.cacheNegativeTTL, err = parseDuration(v, c.cacheNegativeTTL, true)
+			return err
+		}},
+	{key: "alias_charset", reload: true, env: aliasCharsetEnvKey, flag: "alias-charset", usage: "Characters allowed in custom aliases",
+		set: func(c *Configuration, v string) error {
+			c.aliasCharset = v
+			return nil
+		}},
+	{key: "alias_min_len", reload: true, env: aliasMinLenEnvKey, flag: "alias-min-len", usage: "Minimum length of custom alias",
+		set: func(c *Configuration, v string) error {
+			n, err := strconv.Atoi(v)
+			if err == nil {
+				c.aliasMinLen = n
+			}
+			return err
+		}},
+	{key: "alias_max_len", reload: true, env: aliasMaxLenEnvKey, flag: "alias-max-len", usage: "Maximum length of custom alias",
+		set: func(c *Configuration, v string) error {
+			n, err := strconv.Atoi(v)
+			if err == nil {
+				c.aliasMaxLen = n
+			}
+			return err
+		}},
+	{key: "expire_interval", env: expireIntervalEnvKey, flag: "expire-interval", usage: "Period of marking expired aliases.\n\tFor example: 30s",
+		set: func(c *Configuration, v string) (err error) {
+			c.expireInterval, err = parseDuration(v, c.expireInterval, false)
+			return err
+		}},
+	{key: "key_generator", env: keyGeneratorEnvKey, flag: "key-generator", usage: "Strategy of short key generation: sequential, random or obfuscated",
+		set: func(c *Configuration, v string) error {
+			c.keyGenerator = v
+			return nil
+		}},
+	{key: "key_length", env: keyLengthEnvKey, flag: "key-length", usage: "Length of generated short keys",
+		set: func(c *Configuration, v string) error {
+			n, err := strconv.Atoi(v)
+			if err == nil {
+				c.keyLength = n
+			}
+			return err
+		}},
+	{key: "key_salt", env: keySaltEnvKey, flag: "key-salt", usage: "Secret of obfuscated key generator. It must not change while storage is used",
+		set: func(c *Configuration, v string) error {
+			c.keySalt = v
+			return nil
+		}},
+	{key: "key_range_size", env: keyRangeSizeEnvKey, flag: "key-range-size", usage: "Count of keys an instance reserves in storage at once",
+		set: func(c *Configuration, v string) error {
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err == nil && n == 0 {
+				err = errors.New("must be greater than 0")
+			}
+			if err == nil {
+				c.keyRangeSize = n
+			}
+			return err
+		}},
+	{key: "shutdown_timeout", env: shutdownTimeoutEnvKey, flag: "shutdown-timeout", usage: "Time to finish in-flight requests on shutdown.\n\tFor example: 15s",
+		set: func(c *Configuration, v string) (err error) {
+			c.shutdownTimeout, err = parseDuration(v, c.shutdownTimeout, false)
+			return err
+		}},
+}
 
 // ------------------------------------------------------------
 //
-//	Constructor of Config type
+//	Constructor of Config type. The configuration is read from the command line,
+//	environment variables and the configuration file, see Load
 //	Output:
 //		*Config
-func NewConfig() *Configuration {
+//		err error - all errors of settings, flag.ErrHelp if help is requested
+func NewConfig() (*Configuration, error) {
 
-	if config != nil {
-		return config
+	config, err := Load(os.Args[1:], os.LookupEnv)
+	if err != nil {
+		return nil, err
 	}
 
-	config = new(Configuration)
-
-	//	Fill default values
-	config.host = hostDefault
-	config.baseURL = baseURLDefault
-	config.logToFile = logToFileDefault
-	config.storageType = MemoryStor
-
-	config.parseFlags()
-	config.parseEnv()
-
-	config.chooseStorageType()
-
 	log.Printf("Server address: \"%s\"\n", config.host)
-	log.Printf("Base URL: \"%s\"\n", config.host)
+	log.Printf("Base URL: \"%s\"\n", config.baseURL)
+	if config.enableHTTPS {
+		log.Printf("HTTPS is enabled, certificate: \"%s\", minimum TLS version: %s, cipher policy: %s\n", config.tls.CertFile, config.tls.MinVersion, config.tls.CipherPolicy)
+		if config.tls.ClientAuth != tlsconfig.ClientAuthNone {
+			log.Printf("Client certificates: %s, CA file: \"%s\"\n", config.tls.ClientAuth, config.tls.ClientCAFile)
+		}
+		if config.httpsRedirectAddress != "" {
+			log.Printf("HTTP requests to \"%s\" are redirected to HTTPS\n", config.httpsRedirectAddress)
+		}
+		if strings.HasPrefix(config.baseURL, "http://") {
+			log.Print("HTTPS is enabled, but base URL is http://, short URLs are not secure")
+		}
+	} else if strings.HasPrefix(config.baseURL, "https://") {
+		log.Print("Base URL is https://, but HTTPS is not enabled: a TLS proxy must serve it")
+	}
+	if config.configFile != "" {
+		log.Printf("Configuration file: \"%s\"\n", config.configFile)
+	}
+	if config.grpcAddress != "" {
+		log.Printf("gRPC server address: \"%s\"\n", config.grpcAddress)
+	}
+	if config.metricsAddress != "" {
+		log.Printf("Metrics server address: \"%s\"\n", config.metricsAddress)
+	}
+	switch config.tracingExporter {
+	case tracing.ExporterStdout:
+		log.Print("Spans are written to stdout")
+	case tracing.ExporterFile:
+		log.Printf("Spans file: \"%s\"\n", config.tracingFile)
+	}
 
 	switch config.storageType {
 	case DataBaseStor:
@@ -104,7 +587,406 @@ func NewConfig() *Configuration {
 	}
 
 	log.Printf("Save log to file: \"%t\"\n", config.logToFile)
-	return config
+	switch {
+	case config.jwtKeysFile != "":
+		log.Printf("Token keys file: \"%s\"\n", config.jwtKeysFile)
+	case config.jwtSecret != "":
+		//	the secret itself is not logged
+	case config.jwtSecretFile != "":
+		log.Printf("Token secret file: \"%s\"\n", config.jwtSecretFile)
+	case config.storageType == MemoryStor:
+		log.Print("Token secret is not set, tokens are valid only until restart")
+	}
+	if config.cookieSameSite == http.SameSiteNoneMode && !config.cookieSecure {
+		log.Print("Token cookie with SameSite=None must be secure, browsers reject it")
+	}
+	if config.cacheSize > 0 {
+		log.Printf("Cache of redirect lookups: %d entries, ttl %s, negative ttl %s\n", config.cacheSize, config.cacheTTL, config.cacheNegativeTTL)
+	}
+	log.Printf("Custom alias length: %d..%d\n", config.aliasMinLen, config.aliasMaxLen)
+	log.Printf("Key generator: \"%s\", key length: %d\n", config.keyGenerator, config.keyLength)
+	return config, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Load configuration. Defaults are overridden by the configuration file, the file - by environment
+//	variables, environment variables - by flags. The file is set by flag "-c" or environment variable "CONFIG",
+//	it is JSON or YAML if its extension is ".yaml" or ".yml"
+//	Input:
+//		args []string - command line arguments without the program name
+//		lookupEnv func(key string) (string, bool) - source of environment variables, like os.LookupEnv
+//	Output:
+//		*Configuration
+//		err error - all errors of settings joined, flag.ErrHelp if help is requested
+func Load(args []string, lookupEnv func(key string) (string, bool)) (*Configuration, error) {
+
+	c := newDefaultConfig()
+
+	flagValues, configFile, err := parseFlags(args)
+	if err != nil {
+		return nil, err
+	}
+	if configFile == "" {
+		configFile, _ = lookupEnv(configFileEnvKey)
+	}
+
+	var errs []error
+	if configFile != "" {
+		c.configFile = configFile
+		errs = append(errs, c.loadFile(configFile)...)
+	}
+
+	for _, s := range settings {
+		if s.env == "" {
+			continue
+		}
+		if v, ok := lookupEnv(s.env); ok {
+			if err := c.apply(&s, v); err != nil {
+				errs = append(errs, fmt.Errorf("environment variable \"%s\": %w", s.env, err))
+			}
+		}
+	}
+
+	for _, fv := range flagValues {
+		if err := c.apply(fv.setting, fv.value); err != nil {
+			errs = append(errs, fmt.Errorf("flag -%s: %w", fv.setting.flag, err))
+		}
+	}
+
+	if err := checkAliasRules(c.aliasCharset, c.aliasMinLen, c.aliasMaxLen); err != nil {
+		errs = append(errs, err)
+	}
+	if err := checkKeyGenerator(c.keyGenerator, c.keyLength); err != nil {
+		errs = append(errs, err)
+	}
+	if err := checkHTTPS(c); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	c.chooseStorageType()
+	if c.jwtSecretFile == "" && c.storageType == FileStor {
+		c.jwtSecretFile = c.aliasesFile + "-jwt-secret"
+	}
+	return c, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Set the value of setting and remember it
+func (c *Configuration) apply(s *setting, v string) error {
+
+	if err := s.set(c, v); err != nil {
+		return err
+	}
+	c.values[s.key] = v
+	return nil
+}
+
+// ------------------------------------------------------------
+//
+//	Changes returns keys of settings which have other values in next configuration
+//	Input:
+//		next *Configuration - for example, the configuration loaded again
+//	Output:
+//		reloadable []string - the settings can be changed while the service is running
+//		restartRequired []string - the settings are changed only by restart
+func (c *Configuration) Changes(next *Configuration) (reloadable, restartRequired []string) {
+
+	for _, s := range settings {
+
+		v, ok := c.values[s.key]
+		nextV, nextOK := next.values[s.key]
+		if ok == nextOK && v == nextV {
+			continue
+		}
+		if s.reload {
+			reloadable = append(reloadable, s.key)
+		} else {
+			restartRequired = append(restartRequired, s.key)
+		}
+	}
+	return reloadable, restartRequired
+}
+
+// ------------------------------------------------------------
+//
+//	Configuration with default values
+func newDefaultConfig() *Configuration {
+
+	c := &Configuration{
+		host:                 hostDefault,
+		baseURL:              baseURLDefault,
+		logToFile:            logToFileDefault,
+		logLevel:             zaplogger.LevelDefault,
+		values:               make(map[string]string),
+		storageType:          MemoryStor,
+		aliasCharset:         aliasCharsetDefault,
+		aliasMinLen:          aliasMinLenDefault,
+		aliasMaxLen:          aliasMaxLenDefault,
+		expireInterval:       expireIntervalDefault,
+		keyGenerator:         keyGeneratorDefault,
+		keyLength:            keyLengthDefault,
+		keyRangeSize:         keyRangeSizeDefault,
+		shutdownTimeout:      shutdownTimeoutDefault,
+		fileFsync:            fileFsyncDefault,
+		fileCompactThreshold: fileCompactThresholdDefault,
+		cacheSize:            cacheSizeDefault,
+		cacheTTL:             cacheTTLDefault,
+		cacheNegativeTTL:     cacheNegativeTTLDefault,
+		tracingExporter:      tracingExporterDefault,
+		tracingFile:          tracingFileDefault,
+		jwtTokenExp:          jwtTokenExpDefault,
+		cookieHTTPOnly:       cookieHTTPOnlyDefault,
+		cookieSecure:         cookieSecureDefault,
+		compressMinSize:      compressMinSizeDefault,
+		enableHTTPS:          enableHTTPSDefault,
+		tls: tlsconfig.Settings{
+			MinVersion:   tlsMinVersionDefault,
+			CipherPolicy: tlsCipherPolicyDefault,
+			ClientAuth:   tlsClientAuthDefault,
+		},
+	}
+	c.cookieSameSite, _ = parseSameSite(cookieSameSiteDefault)
+	return c
+}
+
+// ------------------------------------------------------------
+//
+//	Value of flag which is set in command line
+type flagValue struct {
+	setting *setting
+	value   string
+}
+
+// ------------------------------------------------------------
+//
+//	flag.Value which records values of the setting in order of command line
+type flagRecorder struct {
+	setting *setting
+	values  *[]flagValue
+}
+
+// String returns nothing, so usage of flags has no defaults: they are layered
+func (f *flagRecorder) String() string {
+	return ""
+}
+
+// Set records the value of flag
+func (f *flagRecorder) Set(value string) error {
+
+	if value == "" && f.setting.flagEmpty != "" {
+		value = f.setting.flagEmpty
+	}
+	*f.values = append(*f.values, flagValue{setting: f.setting, value: value})
+	return nil
+}
+
+// IsBoolFlag lets boolean flags be set without value
+func (f *flagRecorder) IsBoolFlag() bool {
+	return f.setting.isBool
+}
+
+// ------------------------------------------------------------
+//
+//	Parse command line
+//	Input:
+//		args []string - command line arguments without the program name
+//	Output:
+//		[]flagValue - values of settings in order of command line
+//		configFile string - value of flag "-c"
+//		err error
+func parseFlags(args []string) ([]flagValue, string, error) {
+
+	var (
+		values     []flagValue
+		configFile string
+	)
+
+	flags := flag.NewFlagSet(AppName, flag.ContinueOnError)
+	flags.StringVar(&configFile, configFileFlag, "", "Configuration file, JSON or YAML (\".yaml\", \".yml\").\n\tIts settings are overridden by environment variables and flags")
+	for i := range settings {
+		if settings[i].flag != "" {
+			flags.Var(&flagRecorder{setting: &settings[i], values: &values}, settings[i].flag, settings[i].usage)
+		}
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, "", err
+	}
+	return values, configFile, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Load settings from the configuration file
+//	Input:
+//		name string - JSON file or YAML file with extension ".yaml" or ".yml"
+//	Output:
+//		[]error - errors of the file and its settings
+func (c *Configuration) loadFile(name string) []error {
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return []error{fmt.Errorf("configuration file: %w", err)}
+	}
+
+	values := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&values)
+	}
+	if err != nil {
+		return []error{fmt.Errorf("configuration file \"%s\": %w", name, err)}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+
+		s := findSetting(key)
+		if s == nil {
+			errs = append(errs, fmt.Errorf("configuration file key \"%s\": unknown setting", key))
+			continue
+		}
+		v, err := fileValueString(values[key])
+		if err == nil {
+			err = c.apply(s, v)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("configuration file key \"%s\": %w", key, err))
+		}
+	}
+	return errs
+}
+
+// ------------------------------------------------------------
+//
+//	Find setting by key of the configuration file
+func findSetting(key string) *setting {
+
+	for i := range settings {
+		if settings[i].key == key {
+			return &settings[i]
+		}
+	}
+	return nil
+}
+
+// ------------------------------------------------------------
+//
+//	Convert value of the configuration file to the form of environment variables:
+//	lists are comma separated, objects are comma separated "key=value" pairs
+//	Input:
+//		v any - value decoded from JSON or YAML
+//	Output:
+//		string
+//		err error
+func fileValueString(v any) (string, error) {
+
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case json.Number:
+		return v.String(), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		items := make([]string, len(v))
+		for i := range v {
+			item, err := fileValueString(v[i])
+			if err != nil {
+				return "", err
+			}
+			items[i] = item
+		}
+		return strings.Join(items, ","), nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, key := range keys {
+			item, err := fileValueString(v[key])
+			if err != nil {
+				return "", err
+			}
+			items[i] = key + "=" + item
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value: %v", v)
+	}
+}
+
+// ------------------------------------------------------------
+//
+//	Parse boolean setting, current value is kept if v is not valid
+func parseBool(v string, current bool) (bool, error) {
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return current, err
+	}
+	return b, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Parse integer setting which is not less than min
+func parseInt(v string, min int64) (int64, error) {
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n < min {
+		return 0, fmt.Errorf("must not be less than %d", min)
+	}
+	return n, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Parse duration setting, which is positive or may be zero if zeroAllowed.
+//	Current value is kept if v is not valid
+func parseDuration(v string, current time.Duration, zeroAllowed bool) (time.Duration, error) {
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return current, err
+	}
+	if d < 0 || (d == 0 && !zeroAllowed) {
+		return current, fmt.Errorf("duration must be positive: %s", v)
+	}
+	return d, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.configFile"
+func (c *Configuration) ConfigFile() string {
+	return c.configFile
 }
 
 // ------------------------------------------------------------
@@ -116,6 +998,20 @@ func (c *Configuration) Host() string {
 	return c.host
 }
 
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.grpcAddress"
+func (c *Configuration) GRPCAddress() string {
+	return c.grpcAddress
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.metricsAddress"
+func (c *Configuration) MetricsAddress() string {
+	return c.metricsAddress
+}
+
 // ------------------------------------------------------------
 //
 //	Getter "Configuration.baseURL"
@@ -123,6 +1019,13 @@ func (c *Configuration) BaseURL() string {
 	return c.baseURL
 }
 
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.trustedProxies"
+func (c *Configuration) TrustedProxies() []netip.Prefix {
+	return c.trustedProxies
+}
+
 // ------------------------------------------------------------
 //
 //	Getter "Configuration.AliasesFile"
@@ -137,6 +1040,27 @@ func (c *Configuration) UsersFile() string {
 	return c.usersFile
 }
 
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.KeysFile"
+func (c *Configuration) KeysFile() string {
+	return c.keysFile
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.APIKeysFile"
+func (c *Configuration) APIKeysFile() string {
+	return c.apiKeysFile
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.ClicksFile"
+func (c *Configuration) ClicksFile() string {
+	return c.clicksFile
+}
+
 // ------------------------------------------------------------
 //
 //	Getter "Configuration.DBConnection"
@@ -144,6 +1068,20 @@ func (c *Configuration) DBConnection() string {
 	return c.dbConnection
 }
 
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.fileFsync"
+func (c *Configuration) FileFsync() bool {
+	return c.fileFsync
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.fileCompactThreshold"
+func (c *Configuration) FileCompactThreshold() int64 {
+	return c.fileCompactThreshold
+}
+
 // ------------------------------------------------------------
 //
 //	Getter "Configuration.StorageType"
@@ -162,76 +1100,205 @@ func (c *Configuration) LogToFile() bool {
 
 // ------------------------------------------------------------
 //
-//	Parse flags method of "Config" type
-func (c *Configuration) parseFlags() {
+//	Getter "Configuration.cacheSize"
+func (c *Configuration) CacheSize() int {
+	return c.cacheSize
+}
 
-	host := flag.String("a", hostDefault, "Server IP addres and port for server starting.\n\tFor example: 192.168.1.2:80")
-	baseURL := flag.String("b", baseURLDefault, "Response base addres for alias URL.\n\tFor example: http://192.168.1.2")
-	logToFile := flag.Bool("l", logToFileDefault, "Variant of logger: true - save log to file, false - print log to console")
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.cacheTTL"
+func (c *Configuration) CacheTTL() time.Duration {
+	return c.cacheTTL
+}
 
-	storageFile := ""
-	flag.Func("f", "File name of URLs storage. Specify the full name of the file", func(s string) error {
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.cacheNegativeTTL"
+func (c *Configuration) CacheNegativeTTL() time.Duration {
+	return c.cacheNegativeTTL
+}
 
-		//	TODO need to parce file path to check valid
-		if s == "" {
-			storageFile = aliasesFileDefault
-		} else {
-			storageFile = s
-		}
-		return nil
-	})
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.jwtSecret"
+func (c *Configuration) JWTSecret() string {
+	return c.jwtSecret
+}
 
-	dbConnection := flag.String("d", "", "data base connection string")
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.jwtKeysFile"
+func (c *Configuration) JWTKeysFile() string {
+	return c.jwtKeysFile
+}
 
-	flag.Parse()
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.jwtSecretFile"
+func (c *Configuration) JWTSecretFile() string {
+	return c.jwtSecretFile
+}
 
-	if err := checkServerAddres(*host); err == nil {
-		c.host = *host
-	}
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.jwtTokenExp"
+func (c *Configuration) JWTTokenExp() time.Duration {
+	return c.jwtTokenExp
+}
 
-	if err := checkBaseURL(*baseURL); err == nil {
-		c.baseURL = *baseURL
-	}
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.cookieHTTPOnly"
+func (c *Configuration) CookieHTTPOnly() bool {
+	return c.cookieHTTPOnly
+}
 
-	c.logToFile = *logToFile
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.cookieSecure"
+func (c *Configuration) CookieSecure() bool {
+	return c.cookieSecure
+}
 
-	c.dbConnection = *dbConnection
-	c.aliasesFile = storageFile
-	c.usersFile = storageFile + "-users"
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.cookieSameSite"
+func (c *Configuration) CookieSameSite() http.SameSite {
+	return c.cookieSameSite
 }
 
 // ------------------------------------------------------------
 //
-//	Parse environment variables method of "Config" type
-func (c *Configuration) parseEnv() {
+//	Getter "Configuration.cookieDomain"
+func (c *Configuration) CookieDomain() string {
+	return c.cookieDomain
+}
 
-	if host, ok := os.LookupEnv(hostEnvKey); ok {
-		if err := checkServerAddres(host); err == nil {
-			c.host = host
-		} else {
-			log.Printf("The environment variable \"%s\" is written in the wrong format: %s", hostEnvKey, host)
-		}
-	}
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.adminUserIDs"
+func (c *Configuration) AdminUserIDs() []uint64 {
+	return c.adminUserIDs
+}
 
-	//	get baseURL from environment variables
-	if baseURL, ok := os.LookupEnv(baseURLEnvKey); ok {
-		if err := checkBaseURL(baseURL); err == nil {
-			c.baseURL = baseURL
-		} else {
-			log.Printf("The environment variable \"%s\" is written in the wrong format: %s", baseURLEnvKey, baseURL)
-		}
-	}
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.compressMinSize"
+func (c *Configuration) CompressMinSize() int {
+	return c.compressMinSize
+}
 
-	//	get storage file from environment variables
-	if storageFile, ok := os.LookupEnv(storageFileEnvKey); ok {
-		c.aliasesFile = storageFile
-		c.usersFile = storageFile + "-users"
-	}
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.compressLevels"
+func (c *Configuration) CompressLevels() map[server.CompressType]int {
+	return c.compressLevels
+}
 
-	//	get storage file from environment variables
-	if dbConnection, ok := os.LookupEnv(dbConnectionEnvKey); ok {
-		c.dbConnection = dbConnection
-	}
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.enableHTTPS"
+func (c *Configuration) EnableHTTPS() bool {
+	return c.enableHTTPS
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.tls"
+func (c *Configuration) TLS() tlsconfig.Settings {
+	return c.tls
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.httpsRedirectAddress"
+func (c *Configuration) HTTPSRedirectAddress() string {
+	return c.httpsRedirectAddress
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.logLevel"
+func (c *Configuration) LogLevel() string {
+	return c.logLevel
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.tracingExporter"
+func (c *Configuration) TracingExporter() string {
+	return c.tracingExporter
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.tracingFile"
+func (c *Configuration) TracingFile() string {
+	return c.tracingFile
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.aliasCharset"
+func (c *Configuration) AliasCharset() string {
+	return c.aliasCharset
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.aliasMinLen"
+func (c *Configuration) AliasMinLen() int {
+	return c.aliasMinLen
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.aliasMaxLen"
+func (c *Configuration) AliasMaxLen() int {
+	return c.aliasMaxLen
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.expireInterval"
+func (c *Configuration) ExpireInterval() time.Duration {
+	return c.expireInterval
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.keyGenerator"
+func (c *Configuration) KeyGenerator() string {
+	return c.keyGenerator
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.keyLength"
+func (c *Configuration) KeyLength() int {
+	return c.keyLength
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.keySalt"
+func (c *Configuration) KeySalt() string {
+	return c.keySalt
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.keyRangeSize"
+func (c *Configuration) KeyRangeSize() uint64 {
+	return c.keyRangeSize
+}
+
+// ------------------------------------------------------------
+//
+//	Getter "Configuration.shutdownTimeout"
+func (c *Configuration) ShutdownTimeout() time.Duration {
+	return c.shutdownTimeout
 }
 
 // ------------------------------------------------------------
@@ -248,6 +1315,71 @@ func (c *Configuration) chooseStorageType() {
 	}
 }
 
+// ------------------------------------------------------------
+//
+//	Split comma separated list, empty items are dropped
+func parseList(s string) []string {
+
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ------------------------------------------------------------
+//
+//	Parse comma separated user IDs
+//	Input:
+//		s string - for example: 1,42
+//	Output:
+//		[]uint64
+//		err error
+func parseUserIDs(s string) ([]uint64, error) {
+
+	var userIDs []uint64
+	for _, item := range parseList(s) {
+		userID, err := strconv.ParseUint(item, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("user ID must be a number: %s", item)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Parse compression levels by type
+//	Input:
+//		s string - for example: gzip=6,br=4
+//	Output:
+//		map[server.CompressType]int
+//		err error
+func parseCompressLevels(s string) (map[server.CompressType]int, error) {
+
+	levels := make(map[server.CompressType]int)
+	for _, item := range parseList(s) {
+
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("compression level must be written as type=level: %s", item)
+		}
+		compressType := server.CompressType(strings.ToLower(strings.TrimSpace(name)))
+		level, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("compression level of %s is not a number: %s", compressType, value)
+		}
+		if err = server.CheckCompressionLevel(compressType, level); err != nil {
+			return nil, err
+		}
+		levels[compressType] = level
+	}
+	return levels, nil
+}
+
 // ------------------------------------------------------------
 //
 //	Check format IP addres and port.
@@ -305,3 +1437,118 @@ func checkBaseURL(baseURLFromOpt string) error {
 
 	return nil
 }
+
+// ------------------------------------------------------------
+//
+//	Check rules of custom aliases.
+//	Input:
+//		charset string - characters allowed in custom aliases
+//		minLen, maxLen int - length range of custom alias
+//	Output:
+//		err error
+func checkAliasRules(charset string, minLen, maxLen int) error {
+
+	if charset == "" {
+		return fmt.Errorf("charset of custom aliases is empty")
+	}
+
+	if strings.ContainsRune(charset, '/') {
+		return fmt.Errorf("charset of custom aliases can't contain \"/\"")
+	}
+
+	if minLen < 1 || maxLen < minLen {
+		return fmt.Errorf("length range of custom aliases is not right: %d..%d", minLen, maxLen)
+	}
+
+	if maxLen > aliasMaxLenLimit {
+		return fmt.Errorf("maximum length of custom alias can't be greater than %d", aliasMaxLenLimit)
+	}
+	return nil
+}
+
+// ------------------------------------------------------------
+//
+//	Check settings of short key generator.
+//	Input:
+//		name string - name of key generator
+//		keyLen int - length of generated keys
+//	Output:
+//		err error
+func checkKeyGenerator(name string, keyLen int) error {
+
+	switch name {
+	case aliasmaker.KeyGeneratorSequential, aliasmaker.KeyGeneratorRandom, aliasmaker.KeyGeneratorObfuscated:
+	default:
+		return fmt.Errorf("unknown key generator: %s", name)
+	}
+
+	if keyLen < 1 || keyLen > aliasMaxLenLimit {
+		return fmt.Errorf("length of generated keys must be from 1 to %d", aliasMaxLenLimit)
+	}
+	return nil
+}
+
+// ------------------------------------------------------------
+//
+//	Check name of tracing exporter
+//	Input:
+//		name string - none, stdout or file
+//	Output:
+//		err error
+func checkTracingExporter(name string) error {
+
+	switch name {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterFile:
+		return nil
+	default:
+		return fmt.Errorf("unknown tracing exporter: %s", name)
+	}
+}
+
+// ------------------------------------------------------------
+//
+//	Check settings of HTTPS. Files of certificates are read by the server,
+//	only their names are checked here
+func checkHTTPS(c *Configuration) error {
+
+	if !c.enableHTTPS {
+		if c.httpsRedirectAddress != "" {
+			return errors.New("redirect to HTTPS needs HTTPS to be enabled")
+		}
+		return nil
+	}
+	if c.tls.CertFile == "" || c.tls.KeyFile == "" {
+		return errors.New("HTTPS is enabled, but certificate or key file is not set")
+	}
+	if c.tls.ClientAuth != tlsconfig.ClientAuthNone && c.tls.ClientCAFile == "" {
+		return fmt.Errorf("client certificates are %s, but client CA file is not set", c.tls.ClientAuth)
+	}
+	if c.httpsRedirectAddress != "" && c.httpsRedirectAddress == c.host {
+		return errors.New("redirect to HTTPS and the server have the same addres")
+	}
+	return nil
+}
+
+// ------------------------------------------------------------
+//
+//	Parse SameSite attribute of cookie
+//	Input:
+//		name string - lax, strict, none or default
+//	Output:
+//		http.SameSite
+//		err error
+func parseSameSite(name string) (http.SameSite, error) {
+
+	switch strings.ToLower(name) {
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	case "default":
+		return http.SameSiteDefaultMode, nil
+	default:
+		return 0, fmt.Errorf("unknown SameSite attribute: %s", name)
+	}
+}
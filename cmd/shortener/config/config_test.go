@@ -0,0 +1,200 @@
+package config
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Schalure/urlalias/internal/app/server"
+	"github.com/Schalure/urlalias/internal/app/tlsconfig"
+)
+
+// env returns source of environment variables from the map
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
+// writeFile writes the configuration file to the test directory
+func writeFile(t *testing.T, name, content string) string {
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+
+	c, err := Load(nil, env(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, hostDefault, c.Host())
+	assert.Equal(t, baseURLDefault, c.BaseURL())
+	assert.Equal(t, MemoryStor, c.StorageType())
+	assert.Equal(t, shutdownTimeoutDefault, c.ShutdownTimeout())
+	assert.Equal(t, compressMinSizeDefault, c.CompressMinSize())
+	assert.True(t, c.CookieHTTPOnly())
+	assert.Empty(t, c.ConfigFile())
+	assert.Empty(t, c.TrustedProxies())
+	assert.Empty(t, c.JWTSecretFile())
+}
+
+func TestLoad_Precedence(t *testing.T) {
+
+	file := writeFile(t, "config.json", `{
+		"server_address": "localhost:8081",
+		"base_url": "http://localhost:8081",
+		"shutdown_timeout": "20s",
+		"cache_size": 100,
+		"cookie_secure": true,
+		"admin_user_ids": [1, 42],
+		"compress_levels": {"gzip": 1, "br": 9},
+		"file_storage_path": "/tmp/from-file.json"
+	}`)
+
+	vars := map[string]string{
+		"CONFIG":           file,
+		"SERVER_ADDRESS":   "localhost:8082",
+		"SHUTDOWN_TIMEOUT": "30s",
+		"TRUSTED_PROXIES":  "10.0.0.1, 172.16.0.0/12",
+	}
+	c, err := Load([]string{"-a", "localhost:8083", "-l"}, env(vars))
+	require.NoError(t, err)
+
+	assert.Equal(t, file, c.ConfigFile())
+	assert.Equal(t, "localhost:8083", c.Host(), "flags override environment")
+	assert.Equal(t, 30*time.Second, c.ShutdownTimeout(), "environment overrides the file")
+	assert.Equal(t, "http://localhost:8081", c.BaseURL(), "the file overrides defaults")
+	assert.Equal(t, 100, c.CacheSize())
+	assert.True(t, c.CookieSecure())
+	assert.True(t, c.LogToFile())
+	assert.Equal(t, []uint64{1, 42}, c.AdminUserIDs())
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.1/32"), netip.MustParsePrefix("172.16.0.0/12")}, c.TrustedProxies())
+	assert.Equal(t, map[server.CompressType]int{server.TypeGZIP: 1, server.TypeBrotli: 9}, c.CompressLevels())
+	assert.Equal(t, FileStor, c.StorageType())
+	assert.Equal(t, "/tmp/from-file.json-users", c.UsersFile())
+	assert.Equal(t, "/tmp/from-file.json-jwt-secret", c.JWTSecretFile(), "the secret is kept next to the storage file")
+
+	//	the file from flag is preferred, configurations are independent
+	other := writeFile(t, "config.yaml", "server_address: localhost:9000\nkey_generator: random\nkey_length: 12\n")
+	c2, err := Load([]string{"-c", other}, env(vars))
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:8082", c2.Host())
+	assert.Equal(t, "random", c2.KeyGenerator())
+	assert.Equal(t, 12, c2.KeyLength())
+	assert.Equal(t, "localhost:8083", c.Host())
+}
+
+func TestLoad_Errors(t *testing.T) {
+
+	file := writeFile(t, "config.json", `{"server_address": "nowhere", "unknown": 1, "cache_size": -1}`)
+	vars := map[string]string{
+		"CACHE_TTL":       "soon",
+		"ALIAS_MIN_LEN":   "10",
+		"ALIAS_MAX_LEN":   "5",
+		"TRUSTED_PROXIES": "proxy.local",
+		"ADMIN_USER_IDS":  "admin",
+	}
+
+	_, err := Load([]string{"-c", file, "-compress-levels", "gzip=42", "-cookie-secure=maybe"}, env(vars))
+	require.Error(t, err)
+
+	for _, part := range []string{
+		`configuration file key "server_address"`,
+		`configuration file key "unknown": unknown setting`,
+		`configuration file key "cache_size"`,
+		`environment variable "CACHE_TTL"`,
+		`environment variable "TRUSTED_PROXIES"`,
+		`environment variable "ADMIN_USER_IDS"`,
+		`flag -compress-levels`,
+		`flag -cookie-secure`,
+		`length range of custom aliases`,
+	} {
+		assert.Contains(t, err.Error(), part)
+	}
+
+	_, err = Load([]string{"-c", filepath.Join(t.TempDir(), "missing.json")}, env(nil))
+	assert.ErrorContains(t, err, "configuration file")
+
+	_, err = Load([]string{"-unknown-flag"}, env(nil))
+	assert.Error(t, err)
+}
+
+func TestLoad_HTTPS(t *testing.T) {
+
+	c, err := Load(nil, env(nil))
+	require.NoError(t, err)
+	assert.False(t, c.EnableHTTPS())
+	assert.Equal(t, tlsconfig.Settings{MinVersion: tlsconfig.VersionDefault, CipherPolicy: tlsconfig.CipherPolicyDefault, ClientAuth: tlsconfig.ClientAuthNone}, c.TLS())
+
+	vars := map[string]string{
+		"ENABLE_HTTPS":       "true",
+		"TLS_CERT_FILE":      "/etc/urlalias/cert.pem",
+		"TLS_KEY_FILE":       "/etc/urlalias/key.pem",
+		"TLS_CLIENT_CA_FILE": "/etc/urlalias/ca.pem",
+	}
+	c, err = Load([]string{"-tls-min-version", "1.3", "-tls-cipher-policy", "modern", "-tls-client-auth", "require", "-https-redirect-address", "localhost:8081"}, env(vars))
+	require.NoError(t, err)
+	assert.True(t, c.EnableHTTPS())
+	assert.Equal(t, tlsconfig.Settings{
+		CertFile:     "/etc/urlalias/cert.pem",
+		KeyFile:      "/etc/urlalias/key.pem",
+		MinVersion:   tlsconfig.Version13,
+		CipherPolicy: tlsconfig.CipherPolicyModern,
+		ClientAuth:   tlsconfig.ClientAuthRequire,
+		ClientCAFile: "/etc/urlalias/ca.pem",
+	}, c.TLS())
+	assert.Equal(t, "localhost:8081", c.HTTPSRedirectAddress())
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "no certificate", args: []string{"-s"}, want: "certificate or key file is not set"},
+		{name: "client CA is not set", args: []string{"-s", "-tls-cert", "c.pem", "-tls-key", "k.pem", "-tls-client-auth", "optional"}, want: "client CA file is not set"},
+		{name: "redirect without HTTPS", args: []string{"-https-redirect-address", "localhost:8081"}, want: "needs HTTPS to be enabled"},
+		{name: "redirect to itself", args: []string{"-s", "-tls-cert", "c.pem", "-tls-key", "k.pem", "-https-redirect-address", hostDefault}, want: "the same addres"},
+		{name: "unknown version", args: []string{"-tls-min-version", "1.0"}, want: "flag -tls-min-version"},
+		{name: "unknown cipher policy", args: []string{"-tls-cipher-policy", "legacy"}, want: "flag -tls-cipher-policy"},
+		{name: "unknown client auth", args: []string{"-tls-client-auth", "always"}, want: "flag -tls-client-auth"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.args, env(nil))
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestConfiguration_Changes(t *testing.T) {
+
+	file := writeFile(t, "config.json", `{"server_address": "localhost:8081", "log_level": "info", "admin_user_ids": "1"}`)
+	vars := map[string]string{"CONFIG": file}
+
+	current, err := Load(nil, env(vars))
+	require.NoError(t, err)
+
+	same, err := Load(nil, env(vars))
+	require.NoError(t, err)
+	reloadable, restartRequired := current.Changes(same)
+	assert.Empty(t, reloadable)
+	assert.Empty(t, restartRequired)
+
+	//	the file is edited: one setting is changed, one is added and one is removed
+	require.NoError(t, os.WriteFile(file, []byte(`{"server_address": "localhost:8082", "log_level": "debug", "cookie_secure": true}`), 0o600))
+	next, err := Load(nil, env(vars))
+	require.NoError(t, err)
+	assert.Equal(t, "debug", next.LogLevel())
+
+	reloadable, restartRequired = current.Changes(next)
+	assert.Equal(t, []string{"cookie_secure", "admin_user_ids", "log_level"}, reloadable)
+	assert.Equal(t, []string{"server_address"}, restartRequired)
+}
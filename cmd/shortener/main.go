@@ -3,17 +3,29 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	_ "net/http/pprof"
 
 	"github.com/Schalure/urlalias/cmd/shortener/config"
 	"github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
 	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/grpcserver"
+	"github.com/Schalure/urlalias/internal/app/jwtauth"
+	"github.com/Schalure/urlalias/internal/app/metrics"
 	"github.com/Schalure/urlalias/internal/app/server"
 	"github.com/Schalure/urlalias/internal/app/storage"
+	"github.com/Schalure/urlalias/internal/app/tlsconfig"
+	"github.com/Schalure/urlalias/internal/app/tracing"
+	"google.golang.org/grpc"
 )
 
 var (
@@ -31,39 +43,170 @@ var (
 func main() {
 	fmt.Printf("Build version: %s\nBuild date: %s\nBuild commit: %s\n", buildVersion, buildDate, buildCommit)
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalln("Error, while migrating data base!", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "cert" {
+		if err := runCert(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			log.Fatalln("Error, while generating certificate!", err)
+		}
+		return
+	}
+
 	log.Println("Start initialize application...")
-	ctxStop, cancelStop := context.WithCancel(context.Background())
+	ctxStop, cancelStop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	defer cancelStop()
 
 	log.Println("Cofiguration initialize...")
-	conf := config.NewConfig()
+	conf, err := config.NewConfig()
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		log.Fatalln("Error, while initialization configuration!", err)
+	}
 
 	log.Println("Logger initialize...")
 	logger, err := zaplogger.NewZapLogger("")
 	if err != nil {
 		log.Fatalln("Error, while initialization logger!", err)
 	}
+	if err = logger.SetLevel(conf.LogLevel()); err != nil {
+		log.Fatalln("Error, while initialization logger!", err)
+	}
+
+	var appMetrics *metrics.Metrics
+	if conf.MetricsAddress() != "" {
+		appMetrics = metrics.New()
+	}
+
+	var appTracing *tracing.Tracing
+	if conf.TracingExporter() != tracing.ExporterNone {
+		log.Println("Tracing initialize...")
+		if appTracing, err = tracing.New(config.AppName, conf.TracingExporter(), conf.TracingFile()); err != nil {
+			log.Fatalln("Error, while initialization tracing!", err)
+		}
+	}
 
 	log.Println("Storage initialize...")
-	stor, err := storage.NewStorage(conf)
+	stor, err := storage.NewStorage(conf, appMetrics, appTracing)
 	if err != nil {
 		log.Fatalln("Error, while initialization storage!", err)
 	}
 
 	log.Println("Alias maker service initialize...")
-	service, err := aliasmaker.New(stor, logger)
+	keyGenerator, err := aliasmaker.NewKeyGenerator(conf.KeyGenerator(), conf.KeyLength(), conf.KeySalt(), conf.KeyRangeSize(), stor)
+	if err != nil {
+		log.Fatalln("Error, while initialization key generator!", err)
+	}
+	serviceOptions := []aliasmaker.Option{
+		aliasmaker.WithExpireInterval(conf.ExpireInterval()),
+		aliasmaker.WithKeyGenerator(keyGenerator),
+	}
+	if appMetrics != nil {
+		serviceOptions = append(serviceOptions, aliasmaker.WithMetrics(appMetrics))
+	}
+	service, err := aliasmaker.New(stor, logger, serviceOptions...)
 	if err != nil {
 		log.Fatalln("Error, while initialization Alias maker service!", err)
 	}
-	service.Run(ctxStop)
-	defer service.Stop()
+	service.Reconfigure(serviceSettings(conf))
+	//	workers are stopped by service.Stop after the servers have finished,
+	//	so deletions requested by in-flight requests are not lost
+	service.Run(context.Background())
+
+	log.Println("Authentication initialize...")
+	fallbackKey, err := fallbackAuthKey(conf)
+	if err != nil {
+		log.Fatalln("Error, while initialization authentication!", err)
+	}
+	keys, activeID, err := authKeySet(conf, fallbackKey)
+	if err != nil {
+		log.Fatalln("Error, while initialization authentication!", err)
+	}
+	auth, err := jwtauth.New(keys, activeID, jwtauth.WithTokenExp(conf.JWTTokenExp()))
+	if err != nil {
+		log.Fatalln("Error, while initialization authentication!", err)
+	}
 
 	log.Println("Router initialize...")
-	router := server.NewRouter(server.New(service, service, logger, conf.BaseURL()))
+	var routerMiddlewares []func(http.Handler) http.Handler
+	if appTracing != nil {
+		routerMiddlewares = append(routerMiddlewares, appTracing.HTTPMiddleware)
+	}
+	if appMetrics != nil {
+		routerMiddlewares = append(routerMiddlewares, appMetrics.HTTPMiddleware)
+	}
+	configReloader := &reloader{
+		started:     conf,
+		current:     conf,
+		fallbackKey: fallbackKey,
+		logger:      logger,
+		auth:        auth,
+		service:     service,
+	}
+	settings := serverSettings(conf)
+	handler := server.New(service, service, auth, logger, conf.BaseURL(),
+		server.WithCookieAttributes(settings.Cookie),
+		server.WithCompression(settings.Compression),
+		server.WithAdministrator(service),
+		server.WithReloader(configReloader),
+		server.WithTrustedProxies(conf.TrustedProxies()...),
+	)
+	configReloader.handler = handler
+	router := server.NewRouter(handler, routerMiddlewares...)
+
+	var metricsServer *http.Server
+	if appMetrics != nil {
+		log.Println("Metrics server initialize...")
+		appMetrics.RegisterDeleteQueue(service.DeleteQueueLen)
+
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", appMetrics.Handler())
+		metricsServer = &http.Server{
+			Addr:    conf.MetricsAddress(),
+			Handler: metricsMux,
+		}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Errorw("Metrics server stoped!", "error", err)
+			}
+		}()
+	}
+
+	var grpcServer *grpc.Server
+	if conf.GRPCAddress() != "" {
+		log.Println("gRPC server initialize...")
+		listener, err := net.Listen("tcp", conf.GRPCAddress())
+		if err != nil {
+			log.Fatalln("Error, while initialization gRPC server!", err)
+		}
+		var grpcInterceptors []grpc.UnaryServerInterceptor
+		if appTracing != nil {
+			grpcInterceptors = append(grpcInterceptors, appTracing.UnaryServerInterceptor)
+		}
+		grpcServer = grpcserver.NewServer(grpcserver.New(service, service, auth, logger, conf.BaseURL()), grpcInterceptors...)
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Errorw("gRPC server stoped!", "error", err)
+			}
+		}()
+	}
 
 	logger.Infow(
 		fmt.Sprintf("%s service have been started...", config.AppName),
 		"Server address", conf.Host(),
+		"HTTPS", conf.EnableHTTPS(),
+		"Redirect to HTTPS address", conf.HTTPSRedirectAddress(),
+		"gRPC server address", conf.GRPCAddress(),
+		"Metrics server address", conf.MetricsAddress(),
+		"Tracing exporter", conf.TracingExporter(),
 		"Base URL", conf.BaseURL(),
 		"Save log to file", conf.LogToFile(),
 		"Storage file", conf.AliasesFile(),
@@ -71,9 +214,159 @@ func main() {
 		"Storage type", conf.StorageType().String(),
 	)
 
-	err = http.ListenAndServe(conf.Host(), router)
-	logger.Fatalw(
-		"aliasURL service stoped!",
-		"error", err,
-	)
+	httpServer := &http.Server{
+		Addr:    conf.Host(),
+		Handler: router,
+	}
+	var redirectServer *http.Server
+	if conf.EnableHTTPS() {
+		log.Println("TLS initialize...")
+		if httpServer.TLSConfig, err = tlsconfig.New(conf.TLS()); err != nil {
+			log.Fatalln("Error, while initialization TLS!", err)
+		}
+		if conf.HTTPSRedirectAddress() != "" {
+			redirect, err := server.NewHTTPSRedirect(conf.Host(), conf.BaseURL())
+			if err != nil {
+				log.Fatalln("Error, while initialization redirect to HTTPS!", err)
+			}
+			redirectServer = &http.Server{
+				Addr:    conf.HTTPSRedirectAddress(),
+				Handler: redirect,
+			}
+			go func() {
+				if err := redirectServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					logger.Errorw("Redirect to HTTPS server stoped!", "error", err)
+				}
+			}()
+		}
+	}
+	go func() {
+		var err error
+		if httpServer.TLSConfig != nil {
+			//	certificate and key are in TLSConfig already
+			err = httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = httpServer.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorw("HTTP server stoped!", "error", err)
+			cancelStop()
+		}
+	}()
+
+	//	SIGHUP reloads the configuration
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-hangup:
+				logger.Info("SIGHUP received, reloading configuration...")
+				configReloader.Reload(ctxStop)
+			case <-ctxStop.Done():
+				signal.Stop(hangup)
+				return
+			}
+		}
+	}()
+
+	<-ctxStop.Done()
+	logger.Info("Shutdown signal received, stopping servers...")
+
+	ctxShutdown, cancelShutdown := context.WithTimeout(context.Background(), conf.ShutdownTimeout())
+	defer cancelShutdown()
+
+	if err := httpServer.Shutdown(ctxShutdown); err != nil {
+		logger.Errorw("HTTP server shutdown error", "error", err)
+	}
+	if redirectServer != nil {
+		if err := redirectServer.Shutdown(ctxShutdown); err != nil {
+			logger.Errorw("Redirect to HTTPS server shutdown error", "error", err)
+		}
+	}
+	if grpcServer != nil {
+		stopGRPC(ctxShutdown, grpcServer)
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctxShutdown); err != nil {
+			logger.Errorw("Metrics server shutdown error", "error", err)
+		}
+	}
+
+	service.Stop()
+	if appTracing != nil {
+		//	spans of the deletions finished by service.Stop are written too
+		if err := appTracing.Shutdown(ctxShutdown); err != nil {
+			log.Println("Tracing shutdown error", err)
+		}
+	}
+	log.Println("aliasURL service stoped")
+}
+
+// ------------------------------------------------------------
+//
+//	Key set of user tokens. Keys are loaded from the key set file,
+//	or HS256 secret is used. Without both of them fallback key is used,
+//	it is an error if there is no fallback key
+func authKeySet(conf *config.Configuration, fallback *jwtauth.Key) ([]jwtauth.Key, string, error) {
+
+	switch {
+	case conf.JWTKeysFile() != "":
+		return jwtauth.LoadKeySet(conf.JWTKeysFile())
+	case conf.JWTSecret() != "":
+		key, err := jwtauth.NewHMACKey("default", []byte(conf.JWTSecret()))
+		if err != nil {
+			return nil, "", err
+		}
+		return []jwtauth.Key{key}, key.ID, nil
+	case fallback != nil:
+		return []jwtauth.Key{*fallback}, fallback.ID, nil
+	default:
+		return nil, "", errors.New("token key is not set: set key set file, secret or secret file")
+	}
+}
+
+// ------------------------------------------------------------
+//
+//	Key which signs user tokens if neither key set file nor secret is set.
+//	The secret is kept in the secret file, so tokens are valid after restart and on other instances.
+//	A random key is used for memory storage without secret file, the links are lost on restart anyway.
+//	Without the key database storage can't be used, because instances would not accept tokens of each other
+func fallbackAuthKey(conf *config.Configuration) (*jwtauth.Key, error) {
+
+	var key jwtauth.Key
+	var err error
+	switch {
+	case conf.JWTKeysFile() != "" || conf.JWTSecret() != "":
+		return nil, nil
+	case conf.JWTSecretFile() != "":
+		key, err = jwtauth.LoadOrCreateHMACKey("generated", conf.JWTSecretFile())
+	case conf.StorageType() == config.MemoryStor:
+		key, err = jwtauth.GenerateHMACKey("generated")
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Stop gRPC server gracefully, force it to stop when
+//	ctx is done before all RPCs have finished
+func stopGRPC(ctx context.Context, grpcServer *grpc.Server) {
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
 }
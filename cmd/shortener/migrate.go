@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Schalure/urlalias/internal/app/storage/postgrestor"
+)
+
+// dbConnectionEnvKey is the environment variable with data base connection string, like the service uses
+const dbConnectionEnvKey = "DATABASE_DSN"
+
+const migrateUsage = `Usage: shortener migrate [-d connection string] command
+
+Commands:
+	up          apply all migrations which are not applied yet
+	down [N]    revert the last N applied migrations, 1 by default
+	version     print the version of the database schema
+`
+
+// ------------------------------------------------------------
+//
+//	Run "migrate" subcommand with its arguments
+func runMigrate(args []string) error {
+
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), migrateUsage)
+		flags.PrintDefaults()
+	}
+	dbConnection := flags.String("d", os.Getenv(dbConnectionEnvKey), "data base connection string")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *dbConnection == "" {
+		return errors.New("data base connection string is not set")
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("command is not set")
+	}
+
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, *dbConnection)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := postgrestor.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch flags.Arg(0) {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		printMigrations("applied", applied)
+		return err
+
+	case "down":
+		steps := 1
+		if flags.NArg() > 1 {
+			if steps, err = strconv.Atoi(flags.Arg(1)); err != nil || steps <= 0 {
+				return fmt.Errorf("invalid count of migrations: %s", flags.Arg(1))
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		printMigrations("reverted", reverted)
+		return err
+
+	case "version":
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("schema version: %d\n", version)
+		return nil
+
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown command: %s", flags.Arg(0))
+	}
+}
+
+// printMigrations prints the migrations which were applied or reverted
+func printMigrations(action string, migrations []postgrestor.Migration) {
+
+	if len(migrations) == 0 {
+		fmt.Printf("no migrations %s\n", action)
+		return
+	}
+	for _, migration := range migrations {
+		fmt.Printf("%s %04d_%s\n", action, migration.Version, migration.Name)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/Schalure/urlalias/cmd/shortener/config"
+	"github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/jwtauth"
+	"github.com/Schalure/urlalias/internal/app/server"
+)
+
+// ------------------------------------------------------------
+//
+//	Reloader of configuration. It loads the configuration again and applies the settings
+//	which can be changed to the running logger, token manager, service and server.
+//	Changes of other settings are logged, they need a restart
+type reloader struct {
+	mu          sync.Mutex
+	started     *config.Configuration //	started - the configuration the service has started with
+	current     *config.Configuration //	current - the configuration whose runtime settings are applied
+	fallbackKey *jwtauth.Key          //	fallbackKey - signs tokens if neither key set file nor secret is set, nil - there is no such key
+
+	logger  *zaplogger.ZapLogger
+	auth    *jwtauth.Manager
+	service *aliasmaker.AliasMakerServise
+	handler *server.Server
+}
+
+// ------------------------------------------------------------
+//
+//	Reload is the method of "server.Reloader" interface. Nothing is applied if the configuration is not valid
+//	Output:
+//		applied []string - keys of changed settings which are applied
+//		restartRequired []string - keys of changed settings which need a restart
+//		err error
+func (r *reloader) Reload(ctx context.Context) (applied, restartRequired []string, err error) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := config.Load(os.Args[1:], os.LookupEnv)
+	if err != nil {
+		r.logger.Errorw("Configuration is not reloaded", "error", err)
+		return nil, nil, err
+	}
+
+	//	the key set file is read again, even if its name is the same, so keys are rotated by editing it
+	keys, activeID, err := authKeySet(next, r.fallbackKey)
+	if err == nil {
+		err = r.auth.Update(keys, activeID, jwtauth.WithTokenExp(next.JWTTokenExp()))
+	}
+	if err != nil {
+		r.logger.Errorw("Configuration is not reloaded, token keys are not valid", "error", err)
+		return nil, nil, err
+	}
+
+	r.logger.SetLevel(next.LogLevel())
+	r.service.Reconfigure(serviceSettings(next))
+	r.handler.Reconfigure(serverSettings(next))
+
+	applied, _ = r.current.Changes(next)
+	_, restartRequired = r.started.Changes(next)
+	r.current = next
+
+	for _, key := range restartRequired {
+		r.logger.Errorw("Setting is changed, but it is not applied: restart the service to apply it", "setting", key)
+	}
+	r.logger.Infow("Configuration is reloaded", "applied", applied, "restart required", restartRequired)
+	return applied, restartRequired, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Runtime settings of the service from the configuration
+func serviceSettings(conf *config.Configuration) aliasmaker.RuntimeSettings {
+
+	return aliasmaker.RuntimeSettings{
+		AliasCharset: conf.AliasCharset(),
+		AliasMinLen:  conf.AliasMinLen(),
+		AliasMaxLen:  conf.AliasMaxLen(),
+		AdminUserIDs: conf.AdminUserIDs(),
+	}
+}
+
+// ------------------------------------------------------------
+//
+//	Runtime settings of the server from the configuration
+func serverSettings(conf *config.Configuration) server.RuntimeSettings {
+
+	return server.RuntimeSettings{
+		Cookie: server.CookieAttributes{
+			HTTPOnly: conf.CookieHTTPOnly(),
+			Secure:   conf.CookieSecure(),
+			SameSite: conf.CookieSameSite(),
+			Domain:   conf.CookieDomain(),
+		},
+		Compression: server.CompressionSettings{
+			MinSize: conf.CompressMinSize(),
+			Levels:  conf.CompressLevels(),
+		},
+	}
+}
@@ -4,12 +4,17 @@ import (
 	"fmt"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
+// LevelDefault is the minimum level of written messages by default
+const LevelDefault = "info"
+
 // ZapLogger struct
 type ZapLogger struct {
 	logger        *zap.Logger
 	sugaredLogger *zap.SugaredLogger
+	level         zap.AtomicLevel //	level - the minimum level of written messages, it can be changed while logger is used
 }
 
 // Constructor
@@ -19,7 +24,10 @@ func NewZapLogger(logFileName string) (*ZapLogger, error) {
 		panic("Save to log file not implemented")
 	}
 
-	aliasLogger, err := zap.NewDevelopment()
+	config := zap.NewDevelopmentConfig()
+	config.Level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
+	aliasLogger, err := config.Build()
 	if err != nil {
 		// вызываем панику, если ошибка
 		return nil, fmt.Errorf("cannot initialize zap: %s", err)
@@ -29,9 +37,28 @@ func NewZapLogger(logFileName string) (*ZapLogger, error) {
 	return &ZapLogger{
 		logger:        aliasLogger,
 		sugaredLogger: suggarLogger,
+		level:         config.Level,
 	}, nil
 }
 
+// SetLevel sets the minimum level of written messages: debug, info, warn, error, dpanic, panic or fatal
+func (l *ZapLogger) SetLevel(level string) error {
+
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	l.level.SetLevel(lvl)
+	return nil
+}
+
+// CheckLevel returns error if level is not a name of level
+func CheckLevel(level string) error {
+
+	_, err := zapcore.ParseLevel(level)
+	return err
+}
+
 // Info
 func (l *ZapLogger) Info(args ...interface{}) {
 	l.sugaredLogger.Info(args)
@@ -0,0 +1,113 @@
+package aliasmaker
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Schalure/urlalias/internal/app/models/userentity"
+)
+
+const (
+	passwordMinLen int = 8  //	minimum length of password in bytes
+	passwordMaxLen int = 72 //	bcrypt uses only the first 72 bytes of password
+)
+
+// loginPattern is the format of logins: 3-64 letters, digits, dots, underscores, hyphens and at signs
+var loginPattern = regexp.MustCompile(`^[A-Za-z0-9._@-]{3,64}$`)
+
+// dummyPasswordHash is compared with passwords of unknown logins, so they take as long to check as known ones
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// Access interface to storage of registered users
+type UserStorager interface {
+	FindUserByID(ctx context.Context, userID uint64) (*userentity.UserModel, error)
+	FindUserByLogin(ctx context.Context, login string) (*userentity.UserModel, error)
+	//	RegisterUser sets login and password hash of the anonymous user with user.UserID.
+	//	It returns false if the user is already registered or the login is taken
+	RegisterUser(ctx context.Context, user *userentity.UserModel) (bool, error)
+}
+
+// Register registers the user with login and password and returns ID of the user.
+// If claimUserID is an anonymous user, it becomes the registered user and keeps its aliases,
+// otherwise a new user is created
+func (s *AliasMakerServise) Register(ctx context.Context, login, password string, claimUserID *uint64) (userID uint64, err error) {
+
+	ctx, span := startSpan(ctx, "Register")
+	defer func() { endSpan(span, err) }()
+
+	if !loginPattern.MatchString(login) {
+		return 0, ErrInvalidLogin
+	}
+	if len(password) < passwordMinLen || len(password) > passwordMaxLen {
+		return 0, ErrInvalidPassword
+	}
+
+	//	the login is checked before a new user is created, RegisterUser checks it again
+	if _, err = s.storage.FindUserByLogin(ctx, login); err == nil {
+		return 0, ErrConflictLogin
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), s.passwordCost)
+	if err != nil {
+		s.logger.Errorw("can't hash password", "error", err)
+		return 0, ErrInternal
+	}
+
+	user := &userentity.UserModel{
+		Login:        login,
+		PasswordHash: string(passwordHash),
+	}
+	if claimUserID != nil {
+		if claimed, err := s.storage.FindUserByID(ctx, *claimUserID); err == nil && !claimed.IsRegistered() {
+			user.UserID = claimed.UserID
+		} else {
+			claimUserID = nil
+		}
+	}
+	if claimUserID == nil {
+		if user.UserID, err = s.storage.CreateUser(ctx); err != nil {
+			s.logger.Errorw("can't create user", "error", err)
+			return 0, ErrInternal
+		}
+	}
+	span.SetAttributes(userIDKey.Int64(int64(user.UserID)))
+
+	ok, err := s.storage.RegisterUser(ctx, user)
+	if err != nil {
+		s.logger.Errorw("can't register user", "error", err, "user id", user.UserID)
+		return 0, ErrInternal
+	}
+	if !ok {
+		return 0, ErrConflictLogin
+	}
+
+	s.logger.Infow("Register user", "userID", user.UserID, "claimed", claimUserID != nil)
+	return user.UserID, nil
+}
+
+// Login returns ID of the registered user if the password is right
+func (s *AliasMakerServise) Login(ctx context.Context, login, password string) (userID uint64, err error) {
+
+	ctx, span := startSpan(ctx, "Login")
+	defer func() { endSpan(span, err) }()
+
+	user, err := s.storage.FindUserByLogin(ctx, login)
+	if err != nil {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return 0, ErrInvalidCredentials
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	if err != nil {
+		if !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			s.logger.Errorw("can't check password", "error", err, "user id", user.UserID)
+		}
+		return 0, ErrInvalidCredentials
+	}
+
+	span.SetAttributes(userIDKey.Int64(int64(user.UserID)))
+	return user.UserID, nil
+}
@@ -0,0 +1,86 @@
+package aliasmaker
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
+	"github.com/Schalure/urlalias/internal/app/storage/memstor"
+)
+
+func Test_RegisterAndLogin(t *testing.T) {
+
+	ctx := context.Background()
+
+	stor, err := memstor.NewStorage()
+	require.NoError(t, err)
+
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	service, err := New(stor, logger, WithPasswordCost(bcrypt.MinCost))
+	require.NoError(t, err)
+
+	//	anonymous user made a link before registering
+	anonymousID, err := service.CreateUser(ctx)
+	require.NoError(t, err)
+	shortKey, err := service.GetShortKey(ctx, anonymousID, "https://example.com", "", nil)
+	require.NoError(t, err)
+
+	//	the anonymous user is claimed and keeps the link
+	userID, err := service.Register(ctx, "alice", "correct horse", &anonymousID)
+	require.NoError(t, err)
+	assert.Equal(t, anonymousID, userID)
+
+	aliases, err := service.GetUserAliases(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, aliases, 1)
+	assert.Equal(t, shortKey, aliases[0].ShortKey)
+
+	user, err := stor.FindUserByID(ctx, userID)
+	require.NoError(t, err)
+	assert.NotContains(t, user.PasswordHash, "correct horse")
+
+	loggedID, err := service.Login(ctx, "alice", "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, userID, loggedID)
+
+	_, err = service.Login(ctx, "alice", "wrong horse")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = service.Login(ctx, "nobody", "correct horse")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	//	registered user can't be claimed, a new user is created
+	otherID, err := service.Register(ctx, "bob", "battery staple", &userID)
+	require.NoError(t, err)
+	assert.NotEqual(t, userID, otherID)
+
+	//	registration without anonymous user creates a new user
+	thirdID, err := service.Register(ctx, "carol", "battery staple", nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, otherID, thirdID)
+
+	testCases := []struct {
+		name     string
+		login    string
+		password string
+		wantErr  error
+	}{
+		{name: "taken login", login: "alice", password: "other password", wantErr: ErrConflictLogin},
+		{name: "short login", login: "al", password: "other password", wantErr: ErrInvalidLogin},
+		{name: "login with spaces", login: "a l i c e", password: "other password", wantErr: ErrInvalidLogin},
+		{name: "short password", login: "dave", password: "short", wantErr: ErrInvalidPassword},
+		{name: "long password", login: "dave", password: strings.Repeat("p", passwordMaxLen+1), wantErr: ErrInvalidPassword},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := service.Register(ctx, test.login, test.password, nil)
+			assert.ErrorIs(t, err, test.wantErr)
+		})
+	}
+}
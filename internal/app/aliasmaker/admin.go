@@ -0,0 +1,157 @@
+package aliasmaker
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/models/statsentity"
+	"github.com/Schalure/urlalias/internal/app/models/userentity"
+)
+
+// Access interface to storage for administration
+type AdminStorager interface {
+	SearchAliases(ctx context.Context, filter aliasentity.Filter) ([]aliasentity.AliasURLModel, error)
+	//	SetDisabled disables or re-enables the alias, it returns false if the alias is not found
+	SetDisabled(ctx context.Context, shortKey string, disabled bool) (bool, error)
+	//	PurgeAlias removes the alias from the storage, it returns false if the alias is not found
+	PurgeAlias(ctx context.Context, shortKey string) (bool, error)
+	GetServiceStats(ctx context.Context, now time.Time) (*statsentity.ServiceStatsModel, error)
+}
+
+// GetUser returns the user by ID. The role of the user is taken from the current settings
+func (s *AliasMakerServise) GetUser(ctx context.Context, userID uint64) (user *userentity.UserModel, err error) {
+
+	ctx, span := startSpan(ctx, "GetUser", trace.WithAttributes(userIDKey.Int64(int64(userID))))
+	defer func() { endSpan(span, err) }()
+
+	user, err = s.storage.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	user.Role = s.roleOf(user)
+	return user, nil
+}
+
+// SearchAliases returns aliases of all users which match the filter, including deleted, expired and disabled ones
+func (s *AliasMakerServise) SearchAliases(ctx context.Context, adminID uint64, filter aliasentity.Filter) (aliases []aliasentity.AliasURLModel, err error) {
+
+	ctx, span := startSpan(ctx, "SearchAliases", trace.WithAttributes(userIDKey.Int64(int64(adminID))))
+	defer func() { endSpan(span, err) }()
+	defer func() {
+		s.audit(adminID, "search aliases", err, "query", filter.Query, "offset", filter.Offset, "limit", filter.Limit)
+	}()
+
+	aliases, err = s.storage.SearchAliases(ctx, filter)
+	if err != nil {
+		s.logger.Errorw("can't search aliases", "error", err)
+		return nil, ErrInternal
+	}
+	return aliases, nil
+}
+
+// ViewUserAliases returns all aliases of the user as the user sees them, including deleted, expired and disabled ones
+func (s *AliasMakerServise) ViewUserAliases(ctx context.Context, adminID, userID uint64) (aliases []aliasentity.AliasURLModel, err error) {
+
+	ctx, span := startSpan(ctx, "ViewUserAliases", trace.WithAttributes(userIDKey.Int64(int64(adminID))))
+	defer func() { endSpan(span, err) }()
+	defer func() { s.audit(adminID, "view user aliases", err, "user id", userID) }()
+
+	if _, err = s.storage.FindUserByID(ctx, userID); err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	aliases, err = s.storage.FindByUserID(ctx, userID)
+	if err != nil {
+		s.logger.Errorw("can't found aliases by user ID", "error", err, "user ID", userID)
+		return nil, ErrInternal
+	}
+	return aliases, nil
+}
+
+// SetAliasDisabled disables or re-enables the alias of any user. Redirects of disabled aliases fail
+func (s *AliasMakerServise) SetAliasDisabled(ctx context.Context, adminID uint64, shortKey string, disabled bool) (err error) {
+
+	ctx, span := startSpan(ctx, "SetAliasDisabled", trace.WithAttributes(userIDKey.Int64(int64(adminID)), shortKeyKey.String(shortKey)))
+	defer func() { endSpan(span, err) }()
+
+	action := "enable alias"
+	if disabled {
+		action = "disable alias"
+	}
+	defer func() { s.audit(adminID, action, err, "short key", shortKey) }()
+
+	ok, err := s.storage.SetDisabled(ctx, shortKey, disabled)
+	if err != nil {
+		s.logger.Errorw("can't set disabled flag", "error", err, "short key", shortKey)
+		return ErrInternal
+	}
+	if !ok {
+		return ErrURLNotFound
+	}
+	return nil
+}
+
+// PurgeAlias removes the alias of any user from the storage. Unlike deletion by the user,
+// nothing of the alias is kept, so its short key and original URL can be used again
+func (s *AliasMakerServise) PurgeAlias(ctx context.Context, adminID uint64, shortKey string) (err error) {
+
+	ctx, span := startSpan(ctx, "PurgeAlias", trace.WithAttributes(userIDKey.Int64(int64(adminID)), shortKeyKey.String(shortKey)))
+	defer func() { endSpan(span, err) }()
+
+	node, err := s.storage.FindByShortKey(ctx, shortKey)
+	if err != nil {
+		s.audit(adminID, "purge alias", ErrURLNotFound, "short key", shortKey)
+		return ErrURLNotFound
+	}
+	defer func() {
+		s.audit(adminID, "purge alias", err, "short key", shortKey, "user id", node.UserID, "original URL", node.LongURL)
+	}()
+
+	ok, err := s.storage.PurgeAlias(ctx, shortKey)
+	if err != nil {
+		s.logger.Errorw("can't purge alias", "error", err, "short key", shortKey)
+		return ErrInternal
+	}
+	if !ok {
+		return ErrURLNotFound
+	}
+	return nil
+}
+
+// GetServiceStats returns service-wide counts of users and aliases
+func (s *AliasMakerServise) GetServiceStats(ctx context.Context, adminID uint64) (stats *statsentity.ServiceStatsModel, err error) {
+
+	ctx, span := startSpan(ctx, "GetServiceStats", trace.WithAttributes(userIDKey.Int64(int64(adminID))))
+	defer func() { endSpan(span, err) }()
+	defer func() { s.audit(adminID, "get service stats", err) }()
+
+	stats, err = s.storage.GetServiceStats(ctx, time.Now())
+	if err != nil {
+		s.logger.Errorw("can't get service stats", "error", err)
+		return nil, ErrInternal
+	}
+	return stats, nil
+}
+
+// roleOf returns the role of the user by the current settings. Only the operator gives the admin role
+// by listing the ID of the registered user, so nobody gets it by registering a login
+func (s *AliasMakerServise) roleOf(user *userentity.UserModel) string {
+
+	if user.IsRegistered() && s.settings.Load().adminUserIDs[user.UserID] {
+		return userentity.RoleAdmin
+	}
+	return ""
+}
+
+// audit writes the record of the admin action to the log. Failed actions are recorded too
+func (s *AliasMakerServise) audit(adminID uint64, action string, err error, keysAndValues ...interface{}) {
+
+	keysAndValues = append([]interface{}{"admin id", adminID, "action", action}, keysAndValues...)
+	if err != nil {
+		keysAndValues = append(keysAndValues, "error", err)
+	}
+	s.logger.Infow("admin audit", keysAndValues...)
+}
@@ -0,0 +1,138 @@
+package aliasmaker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
+	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/storage/memstor"
+)
+
+func Test_Admin(t *testing.T) {
+
+	ctx := context.Background()
+
+	stor, err := memstor.NewStorage()
+	require.NoError(t, err)
+
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	service, err := New(stor, logger, WithPasswordCost(bcrypt.MinCost))
+	require.NoError(t, err)
+
+	//	the operator gives the role by ID of the registered user
+	adminID, err := service.Register(ctx, "root", "correct horse", nil)
+	require.NoError(t, err)
+	userID, err := service.Register(ctx, "alice", "correct horse", nil)
+	require.NoError(t, err)
+	WithAdminUserIDs(adminID)(service)
+
+	admin, err := service.GetUser(ctx, adminID)
+	require.NoError(t, err)
+	assert.True(t, admin.IsAdmin())
+	user, err := service.GetUser(ctx, userID)
+	require.NoError(t, err)
+	assert.False(t, user.IsAdmin())
+	_, err = service.GetUser(ctx, 100)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	//	the role is taken away at once, without login
+	WithAdminUserIDs()(service)
+	admin, err = service.GetUser(ctx, adminID)
+	require.NoError(t, err)
+	assert.False(t, admin.IsAdmin())
+
+	first, err := service.GetShortKey(ctx, userID, "https://example.com/1", "", nil)
+	require.NoError(t, err)
+	second, err := service.GetShortKey(ctx, userID, "https://example.com/2", "", nil)
+	require.NoError(t, err)
+
+	aliases, err := service.SearchAliases(ctx, adminID, aliasentity.Filter{Query: "example.com/2"})
+	require.NoError(t, err)
+	require.Len(t, aliases, 1)
+	assert.Equal(t, second, aliases[0].ShortKey)
+
+	//	disabled alias is not redirected until it is re-enabled
+	require.NoError(t, service.SetAliasDisabled(ctx, adminID, first, true))
+	_, err = service.GetOriginalURL(ctx, first)
+	assert.ErrorIs(t, err, ErrURLDisabled)
+
+	require.NoError(t, service.SetAliasDisabled(ctx, adminID, first, false))
+	originalURL, err := service.GetOriginalURL(ctx, first)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/1", originalURL)
+
+	assert.ErrorIs(t, service.SetAliasDisabled(ctx, adminID, "unknown", true), ErrURLNotFound)
+
+	//	the user sees the disabled alias, the admin sees deleted aliases too
+	require.NoError(t, service.SetAliasDisabled(ctx, adminID, first, true))
+	service.deleteAliases(ctx, userID, []string{second})
+
+	aliases, err = service.ViewUserAliases(ctx, adminID, userID)
+	require.NoError(t, err)
+	require.Len(t, aliases, 2)
+	for _, alias := range aliases {
+		assert.Equal(t, alias.ShortKey == first, alias.DisabledFlag, alias.ShortKey)
+		assert.Equal(t, alias.ShortKey == second, alias.DeletedFlag, alias.ShortKey)
+	}
+	_, err = service.ViewUserAliases(ctx, adminID, 100)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	stats, err := service.GetServiceStats(ctx, adminID)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), stats.Users)
+	assert.Equal(t, uint64(2), stats.RegisteredUsers)
+	assert.Equal(t, uint64(2), stats.Aliases)
+	assert.Equal(t, uint64(1), stats.DeletedAliases)
+	assert.Equal(t, uint64(1), stats.DisabledAliases)
+
+	//	purged alias is gone and its URL can be shortened again
+	require.NoError(t, service.PurgeAlias(ctx, adminID, first))
+	_, err = service.GetOriginalURL(ctx, first)
+	assert.ErrorIs(t, err, ErrURLNotFound)
+	assert.ErrorIs(t, service.PurgeAlias(ctx, adminID, first), ErrURLNotFound)
+
+	_, err = service.GetShortKey(ctx, userID, "https://example.com/1", "", nil)
+	require.NoError(t, err)
+}
+
+func Test_AdminRoleIsNotClaimed(t *testing.T) {
+
+	ctx := context.Background()
+
+	stor, err := memstor.NewStorage()
+	require.NoError(t, err)
+
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	service, err := New(stor, logger, WithPasswordCost(bcrypt.MinCost))
+	require.NoError(t, err)
+
+	//	the configured admin has not registered yet
+	anonymousID, err := stor.CreateUser(ctx)
+	require.NoError(t, err)
+	WithAdminUserIDs(anonymousID)(service)
+
+	anonymous, err := service.GetUser(ctx, anonymousID)
+	require.NoError(t, err)
+	assert.False(t, anonymous.IsAdmin(), "anonymous user is not admin")
+
+	//	logins like "admin" or "root" give nothing
+	for _, login := range []string{"admin", "root"} {
+		userID, err := service.Register(ctx, login, "correct horse", nil)
+		require.NoError(t, err)
+		_, err = service.Login(ctx, login, "correct horse")
+		require.NoError(t, err)
+
+		user, err := service.GetUser(ctx, userID)
+		require.NoError(t, err)
+		assert.False(t, user.IsAdmin(), login)
+	}
+}
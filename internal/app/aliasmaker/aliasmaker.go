@@ -2,23 +2,33 @@ package aliasmaker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"runtime"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/bcrypt"
+
 	"github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
 	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/models/clickentity"
 )
 
-const aliasKeyLen int = 9
+// ExpireIntervalDefault is the default period of marking expired aliases
+const ExpireIntervalDefault = time.Minute
 
 // Access interface to storage
 //
 //go:generate mockgen -destination=../mocks/mock_storager.go -package=mocks github.com/Schalure/urlalias/internal/app/aliasmaker Storager
 type Storager interface {
-	CreateUser() (uint64, error)
+	KeyRangeReserver
+	APIKeyStorager
+	UserStorager
+	AdminStorager
+	CreateUser(ctx context.Context) (uint64, error)
 	Save(ctx context.Context, urlAliasNode *aliasentity.AliasURLModel) error
 	SaveAll(ctx context.Context, urlAliasNodes []aliasentity.AliasURLModel) error
 	FindByShortKey(ctx context.Context, shortKey string) (*aliasentity.AliasURLModel, error)
@@ -26,14 +36,16 @@ type Storager interface {
 	FindAllByLongURLs(ctx context.Context, longURL []string) (map[string]*aliasentity.AliasURLModel, error)
 	FindByUserID(ctx context.Context, userID uint64) ([]aliasentity.AliasURLModel, error)
 	MarkDeleted(ctx context.Context, aliasesID []uint64) error
+	MarkExpired(ctx context.Context, now time.Time) (int, error)
 	GetLastShortKey() string
-	IsConnected() bool
+	IsConnected(ctx context.Context) bool
 	Close() error
 }
 
 type deleter struct {
 	userID  uint64
 	aliases []string
+	link    trace.Link //	link - span of the request which asked to delete aliases
 }
 
 // Type of service
@@ -41,22 +53,68 @@ type AliasMakerServise struct {
 	logger    *zaplogger.ZapLogger //	logger - object for outputting and saving logs
 	storage   Storager             //	storage - object for interaction with the storage
 	deleterCh chan deleter         //	deleterCh - channel for deleting aliases
-	lastKey   string               //	lastKey - last key created
+
+	keyGenerator KeyGenerator //	keyGenerator - object for creating short keys
+
+	settings atomic.Pointer[runtimeSettings] //	settings - alias rules and admin logins, they are replaced at once by Reconfigure
+
+	expireInterval time.Duration //	expireInterval - period of marking expired aliases
+
+	passwordCost int //	passwordCost - bcrypt cost of password hashes
+
+	analytics AnalyticsSink               //	analytics - object for saving redirect statistics
+	metrics   Metrics                     //	metrics - object for counting operational events
+	clicksCh  chan clickentity.ClickModel //	clicksCh - channel for saving redirects
+
+	stopWorkers context.CancelFunc //	stopWorkers - stops the background tasks started by Run
+	workers     sync.WaitGroup     //	workers - background tasks started by Run
 }
 
 // Constructor
-func New(s Storager, l *zaplogger.ZapLogger) (*AliasMakerServise, error) {
+func New(s Storager, l *zaplogger.ZapLogger, opts ...Option) (*AliasMakerServise, error) {
 
-	return &AliasMakerServise{
+	service := &AliasMakerServise{
 		storage:   s,
 		logger:    l,
-		lastKey:   s.GetLastShortKey(),
 		deleterCh: make(chan deleter, 50),
-	}, nil
+
+		expireInterval: ExpireIntervalDefault,
+
+		passwordCost: bcrypt.DefaultCost,
+
+		metrics:  noMetrics{},
+		clicksCh: make(chan clickentity.ClickModel, clicksChSize),
+	}
+
+	service.Reconfigure(RuntimeSettings{
+		AliasCharset: AliasCharsetDefault,
+		AliasMinLen:  AliasMinLenDefault,
+		AliasMaxLen:  AliasMaxLenDefault,
+	})
+
+	if analytics, ok := s.(AnalyticsSink); ok {
+		service.analytics = analytics
+	}
+
+	for _, opt := range opts {
+		opt(service)
+	}
+
+	if service.keyGenerator == nil {
+		keyGenerator, err := NewSequentialGenerator(KeyLenDefault, s.GetLastShortKey(), s, KeyRangeSizeDefault)
+		if err != nil {
+			return nil, err
+		}
+		service.keyGenerator = keyGenerator
+	}
+	return service, nil
 }
 
 // GetOriginalURL returns original url by shortKey. If original url not found or was deleted, return error
-func (s *AliasMakerServise) GetOriginalURL(ctx context.Context, shortKey string) (string, error) {
+func (s *AliasMakerServise) GetOriginalURL(ctx context.Context, shortKey string) (originalURL string, err error) {
+
+	ctx, span := startSpan(ctx, "GetOriginalURL", trace.WithAttributes(shortKeyKey.String(shortKey)))
+	defer func() { endSpan(span, err) }()
 
 	c, cancel := context.WithTimeout(ctx, time.Second*1)
 	defer cancel()
@@ -68,43 +126,152 @@ func (s *AliasMakerServise) GetOriginalURL(ctx context.Context, shortKey string)
 			"short key", shortKey,
 			"error", err,
 		)
+		s.metrics.Redirect(RedirectMiss)
 		return "", ErrURLNotFound
 	}
 
 	if node.DeletedFlag {
+		s.metrics.Redirect(RedirectGone)
 		return "", ErrURLWasDeleted
 	}
 
+	if node.IsExpired(time.Now()) {
+		s.metrics.Redirect(RedirectGone)
+		return "", ErrURLExpired
+	}
+
+	if node.DisabledFlag {
+		s.metrics.Redirect(RedirectGone)
+		return "", ErrURLDisabled
+	}
+
+	s.metrics.Redirect(RedirectHit)
 	return node.LongURL, nil
 }
 
-// GetShortKey add new URL to service and return alias entity
-func (s *AliasMakerServise) GetShortKey(ctx context.Context, userID uint64, originalURL string) (string, error) {
+// GetShortKey add new URL to service and return alias entity.
+// If alias is not empty, it is used as the short key instead of a generated one.
+// If expiresAt is not nil, the alias stops working at that moment
+func (s *AliasMakerServise) GetShortKey(ctx context.Context, userID uint64, originalURL, alias string, expiresAt *time.Time) (shortKey string, err error) {
+
+	ctx, span := startSpan(ctx, "GetShortKey", trace.WithAttributes(userIDKey.Int64(int64(userID))))
+	defer func() { endSpan(span, err) }()
+
+	if alias != "" {
+		if err := s.validateAlias(alias); err != nil {
+			return "", err
+		}
+	}
 
 	ctxFind, cancelFind := context.WithTimeout(ctx, time.Second*1)
 	defer cancelFind()
 	node, err := s.storage.FindByLongURL(ctxFind, originalURL)
-	if err != nil {
-		node, err := s.NewAliasEntity(userID, originalURL)
-		if err != nil {
-			s.logger.Errorw("error by create new short key", "error", err, "last key", s.lastKey)
+	switch {
+	case err == nil && isLive(node, time.Now()):
+		s.metrics.ShortenConflict(ConflictURL)
+		return node.ShortKey, ErrConflictURL
+	case err == nil:
+		//	the URL gets a new alias instead of the deleted or expired one
+		if err := s.releaseURLs(ctx, []*aliasentity.AliasURLModel{node}); err != nil {
 			return "", ErrInternal
 		}
+	case !errors.Is(err, aliasentity.ErrNotFound):
+		s.logger.Errorw("error by find alias of URL", "error", err)
+		return "", ErrInternal
+	}
 
-		ctxSave, cancelSave := context.WithTimeout(ctx, time.Second*1)
-		defer cancelSave()
-		err = s.storage.Save(ctxSave, node)
+	//	a taken custom alias is rejected by the storage when it is saved, so concurrent requests can't both get it
+	if alias != "" {
+		node = &aliasentity.AliasURLModel{
+			LongURL:  originalURL,
+			ShortKey: alias,
+			UserID:   userID,
+			IsCustom: true,
+		}
+	} else {
+		node, err = s.NewAliasEntity(ctx, userID, originalURL)
 		if err != nil {
-			s.logger.Errorw("error by save new entity of alias", "error", err, "last key", s.lastKey)
+			s.logger.Errorw("error by create new short key", "error", err)
+			if errors.Is(err, ErrKeyspaceExhausted) {
+				return "", ErrKeyspaceExhausted
+			}
 			return "", ErrInternal
 		}
-		return node.ShortKey, nil
 	}
-	return node.ShortKey, ErrConflictURL
+	node.ExpiresAt = expiresAt
+
+	ctxSave, cancelSave := context.WithTimeout(ctx, time.Second*1)
+	defer cancelSave()
+	err = s.storage.Save(ctxSave, node)
+	if errors.Is(err, aliasentity.ErrShortKeyTaken) && node.IsCustom {
+		s.metrics.ShortenConflict(ConflictAlias)
+		return "", ErrConflictAlias
+	}
+	if errors.Is(err, aliasentity.ErrLongURLTaken) {
+		//	a concurrent request has saved the URL after it was looked up
+		ctxConflict, cancelConflict := context.WithTimeout(ctx, time.Second*1)
+		defer cancelConflict()
+		if node, err := s.storage.FindByLongURL(ctxConflict, originalURL); err == nil {
+			s.metrics.ShortenConflict(ConflictURL)
+			return node.ShortKey, ErrConflictURL
+		}
+	}
+	if err != nil {
+		s.logger.Errorw("error by save new entity of alias", "error", err, "short key", node.ShortKey)
+		return "", ErrInternal
+	}
+	return node.ShortKey, nil
+}
+
+// isLive reports whether the alias is returned for its URL instead of a new one.
+// A disabled alias is live, so a new alias does not bypass moderation
+func isLive(node *aliasentity.AliasURLModel, now time.Time) bool {
+	return !node.DeletedFlag && !node.IsExpired(now)
+}
+
+// releaseURLs marks the aliases as expired in storage before their URLs get new aliases,
+// if their expiry time has passed but the sweeper has not marked them yet.
+// Storages keep one live alias of a URL
+func (s *AliasMakerServise) releaseURLs(ctx context.Context, nodes []*aliasentity.AliasURLModel) error {
+
+	needMark := false
+	for _, node := range nodes {
+		needMark = needMark || (!node.DeletedFlag && !node.ExpiredFlag)
+	}
+	if !needMark {
+		return nil
+	}
+
+	ctxMark, cancelMark := context.WithTimeout(ctx, time.Second*1)
+	defer cancelMark()
+	if _, err := s.storage.MarkExpired(ctxMark, time.Now()); err != nil {
+		s.logger.Errorw("can't mark expired aliases", "error", err)
+		return err
+	}
+	return nil
+}
+
+// GetBatchShortURL create batch of aliases and return batch of short keys.
+// batchExpiresAt holds the expiry time of each new alias; it may be nil or contain nil for aliases without expiry
+func (s *AliasMakerServise) GetBatchShortURL(ctx context.Context, userID uint64, batchOriginalURL []string, batchExpiresAt []*time.Time) (shortKeys []string, err error) {
+
+	ctx, span := startSpan(ctx, "GetBatchShortURL", trace.WithAttributes(userIDKey.Int64(int64(userID)), countKey.Int(len(batchOriginalURL))))
+	defer func() { endSpan(span, err) }()
+
+	shortKeys, err = s.getBatchShortURL(ctx, userID, batchOriginalURL, batchExpiresAt)
+	if errors.Is(err, aliasentity.ErrLongURLTaken) {
+		//	a concurrent request has saved one of the URLs after they were looked up, its alias is found now
+		shortKeys, err = s.getBatchShortURL(ctx, userID, batchOriginalURL, batchExpiresAt)
+	}
+	if errors.Is(err, aliasentity.ErrLongURLTaken) {
+		return nil, ErrInternal
+	}
+	return shortKeys, err
 }
 
-// GetBatchShortURL create batch of aliases and return batch of short keys
-func (s *AliasMakerServise) GetBatchShortURL(ctx context.Context, userID uint64, batchOriginalURL []string) ([]string, error) {
+// getBatchShortURL is GetBatchShortURL without retry.
+// It returns aliasentity.ErrLongURLTaken if one of the URLs gets an alias after it was looked up
+func (s *AliasMakerServise) getBatchShortURL(ctx context.Context, userID uint64, batchOriginalURL []string, batchExpiresAt []*time.Time) ([]string, error) {
 
 	ctxFind, cancelFind := context.WithTimeout(ctx, time.Second*1)
 	nodes, err := s.storage.FindAllByLongURLs(ctxFind, batchOriginalURL)
@@ -113,27 +280,55 @@ func (s *AliasMakerServise) GetBatchShortURL(ctx context.Context, userID uint64,
 		s.logger.Errorw("error where FindAllByLongURLs", "error", err)
 		return nil, err
 	}
+	if nodes == nil {
+		nodes = make(map[string]*aliasentity.AliasURLModel)
+	}
+
+	//	URLs of deleted and expired aliases get new aliases
+	now := time.Now()
+	var dead []*aliasentity.AliasURLModel
+	for originalURL, node := range nodes {
+		if !isLive(node, now) {
+			dead = append(dead, node)
+			delete(nodes, originalURL)
+		}
+	}
+	if err := s.releaseURLs(ctx, dead); err != nil {
+		return nil, ErrInternal
+	}
 
 	batchShortURL := make([]string, len(batchOriginalURL))
-	batchNodesToSave := make([]aliasentity.AliasURLModel, len(batchShortURL)-len(nodes))
+	batchNodesToSave := make([]aliasentity.AliasURLModel, 0, len(batchShortURL)-len(nodes))
 
 	for i, originalURL := range batchOriginalURL {
 		var err error
 		node, ok := nodes[originalURL]
 		if !ok {
-			node, err = s.NewAliasEntity(userID, originalURL)
+			node, err = s.NewAliasEntity(ctx, userID, originalURL)
 			if err != nil {
-				s.logger.Errorw("error by create new short key", "error", err, "last key", s.lastKey)
+				s.logger.Errorw("error by create new short key", "error", err)
+				if errors.Is(err, ErrKeyspaceExhausted) {
+					return nil, ErrKeyspaceExhausted
+				}
 				return nil, ErrInternal
 			}
-			batchNodesToSave[i] = *node
+			if i < len(batchExpiresAt) {
+				node.ExpiresAt = batchExpiresAt[i]
+			}
+			batchNodesToSave = append(batchNodesToSave, *node)
+			//	the same URL repeated in the batch gets the same alias
+			nodes[originalURL] = node
 		}
 		batchShortURL[i] = node.ShortKey
 	}
 
 	ctxSaveAll, cancelSaveAll := context.WithTimeout(ctx, time.Second*1)
 	defer cancelSaveAll()
-	if err := s.storage.SaveAll(ctxSaveAll, batchNodesToSave); err != nil {
+	err = s.storage.SaveAll(ctxSaveAll, batchNodesToSave)
+	if errors.Is(err, aliasentity.ErrLongURLTaken) {
+		return nil, err
+	}
+	if err != nil {
 		s.logger.Errorw("can't save all URLs", "error", err)
 		return nil, ErrInternal
 	}
@@ -142,17 +337,24 @@ func (s *AliasMakerServise) GetBatchShortURL(ctx context.Context, userID uint64,
 }
 
 // CreateUser creates a new user
-func (s *AliasMakerServise) CreateUser() (uint64, error) {
+func (s *AliasMakerServise) CreateUser(ctx context.Context) (userID uint64, err error) {
+
+	ctx, span := startSpan(ctx, "CreateUser")
+	defer func() { endSpan(span, err) }()
 
-	userID, err := s.storage.CreateUser()
+	userID, err = s.storage.CreateUser(ctx)
 	if err != nil {
 		return 0, err
 	}
+	span.SetAttributes(userIDKey.Int64(int64(userID)))
 	return userID, nil
 }
 
 // GetUserAliases returns all aliases which user created
-func (s *AliasMakerServise) GetUserAliases(ctx context.Context, userID uint64) ([]aliasentity.AliasURLModel, error) {
+func (s *AliasMakerServise) GetUserAliases(ctx context.Context, userID uint64) (aliases []aliasentity.AliasURLModel, err error) {
+
+	ctx, span := startSpan(ctx, "GetUserAliases", trace.WithAttributes(userIDKey.Int64(int64(userID))))
+	defer func() { endSpan(span, err) }()
 
 	ctxGetAliases, cancelGetAliases := context.WithTimeout(ctx, time.Second*1)
 	defer cancelGetAliases()
@@ -162,36 +364,49 @@ func (s *AliasMakerServise) GetUserAliases(ctx context.Context, userID uint64) (
 		s.logger.Errorw("can't found aliases by user ID", "error", err, "user ID", userID)
 		return nil, ErrInternal
 	}
-	return nodes, nil
+
+	now := time.Now()
+	activeNodes := make([]aliasentity.AliasURLModel, 0, len(nodes))
+	for _, node := range nodes {
+		if !node.IsExpired(now) {
+			activeNodes = append(activeNodes, node)
+		}
+	}
+	return activeNodes, nil
 }
 
-// AddAliasesToDelete adds aliases to delete
-func (s *AliasMakerServise) AddAliasesToDelete(ctx context.Context, userID uint64, aliases ...string) error {
+// AddAliasesToDelete adds aliases to delete. Spans of the deletion are linked to the span of ctx
+func (s *AliasMakerServise) AddAliasesToDelete(ctx context.Context, userID uint64, aliases ...string) (err error) {
+
+	ctx, span := startSpan(ctx, "AddAliasesToDelete", trace.WithAttributes(userIDKey.Int64(int64(userID)), countKey.Int(len(aliases))))
+	defer func() { endSpan(span, err) }()
 
 	select {
 	case <-ctx.Done():
 		s.logger.Infow("AddAliasesToDelete: context Done", "userID", userID, "aliases", aliases)
 		return fmt.Errorf("can't create a delete request, try again later")
-	case s.deleterCh <- deleter{userID: userID, aliases: aliases}:
+	case s.deleterCh <- deleter{userID: userID, aliases: aliases, link: trace.LinkFromContext(ctx)}:
 		s.logger.Infow("AddAliasesToDelete: add aliases to delete", "userID", userID, "aliases", aliases)
 	}
 	return nil
 }
 
 // IsDatabaseActive checks the database connection and returns true or false
-func (s *AliasMakerServise) IsDatabaseActive() bool {
+func (s *AliasMakerServise) IsDatabaseActive(ctx context.Context) bool {
 
-	return s.storage.IsConnected()
+	ctx, span := startSpan(ctx, "IsDatabaseActive")
+	defer span.End()
+
+	return s.storage.IsConnected(ctx)
 }
 
-// NewAliasEntity creates a new URL pair
-func (s *AliasMakerServise) NewAliasEntity(userID uint64, longURL string) (*aliasentity.AliasURLModel, error) {
+// NewAliasEntity creates a new URL pair with a key made by the key generator
+func (s *AliasMakerServise) NewAliasEntity(ctx context.Context, userID uint64, longURL string) (*aliasentity.AliasURLModel, error) {
 
-	newAliasKey, err := createAliasKey(s.lastKey)
+	newAliasKey, err := s.keyGenerator.NewKey(ctx)
 	if err != nil {
 		return nil, err
 	}
-	s.lastKey = newAliasKey
 	return &aliasentity.AliasURLModel{
 		LongURL:  longURL,
 		ShortKey: newAliasKey,
@@ -199,24 +414,48 @@ func (s *AliasMakerServise) NewAliasEntity(userID uint64, longURL string) (*alia
 	}, nil
 }
 
-// deleteWorker is a task that reads s.deleterCh and runs the delete aliases function
+// deleteWorker is a task that reads s.deleterCh and runs the delete aliases function.
+// A request taken from the channel is always processed to the end, even if ctx is done
 func (s *AliasMakerServise) deleteWorker(ctx context.Context) {
 
+	s.workers.Add(1)
 	go func() {
+		defer s.workers.Done()
 		for {
 			select {
 			case <-ctx.Done():
 				s.logger.Info("deleteWorker stopped by ctx.Done()")
 				return
 			case deleter := <-s.deleterCh:
-				s.deleteAliases(ctx, deleter.userID, deleter.aliases)
+				s.deleteAliases(context.Background(), deleter.userID, deleter.aliases, deleter.link)
 			}
 		}
 	}()
 }
 
-// deleteAliases marks aliases deleted if they are assigned to a user and returns a slise of marked aliases
-func (s *AliasMakerServise) deleteAliases(ctx context.Context, userID uint64, shortKeys []string) []string {
+// drainDeleter processes the delete requests remaining in s.deleterCh and returns their count
+func (s *AliasMakerServise) drainDeleter() int {
+
+	for count := 0; ; count++ {
+		select {
+		case deleter := <-s.deleterCh:
+			s.deleteAliases(context.Background(), deleter.userID, deleter.aliases, deleter.link)
+		default:
+			return count
+		}
+	}
+}
+
+// deleteAliases marks aliases deleted if they are assigned to a user and returns a slise of marked aliases.
+// The deletion is traced by a new trace linked to the spans of the requests which asked for it
+func (s *AliasMakerServise) deleteAliases(ctx context.Context, userID uint64, shortKeys []string, links ...trace.Link) []string {
+
+	ctx, span := startSpan(ctx, "deleteAliases",
+		trace.WithNewRoot(),
+		trace.WithLinks(links...),
+		trace.WithAttributes(userIDKey.Int64(int64(userID)), countKey.Int(len(shortKeys))),
+	)
+	defer span.End()
 
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
@@ -255,7 +494,7 @@ func (s *AliasMakerServise) deleteAliases(ctx context.Context, userID uint64, sh
 						node, err := s.storage.FindByShortKey(ctx, shortKey)
 						if err != nil {
 							s.logger.Infow("func DeleteUserURLs: can't Storage.FindByShortKey", "shortKey", shortKey)
-							break
+							continue
 						}
 						s.logger.Info(node)
 						select {
@@ -336,6 +575,7 @@ func (s *AliasMakerServise) deleteAliases(ctx context.Context, userID uint64, sh
 		}
 	}()
 
+	s.metrics.DeleteBatch(len(aliasesID))
 	err := s.storage.MarkDeleted(ctx, aliasesID)
 	if err != nil {
 		s.logger.Info(err)
@@ -343,50 +583,66 @@ func (s *AliasMakerServise) deleteAliases(ctx context.Context, userID uint64, sh
 	return deleteAliases
 }
 
-// Run runs s.deleteWorker
-func (s *AliasMakerServise) Run(ctx context.Context) {
-	s.deleteWorker(ctx)
-}
+// expireWorker is a task that periodically marks expired aliases in the storage
+func (s *AliasMakerServise) expireWorker(ctx context.Context) {
 
-// Stop service and full release
-func (s *AliasMakerServise) Stop() {
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		ticker := time.NewTicker(s.expireInterval)
+		defer ticker.Stop()
 
-	s.storage.Close()
-	s.logger.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				s.logger.Info("expireWorker stopped by ctx.Done()")
+				return
+			case <-ticker.C:
+				s.markExpired(ctx)
+			}
+		}
+	}()
 }
 
-// Make short alias from URL
-func createAliasKey(lastKey string) (string, error) {
+// markExpired marks aliases whose expiry time has passed and returns their count
+func (s *AliasMakerServise) markExpired(ctx context.Context) int {
 
-	var charset = []string{
-		"0", "1", "2", "3", "4", "5", "6", "7", "8", "9",
-		"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z",
-		"A", "B", "C", "D", "E", "F", "G", "H", "I", "J", "K", "L", "M", "N", "O", "P", "Q", "R", "S", "T", "U", "V", "W", "X", "Y", "Z",
-	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
-	if lastKey == "" {
-		lastKey = strings.Repeat("0", aliasKeyLen) //"000000000"
-		return lastKey, nil
+	count, err := s.storage.MarkExpired(ctx, time.Now())
+	if err != nil {
+		s.logger.Errorw("can't mark expired aliases", "error", err)
+		return 0
+	}
+	if count > 0 {
+		s.logger.Infow("expired aliases marked", "count", count)
 	}
+	return count
+}
+
+// Run runs s.deleteWorker, s.expireWorker and s.clickWorker. The tasks are stopped by ctx or by Stop
+func (s *AliasMakerServise) Run(ctx context.Context) {
+
+	ctx, s.stopWorkers = context.WithCancel(ctx)
+	s.deleteWorker(ctx)
+	s.expireWorker(ctx)
+	s.clickWorker(ctx)
+}
+
+// Stop service and full release. Stop waits for the background tasks, processes the delete requests
+// remaining in the queue and only then closes the storage
+func (s *AliasMakerServise) Stop() {
 
-	newKey := strings.Split(lastKey, "")
-	if len(newKey) != aliasKeyLen {
-		return "", fmt.Errorf("a non-valid key was received from the repository: %s", lastKey)
+	if s.stopWorkers != nil {
+		s.stopWorkers()
 	}
+	s.workers.Wait()
 
-	for i := aliasKeyLen - 1; i > 0; i-- {
-		for n, char := range charset {
-			if newKey[i] == char {
-				if n == len(charset)-1 {
-					newKey[i] = charset[0]
-					break
-				} else {
-					newKey[i] = charset[n+1]
-					lastKey = strings.Join(newKey, "")
-					return lastKey, nil
-				}
-			}
-		}
+	if count := s.drainDeleter(); count > 0 {
+		s.logger.Infow("Stop: remaining delete requests processed", "count", count)
 	}
-	return "", fmt.Errorf("it is impossible to generate a new string because the storage is full")
+
+	s.storage.Close()
+	s.logger.Close()
 }
@@ -3,81 +3,27 @@ package aliasmaker
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sort"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 
 	"github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
 	"github.com/Schalure/urlalias/internal/app/mocks"
 	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/models/clickentity"
+	"github.com/Schalure/urlalias/internal/app/storage/memstor"
 )
 
-func Test_createAliasKey(t *testing.T) {
-
-	testCases := []struct {
-		name    string
-		lastKey string
-		want    struct {
-			newKey string
-			err    error
-		}
-	}{
-		{
-			name:    "symple test",
-			lastKey: "000000000",
-			want: struct {
-				newKey string
-				err    error
-			}{
-				newKey: "000000001",
-				err:    nil,
-			},
-		},
-		{
-			name:    "overload test",
-			lastKey: "00000000Z",
-			want: struct {
-				newKey string
-				err    error
-			}{
-				newKey: "000000010",
-				err:    nil,
-			},
-		},
-		{
-			name:    "storage full test",
-			lastKey: "ZZZZZZZZZ",
-			want: struct {
-				newKey string
-				err    error
-			}{
-				newKey: "",
-				err:    errors.New("it is impossible to generate a new string because the storage is full"),
-			},
-		},
-	}
-
-	for _, test := range testCases {
-		t.Run(test.name, func(t *testing.T) {
-
-			aliasKey, err := createAliasKey(test.lastKey)
-
-			assert.Equal(t, aliasKey, test.want.newKey)
-			assert.Equal(t, err, test.want.err)
-		})
-	}
-}
-
-func Benchmark_createAliasKey(b *testing.B) {
-
-	for i := 0; i < b.N; i++ {
-		createAliasKey("YZZZZZZZZ")
-	}
-}
-
 func Test_deleteAliasesSimple(t *testing.T) {
 
 	userID := uint64(1)
@@ -248,3 +194,380 @@ func Benchmark_deleteAliasesSimple(b *testing.B) {
 		service.deleteAliases(context.Background(), userID, test.aliasesToDelete)
 	}
 }
+
+func Test_validateAlias(t *testing.T) {
+
+	storage := mocks.NewMockStorager(gomock.NewController(t))
+	storage.EXPECT().GetLastShortKey().Return("").AnyTimes()
+
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	service, err := New(storage, logger, WithAliasRules(AliasCharsetDefault, 3, 16))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		alias string
+		valid bool
+	}{
+		{name: "simple alias", alias: "spring-sale", valid: true},
+		{name: "too short", alias: "ab", valid: false},
+		{name: "too long", alias: "spring-sale-2024-edition", valid: false},
+		{name: "not allowed character", alias: "spring/sale", valid: false},
+		{name: "reserved path", alias: "ping", valid: false},
+		{name: "generator key", alias: "00000000a", valid: false},
+		{name: "generator length with dash", alias: "0000-000a", valid: true},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+
+			err := service.validateAlias(test.alias)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidAlias)
+			}
+		})
+	}
+}
+
+func Test_GetShortKeyCustomAlias(t *testing.T) {
+
+	userID := uint64(1)
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	storage := mocks.NewMockStorager(mockController)
+	storage.EXPECT().GetLastShortKey().Return("000000001").AnyTimes()
+	storage.EXPECT().FindByLongURL(gomock.Any(), "https://example.com/autumn").Return(nil, errors.New("timeout"))
+	storage.EXPECT().FindByLongURL(gomock.Any(), gomock.Any()).Return(nil, aliasentity.ErrNotFound).AnyTimes()
+	storage.EXPECT().Save(gomock.Any(), &aliasentity.AliasURLModel{
+		UserID:   userID,
+		ShortKey: "spring-sale",
+		LongURL:  "https://example.com/spring",
+		IsCustom: true,
+	}).Return(nil)
+	storage.EXPECT().Save(gomock.Any(), &aliasentity.AliasURLModel{
+		UserID:   userID,
+		ShortKey: "summer-sale",
+		LongURL:  "https://example.com/summer",
+		IsCustom: true,
+	}).Return(aliasentity.ErrShortKeyTaken)
+
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	service, err := New(storage, logger)
+	require.NoError(t, err)
+
+	shortKey, err := service.GetShortKey(context.Background(), userID, "https://example.com/spring", "spring-sale", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "spring-sale", shortKey)
+
+	_, err = service.GetShortKey(context.Background(), userID, "https://example.com/summer", "summer-sale", nil)
+	assert.ErrorIs(t, err, ErrConflictAlias)
+
+	//	an outage of the storage is not a free URL
+	_, err = service.GetShortKey(context.Background(), userID, "https://example.com/autumn", "autumn-sale", nil)
+	assert.ErrorIs(t, err, ErrInternal)
+
+	//	key generator is not used for custom aliases, so the first range is reserved now
+	storage.EXPECT().ReserveKeyRange(gomock.Any(), "sequential-9", uint64(2), uint64(KeyRangeSizeDefault)).Return(uint64(2), nil)
+	shortKey, err = service.keyGenerator.NewKey(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "000000002", shortKey)
+}
+
+func Test_GetShortKeyDeadURL(t *testing.T) {
+
+	userID := uint64(1)
+	past := time.Now().Add(-time.Hour)
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	storage := mocks.NewMockStorager(mockController)
+	storage.EXPECT().GetLastShortKey().Return("000000001").AnyTimes()
+
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+	service, err := New(storage, logger)
+	require.NoError(t, err)
+
+	//	the expiry is marked before the URL gets a new alias
+	gomock.InOrder(
+		storage.EXPECT().FindByLongURL(gomock.Any(), "https://example.com/expired").Return(&aliasentity.AliasURLModel{ShortKey: "old-sale", LongURL: "https://example.com/expired", ExpiresAt: &past}, nil),
+		storage.EXPECT().MarkExpired(gomock.Any(), gomock.Any()).Return(1, nil),
+		storage.EXPECT().Save(gomock.Any(), &aliasentity.AliasURLModel{UserID: userID, ShortKey: "new-sale", LongURL: "https://example.com/expired", IsCustom: true}).Return(nil),
+	)
+	shortKey, err := service.GetShortKey(context.Background(), userID, "https://example.com/expired", "new-sale", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "new-sale", shortKey)
+
+	//	a deleted alias needs no marking
+	storage.EXPECT().FindByLongURL(gomock.Any(), "https://example.com/deleted").Return(&aliasentity.AliasURLModel{ShortKey: "del-sale", LongURL: "https://example.com/deleted", DeletedFlag: true}, nil)
+	storage.EXPECT().Save(gomock.Any(), &aliasentity.AliasURLModel{UserID: userID, ShortKey: "next-sale", LongURL: "https://example.com/deleted", IsCustom: true}).Return(nil)
+	shortKey, err = service.GetShortKey(context.Background(), userID, "https://example.com/deleted", "next-sale", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "next-sale", shortKey)
+
+	//	a disabled alias is not replaced, so moderation is not bypassed
+	storage.EXPECT().FindByLongURL(gomock.Any(), "https://example.com/disabled").Return(&aliasentity.AliasURLModel{ShortKey: "bad-sale", LongURL: "https://example.com/disabled", DisabledFlag: true}, nil)
+	shortKey, err = service.GetShortKey(context.Background(), userID, "https://example.com/disabled", "other-sale", nil)
+	assert.ErrorIs(t, err, ErrConflictURL)
+	assert.Equal(t, "bad-sale", shortKey)
+}
+
+func Test_GetShortKeyRacedURL(t *testing.T) {
+
+	userID := uint64(1)
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	storage := mocks.NewMockStorager(mockController)
+	storage.EXPECT().GetLastShortKey().Return("000000001").AnyTimes()
+
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+	service, err := New(storage, logger)
+	require.NoError(t, err)
+
+	//	a concurrent request saves the URL between the lookup and the save
+	saved := &aliasentity.AliasURLModel{ShortKey: "first-sale", LongURL: "https://example.com/race"}
+	gomock.InOrder(
+		storage.EXPECT().FindByLongURL(gomock.Any(), "https://example.com/race").Return(nil, aliasentity.ErrNotFound),
+		storage.EXPECT().Save(gomock.Any(), gomock.Any()).Return(aliasentity.ErrLongURLTaken),
+		storage.EXPECT().FindByLongURL(gomock.Any(), "https://example.com/race").Return(saved, nil),
+	)
+	shortKey, err := service.GetShortKey(context.Background(), userID, "https://example.com/race", "second-sale", nil)
+	assert.ErrorIs(t, err, ErrConflictURL)
+	assert.Equal(t, "first-sale", shortKey)
+
+	//	the batch is looked up again and gets the saved alias
+	gomock.InOrder(
+		storage.EXPECT().FindAllByLongURLs(gomock.Any(), []string{"https://example.com/race"}).Return(nil, nil),
+		storage.EXPECT().SaveAll(gomock.Any(), gomock.Any()).Return(aliasentity.ErrLongURLTaken),
+		storage.EXPECT().FindAllByLongURLs(gomock.Any(), []string{"https://example.com/race"}).Return(map[string]*aliasentity.AliasURLModel{"https://example.com/race": saved}, nil),
+		storage.EXPECT().SaveAll(gomock.Any(), []aliasentity.AliasURLModel{}).Return(nil),
+	)
+	storage.EXPECT().ReserveKeyRange(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(uint64(2), nil).AnyTimes()
+	shortKeys, err := service.GetBatchShortURL(context.Background(), userID, []string{"https://example.com/race"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first-sale"}, shortKeys)
+}
+
+func Test_expiredAliases(t *testing.T) {
+
+	userID := uint64(1)
+
+	stor, err := memstor.NewStorage()
+	require.NoError(t, err)
+
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	service, err := New(stor, logger)
+	require.NoError(t, err)
+
+	expiresAt := time.Now().Add(50 * time.Millisecond)
+	expiringKey, err := service.GetShortKey(context.Background(), userID, "https://example.com/campaign", "", &expiresAt)
+	require.NoError(t, err)
+	permanentKey, err := service.GetShortKey(context.Background(), userID, "https://example.com", "", nil)
+	require.NoError(t, err)
+
+	originalURL, err := service.GetOriginalURL(context.Background(), expiringKey)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/campaign", originalURL)
+
+	time.Sleep(100 * time.Millisecond)
+
+	_, err = service.GetOriginalURL(context.Background(), expiringKey)
+	assert.ErrorIs(t, err, ErrURLExpired)
+	_, err = service.GetOriginalURL(context.Background(), permanentKey)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, service.markExpired(context.Background()))
+	assert.Equal(t, 0, service.markExpired(context.Background()))
+
+	nodes, err := service.GetUserAliases(context.Background(), userID)
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, permanentKey, nodes[0].ShortKey)
+}
+
+func Test_GetBatchShortURL(t *testing.T) {
+
+	userID := uint64(1)
+
+	stor, err := memstor.NewStorage()
+	require.NoError(t, err)
+
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	service, err := New(stor, logger)
+	require.NoError(t, err)
+
+	existingKey, err := service.GetShortKey(context.Background(), userID, "https://example.com", "", nil)
+	require.NoError(t, err)
+
+	shortKeys, err := service.GetBatchShortURL(context.Background(), userID, []string{
+		"https://example.com/1",
+		"https://example.com",
+		"https://example.com/2",
+		"https://example.com/1",
+	}, nil)
+	require.NoError(t, err)
+	require.Len(t, shortKeys, 4)
+
+	assert.Equal(t, existingKey, shortKeys[1])
+	assert.Equal(t, shortKeys[0], shortKeys[3])
+	assert.NotEqual(t, shortKeys[0], shortKeys[2])
+
+	for i, longURL := range []string{"https://example.com/1", "https://example.com", "https://example.com/2"} {
+		originalURL, err := service.GetOriginalURL(context.Background(), shortKeys[i])
+		require.NoError(t, err)
+		assert.Equal(t, longURL, originalURL)
+	}
+
+	nodes, err := service.GetUserAliases(context.Background(), userID)
+	require.NoError(t, err)
+	assert.Len(t, nodes, 3)
+}
+
+func Test_recordClicks(t *testing.T) {
+
+	userID := uint64(1)
+
+	stor, err := memstor.NewStorage()
+	require.NoError(t, err)
+
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	service, err := New(stor, logger)
+	require.NoError(t, err)
+
+	shortKey, err := service.GetShortKey(context.Background(), userID, "https://example.com", "", nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	service.Run(ctx)
+
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	service.RecordClick(context.Background(), clickentity.ClickModel{ShortKey: shortKey, ClickedAt: day, ClientIP: "10.0.0.1", UserAgent: "curl"})
+	service.RecordClick(context.Background(), clickentity.ClickModel{ShortKey: shortKey, ClickedAt: day, ClientIP: "10.0.0.1", UserAgent: "curl"})
+	service.RecordClick(context.Background(), clickentity.ClickModel{ShortKey: shortKey, ClickedAt: day.Add(24 * time.Hour), ClientIP: "10.0.0.2", UserAgent: "curl"})
+
+	require.Eventually(t, func() bool {
+		stats, err := service.GetAliasStats(context.Background(), userID, shortKey)
+		return err == nil && stats.TotalClicks == 3
+	}, 3*clickFlushInterval, 10*time.Millisecond)
+	cancel()
+
+	stats, err := service.GetAliasStats(context.Background(), userID, shortKey)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), stats.UniqueVisitors)
+	assert.Equal(t, []clickentity.DailyStatsModel{
+		{Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Clicks: 2, UniqueVisitors: 1},
+		{Date: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), Clicks: 1, UniqueVisitors: 1},
+	}, stats.Daily)
+
+	_, err = service.GetAliasStats(context.Background(), userID+1, shortKey)
+	assert.ErrorIs(t, err, ErrURLNotFound)
+}
+
+func Test_StopDrainsDeleteQueue(t *testing.T) {
+
+	userID := uint64(1)
+	requests := 30
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	var (
+		mx         sync.Mutex
+		deletedIDs []uint64
+	)
+
+	storage := mocks.NewMockStorager(mockController)
+	storage.EXPECT().GetLastShortKey().Return("000000001").AnyTimes()
+	storage.EXPECT().MarkExpired(gomock.Any(), gomock.Any()).Return(0, nil).AnyTimes()
+	storage.EXPECT().FindByShortKey(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, shortKey string) (*aliasentity.AliasURLModel, error) {
+			id, err := strconv.ParseUint(shortKey, 10, 64)
+			require.NoError(t, err)
+			return &aliasentity.AliasURLModel{ID: id, UserID: userID, ShortKey: shortKey}, nil
+		}).AnyTimes()
+	storage.EXPECT().MarkDeleted(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, aliasesID []uint64) error {
+			//	slow storage keeps requests in the queue
+			time.Sleep(5 * time.Millisecond)
+			mx.Lock()
+			defer mx.Unlock()
+			deletedIDs = append(deletedIDs, aliasesID...)
+			return nil
+		}).AnyTimes()
+	storage.EXPECT().Close().Return(nil)
+
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	service, err := New(storage, logger)
+	require.NoError(t, err)
+	service.Run(context.Background())
+
+	wantIDs := make([]uint64, 0, requests*2)
+	for i := 0; i < requests; i++ {
+		first, second := uint64(2*i+1), uint64(2*i+2)
+		wantIDs = append(wantIDs, first, second)
+		err := service.AddAliasesToDelete(context.Background(), userID, fmt.Sprintf("%09d", first), fmt.Sprintf("%09d", second))
+		require.NoError(t, err)
+	}
+
+	service.Stop()
+
+	sort.Slice(deletedIDs, func(i, j int) bool { return deletedIDs[i] < deletedIDs[j] })
+	assert.Equal(t, wantIDs, deletedIDs)
+	assert.Empty(t, service.deleterCh)
+}
+
+func Test_deleteAliasesTraceLink(t *testing.T) {
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	stor, err := memstor.NewStorage()
+	require.NoError(t, err)
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	service, err := New(stor, logger)
+	require.NoError(t, err)
+
+	ctx, request := otel.Tracer("test").Start(context.Background(), "DELETE /api/user/urls")
+	require.NoError(t, service.AddAliasesToDelete(ctx, 1, "000000001"))
+	request.End()
+
+	assert.Equal(t, 1, service.drainDeleter())
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	add := spans["AliasMakerServise.AddAliasesToDelete"]
+	deletion := spans["AliasMakerServise.deleteAliases"]
+	require.NotNil(t, add)
+	require.NotNil(t, deletion)
+
+	//	the deletion is a trace of its own which links back to the request
+	assert.Equal(t, request.SpanContext().TraceID(), add.SpanContext().TraceID())
+	assert.False(t, deletion.Parent().IsValid())
+	assert.NotEqual(t, request.SpanContext().TraceID(), deletion.SpanContext().TraceID())
+	require.Len(t, deletion.Links(), 1)
+	assert.Equal(t, add.SpanContext(), deletion.Links()[0].SpanContext)
+}
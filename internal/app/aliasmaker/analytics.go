@@ -0,0 +1,122 @@
+package aliasmaker
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Schalure/urlalias/internal/app/models/clickentity"
+)
+
+const (
+	clicksChSize       int           = 1024        //	size of the clicks queue
+	clickBatchSize     int           = 100         //	max count of clicks saved by one call of sink
+	clickFlushInterval time.Duration = time.Second //	max time a click waits in the batch
+)
+
+// Access interface to storage of redirect statistics
+//
+//go:generate mockgen -destination=../mocks/mock_analyticssink.go -package=mocks github.com/Schalure/urlalias/internal/app/aliasmaker AnalyticsSink
+type AnalyticsSink interface {
+	SaveClicks(ctx context.Context, clicks []clickentity.ClickModel) error
+	GetClickStats(ctx context.Context, shortKey string) (*clickentity.StatsModel, error)
+}
+
+// RecordClick queues the redirect for saving. It never blocks: if the queue is full the click is dropped
+func (s *AliasMakerServise) RecordClick(ctx context.Context, click clickentity.ClickModel) {
+
+	_, span := startSpan(ctx, "RecordClick", trace.WithAttributes(shortKeyKey.String(click.ShortKey)))
+	defer span.End()
+
+	if s.analytics == nil {
+		return
+	}
+
+	select {
+	case s.clicksCh <- click:
+	default:
+		s.logger.Infow("RecordClick: clicks queue is full, click dropped", "short key", click.ShortKey)
+	}
+}
+
+// GetAliasStats returns redirect statistics of the alias if it is assigned to the user
+func (s *AliasMakerServise) GetAliasStats(ctx context.Context, userID uint64, shortKey string) (aliasStats *clickentity.StatsModel, err error) {
+
+	ctx, span := startSpan(ctx, "GetAliasStats", trace.WithAttributes(userIDKey.Int64(int64(userID)), shortKeyKey.String(shortKey)))
+	defer func() { endSpan(span, err) }()
+
+	if s.analytics == nil {
+		return nil, ErrInternal
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*1)
+	defer cancel()
+
+	node, err := s.storage.FindByShortKey(ctx, shortKey)
+	if err != nil || node.UserID != userID {
+		return nil, ErrURLNotFound
+	}
+
+	stats, err := s.analytics.GetClickStats(ctx, shortKey)
+	if err != nil {
+		s.logger.Errorw("can't get click stats", "error", err, "short key", shortKey)
+		return nil, ErrInternal
+	}
+	return stats, nil
+}
+
+// clickWorker is a task that reads s.clicksCh and saves clicks by batches
+func (s *AliasMakerServise) clickWorker(ctx context.Context) {
+
+	if s.analytics == nil {
+		return
+	}
+
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		ticker := time.NewTicker(clickFlushInterval)
+		defer ticker.Stop()
+
+		batch := make([]clickentity.ClickModel, 0, clickBatchSize)
+		for {
+			select {
+			case <-ctx.Done():
+				for {
+					select {
+					case click := <-s.clicksCh:
+						batch = append(batch, click)
+					default:
+						s.saveClicks(context.Background(), batch)
+						s.logger.Info("clickWorker stopped by ctx.Done()")
+						return
+					}
+				}
+			case click := <-s.clicksCh:
+				batch = append(batch, click)
+				if len(batch) >= clickBatchSize {
+					batch = s.saveClicks(ctx, batch)
+				}
+			case <-ticker.C:
+				batch = s.saveClicks(ctx, batch)
+			}
+		}
+	}()
+}
+
+// saveClicks writes the batch to the sink and returns the emptied batch
+func (s *AliasMakerServise) saveClicks(ctx context.Context, batch []clickentity.ClickModel) []clickentity.ClickModel {
+
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.analytics.SaveClicks(ctx, batch); err != nil {
+		s.logger.Errorw("can't save clicks", "error", err, "count", len(batch))
+	}
+	return batch[:0]
+}
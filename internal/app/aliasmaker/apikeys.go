@@ -0,0 +1,173 @@
+package aliasmaker
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+)
+
+const (
+	apiKeyPrefix     string        = "ua_"       //	prefix of API keys, so they are told apart from tokens
+	apiKeyRandomLen  int           = 32          //	count of random bytes of API key
+	apiKeyShownLen   int           = 8           //	count of characters after the prefix kept to recognize the key in lists
+	apiKeyNameMaxLen int           = 64          //	maximum length of the name of API key
+	apiKeyTouchDelay time.Duration = time.Minute //	last used time of API key is not written more often
+)
+
+// Access interface to storage of API keys
+type APIKeyStorager interface {
+	//	SaveAPIKey saves a new key and sets its ID
+	SaveAPIKey(ctx context.Context, key *apikeyentity.APIKeyModel) error
+	FindAPIKeyByHash(ctx context.Context, hash string) (*apikeyentity.APIKeyModel, error)
+	FindAPIKeysByUserID(ctx context.Context, userID uint64) ([]apikeyentity.APIKeyModel, error)
+	//	RevokeAPIKey marks the key of the user revoked, it returns false if the user has no such key
+	RevokeAPIKey(ctx context.Context, userID, keyID uint64) (bool, error)
+	TouchAPIKey(ctx context.Context, keyID uint64, usedAt time.Time) error
+}
+
+// CreateAPIKey creates API key of the user and returns the key with its stored model.
+// The key is not kept by the service, so it can't be shown again.
+// A key created without scopes gets all of them, a key without expiry time never expires
+func (s *AliasMakerServise) CreateAPIKey(ctx context.Context, userID uint64, name string, scopes []string, expiresAt *time.Time) (key string, model *apikeyentity.APIKeyModel, err error) {
+
+	ctx, span := startSpan(ctx, "CreateAPIKey", trace.WithAttributes(userIDKey.Int64(int64(userID))))
+	defer func() { endSpan(span, err) }()
+
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > apiKeyNameMaxLen {
+		return "", nil, ErrInvalidAPIKeyName
+	}
+
+	scopes, err = normalizeScopes(scopes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	random := make([]byte, apiKeyRandomLen)
+	if _, err = rand.Read(random); err != nil {
+		s.logger.Errorw("can't generate API key", "error", err)
+		return "", nil, ErrInternal
+	}
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(random)
+
+	model = &apikeyentity.APIKeyModel{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    key[:len(apiKeyPrefix)+apiKeyShownLen],
+		Hash:      hashAPIKey(key),
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+		ExpiresAt: expiresAt,
+	}
+
+	if err = s.storage.SaveAPIKey(ctx, model); err != nil {
+		s.logger.Errorw("can't save API key", "error", err, "user id", userID)
+		return "", nil, ErrInternal
+	}
+	return key, model, nil
+}
+
+// GetAPIKeys returns all API keys of the user, including revoked and expired ones
+func (s *AliasMakerServise) GetAPIKeys(ctx context.Context, userID uint64) (keys []apikeyentity.APIKeyModel, err error) {
+
+	ctx, span := startSpan(ctx, "GetAPIKeys", trace.WithAttributes(userIDKey.Int64(int64(userID))))
+	defer func() { endSpan(span, err) }()
+
+	keys, err = s.storage.FindAPIKeysByUserID(ctx, userID)
+	if err != nil {
+		s.logger.Errorw("can't get API keys", "error", err, "user id", userID)
+		return nil, ErrInternal
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes API key of the user. The key stops working at once
+func (s *AliasMakerServise) RevokeAPIKey(ctx context.Context, userID, keyID uint64) (err error) {
+
+	ctx, span := startSpan(ctx, "RevokeAPIKey", trace.WithAttributes(userIDKey.Int64(int64(userID))))
+	defer func() { endSpan(span, err) }()
+
+	ok, err := s.storage.RevokeAPIKey(ctx, userID, keyID)
+	if err != nil {
+		s.logger.Errorw("can't revoke API key", "error", err, "user id", userID, "key id", keyID)
+		return ErrInternal
+	}
+	if !ok {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// AuthenticateAPIKey returns the model of API key if the key is known, not revoked and not expired.
+// The last used time of the key is updated
+func (s *AliasMakerServise) AuthenticateAPIKey(ctx context.Context, key string) (model *apikeyentity.APIKeyModel, err error) {
+
+	ctx, span := startSpan(ctx, "AuthenticateAPIKey")
+	defer func() { endSpan(span, err) }()
+
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrAPIKeyNotValid
+	}
+
+	model, err = s.storage.FindAPIKeyByHash(ctx, hashAPIKey(key))
+	if err != nil {
+		return nil, ErrAPIKeyNotValid
+	}
+
+	now := time.Now()
+	if model.RevokedFlag || model.IsExpired(now) {
+		return nil, ErrAPIKeyNotValid
+	}
+	span.SetAttributes(userIDKey.Int64(int64(model.UserID)))
+
+	if model.LastUsedAt == nil || now.Sub(*model.LastUsedAt) >= apiKeyTouchDelay {
+		if err := s.storage.TouchAPIKey(ctx, model.ID, now); err != nil {
+			s.logger.Infow("can't update last used time of API key", "error", err, "key id", model.ID)
+		}
+		model.LastUsedAt = &now
+	}
+	return model, nil
+}
+
+// hashAPIKey returns the hash of API key which is kept in storage.
+// Keys are random, so a fast hash without salt is enough
+func hashAPIKey(key string) string {
+
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeScopes checks scopes and returns them without duplicates in the order of "apikeyentity.Scopes".
+// Empty scopes mean all scopes
+func normalizeScopes(scopes []string) ([]string, error) {
+
+	if len(scopes) == 0 {
+		return append([]string(nil), apikeyentity.Scopes...), nil
+	}
+
+	wanted := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		wanted[scope] = true
+	}
+
+	normalized := make([]string, 0, len(wanted))
+	for _, scope := range apikeyentity.Scopes {
+		if wanted[scope] {
+			normalized = append(normalized, scope)
+			delete(wanted, scope)
+		}
+	}
+	if len(wanted) != 0 {
+		return nil, ErrInvalidScope
+	}
+	return normalized, nil
+}
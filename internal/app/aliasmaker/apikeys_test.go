@@ -0,0 +1,82 @@
+package aliasmaker
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+	"github.com/Schalure/urlalias/internal/app/storage/memstor"
+)
+
+func Test_APIKeys(t *testing.T) {
+
+	userID := uint64(1)
+	ctx := context.Background()
+
+	stor, err := memstor.NewStorage()
+	require.NoError(t, err)
+
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	service, err := New(stor, logger)
+	require.NoError(t, err)
+
+	key, model, err := service.CreateAPIKey(ctx, userID, " ci ", []string{apikeyentity.ScopeRead, apikeyentity.ScopeShorten, apikeyentity.ScopeRead}, nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, apiKeyPrefix))
+	assert.True(t, strings.HasPrefix(key, model.Prefix))
+	assert.Equal(t, "ci", model.Name)
+	assert.Equal(t, []string{apikeyentity.ScopeShorten, apikeyentity.ScopeRead}, model.Scopes)
+
+	//	only the hash of the key is stored
+	keys, err := service.GetAPIKeys(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.NotContains(t, keys[0].Hash, key)
+	assert.Nil(t, keys[0].LastUsedAt)
+
+	//	the key resolves to its owner and the use is recorded
+	authenticated, err := service.AuthenticateAPIKey(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, userID, authenticated.UserID)
+
+	keys, err = service.GetAPIKeys(ctx, userID)
+	require.NoError(t, err)
+	require.NotNil(t, keys[0].LastUsedAt)
+
+	_, err = service.AuthenticateAPIKey(ctx, key+"x")
+	assert.ErrorIs(t, err, ErrAPIKeyNotValid)
+	_, err = service.AuthenticateAPIKey(ctx, "not a key")
+	assert.ErrorIs(t, err, ErrAPIKeyNotValid)
+
+	//	revoked key stops working, other users can't revoke it
+	assert.ErrorIs(t, service.RevokeAPIKey(ctx, userID+1, model.ID), ErrAPIKeyNotFound)
+	require.NoError(t, service.RevokeAPIKey(ctx, userID, model.ID))
+	_, err = service.AuthenticateAPIKey(ctx, key)
+	assert.ErrorIs(t, err, ErrAPIKeyNotValid)
+
+	//	key without scopes gets all of them, expired key stops working
+	expiresAt := time.Now().Add(50 * time.Millisecond)
+	key, model, err = service.CreateAPIKey(ctx, userID, "", nil, &expiresAt)
+	require.NoError(t, err)
+	assert.Equal(t, apikeyentity.Scopes, model.Scopes)
+
+	_, err = service.AuthenticateAPIKey(ctx, key)
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	_, err = service.AuthenticateAPIKey(ctx, key)
+	assert.ErrorIs(t, err, ErrAPIKeyNotValid)
+
+	//	keys which are not valid
+	_, _, err = service.CreateAPIKey(ctx, userID, "", []string{"admin"}, nil)
+	assert.ErrorIs(t, err, ErrInvalidScope)
+	_, _, err = service.CreateAPIKey(ctx, userID, strings.Repeat("n", apiKeyNameMaxLen+1), nil, nil)
+	assert.ErrorIs(t, err, ErrInvalidAPIKeyName)
+}
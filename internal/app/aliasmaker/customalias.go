@@ -0,0 +1,46 @@
+package aliasmaker
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Default rules for custom aliases
+const (
+	AliasCharsetDefault = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ-_"
+	AliasMinLenDefault  = 3
+	AliasMaxLenDefault  = 32
+)
+
+// reservedAliases are the paths served by the router itself
+var reservedAliases = []string{"api", "ping"}
+
+// validateAlias checks the custom alias against the service rules
+func (s *AliasMakerServise) validateAlias(alias string) error {
+
+	settings := s.RuntimeSettings()
+
+	length := utf8.RuneCountInString(alias)
+	if length < settings.AliasMinLen || length > settings.AliasMaxLen {
+		return fmt.Errorf("%w: length must be from %d to %d characters", ErrInvalidAlias, settings.AliasMinLen, settings.AliasMaxLen)
+	}
+
+	for _, char := range alias {
+		if !strings.ContainsRune(settings.AliasCharset, char) {
+			return fmt.Errorf("%w: character %q is not allowed", ErrInvalidAlias, char)
+		}
+	}
+
+	for _, reserved := range reservedAliases {
+		if strings.EqualFold(alias, reserved) {
+			return fmt.Errorf("%w: \"%s\" is reserved", ErrInvalidAlias, alias)
+		}
+	}
+
+	//	keys of this shape belong to the key generator and may be handed out later
+	if s.keyGenerator.IsGenerated(alias) {
+		return fmt.Errorf("%w: aliases of this shape are reserved for generated keys", ErrInvalidAlias)
+	}
+	return nil
+}
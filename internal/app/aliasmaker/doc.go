@@ -6,7 +6,11 @@ Package aliasmaker povides the implementation of a link shortening service
 	...
 
 	//	Create new short URL
-	shortKey, err := service.GetShortKey(r.Context(), userID, http://example.com)
+	shortKey, err := service.GetShortKey(r.Context(), userID, http://example.com, "")
+	...
+
+	//	Create new short URL with custom alias
+	shortKey, err := service.GetShortKey(r.Context(), userID, http://example.com, "spring-sale")
 	...
 
 	//	Get original URL
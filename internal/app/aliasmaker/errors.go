@@ -8,6 +8,23 @@ var (
 
 	ErrURLNotFound   = errors.New("url not found")
 	ErrURLWasDeleted = errors.New("url was deleted")
+	ErrURLExpired    = errors.New("url has expired")
+	ErrURLDisabled   = errors.New("url was disabled")
 
-	ErrConflictURL = errors.New("this URL already exists")
+	ErrConflictURL   = errors.New("this URL already exists")
+	ErrConflictAlias = errors.New("this alias is already taken")
+	ErrInvalidAlias  = errors.New("invalid alias")
+
+	ErrKeyspaceExhausted = errors.New("no free short keys left")
+
+	ErrAPIKeyNotFound    = errors.New("api key not found")
+	ErrAPIKeyNotValid    = errors.New("api key is not valid")
+	ErrInvalidAPIKeyName = errors.New("invalid api key name")
+	ErrInvalidScope      = errors.New("invalid api key scope")
+
+	ErrConflictLogin      = errors.New("this login is already taken")
+	ErrInvalidLogin       = errors.New("invalid login")
+	ErrInvalidPassword    = errors.New("invalid password")
+	ErrInvalidCredentials = errors.New("wrong login or password")
+	ErrUserNotFound       = errors.New("user not found")
 )
@@ -29,7 +29,7 @@ func Example() {
 	userID := uint64(1)
 
 	//	Get short key by original URL
-	shortKey, err := service.GetShortKey(context.Background(), userID, "https://example.com")
+	shortKey, err := service.GetShortKey(context.Background(), userID, "https://example.com", "", nil)
 	if err != nil {
 		panic("Can't create shortKey")
 	}
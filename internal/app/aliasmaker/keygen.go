@@ -0,0 +1,370 @@
+package aliasmaker
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+)
+
+// Names of key generators
+const (
+	KeyGeneratorSequential = "sequential" //	base62 counter
+	KeyGeneratorRandom     = "random"     //	cryptographically random keys
+	KeyGeneratorObfuscated = "obfuscated" //	counter scrambled by a salt, like hashids
+)
+
+// generatorCharset is the set of characters the key generators build keys from
+const generatorCharset = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// KeyLenDefault is the default length of generated keys
+const KeyLenDefault = 9
+
+// keyMaxLen is the limit of length of generated keys, it is the size of short key column in storage
+const keyMaxLen = 64
+
+// randomKeyAttempts is how many times the random generator tries to find a free key
+const randomKeyAttempts = 10
+
+// KeyGenerator creates short keys of new aliases. Implementations are safe for concurrent use
+type KeyGenerator interface {
+	//	NewKey returns a new key or ErrKeyspaceExhausted when there are no free keys left
+	NewKey(ctx context.Context) (string, error)
+	//	IsGenerated reports whether the key has the shape of the keys the generator makes.
+	//	Custom aliases of this shape are not allowed
+	IsGenerated(key string) bool
+}
+
+// ------------------------------------------------------------
+//
+//	Create key generator by name
+//	Input:
+//		name - one of KeyGeneratorSequential, KeyGeneratorRandom, KeyGeneratorObfuscated
+//		keyLen - length of keys
+//		salt - secret of the obfuscated generator
+//		rangeSize - count of keys reserved in storage at once
+//		storage - storage of aliases
+func NewKeyGenerator(name string, keyLen int, salt string, rangeSize uint64, storage Storager) (KeyGenerator, error) {
+
+	switch name {
+	case KeyGeneratorSequential:
+		return NewSequentialGenerator(keyLen, storage.GetLastShortKey(), storage, rangeSize)
+	case KeyGeneratorRandom:
+		return NewRandomGenerator(keyLen, storage)
+	case KeyGeneratorObfuscated:
+		return NewObfuscatedGenerator(keyLen, salt, storage.GetLastShortKey(), storage, rangeSize)
+	default:
+		return nil, fmt.Errorf("unknown key generator: %s", name)
+	}
+}
+
+// keyspace returns count of keyLen-character base62 keys
+func keyspace(keyLen int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(int64(len(generatorCharset))), big.NewInt(int64(keyLen)), nil)
+}
+
+// counterLimit returns the limit of counter values for keyLen-character keys
+func counterLimit(keyLen int) uint64 {
+
+	space := keyspace(keyLen)
+	if space.Cmp(new(big.Int).SetUint64(keyCounterLimit)) > 0 {
+		return keyCounterLimit
+	}
+	return space.Uint64()
+}
+
+// counterStart returns the value of a new counter: the one following the counter of lastKey
+func counterStart(lastCounter *big.Int, limit uint64) uint64 {
+
+	if lastCounter == nil {
+		return 0
+	}
+	if !lastCounter.IsUint64() || lastCounter.Uint64() >= limit-1 {
+		return limit
+	}
+	return lastCounter.Uint64() + 1
+}
+
+// base62Shape reports whether the key has keyLen characters of generatorCharset
+func base62Shape(key string, keyLen int) bool {
+
+	if len(key) != keyLen {
+		return false
+	}
+	for _, char := range key {
+		if !strings.ContainsRune(generatorCharset, char) {
+			return false
+		}
+	}
+	return true
+}
+
+// SequentialGenerator makes keys by writing a counter in base62: "000000000", "000000001", ...
+// Values of the counter are reserved in storage by ranges, so instances sharing storage
+// make different keys
+type SequentialGenerator struct {
+	keyLen  int
+	counter *rangeCounter
+}
+
+// ------------------------------------------------------------
+//
+//	SequentialGenerator constructor
+//	Input:
+//		keyLen - length of keys
+//		lastKey - the last key made before, empty if there are no keys yet.
+//			It is used only if the counter does not exist in storage yet
+//		reserver - storage of key counters
+//		rangeSize - count of keys reserved in storage at once
+func NewSequentialGenerator(keyLen int, lastKey string, reserver KeyRangeReserver, rangeSize uint64) (*SequentialGenerator, error) {
+
+	if keyLen <= 0 || keyLen > keyMaxLen {
+		return nil, fmt.Errorf("key length must be from 1 to %d", keyMaxLen)
+	}
+
+	var lastCounter *big.Int
+	if lastKey != "" {
+		if !base62Shape(lastKey, keyLen) {
+			return nil, fmt.Errorf("the last key \"%s\" can't be made by sequential generator with key length %d", lastKey, keyLen)
+		}
+		lastCounter = decodeBase62(lastKey, generatorCharset)
+	}
+
+	limit := counterLimit(keyLen)
+	counter, err := newRangeCounter(fmt.Sprintf("%s-%d", KeyGeneratorSequential, keyLen), reserver, counterStart(lastCounter, limit), limit, rangeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SequentialGenerator{
+		keyLen:  keyLen,
+		counter: counter,
+	}, nil
+}
+
+// NewKey returns the key of the next counter value
+func (g *SequentialGenerator) NewKey(ctx context.Context) (string, error) {
+
+	n, err := g.counter.next(ctx)
+	if errors.Is(err, ErrKeyspaceExhausted) {
+		return "", fmt.Errorf("%w: all %d-character sequential keys are used", ErrKeyspaceExhausted, g.keyLen)
+	}
+	if err != nil {
+		return "", err
+	}
+	return encodeBase62(new(big.Int).SetUint64(n), generatorCharset, g.keyLen), nil
+}
+
+// IsGenerated reports whether the key is a base62 key of the generator length
+func (g *SequentialGenerator) IsGenerated(key string) bool {
+	return base62Shape(key, g.keyLen)
+}
+
+// RandomGenerator makes cryptographically random base62 keys.
+// A key is checked in the storage and made again if it is already used
+type RandomGenerator struct {
+	keyLen  int
+	storage Storager
+}
+
+// ------------------------------------------------------------
+//
+//	RandomGenerator constructor
+//	Input:
+//		keyLen - length of keys
+//		storage - storage the keys are checked in
+func NewRandomGenerator(keyLen int, storage Storager) (*RandomGenerator, error) {
+
+	if keyLen <= 0 || keyLen > keyMaxLen {
+		return nil, fmt.Errorf("key length must be from 1 to %d", keyMaxLen)
+	}
+
+	return &RandomGenerator{
+		keyLen:  keyLen,
+		storage: storage,
+	}, nil
+}
+
+// NewKey returns a random key which is not used yet
+func (g *RandomGenerator) NewKey(ctx context.Context) (string, error) {
+
+	charsetLen := big.NewInt(int64(len(generatorCharset)))
+	key := make([]byte, g.keyLen)
+
+	for attempt := 0; attempt < randomKeyAttempts; attempt++ {
+		for i := range key {
+			n, err := rand.Int(rand.Reader, charsetLen)
+			if err != nil {
+				return "", err
+			}
+			key[i] = generatorCharset[n.Int64()]
+		}
+
+		ctxFind, cancelFind := context.WithTimeout(ctx, time.Second*1)
+		_, err := g.storage.FindByShortKey(ctxFind, string(key))
+		cancelFind()
+		if errors.Is(err, aliasentity.ErrNotFound) {
+			return string(key), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("can't check random key %s: %w", key, err)
+		}
+	}
+	return "", fmt.Errorf("%w: %d random %d-character keys in a row are used", ErrKeyspaceExhausted, randomKeyAttempts, g.keyLen)
+}
+
+// IsGenerated reports whether the key is a base62 key of the generator length
+func (g *RandomGenerator) IsGenerated(key string) bool {
+	return base62Shape(key, g.keyLen)
+}
+
+// ObfuscatedGenerator makes keys from a counter like hashids do: the counter is
+// scrambled by a bijection derived from the salt and written with a shuffled alphabet.
+// Keys look random, but every counter value gives its own key.
+// Values of the counter are reserved in storage by ranges like SequentialGenerator does
+type ObfuscatedGenerator struct {
+	keyLen   int
+	alphabet string
+	space    *big.Int //	count of keys, 62^keyLen
+	factor   *big.Int //	multiplier coprime to space
+	inverse  *big.Int //	inverse of factor modulo space
+	offset   *big.Int //	added to the scrambled counter
+	counter  *rangeCounter
+}
+
+// ------------------------------------------------------------
+//
+//	ObfuscatedGenerator constructor
+//	Input:
+//		keyLen - length of keys
+//		salt - secret the keys are scrambled with, it must not change while storage is used
+//		lastKey - the last key made before, empty if there are no keys yet.
+//			It is used only if the counter does not exist in storage yet
+//		reserver - storage of key counters
+//		rangeSize - count of keys reserved in storage at once
+func NewObfuscatedGenerator(keyLen int, salt, lastKey string, reserver KeyRangeReserver, rangeSize uint64) (*ObfuscatedGenerator, error) {
+
+	if keyLen <= 0 || keyLen > keyMaxLen {
+		return nil, fmt.Errorf("key length must be from 1 to %d", keyMaxLen)
+	}
+
+	seed := sha256.Sum256([]byte(salt))
+
+	g := &ObfuscatedGenerator{
+		keyLen:   keyLen,
+		alphabet: shuffle(generatorCharset, seed[:]),
+		space:    keyspace(keyLen),
+	}
+
+	//	62^keyLen has only prime factors 2 and 31, so the multiplier must not be divisible by them
+	factor := new(big.Int).SetUint64(binary.BigEndian.Uint64(seed[8:16]))
+	factor.Mod(factor, g.space)
+	for new(big.Int).GCD(nil, nil, factor, g.space).Cmp(big.NewInt(1)) != 0 {
+		factor.Add(factor, big.NewInt(1))
+		factor.Mod(factor, g.space)
+	}
+	g.factor = factor
+	g.inverse = new(big.Int).ModInverse(factor, g.space)
+	g.offset = new(big.Int).Mod(new(big.Int).SetUint64(binary.BigEndian.Uint64(seed[16:24])), g.space)
+
+	var lastCounter *big.Int
+	if lastKey != "" {
+		var ok bool
+		if lastCounter, ok = g.decode(lastKey); !ok {
+			return nil, fmt.Errorf("the last key \"%s\" can't be made by obfuscated generator with key length %d", lastKey, keyLen)
+		}
+	}
+
+	limit := counterLimit(keyLen)
+	counter, err := newRangeCounter(fmt.Sprintf("%s-%d", KeyGeneratorObfuscated, keyLen), reserver, counterStart(lastCounter, limit), limit, rangeSize)
+	if err != nil {
+		return nil, err
+	}
+	g.counter = counter
+	return g, nil
+}
+
+// NewKey returns the key of the next counter value
+func (g *ObfuscatedGenerator) NewKey(ctx context.Context) (string, error) {
+
+	n, err := g.counter.next(ctx)
+	if errors.Is(err, ErrKeyspaceExhausted) {
+		return "", fmt.Errorf("%w: all %d-character obfuscated keys are used", ErrKeyspaceExhausted, g.keyLen)
+	}
+	if err != nil {
+		return "", err
+	}
+	return g.encode(new(big.Int).SetUint64(n)), nil
+}
+
+// IsGenerated reports whether the key is a base62 key of the generator length
+func (g *ObfuscatedGenerator) IsGenerated(key string) bool {
+	return base62Shape(key, g.keyLen)
+}
+
+// encode scrambles the counter and writes it with the generator alphabet
+func (g *ObfuscatedGenerator) encode(counter *big.Int) string {
+
+	n := new(big.Int).Mul(counter, g.factor)
+	n.Add(n, g.offset)
+	n.Mod(n, g.space)
+	return encodeBase62(n, g.alphabet, g.keyLen)
+}
+
+// decode returns the counter the key was made from
+func (g *ObfuscatedGenerator) decode(key string) (*big.Int, bool) {
+
+	if !base62Shape(key, g.keyLen) {
+		return nil, false
+	}
+
+	n := decodeBase62(key, g.alphabet)
+	n.Sub(n, g.offset)
+	n.Mul(n, g.inverse)
+	n.Mod(n, g.space)
+	return n, true
+}
+
+// encodeBase62 writes n with keyLen characters of alphabet, the first character is zero
+func encodeBase62(n *big.Int, alphabet string, keyLen int) string {
+
+	n = new(big.Int).Set(n)
+	base := big.NewInt(int64(len(alphabet)))
+	digit := new(big.Int)
+	key := make([]byte, keyLen)
+	for i := keyLen - 1; i >= 0; i-- {
+		n.DivMod(n, base, digit)
+		key[i] = alphabet[digit.Int64()]
+	}
+	return string(key)
+}
+
+// decodeBase62 returns the number written in key with characters of alphabet
+func decodeBase62(key, alphabet string) *big.Int {
+
+	base := big.NewInt(int64(len(alphabet)))
+	n := new(big.Int)
+	for i := 0; i < len(key); i++ {
+		n.Mul(n, base)
+		n.Add(n, big.NewInt(int64(strings.IndexByte(alphabet, key[i]))))
+	}
+	return n
+}
+
+// shuffle returns the characters of alphabet mixed in the order defined by seed
+func shuffle(alphabet string, seed []byte) string {
+
+	chars := []byte(alphabet)
+	for i := len(chars) - 1; i > 0; i-- {
+		j := int(seed[i%len(seed)]) % (i + 1)
+		chars[i], chars[j] = chars[j], chars[i]
+	}
+	return string(chars)
+}
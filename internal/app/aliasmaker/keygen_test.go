@@ -0,0 +1,310 @@
+package aliasmaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Schalure/urlalias/internal/app/mocks"
+	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/storage/memstor"
+)
+
+func Test_SequentialGenerator(t *testing.T) {
+
+	testCases := []struct {
+		name    string
+		keyLen  int
+		lastKey string
+		want    struct {
+			newKey string
+			err    error
+		}
+	}{
+		{
+			name:    "first key",
+			keyLen:  9,
+			lastKey: "",
+			want: struct {
+				newKey string
+				err    error
+			}{
+				newKey: "000000000",
+			},
+		},
+		{
+			name:    "symple test",
+			keyLen:  9,
+			lastKey: "000000000",
+			want: struct {
+				newKey string
+				err    error
+			}{
+				newKey: "000000001",
+			},
+		},
+		{
+			name:    "overload test",
+			keyLen:  9,
+			lastKey: "00000000Z",
+			want: struct {
+				newKey string
+				err    error
+			}{
+				newKey: "000000010",
+			},
+		},
+		{
+			name:    "first position is used",
+			keyLen:  9,
+			lastKey: "0ZZZZZZZZ",
+			want: struct {
+				newKey string
+				err    error
+			}{
+				newKey: "100000000",
+			},
+		},
+		{
+			name:    "storage full test",
+			keyLen:  9,
+			lastKey: "ZZZZZZZZZ",
+			want: struct {
+				newKey string
+				err    error
+			}{
+				err: ErrKeyspaceExhausted,
+			},
+		},
+		{
+			name:    "configured length",
+			keyLen:  4,
+			lastKey: "00zZ",
+			want: struct {
+				newKey string
+				err    error
+			}{
+				newKey: "00A0",
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+
+			stor, err := memstor.NewStorage()
+			require.NoError(t, err)
+
+			g, err := NewSequentialGenerator(test.keyLen, test.lastKey, stor, KeyRangeSizeDefault)
+			require.NoError(t, err)
+
+			aliasKey, err := g.NewKey(context.Background())
+
+			assert.Equal(t, test.want.newKey, aliasKey)
+			assert.ErrorIs(t, err, test.want.err)
+		})
+	}
+
+	//	the last key of other length can't be continued
+	_, err := NewSequentialGenerator(5, "000000000", nil, KeyRangeSizeDefault)
+	assert.Error(t, err)
+}
+
+func Test_SequentialGeneratorInstances(t *testing.T) {
+
+	const (
+		instances   = 4
+		perInstance = 500
+	)
+
+	//	instances share storage, every one of them serves keys from its own ranges
+	stor, err := memstor.NewStorage()
+	require.NoError(t, err)
+
+	var (
+		mx   sync.Mutex
+		keys = make(map[string]bool)
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < instances; i++ {
+		g, err := NewSequentialGenerator(KeyLenDefault, "", stor, 10)
+		require.NoError(t, err)
+
+		//	concurrent requests of one instance
+		for r := 0; r < 2; r++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for n := 0; n < perInstance/2; n++ {
+					key, err := g.NewKey(context.Background())
+					if !assert.NoError(t, err) {
+						return
+					}
+					mx.Lock()
+					assert.False(t, keys[key], "duplicate key %s", key)
+					keys[key] = true
+					mx.Unlock()
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	assert.Len(t, keys, instances*perInstance)
+
+	//	the first key of a new instance follows all reserved ranges
+	g, err := NewSequentialGenerator(KeyLenDefault, "", stor, 10)
+	require.NoError(t, err)
+	key, err := g.NewKey(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "0000000wg", key)
+}
+
+func Test_RandomGenerator(t *testing.T) {
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	storage := mocks.NewMockStorager(mockController)
+
+	g, err := NewRandomGenerator(12, storage)
+	require.NoError(t, err)
+
+	//	used keys are skipped
+	gomock.InOrder(
+		storage.EXPECT().FindByShortKey(gomock.Any(), gomock.Any()).Return(&aliasentity.AliasURLModel{}, nil).Times(3),
+		storage.EXPECT().FindByShortKey(gomock.Any(), gomock.Any()).Return(nil, aliasentity.ErrNotFound),
+	)
+	key, err := g.NewKey(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, key, 12)
+	assert.True(t, g.IsGenerated(key))
+
+	//	keys differ
+	storage.EXPECT().FindByShortKey(gomock.Any(), gomock.Any()).Return(nil, aliasentity.ErrNotFound)
+	other, err := g.NewKey(context.Background())
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+
+	//	all keys are used
+	storage.EXPECT().FindByShortKey(gomock.Any(), gomock.Any()).Return(&aliasentity.AliasURLModel{}, nil).Times(randomKeyAttempts)
+	_, err = g.NewKey(context.Background())
+	assert.ErrorIs(t, err, ErrKeyspaceExhausted)
+
+	//	an error of storage is not a free key
+	errOutage := errors.New("connection refused")
+	storage.EXPECT().FindByShortKey(gomock.Any(), gomock.Any()).Return(nil, errOutage)
+	key, err = g.NewKey(context.Background())
+	assert.ErrorIs(t, err, errOutage)
+	assert.Empty(t, key)
+}
+
+func Test_ObfuscatedGenerator(t *testing.T) {
+
+	const keyLen = 2
+
+	newStorage := func() KeyRangeReserver {
+		stor, err := memstor.NewStorage()
+		require.NoError(t, err)
+		return stor
+	}
+
+	g, err := NewObfuscatedGenerator(keyLen, "salt", "", newStorage(), 100)
+	require.NoError(t, err)
+
+	//	every counter value gives its own key until the keyspace is exhausted
+	keys := make(map[string]bool)
+	var lastKey string
+	for {
+		key, err := g.NewKey(context.Background())
+		if errors.Is(err, ErrKeyspaceExhausted) {
+			break
+		}
+		require.NoError(t, err)
+		require.True(t, g.IsGenerated(key), key)
+		require.False(t, keys[key], "duplicate key %s", key)
+		keys[key] = true
+		lastKey = key
+	}
+	assert.Len(t, keys, len(generatorCharset)*len(generatorCharset))
+
+	//	keys are not sequential
+	first, err := NewObfuscatedGenerator(keyLen, "salt", "", newStorage(), 100)
+	require.NoError(t, err)
+	key0, _ := first.NewKey(context.Background())
+	key1, _ := first.NewKey(context.Background())
+	assert.NotEqual(t, "00", key0)
+	assert.NotEqual(t, "01", key1)
+
+	//	generator continues after the last key
+	restarted, err := NewObfuscatedGenerator(keyLen, "salt", key0, newStorage(), 100)
+	require.NoError(t, err)
+	key, err := restarted.NewKey(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, key1, key)
+
+	restarted, err = NewObfuscatedGenerator(keyLen, "salt", lastKey, newStorage(), 100)
+	require.NoError(t, err)
+	_, err = restarted.NewKey(context.Background())
+	assert.ErrorIs(t, err, ErrKeyspaceExhausted)
+
+	//	other salt gives other keys
+	salted, err := NewObfuscatedGenerator(keyLen, "pepper", "", newStorage(), 100)
+	require.NoError(t, err)
+	key, err = salted.NewKey(context.Background())
+	require.NoError(t, err)
+	assert.NotEqual(t, key0, key)
+}
+
+func Test_NewKeyGenerator(t *testing.T) {
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	storage := mocks.NewMockStorager(mockController)
+	storage.EXPECT().GetLastShortKey().Return("").AnyTimes()
+
+	for _, name := range []string{KeyGeneratorSequential, KeyGeneratorRandom, KeyGeneratorObfuscated} {
+		g, err := NewKeyGenerator(name, KeyLenDefault, "salt", KeyRangeSizeDefault, storage)
+		require.NoError(t, err, name)
+		assert.True(t, g.IsGenerated("000000000"), name)
+		assert.False(t, g.IsGenerated("spring-sale"), name)
+	}
+
+	_, err := NewKeyGenerator("uuid", KeyLenDefault, "", KeyRangeSizeDefault, storage)
+	assert.Error(t, err)
+
+	_, err = NewKeyGenerator(KeyGeneratorSequential, keyMaxLen+1, "", KeyRangeSizeDefault, storage)
+	assert.Error(t, err)
+}
+
+func Benchmark_SequentialGenerator(b *testing.B) {
+
+	stor, err := memstor.NewStorage()
+	require.NoError(b, err)
+
+	g, err := NewSequentialGenerator(KeyLenDefault, "YZZZZZZZZ", stor, KeyRangeSizeDefault)
+	require.NoError(b, err)
+
+	for i := 0; i < b.N; i++ {
+		g.NewKey(context.Background())
+	}
+}
+
+func Benchmark_ObfuscatedGenerator(b *testing.B) {
+
+	stor, err := memstor.NewStorage()
+	require.NoError(b, err)
+
+	g, err := NewObfuscatedGenerator(KeyLenDefault, "salt", "", stor, KeyRangeSizeDefault)
+	require.NoError(b, err)
+
+	for i := 0; i < b.N; i++ {
+		g.NewKey(context.Background())
+	}
+}
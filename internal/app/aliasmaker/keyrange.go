@@ -0,0 +1,125 @@
+package aliasmaker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// KeyRangeSizeDefault is the default count of keys an instance reserves in storage at once
+const KeyRangeSizeDefault = 1000
+
+// keyCounterLimit bounds counters of long keys, so counters fit the bigint column of storage
+const keyCounterLimit = uint64(1) << 62
+
+// KeyRangeReserver is the part of storage which shares key counters between instances
+type KeyRangeReserver interface {
+	//	ReserveKeyRange atomically moves the counter named name forward by size and returns
+	//	the value it had before, so the range [value, value+size) belongs to the caller only.
+	//	A counter which does not exist yet is created with value start
+	ReserveKeyRange(ctx context.Context, name string, start, size uint64) (uint64, error)
+}
+
+// keyRange is a range of counter values [next, end) reserved by the instance
+type keyRange struct {
+	next atomic.Uint64
+	end  uint64
+}
+
+// rangeCounter is a hi/lo counter: ranges of values are reserved in storage
+// and values of the current range are handed out without locking.
+// Instances sharing storage never get the same value
+type rangeCounter struct {
+	name     string
+	reserver KeyRangeReserver
+	start    uint64 //	value of the counter when it is created in storage
+	limit    uint64 //	values of the counter are less than limit
+	size     uint64 //	count of values reserved at once
+
+	current   atomic.Pointer[keyRange]
+	exhausted atomic.Bool
+	mx        sync.Mutex //	only one range is reserved at a time
+}
+
+// ------------------------------------------------------------
+//
+//	rangeCounter constructor
+//	Input:
+//		name - name of the counter in storage
+//		reserver - storage of counters
+//		start - value of the counter when it is created in storage
+//		limit - values of the counter are less than limit
+//		size - count of values reserved at once
+func newRangeCounter(name string, reserver KeyRangeReserver, start, limit, size uint64) (*rangeCounter, error) {
+
+	if reserver == nil {
+		return nil, fmt.Errorf("storage of key ranges is not set")
+	}
+	if size == 0 {
+		return nil, fmt.Errorf("size of key range must be positive")
+	}
+
+	return &rangeCounter{
+		name:     name,
+		reserver: reserver,
+		start:    start,
+		limit:    limit,
+		size:     size,
+	}, nil
+}
+
+// next returns the next value of the current range, reserving a new range when it is used up
+func (c *rangeCounter) next(ctx context.Context) (uint64, error) {
+
+	for {
+		if c.exhausted.Load() {
+			return 0, ErrKeyspaceExhausted
+		}
+
+		current := c.current.Load()
+		if current != nil {
+			if n := current.next.Add(1) - 1; n < current.end {
+				return n, nil
+			}
+		}
+
+		if err := c.reserve(ctx, current); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// reserve replaces the used range by a new one. Nothing is done if other goroutine has already replaced it
+func (c *rangeCounter) reserve(ctx context.Context, used *keyRange) error {
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.current.Load() != used {
+		return nil
+	}
+
+	ctxReserve, cancelReserve := context.WithTimeout(ctx, time.Second*1)
+	defer cancelReserve()
+
+	start, err := c.reserver.ReserveKeyRange(ctxReserve, c.name, c.start, c.size)
+	if err != nil {
+		return fmt.Errorf("can't reserve key range \"%s\": %w", c.name, err)
+	}
+	if start >= c.limit {
+		c.exhausted.Store(true)
+		return ErrKeyspaceExhausted
+	}
+
+	end := start + c.size
+	if end > c.limit || end < start {
+		end = c.limit
+	}
+
+	reserved := &keyRange{end: end}
+	reserved.next.Store(start)
+	c.current.Store(reserved)
+	return nil
+}
@@ -0,0 +1,36 @@
+package aliasmaker
+
+// Results of redirect lookups
+const (
+	RedirectHit  = "hit"  //	the alias is found and works
+	RedirectMiss = "miss" //	the alias is not found
+	RedirectGone = "gone" //	the alias was deleted or has expired
+)
+
+// Reasons of shorten conflicts
+const (
+	ConflictURL   = "url"   //	the URL is already shortened
+	ConflictAlias = "alias" //	the custom alias is already taken
+)
+
+// Metrics receives operational events of the service
+type Metrics interface {
+	//	Redirect counts a redirect lookup by its result: RedirectHit, RedirectMiss or RedirectGone
+	Redirect(result string)
+	//	ShortenConflict counts a rejected shorten request by its reason: ConflictURL or ConflictAlias
+	ShortenConflict(reason string)
+	//	DeleteBatch observes the count of aliases marked deleted at once
+	DeleteBatch(size int)
+}
+
+// noMetrics is used when metrics are not set
+type noMetrics struct{}
+
+func (noMetrics) Redirect(result string)        {}
+func (noMetrics) ShortenConflict(reason string) {}
+func (noMetrics) DeleteBatch(size int)          {}
+
+// DeleteQueueLen returns the count of delete requests waiting in the queue
+func (s *AliasMakerServise) DeleteQueueLen() int {
+	return len(s.deleterCh)
+}
@@ -0,0 +1,62 @@
+package aliasmaker
+
+import "time"
+
+// Option configures AliasMakerServise
+type Option func(*AliasMakerServise)
+
+// WithAliasRules sets the character set and the length range that custom aliases are checked against
+func WithAliasRules(charset string, minLen, maxLen int) Option {
+	return func(s *AliasMakerServise) {
+		s.updateSettings(func(settings *RuntimeSettings) {
+			settings.AliasCharset = charset
+			settings.AliasMinLen = minLen
+			settings.AliasMaxLen = maxLen
+		})
+	}
+}
+
+// WithExpireInterval sets the period of marking expired aliases
+func WithExpireInterval(interval time.Duration) Option {
+	return func(s *AliasMakerServise) {
+		s.expireInterval = interval
+	}
+}
+
+// WithAnalyticsSink sets the storage of redirect statistics. By default the storage is used if it implements AnalyticsSink
+func WithAnalyticsSink(sink AnalyticsSink) Option {
+	return func(s *AliasMakerServise) {
+		s.analytics = sink
+	}
+}
+
+// WithKeyGenerator sets the generator of short keys. By default keys are made by SequentialGenerator
+func WithKeyGenerator(keyGenerator KeyGenerator) Option {
+	return func(s *AliasMakerServise) {
+		s.keyGenerator = keyGenerator
+	}
+}
+
+// WithAdminUserIDs sets IDs of registered users with the admin role. The role is checked
+// against the current settings every time the user is read, so it is taken away at once
+func WithAdminUserIDs(userIDs ...uint64) Option {
+	return func(s *AliasMakerServise) {
+		s.updateSettings(func(settings *RuntimeSettings) {
+			settings.AdminUserIDs = userIDs
+		})
+	}
+}
+
+// WithPasswordCost sets the bcrypt cost of password hashes. By default it is bcrypt.DefaultCost
+func WithPasswordCost(cost int) Option {
+	return func(s *AliasMakerServise) {
+		s.passwordCost = cost
+	}
+}
+
+// WithMetrics sets the receiver of operational events. By default events are not counted
+func WithMetrics(metrics Metrics) Option {
+	return func(s *AliasMakerServise) {
+		s.metrics = metrics
+	}
+}
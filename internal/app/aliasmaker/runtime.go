@@ -0,0 +1,47 @@
+package aliasmaker
+
+// RuntimeSettings are settings of the service which can be changed while it is running
+type RuntimeSettings struct {
+	AliasCharset string   //	AliasCharset - characters allowed in custom aliases
+	AliasMinLen  int      //	AliasMinLen - minimum length of custom alias
+	AliasMaxLen  int      //	AliasMaxLen - maximum length of custom alias
+	AdminUserIDs []uint64 //	AdminUserIDs - IDs of registered users with the admin role
+}
+
+// runtimeSettings are RuntimeSettings prepared for use
+type runtimeSettings struct {
+	RuntimeSettings
+	adminUserIDs map[uint64]bool //	adminUserIDs - set of AdminUserIDs
+}
+
+// newRuntimeSettings prepares settings for use
+func newRuntimeSettings(settings RuntimeSettings) *runtimeSettings {
+
+	rs := &runtimeSettings{
+		RuntimeSettings: settings,
+		adminUserIDs:    make(map[uint64]bool, len(settings.AdminUserIDs)),
+	}
+	for _, userID := range settings.AdminUserIDs {
+		rs.adminUserIDs[userID] = true
+	}
+	return rs
+}
+
+// RuntimeSettings returns the settings which are used by the service now
+func (s *AliasMakerServise) RuntimeSettings() RuntimeSettings {
+	return s.settings.Load().RuntimeSettings
+}
+
+// Reconfigure replaces the runtime settings of the running service at once.
+// Requests which are in progress finish with the settings they have started with
+func (s *AliasMakerServise) Reconfigure(settings RuntimeSettings) {
+	s.settings.Store(newRuntimeSettings(settings))
+}
+
+// updateSettings changes a copy of the runtime settings and replaces them
+func (s *AliasMakerServise) updateSettings(update func(settings *RuntimeSettings)) {
+
+	settings := s.RuntimeSettings()
+	update(&settings)
+	s.Reconfigure(settings)
+}
@@ -0,0 +1,45 @@
+package aliasmaker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
+	"github.com/Schalure/urlalias/internal/app/storage/memstor"
+)
+
+func Test_Reconfigure(t *testing.T) {
+
+	ctx := context.Background()
+
+	stor, err := memstor.NewStorage()
+	require.NoError(t, err)
+
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	service, err := New(stor, logger, WithPasswordCost(bcrypt.MinCost), WithAliasRules("abc", 3, 5))
+	require.NoError(t, err)
+	assert.Equal(t, RuntimeSettings{AliasCharset: "abc", AliasMinLen: 3, AliasMaxLen: 5}, service.RuntimeSettings())
+
+	userID, err := service.Register(ctx, "alice", "correct horse", nil)
+	require.NoError(t, err)
+	_, err = service.GetShortKey(ctx, userID, "https://example.com/1", "xyz", nil)
+	assert.ErrorIs(t, err, ErrInvalidAlias)
+
+	//	the running service uses new rules and admin users
+	service.Reconfigure(RuntimeSettings{AliasCharset: "xyz", AliasMinLen: 3, AliasMaxLen: 5, AdminUserIDs: []uint64{userID}})
+
+	_, err = service.GetShortKey(ctx, userID, "https://example.com/1", "xyz", nil)
+	assert.NoError(t, err)
+	_, err = service.GetShortKey(ctx, userID, "https://example.com/2", "abc", nil)
+	assert.ErrorIs(t, err, ErrInvalidAlias)
+
+	user, err := service.GetUser(ctx, userID)
+	require.NoError(t, err)
+	assert.True(t, user.IsAdmin())
+}
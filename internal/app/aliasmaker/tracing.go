@@ -0,0 +1,36 @@
+package aliasmaker
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the tracer of the service
+const instrumentationName = "github.com/Schalure/urlalias/internal/app/aliasmaker"
+
+// Attributes of service spans
+const (
+	shortKeyKey = attribute.Key("urlalias.short_key")
+	userIDKey   = attribute.Key("urlalias.user_id")
+	countKey    = attribute.Key("urlalias.count")
+)
+
+// startSpan starts a span of the service method. Spans are recorded
+// by the global tracer provider, by default they are not recorded at all
+func startSpan(ctx context.Context, method string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, "AliasMakerServise."+method, opts...)
+}
+
+// endSpan records err in the span if it is not nil and ends the span
+func endSpan(span trace.Span, err error) {
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,87 @@
+package compressor
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Compression levels of brotli. The levels above the default are too slow to compress responses on the fly
+const (
+	BrotliLevelMin     = brotli.BestSpeed
+	BrotliLevelMax     = brotli.BestCompression
+	BrotliLevelDefault = 4
+)
+
+// DecompressorBrotli type
+type DecompressorBrotli struct {
+	br *brotli.Reader
+}
+
+// ------------------------------------------------------------
+//
+//	Constructor of DecompressorBrotli
+//	Input:
+//		r io.Reader
+//	Output:
+//		*DecompressorBrotli
+//		error
+func NewDecompressorBrotli(r io.Reader) (*DecompressorBrotli, error) {
+
+	return &DecompressorBrotli{
+		br: brotli.NewReader(r),
+	}, nil
+}
+
+// Read
+func (c *DecompressorBrotli) Read(p []byte) (n int, err error) {
+	return c.br.Read(p)
+}
+
+// Close. Brotli reader holds no resources
+func (c *DecompressorBrotli) Close() error {
+	return nil
+}
+
+// Type
+func (c *DecompressorBrotli) Type() string {
+	return "br"
+}
+
+// CompressorBrotli type
+type CompressorBrotli struct {
+	bw *brotli.Writer
+}
+
+// ------------------------------------------------------------
+//
+//	Constructor of CompressorBrotli
+//	Input:
+//		w io.Writer
+//		level int - from BrotliLevelMin to BrotliLevelMax, the default level if it is out of range
+//	Output:
+//		*CompressorBrotli
+func NewCompressorBrotli(w io.Writer, level int) *CompressorBrotli {
+
+	if level < BrotliLevelMin || level > BrotliLevelMax {
+		level = BrotliLevelDefault
+	}
+	return &CompressorBrotli{
+		bw: brotli.NewWriterLevel(w, level),
+	}
+}
+
+// Write
+func (c *CompressorBrotli) Write(p []byte) (n int, err error) {
+	return c.bw.Write(p)
+}
+
+// Close
+func (c *CompressorBrotli) Close() error {
+	return c.bw.Close()
+}
+
+// Type
+func (c *CompressorBrotli) Type() string {
+	return "br"
+}
@@ -0,0 +1,91 @@
+package compressor
+
+import (
+	"compress/zlib"
+	"io"
+)
+
+// Compression levels of deflate
+const (
+	DeflateLevelMin     = zlib.BestSpeed
+	DeflateLevelMax     = zlib.BestCompression
+	DeflateLevelDefault = 6
+)
+
+// DecompressorDeflate type. HTTP "deflate" coding is the zlib format (RFC 1950)
+type DecompressorDeflate struct {
+	zr io.ReadCloser
+}
+
+// ------------------------------------------------------------
+//
+//	Constructor of DecompressorDeflate
+//	Input:
+//		r io.Reader
+//	Output:
+//		*DecompressorDeflate
+//		error
+func NewDecompressorDeflate(r io.Reader) (*DecompressorDeflate, error) {
+
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &DecompressorDeflate{
+		zr: zr,
+	}, nil
+}
+
+// Read
+func (c *DecompressorDeflate) Read(p []byte) (n int, err error) {
+	return c.zr.Read(p)
+}
+
+// Close
+func (c *DecompressorDeflate) Close() error {
+	return c.zr.Close()
+}
+
+// Type
+func (c *DecompressorDeflate) Type() string {
+	return "deflate"
+}
+
+// CompressorDeflate type
+type CompressorDeflate struct {
+	zw *zlib.Writer
+}
+
+// ------------------------------------------------------------
+//
+//	Constructor of CompressorDeflate
+//	Input:
+//		w io.Writer
+//		level int - from DeflateLevelMin to DeflateLevelMax, the default level if it is out of range
+//	Output:
+//		*CompressorDeflate
+func NewCompressorDeflate(w io.Writer, level int) *CompressorDeflate {
+
+	zw, err := zlib.NewWriterLevel(w, level)
+	if err != nil {
+		zw = zlib.NewWriter(w)
+	}
+	return &CompressorDeflate{
+		zw: zw,
+	}
+}
+
+// Write
+func (c *CompressorDeflate) Write(p []byte) (n int, err error) {
+	return c.zw.Write(p)
+}
+
+// Close
+func (c *CompressorDeflate) Close() error {
+	return c.zw.Close()
+}
+
+// Type
+func (c *CompressorDeflate) Type() string {
+	return "deflate"
+}
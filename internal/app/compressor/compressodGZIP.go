@@ -5,6 +5,13 @@ import (
 	"io"
 )
 
+// Compression levels of gzip
+const (
+	GZIPLevelMin     = gzip.BestSpeed
+	GZIPLevelMax     = gzip.BestCompression
+	GZIPLevelDefault = 6
+)
+
 // DecompressorGZIP type
 type DecompressorGZIP struct {
 	zr *gzip.Reader
@@ -53,14 +60,18 @@ type CompressorGZIP struct {
 //
 //	Constructor of CompressorGZIP
 //	Input:
-//		r io.Reader
+//		w io.Writer
+//		level int - from GZIPLevelMin to GZIPLevelMax, the default level if it is out of range
 //	Output:
 //		*CompressorGZIP
-//		error
-func NewCompressorGZIP(w io.Writer) *CompressorGZIP {
+func NewCompressorGZIP(w io.Writer, level int) *CompressorGZIP {
 
+	zw, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		zw = gzip.NewWriter(w)
+	}
 	return &CompressorGZIP{
-		zw: gzip.NewWriter(w),
+		zw: zw,
 	}
 }
 
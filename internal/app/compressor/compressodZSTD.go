@@ -0,0 +1,97 @@
+package compressor
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression levels of zstd, they are mapped to the levels of the encoder as zstd command does
+const (
+	ZSTDLevelMin     = 1
+	ZSTDLevelMax     = 22
+	ZSTDLevelDefault = 3
+)
+
+// zstdMaxWindowSize limits memory which one request body can take to decompress
+const zstdMaxWindowSize uint64 = 8 << 20
+
+// DecompressorZSTD type
+type DecompressorZSTD struct {
+	zr *zstd.Decoder
+}
+
+// ------------------------------------------------------------
+//
+//	Constructor of DecompressorZSTD
+//	Input:
+//		r io.Reader
+//	Output:
+//		*DecompressorZSTD
+//		error
+func NewDecompressorZSTD(r io.Reader) (*DecompressorZSTD, error) {
+
+	zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(zstdMaxWindowSize))
+	if err != nil {
+		return nil, err
+	}
+	return &DecompressorZSTD{
+		zr: zr,
+	}, nil
+}
+
+// Read
+func (c *DecompressorZSTD) Read(p []byte) (n int, err error) {
+	return c.zr.Read(p)
+}
+
+// Close
+func (c *DecompressorZSTD) Close() error {
+	c.zr.Close()
+	return nil
+}
+
+// Type
+func (c *DecompressorZSTD) Type() string {
+	return "zstd"
+}
+
+// CompressorZSTD type
+type CompressorZSTD struct {
+	zw *zstd.Encoder
+}
+
+// ------------------------------------------------------------
+//
+//	Constructor of CompressorZSTD
+//	Input:
+//		w io.Writer
+//		level int - from ZSTDLevelMin to ZSTDLevelMax, the default level if it is out of range
+//	Output:
+//		*CompressorZSTD
+func NewCompressorZSTD(w io.Writer, level int) *CompressorZSTD {
+
+	if level < ZSTDLevelMin || level > ZSTDLevelMax {
+		level = ZSTDLevelDefault
+	}
+	//	NewWriter fails only on invalid options
+	zw, _ := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1), zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	return &CompressorZSTD{
+		zw: zw,
+	}
+}
+
+// Write
+func (c *CompressorZSTD) Write(p []byte) (n int, err error) {
+	return c.zw.Write(p)
+}
+
+// Close
+func (c *CompressorZSTD) Close() error {
+	return c.zw.Close()
+}
+
+// Type
+func (c *CompressorZSTD) Type() string {
+	return "zstd"
+}
@@ -0,0 +1,75 @@
+package compressor
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressors(t *testing.T) {
+
+	type compressor interface {
+		io.WriteCloser
+		Type() string
+	}
+	type decompressor interface {
+		io.ReadCloser
+		Type() string
+	}
+
+	testCases := []struct {
+		name            string
+		newCompressor   func(w io.Writer) compressor
+		newDecompressor func(r io.Reader) (decompressor, error)
+	}{
+		{
+			name:            "gzip",
+			newCompressor:   func(w io.Writer) compressor { return NewCompressorGZIP(w, GZIPLevelDefault) },
+			newDecompressor: func(r io.Reader) (decompressor, error) { return NewDecompressorGZIP(r) },
+		},
+		{
+			name:            "deflate",
+			newCompressor:   func(w io.Writer) compressor { return NewCompressorDeflate(w, DeflateLevelDefault) },
+			newDecompressor: func(r io.Reader) (decompressor, error) { return NewDecompressorDeflate(r) },
+		},
+		{
+			name:            "br",
+			newCompressor:   func(w io.Writer) compressor { return NewCompressorBrotli(w, BrotliLevelDefault) },
+			newDecompressor: func(r io.Reader) (decompressor, error) { return NewDecompressorBrotli(r) },
+		},
+		{
+			name:            "zstd",
+			newCompressor:   func(w io.Writer) compressor { return NewCompressorZSTD(w, ZSTDLevelDefault) },
+			newDecompressor: func(r io.Reader) (decompressor, error) { return NewDecompressorZSTD(r) },
+		},
+	}
+
+	data := strings.Repeat(`{"url": "https://example.com/some/long/path"}`, 100)
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+
+			var buf bytes.Buffer
+			c := test.newCompressor(&buf)
+			assert.Equal(t, test.name, c.Type())
+
+			_, err := c.Write([]byte(data))
+			require.NoError(t, err)
+			require.NoError(t, c.Close())
+			assert.Less(t, buf.Len(), len(data))
+
+			d, err := test.newDecompressor(&buf)
+			require.NoError(t, err)
+			assert.Equal(t, test.name, d.Type())
+
+			got, err := io.ReadAll(d)
+			require.NoError(t, err)
+			require.NoError(t, d.Close())
+			assert.Equal(t, data, string(got))
+		})
+	}
+}
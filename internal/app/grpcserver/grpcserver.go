@@ -0,0 +1,206 @@
+// Package grpcserver implements the gRPC API of the service. It mirrors the HTTP API of package server
+package grpcserver
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative proto/shortener.proto
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	pb "github.com/Schalure/urlalias/internal/app/grpcserver/proto"
+	"github.com/Schalure/urlalias/internal/app/jwtauth"
+	"github.com/Schalure/urlalias/internal/app/server"
+)
+
+// Server type
+type Server struct {
+	pb.UnimplementedShortenerServer
+
+	userManager server.UserManager
+	shortner    server.Shortner
+	auth        *jwtauth.Manager
+	logger      *zaplogger.ZapLogger
+	baseURL     string
+}
+
+// Constructor of Server type
+func New(userManager server.UserManager, shortner server.Shortner, auth *jwtauth.Manager, logger *zaplogger.ZapLogger, baseURL string) *Server {
+
+	return &Server{
+		userManager: userManager,
+		shortner:    shortner,
+		auth:        auth,
+		logger:      logger,
+		baseURL:     baseURL,
+	}
+}
+
+// NewServer creates gRPC server with registered Shortener service.
+// interceptors are used before authentication, for example to trace calls
+func NewServer(handler *Server, interceptors ...grpc.UnaryServerInterceptor) *grpc.Server {
+
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(append(interceptors, handler.withAuthentication)...))
+	pb.RegisterShortenerServer(s, handler)
+	return s
+}
+
+// Shorten returns short URL by original URL
+func (s *Server) Shorten(ctx context.Context, in *pb.ShortenRequest) (*pb.ShortenResponse, error) {
+
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	expiresAt, err := getExpiresAt(in.GetExpiresAt(), in.GetTtlSeconds())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	shortKey, err := s.shortner.GetShortKey(ctx, userID, in.GetUrl(), in.GetAlias(), expiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, aliasmaker.ErrConflictURL):
+			return &pb.ShortenResponse{ShortUrl: s.baseURL + "/" + shortKey, AlreadyExists: true}, nil
+		case errors.Is(err, aliasmaker.ErrConflictAlias):
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		case errors.Is(err, aliasmaker.ErrInvalidAlias):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		case errors.Is(err, aliasmaker.ErrKeyspaceExhausted):
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		default:
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+	return &pb.ShortenResponse{ShortUrl: s.baseURL + "/" + shortKey}, nil
+}
+
+// ShortenBatch returns batch of short URLs by batch of original URLs
+func (s *Server) ShortenBatch(ctx context.Context, in *pb.ShortenBatchRequest) (*pb.ShortenBatchResponse, error) {
+
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	items := in.GetItems()
+	batchOriginalURL := make([]string, len(items))
+	batchExpiresAt := make([]*time.Time, len(items))
+	for i, item := range items {
+		batchOriginalURL[i] = item.GetOriginalUrl()
+		if batchExpiresAt[i], err = getExpiresAt(item.GetExpiresAt(), item.GetTtlSeconds()); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "correlation_id \"%s\": %s", item.GetCorrelationId(), err)
+		}
+	}
+
+	batchShortKey, err := s.shortner.GetBatchShortURL(ctx, userID, batchOriginalURL, batchExpiresAt)
+	if err != nil {
+		s.logger.Infow("Can't save to storage", "err", err.Error())
+		if errors.Is(err, aliasmaker.ErrKeyspaceExhausted) {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	response := &pb.ShortenBatchResponse{Items: make([]*pb.ShortenBatchResponse_Item, len(batchShortKey))}
+	for i, shortKey := range batchShortKey {
+		response.Items[i] = &pb.ShortenBatchResponse_Item{
+			CorrelationId: items[i].GetCorrelationId(),
+			ShortUrl:      s.baseURL + "/" + shortKey,
+		}
+	}
+	return response, nil
+}
+
+// Resolve returns original URL by short key
+func (s *Server) Resolve(ctx context.Context, in *pb.ResolveRequest) (*pb.ResolveResponse, error) {
+
+	originalURL, err := s.shortner.GetOriginalURL(ctx, in.GetShortKey())
+	if err != nil {
+		switch {
+		case errors.Is(err, aliasmaker.ErrURLNotFound):
+			return nil, status.Errorf(codes.NotFound, "the url alias not found by key \"%s\"", in.GetShortKey())
+		case errors.Is(err, aliasmaker.ErrURLWasDeleted), errors.Is(err, aliasmaker.ErrURLExpired), errors.Is(err, aliasmaker.ErrURLDisabled):
+			return nil, status.Errorf(codes.FailedPrecondition, "the url alias \"%s\": %s", in.GetShortKey(), err)
+		default:
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+	return &pb.ResolveResponse{OriginalUrl: originalURL}, nil
+}
+
+// ListUserURLs returns all aliases of the user
+func (s *Server) ListUserURLs(ctx context.Context, in *pb.ListUserURLsRequest) (*pb.ListUserURLsResponse, error) {
+
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	nodes, err := s.userManager.GetUserAliases(ctx, userID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	response := &pb.ListUserURLsResponse{Items: make([]*pb.ListUserURLsResponse_Item, len(nodes))}
+	for i, node := range nodes {
+		response.Items[i] = &pb.ListUserURLsResponse_Item{
+			ShortUrl:    s.baseURL + "/" + node.ShortKey,
+			OriginalUrl: node.LongURL,
+		}
+	}
+	return response, nil
+}
+
+// DeleteUserURLs adds aliases of the user to delete
+func (s *Server) DeleteUserURLs(ctx context.Context, in *pb.DeleteUserURLsRequest) (*pb.DeleteUserURLsResponse, error) {
+
+	userID, err := getUserIDFromContext(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+	if err := s.shortner.AddAliasesToDelete(ctx, userID, in.GetShortKeys()...); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return &pb.DeleteUserURLsResponse{}, nil
+}
+
+// Ping checks the storage connection
+func (s *Server) Ping(ctx context.Context, in *pb.PingRequest) (*pb.PingResponse, error) {
+
+	if !s.shortner.IsDatabaseActive(ctx) {
+		return nil, status.Error(codes.Unavailable, "storage is not connected")
+	}
+	return &pb.PingResponse{}, nil
+}
+
+// getExpiresAt converts the optional expiry fields of request to expiry time of alias
+func getExpiresAt(expiresAt *timestamppb.Timestamp, ttlSeconds int64) (*time.Time, error) {
+
+	var (
+		t   *time.Time
+		ttl *int64
+	)
+	if expiresAt != nil {
+		if err := expiresAt.CheckValid(); err != nil {
+			return nil, err
+		}
+		asTime := expiresAt.AsTime()
+		t = &asTime
+	}
+	if ttlSeconds != 0 {
+		ttl = &ttlSeconds
+	}
+	return server.GetExpiresAt(t, ttl)
+}
@@ -0,0 +1,206 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	pb "github.com/Schalure/urlalias/internal/app/grpcserver/proto"
+	"github.com/Schalure/urlalias/internal/app/jwtauth"
+	"github.com/Schalure/urlalias/internal/app/mocks"
+	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+)
+
+const testLocalHost = "http://localhost"
+
+// testAuth issues tokens of the tests
+var testAuth = func() *jwtauth.Manager {
+
+	key, err := jwtauth.GenerateHMACKey("test")
+	if err != nil {
+		panic(err)
+	}
+	auth, err := jwtauth.New([]jwtauth.Key{key}, key.ID)
+	if err != nil {
+		panic(err)
+	}
+	return auth
+}()
+
+// newTestClient starts the gRPC server on in-memory listener and returns a client connected to it
+func newTestClient(t *testing.T, userManager *mocks.MockUserManager, shortner *mocks.MockShortner) pb.ShortenerClient {
+
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := NewServer(New(userManager, shortner, testAuth, logger, testLocalHost))
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewShortenerClient(conn)
+}
+
+func Test_Shorten(t *testing.T) {
+
+	userID := uint64(1)
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	userManager := mocks.NewMockUserManager(mockController)
+	shortner := mocks.NewMockShortner(mockController)
+	client := newTestClient(t, userManager, shortner)
+
+	//	new user gets a token in header metadata
+	userManager.EXPECT().CreateUser(gomock.Any()).Return(userID, nil)
+	shortner.EXPECT().GetShortKey(gomock.Any(), userID, "https://ya.ru", "", nil).Return("000000001", nil)
+
+	var header metadata.MD
+	response, err := client.Shorten(context.Background(), &pb.ShortenRequest{Url: "https://ya.ru"}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, testLocalHost+"/000000001", response.GetShortUrl())
+	assert.False(t, response.GetAlreadyExists())
+
+	tokens := header.Get(authorization)
+	require.Len(t, tokens, 1)
+	tokenUserID, err := testAuth.GetUserID(tokens[0])
+	require.NoError(t, err)
+	assert.Equal(t, userID, tokenUserID)
+
+	//	known user reuses the token
+	shortner.EXPECT().GetShortKey(gomock.Any(), userID, "https://ya.ru", "", nil).Return("000000001", aliasmaker.ErrConflictURL)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authorization, "Bearer "+tokens[0])
+	response, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "https://ya.ru"})
+	require.NoError(t, err)
+	assert.Equal(t, testLocalHost+"/000000001", response.GetShortUrl())
+	assert.True(t, response.GetAlreadyExists())
+
+	//	taken custom alias
+	shortner.EXPECT().GetShortKey(gomock.Any(), userID, "https://goo.com", "spring-sale", nil).Return("", aliasmaker.ErrConflictAlias)
+
+	_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "https://goo.com", Alias: "spring-sale"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+}
+
+func Test_Resolve(t *testing.T) {
+
+	testCases := []struct {
+		name     string
+		outURL   string
+		outErr   error
+		wantCode codes.Code
+	}{
+		{name: "simple test", outURL: "https://ya.ru", outErr: nil, wantCode: codes.OK},
+		{name: "not found test", outURL: "", outErr: aliasmaker.ErrURLNotFound, wantCode: codes.NotFound},
+		{name: "deleted test", outURL: "", outErr: aliasmaker.ErrURLWasDeleted, wantCode: codes.FailedPrecondition},
+		{name: "expired test", outURL: "", outErr: aliasmaker.ErrURLExpired, wantCode: codes.FailedPrecondition},
+	}
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	userManager := mocks.NewMockUserManager(mockController)
+	shortner := mocks.NewMockShortner(mockController)
+	client := newTestClient(t, userManager, shortner)
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+
+			shortner.EXPECT().GetOriginalURL(gomock.Any(), "000000001").Return(test.outURL, test.outErr)
+
+			response, err := client.Resolve(context.Background(), &pb.ResolveRequest{ShortKey: "000000001"})
+			assert.Equal(t, test.wantCode, status.Code(err))
+			assert.Equal(t, test.outURL, response.GetOriginalUrl())
+		})
+	}
+}
+
+func Test_ListUserURLs(t *testing.T) {
+
+	userID := uint64(1)
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	userManager := mocks.NewMockUserManager(mockController)
+	shortner := mocks.NewMockShortner(mockController)
+	client := newTestClient(t, userManager, shortner)
+
+	_, err := client.ListUserURLs(context.Background(), &pb.ListUserURLsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	userManager.EXPECT().GetUserAliases(gomock.Any(), userID).Return([]aliasentity.AliasURLModel{
+		{ShortKey: "000000001", LongURL: "https://ya.ru"},
+	}, nil)
+
+	tokenString, err := testAuth.CreateToken(userID)
+	require.NoError(t, err)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authorization, tokenString)
+
+	response, err := client.ListUserURLs(ctx, &pb.ListUserURLsRequest{})
+	require.NoError(t, err)
+	require.Len(t, response.GetItems(), 1)
+	assert.Equal(t, testLocalHost+"/000000001", response.GetItems()[0].GetShortUrl())
+	assert.Equal(t, "https://ya.ru", response.GetItems()[0].GetOriginalUrl())
+}
+
+func Test_APIKey(t *testing.T) {
+
+	userID := uint64(1)
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	userManager := mocks.NewMockUserManager(mockController)
+	shortner := mocks.NewMockShortner(mockController)
+	client := newTestClient(t, userManager, shortner)
+
+	userManager.EXPECT().AuthenticateAPIKey(gomock.Any(), "ua_shorten").Return(&apikeyentity.APIKeyModel{
+		UserID: userID,
+		Scopes: []string{apikeyentity.ScopeShorten},
+	}, nil).Times(2)
+	userManager.EXPECT().AuthenticateAPIKey(gomock.Any(), "ua_unknown").Return(nil, aliasmaker.ErrAPIKeyNotValid)
+
+	//	the key resolves to its owner, a token is not issued
+	shortner.EXPECT().GetShortKey(gomock.Any(), userID, "https://ya.ru", "", nil).Return("000000001", nil)
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), apiKeyMetadata, "ua_shorten")
+	_, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "https://ya.ru"}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Empty(t, header.Get(authorization))
+
+	//	scopes of the key are enforced
+	_, err = client.ListUserURLs(ctx, &pb.ListUserURLsRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	//	not valid key is rejected, a new user is not created
+	ctx = metadata.AppendToOutgoingContext(context.Background(), apiKeyMetadata, "ua_unknown")
+	_, err = client.Shorten(ctx, &pb.ShortenRequest{Url: "https://ya.ru"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,115 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Schalure/urlalias/internal/app/grpcserver/proto"
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+)
+
+// authorization is the metadata key of JWT token
+const authorization = "authorization"
+
+// apiKeyMetadata is the metadata key of API key
+const apiKeyMetadata = "x-api-key"
+
+// methodScopes are scopes of API keys required by methods which work with user data
+var methodScopes = map[string]string{
+	pb.Shortener_Shorten_FullMethodName:        apikeyentity.ScopeShorten,
+	pb.Shortener_ShortenBatch_FullMethodName:   apikeyentity.ScopeShorten,
+	pb.Shortener_ListUserURLs_FullMethodName:   apikeyentity.ScopeRead,
+	pb.Shortener_DeleteUserURLs_FullMethodName: apikeyentity.ScopeDelete,
+}
+
+type contextKey string
+
+// userIDKey is the context key of authenticated user ID
+const userIDKey contextKey = "userID"
+
+// withAuthentication interceptor authenticates users of methods which work with user data.
+// Shorten and ShortenBatch create a new user if token is missing or not valid,
+// ListUserURLs and DeleteUserURLs require a valid token.
+// A call with API key is authenticated by the key only, the key must have the scope of the method
+func (s *Server) withAuthentication(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+	if scope, ok := methodScopes[info.FullMethod]; ok {
+		if apiKey := getMetadata(ctx, apiKeyMetadata); apiKey != "" {
+			key, err := s.userManager.AuthenticateAPIKey(ctx, apiKey)
+			if err != nil {
+				s.logger.Infow("withAuthentication: key, err := s.userManager.AuthenticateAPIKey(ctx, apiKey)", "error", err)
+				return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+			}
+			if !key.HasScope(scope) {
+				return nil, status.Errorf(codes.PermissionDenied, "API key has no \"%s\" scope", scope)
+			}
+			return handler(context.WithValue(ctx, userIDKey, key.UserID), req)
+		}
+	}
+
+	switch info.FullMethod {
+	case pb.Shortener_Shorten_FullMethodName, pb.Shortener_ShortenBatch_FullMethodName:
+		userID, err := s.auth.GetUserID(getToken(ctx))
+		if err != nil {
+			if userID, err = s.userManager.CreateUser(ctx); err != nil {
+				s.logger.Infow("withAuthentication: userID, err = s.userManager.CreateUser(ctx)", "error", err)
+				return nil, status.Error(codes.Internal, "internal error")
+			}
+			tokenString, err := s.auth.CreateToken(userID)
+			if err != nil {
+				s.logger.Infow("withAuthentication: tokenString, err = s.auth.CreateToken(userID)", "error", err)
+				return nil, status.Error(codes.Internal, "internal error")
+			}
+			if err := grpc.SetHeader(ctx, metadata.Pairs(authorization, tokenString)); err != nil {
+				return nil, status.Error(codes.Internal, "internal error")
+			}
+			s.logger.Infow("Add new user", "userID", userID)
+		}
+		ctx = context.WithValue(ctx, userIDKey, userID)
+
+	case pb.Shortener_ListUserURLs_FullMethodName, pb.Shortener_DeleteUserURLs_FullMethodName:
+		userID, err := s.auth.GetUserID(getToken(ctx))
+		if err != nil {
+			s.logger.Infow("withAuthentication: userID, err = s.auth.GetUserID(token)", "error", err)
+			return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+		}
+		ctx = context.WithValue(ctx, userIDKey, userID)
+	}
+
+	return handler(ctx, req)
+}
+
+// getToken returns JWT token from incoming metadata. "Bearer " prefix is allowed
+func getToken(ctx context.Context) string {
+	return strings.TrimPrefix(getMetadata(ctx, authorization), "Bearer ")
+}
+
+// getMetadata returns the first value of incoming metadata by key
+func getMetadata(ctx context.Context, key string) string {
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// getUserIDFromContext returns user ID set by withAuthentication
+func getUserIDFromContext(ctx context.Context) (uint64, error) {
+
+	userID, ok := ctx.Value(userIDKey).(uint64)
+	if !ok {
+		return 0, errors.New("login is not valid")
+	}
+	return userID, nil
+}
@@ -0,0 +1,154 @@
+// Package jwtauth issues and parses JWT tokens which identify users of the service.
+//
+// Tokens are signed by the active key of a key set and carry its ID in "kid" header.
+// Keys retired from signing stay in the set, so tokens they signed are valid until they expire
+package jwtauth
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// TokenExpDefault is the default lifetime of tokens
+const TokenExpDefault = time.Hour * 3
+
+// Claims type
+type Claims struct {
+	jwt.RegisteredClaims
+	UserID uint64
+}
+
+// Manager issues and verifies tokens. Manager is safe for concurrent use
+type Manager struct {
+	state atomic.Pointer[keySet] //	state - key set and options, they are replaced at once by Update
+}
+
+// keySet is the state of Manager
+type keySet struct {
+	keys     map[string]Key //	keys - [key, value] = [kid, key]
+	active   Key            //	active - key which signs new tokens
+	tokenExp time.Duration  //	tokenExp - lifetime of new tokens
+}
+
+// Option configures Manager
+type Option func(*keySet)
+
+// WithTokenExp sets the lifetime of new tokens
+func WithTokenExp(tokenExp time.Duration) Option {
+	return func(ks *keySet) {
+		ks.tokenExp = tokenExp
+	}
+}
+
+// ------------------------------------------------------------
+//
+//	Manager constructor
+//	Input:
+//		keys - key set, IDs of keys must be unique
+//		activeID - kid of the key which signs new tokens, it must have the private part
+func New(keys []Key, activeID string, opts ...Option) (*Manager, error) {
+
+	m := &Manager{}
+	if err := m.Update(keys, activeID, opts...); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Update replaces the key set and options of the running Manager at once. Options which are not set
+//	get default values. The Manager is not changed if the key set is not valid
+//	Input:
+//		keys - key set, IDs of keys must be unique
+//		activeID - kid of the key which signs new tokens, it must have the private part
+func (m *Manager) Update(keys []Key, activeID string, opts ...Option) error {
+
+	ks := &keySet{
+		keys:     make(map[string]Key, len(keys)),
+		tokenExp: TokenExpDefault,
+	}
+	for _, key := range keys {
+		if _, ok := ks.keys[key.ID]; ok {
+			return fmt.Errorf("key \"%s\" is duplicated", key.ID)
+		}
+		ks.keys[key.ID] = key
+	}
+
+	active, ok := ks.keys[activeID]
+	if !ok {
+		return fmt.Errorf("active key \"%s\" is not found in key set", activeID)
+	}
+	if !active.CanSign() {
+		return fmt.Errorf("active key \"%s\" has no private part", activeID)
+	}
+	ks.active = active
+
+	for _, opt := range opts {
+		opt(ks)
+	}
+	if ks.tokenExp <= 0 {
+		return errors.New("lifetime of tokens must be positive")
+	}
+
+	m.state.Store(ks)
+	return nil
+}
+
+// GetUserID returns user id from JWT token string
+func (m *Manager) GetUserID(tokenString string) (uint64, error) {
+
+	ks := m.state.Load()
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := ks.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key: %v", t.Header["kid"])
+		}
+		//	the algorithm is bound to the key, so a public key can't be used as HMAC secret
+		if t.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return key.verifyKey, nil
+	})
+	if err != nil {
+		return 0, errors.New("can't parse token string")
+	}
+	if !token.Valid {
+		return 0, errors.New("token not valid")
+	}
+	return claims.UserID, nil
+}
+
+// CreateToken returns JWT token string for user id signed by the active key
+func (m *Manager) CreateToken(userID uint64) (string, error) {
+
+	ks := m.state.Load()
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(ks.active.Algorithm), Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ks.tokenExp)),
+		},
+		UserID: userID,
+	})
+	token.Header["kid"] = ks.active.ID
+
+	tokenString, err := token.SignedString(ks.active.signKey)
+	if err != nil {
+		return "", err
+	}
+
+	// возвращаем строку токена
+	return tokenString, nil
+}
+
+// TokenExp returns the lifetime of new tokens
+func (m *Manager) TokenExp() time.Duration {
+	return m.state.Load().tokenExp
+}
@@ -0,0 +1,269 @@
+package jwtauth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pemPrivateKey returns private key in PKCS#8 PEM format
+func pemPrivateKey(t *testing.T, key interface{}) []byte {
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+// pemPublicKey returns public key in PKIX PEM format
+func pemPublicKey(t *testing.T, key interface{}) []byte {
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// tokenHeader returns the header of the token
+func tokenHeader(t *testing.T, tokenString string) map[string]interface{} {
+
+	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, &Claims{})
+	require.NoError(t, err)
+	return token.Header
+}
+
+func Test_Algorithms(t *testing.T) {
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	hsKey, err := NewHMACKey("hs", []byte(strings.Repeat("s", 32)))
+	require.NoError(t, err)
+	rsKey, err := ParsePEMKey("rs", AlgorithmRS256, pemPrivateKey(t, rsaKey))
+	require.NoError(t, err)
+	eddsaKey, err := ParsePEMKey("ed", AlgorithmEdDSA, pemPrivateKey(t, edKey))
+	require.NoError(t, err)
+
+	for _, key := range []Key{hsKey, rsKey, eddsaKey} {
+		t.Run(key.Algorithm, func(t *testing.T) {
+
+			auth, err := New([]Key{key}, key.ID)
+			require.NoError(t, err)
+
+			tokenString, err := auth.CreateToken(42)
+			require.NoError(t, err)
+
+			header := tokenHeader(t, tokenString)
+			assert.Equal(t, key.Algorithm, header["alg"])
+			assert.Equal(t, key.ID, header["kid"])
+
+			userID, err := auth.GetUserID(tokenString)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(42), userID)
+		})
+	}
+}
+
+func Test_KeyRotation(t *testing.T) {
+
+	_, oldEdKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	oldKey, err := ParsePEMKey("2024-01", AlgorithmEdDSA, pemPrivateKey(t, oldEdKey))
+	require.NoError(t, err)
+
+	before, err := New([]Key{oldKey}, oldKey.ID)
+	require.NoError(t, err)
+	oldToken, err := before.CreateToken(1)
+	require.NoError(t, err)
+
+	//	the old key is retired: only its public part is kept
+	retiredKey, err := ParsePEMKey("2024-01", AlgorithmEdDSA, pemPublicKey(t, oldEdKey.Public()))
+	require.NoError(t, err)
+	assert.False(t, retiredKey.CanSign())
+	activeKey, err := GenerateHMACKey("2024-06")
+	require.NoError(t, err)
+
+	after, err := New([]Key{activeKey, retiredKey}, activeKey.ID)
+	require.NoError(t, err)
+
+	//	tokens of the retired key are still valid
+	userID, err := after.GetUserID(oldToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), userID)
+
+	//	new tokens are signed by the active key
+	newToken, err := after.CreateToken(2)
+	require.NoError(t, err)
+	assert.Equal(t, activeKey.ID, tokenHeader(t, newToken)["kid"])
+
+	//	retired key can't be active
+	_, err = New([]Key{activeKey, retiredKey}, retiredKey.ID)
+	assert.Error(t, err)
+
+	//	tokens of keys removed from the set are not valid
+	removed, err := New([]Key{activeKey}, activeKey.ID)
+	require.NoError(t, err)
+	_, err = removed.GetUserID(oldToken)
+	assert.Error(t, err)
+}
+
+func Test_Update(t *testing.T) {
+
+	oldKey, err := GenerateHMACKey("old")
+	require.NoError(t, err)
+	auth, err := New([]Key{oldKey}, oldKey.ID)
+	require.NoError(t, err)
+	oldToken, err := auth.CreateToken(1)
+	require.NoError(t, err)
+
+	//	the running manager gets the rotated key set and the new lifetime of tokens
+	newKey, err := GenerateHMACKey("new")
+	require.NoError(t, err)
+	require.NoError(t, auth.Update([]Key{newKey, oldKey}, newKey.ID, WithTokenExp(time.Minute)))
+	assert.Equal(t, time.Minute, auth.TokenExp())
+
+	userID, err := auth.GetUserID(oldToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), userID)
+	newToken, err := auth.CreateToken(2)
+	require.NoError(t, err)
+	assert.Equal(t, newKey.ID, tokenHeader(t, newToken)["kid"])
+
+	//	the key set which is not valid doesn't change the manager
+	assert.Error(t, auth.Update([]Key{oldKey}, newKey.ID))
+	_, err = auth.GetUserID(newToken)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, auth.TokenExp())
+}
+
+func Test_LoadOrCreateHMACKey(t *testing.T) {
+
+	secretFile := filepath.Join(t.TempDir(), "jwt-secret")
+
+	//	the secret is generated once, tokens are valid after restart
+	key, err := LoadOrCreateHMACKey("generated", secretFile)
+	require.NoError(t, err)
+	auth, err := New([]Key{key}, key.ID)
+	require.NoError(t, err)
+	token, err := auth.CreateToken(1)
+	require.NoError(t, err)
+
+	info, err := os.Stat(secretFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	restartedKey, err := LoadOrCreateHMACKey("generated", secretFile)
+	require.NoError(t, err)
+	restarted, err := New([]Key{restartedKey}, restartedKey.ID)
+	require.NoError(t, err)
+	userID, err := restarted.GetUserID(token)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), userID)
+
+	//	the secret written by the operator is used as is
+	require.NoError(t, os.WriteFile(secretFile, []byte("operator secret which is long enough\n"), 0600))
+	_, err = LoadOrCreateHMACKey("generated", secretFile)
+	assert.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(secretFile, []byte("short"), 0600))
+	_, err = LoadOrCreateHMACKey("generated", secretFile)
+	assert.Error(t, err)
+
+	_, err = LoadOrCreateHMACKey("generated", filepath.Join(t.TempDir(), "missing", "jwt-secret"))
+	assert.Error(t, err)
+}
+
+func Test_GetUserIDNotValid(t *testing.T) {
+
+	key, err := GenerateHMACKey("hs")
+	require.NoError(t, err)
+	auth, err := New([]Key{key}, key.ID, WithTokenExp(time.Minute))
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	sign := func(method jwt.SigningMethod, kid string, signKey interface{}, expiresAt time.Time) string {
+		token := jwt.NewWithClaims(method, Claims{
+			RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(expiresAt)},
+			UserID:           1,
+		})
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		tokenString, err := token.SignedString(signKey)
+		require.NoError(t, err)
+		return tokenString
+	}
+
+	testCases := []struct {
+		name        string
+		tokenString string
+	}{
+		{name: "expired", tokenString: sign(jwt.SigningMethodHS256, "hs", key.signKey, time.Now().Add(-time.Minute))},
+		{name: "without kid", tokenString: sign(jwt.SigningMethodHS256, "", key.signKey, time.Now().Add(time.Minute))},
+		{name: "unknown kid", tokenString: sign(jwt.SigningMethodHS256, "other", key.signKey, time.Now().Add(time.Minute))},
+		{name: "other algorithm", tokenString: sign(jwt.SigningMethodRS256, "hs", rsaKey, time.Now().Add(time.Minute))},
+		{name: "other secret", tokenString: sign(jwt.SigningMethodHS256, "hs", []byte(strings.Repeat("x", 32)), time.Now().Add(time.Minute))},
+		{name: "garbage", tokenString: "not.a.token"},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := auth.GetUserID(test.tokenString)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_LoadKeySet(t *testing.T) {
+
+	dir := t.TempDir()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ed25519.pem"), pemPrivateKey(t, edKey), 0600))
+
+	keySetFile := filepath.Join(dir, "keys.json")
+	require.NoError(t, os.WriteFile(keySetFile, []byte(`{
+		"active": "2024-06",
+		"keys": [
+			{"kid": "2024-06", "alg": "EdDSA", "file": "ed25519.pem"},
+			{"kid": "2024-01", "alg": "HS256", "secret": "`+strings.Repeat("s", 32)+`"}
+		]
+	}`), 0600))
+
+	keys, active, err := LoadKeySet(keySetFile)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-06", active)
+	require.Len(t, keys, 2)
+	assert.Equal(t, AlgorithmEdDSA, keys[0].Algorithm)
+	assert.True(t, keys[0].CanSign())
+
+	_, err = New(keys, active)
+	require.NoError(t, err)
+
+	//	keys which are not valid
+	for name, content := range map[string]string{
+		"short secret":  `{"active": "a", "keys": [{"kid": "a", "alg": "HS256", "secret": "short"}]}`,
+		"unknown alg":   `{"active": "a", "keys": [{"kid": "a", "alg": "none", "file": "ed25519.pem"}]}`,
+		"missing file":  `{"active": "a", "keys": [{"kid": "a", "alg": "RS256", "file": "rsa.pem"}]}`,
+		"wrong key":     `{"active": "a", "keys": [{"kid": "a", "alg": "RS256", "file": "ed25519.pem"}]}`,
+		"empty set":     `{"active": "a", "keys": []}`,
+		"not json file": `active: a`,
+	} {
+		require.NoError(t, os.WriteFile(keySetFile, []byte(content), 0600))
+		_, _, err := LoadKeySet(keySetFile)
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,228 @@
+package jwtauth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Signing algorithms of tokens
+const (
+	AlgorithmHS256 = "HS256" //	HMAC with SHA-256, the key is a shared secret
+	AlgorithmRS256 = "RS256" //	RSA PKCS#1 v1.5 with SHA-256, the key is a PEM file
+	AlgorithmEdDSA = "EdDSA" //	Ed25519, the key is a PEM file
+)
+
+// hmacSecretMinLen is the minimum length of HS256 secret, it must not be shorter than the hash
+const hmacSecretMinLen = 32
+
+// Key signs and verifies tokens. A key which has only the public part
+// verifies tokens it signed before it was retired, but can't sign new ones
+type Key struct {
+	ID        string //	ID is "kid" header of tokens signed by the key
+	Algorithm string //	Algorithm is "alg" header of tokens signed by the key
+
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// ------------------------------------------------------------
+//
+//	Create HS256 key
+//	Input:
+//		id - "kid" of the key
+//		secret - shared secret, at least 32 bytes
+func NewHMACKey(id string, secret []byte) (Key, error) {
+
+	if len(secret) < hmacSecretMinLen {
+		return Key{}, fmt.Errorf("secret of key \"%s\" must be at least %d bytes", id, hmacSecretMinLen)
+	}
+	return Key{
+		ID:        id,
+		Algorithm: AlgorithmHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Create HS256 key with random secret. Tokens signed by the key
+//	are valid only while the process runs
+func GenerateHMACKey(id string) (Key, error) {
+
+	secret := make([]byte, hmacSecretMinLen)
+	if _, err := rand.Read(secret); err != nil {
+		return Key{}, err
+	}
+	return NewHMACKey(id, secret)
+}
+
+// ------------------------------------------------------------
+//
+//	Create HS256 key with the secret from the file. If the file does not exist,
+//	a random secret is generated and written to it, so tokens signed by the key
+//	stay valid after restart and on other instances which use the file
+//	Input:
+//		id - "kid" of the key
+//		fileName - file of the secret, at least 32 bytes
+func LoadOrCreateHMACKey(id, fileName string) (Key, error) {
+
+	data, err := os.ReadFile(fileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		data, err = createSecretFile(fileName)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("can't read secret of key \"%s\": %w", id, err)
+	}
+	return NewHMACKey(id, bytes.TrimSpace(data))
+}
+
+// createSecretFile writes a random secret to a new file. If the file is created
+// by another process at the same time, the secret of that process is read
+func createSecretFile(fileName string) ([]byte, error) {
+
+	secret := make([]byte, hmacSecretMinLen)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	data := []byte(hex.EncodeToString(secret))
+
+	file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, fs.ErrExist) {
+		return os.ReadFile(fileName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(fileName)
+		return nil, err
+	}
+	return data, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Create RS256 or EdDSA key from PEM data. A private key signs and verifies tokens,
+//	a public key only verifies them
+//	Input:
+//		id - "kid" of the key
+//		algorithm - AlgorithmRS256 or AlgorithmEdDSA
+//		data - PEM encoded private or public key
+func ParsePEMKey(id, algorithm string, data []byte) (Key, error) {
+
+	key := Key{ID: id, Algorithm: algorithm}
+
+	switch algorithm {
+	case AlgorithmRS256:
+		if private, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+			key.signKey, key.verifyKey = private, &private.PublicKey
+		} else if public, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+			key.verifyKey = public
+		} else {
+			return Key{}, fmt.Errorf("key \"%s\" is not RSA key in PEM format", id)
+		}
+
+	case AlgorithmEdDSA:
+		if private, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+			key.signKey, key.verifyKey = private, private.(crypto.Signer).Public()
+		} else if public, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+			key.verifyKey = public
+		} else {
+			return Key{}, fmt.Errorf("key \"%s\" is not Ed25519 key in PEM format", id)
+		}
+
+	default:
+		return Key{}, fmt.Errorf("unsupported algorithm \"%s\" of key \"%s\"", algorithm, id)
+	}
+	return key, nil
+}
+
+// CanSign reports whether the key has the private part
+func (k Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// keySetFile is the format of key set file
+type keySetFile struct {
+	Active string `json:"active"` //	kid of the key which signs new tokens
+	Keys   []struct {
+		ID        string `json:"kid"`
+		Algorithm string `json:"alg"`
+		Secret    string `json:"secret,omitempty"` //	secret of HS256 key
+		File      string `json:"file,omitempty"`   //	PEM file of RS256 or EdDSA key, relative to the key set file
+	} `json:"keys"`
+}
+
+// ------------------------------------------------------------
+//
+//	Load key set from JSON file:
+//
+//	{
+//		"active": "2024-06",
+//		"keys": [
+//			{"kid": "2024-06", "alg": "EdDSA", "file": "ed25519.pem"},
+//			{"kid": "2024-01", "alg": "HS256", "secret": "..."}
+//		]
+//	}
+//
+//	Output:
+//		[]Key - all keys of the set
+//		string - kid of the active key
+//		error
+func LoadKeySet(fileName string) ([]Key, string, error) {
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var set keySetFile
+	if err = json.Unmarshal(data, &set); err != nil {
+		return nil, "", fmt.Errorf("can't parse key set file: %w", err)
+	}
+	if len(set.Keys) == 0 {
+		return nil, "", errors.New("key set is empty")
+	}
+
+	keys := make([]Key, 0, len(set.Keys))
+	for _, k := range set.Keys {
+
+		var key Key
+		if k.Algorithm == AlgorithmHS256 {
+			key, err = NewHMACKey(k.ID, []byte(k.Secret))
+		} else {
+			pemFile := k.File
+			if !filepath.IsAbs(pemFile) {
+				pemFile = filepath.Join(filepath.Dir(fileName), pemFile)
+			}
+			var pemData []byte
+			if pemData, err = os.ReadFile(pemFile); err != nil {
+				return nil, "", fmt.Errorf("can't read key \"%s\": %w", k.ID, err)
+			}
+			key, err = ParsePEMKey(k.ID, k.Algorithm, pemData)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		keys = append(keys, key)
+	}
+	return keys, set.Active, nil
+}
@@ -0,0 +1,212 @@
+/*
+Package metrics collects operational metrics of the service and serves them
+in Prometheus text format.
+
+Metrics are served by Handler, which is meant to be mounted on a separate
+listen address, so the metrics are not public.
+*/
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/Schalure/urlalias/internal/app/storage/cachestor"
+)
+
+// namespace is the prefix of metric names
+const namespace = "urlalias"
+
+// unmatchedRoute is the route label of requests which matched no route
+const unmatchedRoute = "unmatched"
+
+// Metrics of the service. Metrics is safe for concurrent use
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests    *prometheus.CounterVec
+	httpDuration    *prometheus.HistogramVec
+	redirects       *prometheus.CounterVec
+	conflicts       *prometheus.CounterVec
+	deleteBatch     prometheus.Histogram
+	storageDuration *prometheus.HistogramVec
+}
+
+// ------------------------------------------------------------
+//
+//	Metrics constructor
+func New() *Metrics {
+
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Count of HTTP requests by route pattern, method and status.",
+		}, []string{"route", "method", "status"}),
+
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of HTTP requests by route pattern, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+
+		redirects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redirects_total",
+			Help:      "Count of redirect lookups by result: hit, miss or gone.",
+		}, []string{"result"}),
+
+		conflicts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "shorten_conflicts_total",
+			Help:      "Count of shorten requests rejected by conflict, by reason: url or alias.",
+		}, []string{"reason"}),
+
+		deleteBatch: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "delete_batch_size",
+			Help:      "Count of aliases marked deleted at once.",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+		}),
+
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_call_duration_seconds",
+			Help:      "Duration of storage calls by method.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 9),
+		}, []string{"method"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.redirects,
+		m.conflicts,
+		m.deleteBatch,
+		m.storageDuration,
+	)
+	return m
+}
+
+// ------------------------------------------------------------
+//
+//	Handler serves the metrics in Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ------------------------------------------------------------
+//
+//	HTTPMiddleware counts requests and observes their duration.
+//	Requests are labeled by chi route pattern, so the middleware must be used by chi router
+func (m *Metrics) HTTPMiddleware(h http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		sw := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+
+		start := time.Now()
+		h.ServeHTTP(sw, r)
+		duration := time.Since(start)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := strconv.Itoa(sw.status)
+
+		m.httpRequests.WithLabelValues(route, r.Method, status).Inc()
+		m.httpDuration.WithLabelValues(route, r.Method, status).Observe(duration.Seconds())
+	})
+}
+
+// Redirect counts a redirect lookup by its result
+// This is interfase method of "aliasmaker.Metrics" interface
+func (m *Metrics) Redirect(result string) {
+	m.redirects.WithLabelValues(result).Inc()
+}
+
+// ShortenConflict counts a rejected shorten request by its reason
+// This is interfase method of "aliasmaker.Metrics" interface
+func (m *Metrics) ShortenConflict(reason string) {
+	m.conflicts.WithLabelValues(reason).Inc()
+}
+
+// DeleteBatch observes the count of aliases marked deleted at once
+// This is interfase method of "aliasmaker.Metrics" interface
+func (m *Metrics) DeleteBatch(size int) {
+	m.deleteBatch.Observe(float64(size))
+}
+
+// ------------------------------------------------------------
+//
+//	Report depth of the delete queue
+//	Input:
+//		queueLen - returns count of delete requests waiting in the queue
+func (m *Metrics) RegisterDeleteQueue(queueLen func() int) {
+
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "delete_queue_length",
+		Help:      "Count of delete requests waiting in the queue.",
+	}, func() float64 {
+		return float64(queueLen())
+	}))
+}
+
+// ------------------------------------------------------------
+//
+//	Report counters of the cache of redirect lookups
+//	Input:
+//		stats - returns snapshot of cache counters
+func (m *Metrics) RegisterCache(stats func() cachestor.Stats) {
+
+	counter := func(name, help string, value func(s cachestor.Stats) uint64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      name,
+			Help:      help,
+		}, func() float64 {
+			return float64(value(stats()))
+		})
+	}
+
+	m.registry.MustRegister(
+		counter("hits_total", "Count of lookups answered with a cached alias.", func(s cachestor.Stats) uint64 { return s.Hits }),
+		counter("negative_hits_total", "Count of lookups answered with a cached miss.", func(s cachestor.Stats) uint64 { return s.NegativeHits }),
+		counter("misses_total", "Count of lookups passed to the storage.", func(s cachestor.Stats) uint64 { return s.Misses }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "entries",
+			Help:      "Count of cached entries.",
+		}, func() float64 {
+			return float64(stats().Len)
+		}),
+	)
+}
+
+// statusResponseWriter remembers the status of the response
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader remembers the status and writes it
+func (w *statusResponseWriter) WriteHeader(statusCode int) {
+
+	w.status = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/storage/cachestor"
+	"github.com/Schalure/urlalias/internal/app/storage/memstor"
+	"github.com/Schalure/urlalias/internal/app/storage/storagetest"
+)
+
+// scrape returns the metrics in Prometheus text format
+func scrape(t *testing.T, m *Metrics) string {
+
+	response := httptest.NewRecorder()
+	m.Handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, response.Code)
+
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestMetrics_HTTPMiddleware(t *testing.T) {
+
+	m := New()
+
+	r := chi.NewRouter()
+	r.Use(m.HTTPMiddleware)
+	r.Get("/{shortkey}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+	r.Post("/api/shorten", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	for _, request := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/000000001", nil),
+		httptest.NewRequest(http.MethodGet, "/000000002", nil),
+		httptest.NewRequest(http.MethodPost, "/api/shorten", nil),
+		httptest.NewRequest(http.MethodGet, "/api/unknown/route", nil),
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	body := scrape(t, m)
+
+	//	requests are labeled by route pattern, not by path
+	assert.Contains(t, body, `urlalias_http_requests_total{method="GET",route="/{shortkey}",status="307"} 2`)
+	assert.Contains(t, body, `urlalias_http_requests_total{method="POST",route="/api/shorten",status="200"} 1`)
+	assert.Contains(t, body, `urlalias_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `urlalias_http_request_duration_seconds_count{method="GET",route="/{shortkey}",status="307"} 2`)
+}
+
+func TestMetrics_Service(t *testing.T) {
+
+	m := New()
+	var _ aliasmaker.Metrics = m
+
+	m.Redirect(aliasmaker.RedirectHit)
+	m.Redirect(aliasmaker.RedirectHit)
+	m.Redirect(aliasmaker.RedirectGone)
+	m.ShortenConflict(aliasmaker.ConflictAlias)
+	m.DeleteBatch(3)
+
+	queueLen := 5
+	m.RegisterDeleteQueue(func() int { return queueLen })
+
+	body := scrape(t, m)
+
+	assert.Contains(t, body, `urlalias_redirects_total{result="hit"} 2`)
+	assert.Contains(t, body, `urlalias_redirects_total{result="gone"} 1`)
+	assert.Contains(t, body, `urlalias_shorten_conflicts_total{reason="alias"} 1`)
+	assert.Contains(t, body, `urlalias_delete_batch_size_sum 3`)
+	assert.Contains(t, body, `urlalias_delete_queue_length 5`)
+}
+
+func TestMetrics_Storage(t *testing.T) {
+
+	m := New()
+
+	backend, err := memstor.NewStorage()
+	require.NoError(t, err)
+	stor := m.InstrumentStorage(backend)
+
+	cached, err := cachestor.NewStorage(stor, 10)
+	require.NoError(t, err)
+	m.RegisterCache(cached.Stats)
+
+	for i := 0; i < 3; i++ {
+		_, err = cached.FindByShortKey(context.Background(), "000000001")
+		assert.Error(t, err)
+	}
+
+	body := scrape(t, m)
+
+	//	the storage is called once, the next lookups are answered by the cache
+	assert.Contains(t, body, `urlalias_storage_call_duration_seconds_count{method="FindByShortKey"} 1`)
+	assert.Contains(t, body, `urlalias_cache_misses_total 1`)
+	assert.Contains(t, body, `urlalias_cache_negative_hits_total 2`)
+	assert.Contains(t, body, `urlalias_cache_entries 1`)
+}
+
+func TestStorager(t *testing.T) {
+
+	m := New()
+	storagetest.Run(t, func(t *testing.T) aliasmaker.Storager {
+		stor, err := memstor.NewStorage()
+		require.NoError(t, err)
+		return m.InstrumentStorage(stor)
+	})
+}
@@ -0,0 +1,211 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+	"github.com/Schalure/urlalias/internal/app/models/clickentity"
+	"github.com/Schalure/urlalias/internal/app/models/statsentity"
+	"github.com/Schalure/urlalias/internal/app/models/userentity"
+)
+
+// Storage observes duration of calls to the wrapped storage by method
+type Storage struct {
+	storage  aliasmaker.Storager
+	observer func(method string, start time.Time)
+}
+
+// ------------------------------------------------------------
+//
+//	Wrap the storage, so duration of its calls is observed
+func (m *Metrics) InstrumentStorage(storage aliasmaker.Storager) *Storage {
+
+	return &Storage{
+		storage: storage,
+		observer: func(method string, start time.Time) {
+			m.storageDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		},
+	}
+}
+
+// CreateUser is instrumented "Storager.CreateUser"
+func (s *Storage) CreateUser(ctx context.Context) (uint64, error) {
+	defer s.observer("CreateUser", time.Now())
+	return s.storage.CreateUser(ctx)
+}
+
+// FindUserByID is instrumented "Storager.FindUserByID"
+func (s *Storage) FindUserByID(ctx context.Context, userID uint64) (*userentity.UserModel, error) {
+	defer s.observer("FindUserByID", time.Now())
+	return s.storage.FindUserByID(ctx, userID)
+}
+
+// FindUserByLogin is instrumented "Storager.FindUserByLogin"
+func (s *Storage) FindUserByLogin(ctx context.Context, login string) (*userentity.UserModel, error) {
+	defer s.observer("FindUserByLogin", time.Now())
+	return s.storage.FindUserByLogin(ctx, login)
+}
+
+// RegisterUser is instrumented "Storager.RegisterUser"
+func (s *Storage) RegisterUser(ctx context.Context, user *userentity.UserModel) (bool, error) {
+	defer s.observer("RegisterUser", time.Now())
+	return s.storage.RegisterUser(ctx, user)
+}
+
+// Save is instrumented "Storager.Save"
+func (s *Storage) Save(ctx context.Context, urlAliasNode *aliasentity.AliasURLModel) error {
+	defer s.observer("Save", time.Now())
+	return s.storage.Save(ctx, urlAliasNode)
+}
+
+// SaveAll is instrumented "Storager.SaveAll"
+func (s *Storage) SaveAll(ctx context.Context, urlAliasNodes []aliasentity.AliasURLModel) error {
+	defer s.observer("SaveAll", time.Now())
+	return s.storage.SaveAll(ctx, urlAliasNodes)
+}
+
+// FindByShortKey is instrumented "Storager.FindByShortKey"
+func (s *Storage) FindByShortKey(ctx context.Context, shortKey string) (*aliasentity.AliasURLModel, error) {
+	defer s.observer("FindByShortKey", time.Now())
+	return s.storage.FindByShortKey(ctx, shortKey)
+}
+
+// FindByLongURL is instrumented "Storager.FindByLongURL"
+func (s *Storage) FindByLongURL(ctx context.Context, longURL string) (*aliasentity.AliasURLModel, error) {
+	defer s.observer("FindByLongURL", time.Now())
+	return s.storage.FindByLongURL(ctx, longURL)
+}
+
+// FindAllByLongURLs is instrumented "Storager.FindAllByLongURLs"
+func (s *Storage) FindAllByLongURLs(ctx context.Context, longURL []string) (map[string]*aliasentity.AliasURLModel, error) {
+	defer s.observer("FindAllByLongURLs", time.Now())
+	return s.storage.FindAllByLongURLs(ctx, longURL)
+}
+
+// FindByUserID is instrumented "Storager.FindByUserID"
+func (s *Storage) FindByUserID(ctx context.Context, userID uint64) ([]aliasentity.AliasURLModel, error) {
+	defer s.observer("FindByUserID", time.Now())
+	return s.storage.FindByUserID(ctx, userID)
+}
+
+// MarkDeleted is instrumented "Storager.MarkDeleted"
+func (s *Storage) MarkDeleted(ctx context.Context, aliasesID []uint64) error {
+	defer s.observer("MarkDeleted", time.Now())
+	return s.storage.MarkDeleted(ctx, aliasesID)
+}
+
+// MarkExpired is instrumented "Storager.MarkExpired"
+func (s *Storage) MarkExpired(ctx context.Context, now time.Time) (int, error) {
+	defer s.observer("MarkExpired", time.Now())
+	return s.storage.MarkExpired(ctx, now)
+}
+
+// ReserveKeyRange is instrumented "Storager.ReserveKeyRange"
+func (s *Storage) ReserveKeyRange(ctx context.Context, name string, start, size uint64) (uint64, error) {
+	defer s.observer("ReserveKeyRange", time.Now())
+	return s.storage.ReserveKeyRange(ctx, name, start, size)
+}
+
+// SaveAPIKey is instrumented "Storager.SaveAPIKey"
+func (s *Storage) SaveAPIKey(ctx context.Context, key *apikeyentity.APIKeyModel) error {
+	defer s.observer("SaveAPIKey", time.Now())
+	return s.storage.SaveAPIKey(ctx, key)
+}
+
+// FindAPIKeyByHash is instrumented "Storager.FindAPIKeyByHash"
+func (s *Storage) FindAPIKeyByHash(ctx context.Context, hash string) (*apikeyentity.APIKeyModel, error) {
+	defer s.observer("FindAPIKeyByHash", time.Now())
+	return s.storage.FindAPIKeyByHash(ctx, hash)
+}
+
+// FindAPIKeysByUserID is instrumented "Storager.FindAPIKeysByUserID"
+func (s *Storage) FindAPIKeysByUserID(ctx context.Context, userID uint64) ([]apikeyentity.APIKeyModel, error) {
+	defer s.observer("FindAPIKeysByUserID", time.Now())
+	return s.storage.FindAPIKeysByUserID(ctx, userID)
+}
+
+// RevokeAPIKey is instrumented "Storager.RevokeAPIKey"
+func (s *Storage) RevokeAPIKey(ctx context.Context, userID, keyID uint64) (bool, error) {
+	defer s.observer("RevokeAPIKey", time.Now())
+	return s.storage.RevokeAPIKey(ctx, userID, keyID)
+}
+
+// TouchAPIKey is instrumented "Storager.TouchAPIKey"
+func (s *Storage) TouchAPIKey(ctx context.Context, keyID uint64, usedAt time.Time) error {
+	defer s.observer("TouchAPIKey", time.Now())
+	return s.storage.TouchAPIKey(ctx, keyID, usedAt)
+}
+
+// SearchAliases is instrumented "Storager.SearchAliases"
+func (s *Storage) SearchAliases(ctx context.Context, filter aliasentity.Filter) ([]aliasentity.AliasURLModel, error) {
+	defer s.observer("SearchAliases", time.Now())
+	return s.storage.SearchAliases(ctx, filter)
+}
+
+// SetDisabled is instrumented "Storager.SetDisabled"
+func (s *Storage) SetDisabled(ctx context.Context, shortKey string, disabled bool) (bool, error) {
+	defer s.observer("SetDisabled", time.Now())
+	return s.storage.SetDisabled(ctx, shortKey, disabled)
+}
+
+// PurgeAlias is instrumented "Storager.PurgeAlias"
+func (s *Storage) PurgeAlias(ctx context.Context, shortKey string) (bool, error) {
+	defer s.observer("PurgeAlias", time.Now())
+	return s.storage.PurgeAlias(ctx, shortKey)
+}
+
+// GetServiceStats is instrumented "Storager.GetServiceStats"
+func (s *Storage) GetServiceStats(ctx context.Context, now time.Time) (*statsentity.ServiceStatsModel, error) {
+	defer s.observer("GetServiceStats", time.Now())
+	return s.storage.GetServiceStats(ctx, now)
+}
+
+// GetLastShortKey is instrumented "Storager.GetLastShortKey"
+func (s *Storage) GetLastShortKey() string {
+	defer s.observer("GetLastShortKey", time.Now())
+	return s.storage.GetLastShortKey()
+}
+
+// IsConnected is instrumented "Storager.IsConnected"
+func (s *Storage) IsConnected(ctx context.Context) bool {
+	defer s.observer("IsConnected", time.Now())
+	return s.storage.IsConnected(ctx)
+}
+
+// Close closes the wrapped storage
+func (s *Storage) Close() error {
+	return s.storage.Close()
+}
+
+// SaveClicks is instrumented "AnalyticsSink.SaveClicks"
+func (s *Storage) SaveClicks(ctx context.Context, clicks []clickentity.ClickModel) error {
+
+	sink, ok := s.storage.(aliasmaker.AnalyticsSink)
+	if !ok {
+		return errors.New("storage does not keep redirect statistics")
+	}
+
+	defer s.observer("SaveClicks", time.Now())
+	return sink.SaveClicks(ctx, clicks)
+}
+
+// GetClickStats is instrumented "AnalyticsSink.GetClickStats"
+func (s *Storage) GetClickStats(ctx context.Context, shortKey string) (*clickentity.StatsModel, error) {
+
+	sink, ok := s.storage.(aliasmaker.AnalyticsSink)
+	if !ok {
+		return nil, errors.New("storage does not keep redirect statistics")
+	}
+
+	defer s.observer("GetClickStats", time.Now())
+	return sink.GetClickStats(ctx, shortKey)
+}
+
+// Unwrap returns the wrapped storage, so optional methods of the storage can be found
+func (s *Storage) Unwrap() aliasmaker.Storager {
+	return s.storage
+}
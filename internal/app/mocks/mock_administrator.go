@@ -0,0 +1,111 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/Schalure/urlalias/internal/app/server (interfaces: Administrator)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+
+	aliasentity "github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	statsentity "github.com/Schalure/urlalias/internal/app/models/statsentity"
+)
+
+// MockAdministrator is a mock of Administrator interface.
+type MockAdministrator struct {
+	ctrl     *gomock.Controller
+	recorder *MockAdministratorMockRecorder
+}
+
+// MockAdministratorMockRecorder is the mock recorder for MockAdministrator.
+type MockAdministratorMockRecorder struct {
+	mock *MockAdministrator
+}
+
+// NewMockAdministrator creates a new mock instance.
+func NewMockAdministrator(ctrl *gomock.Controller) *MockAdministrator {
+	mock := &MockAdministrator{ctrl: ctrl}
+	mock.recorder = &MockAdministratorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAdministrator) EXPECT() *MockAdministratorMockRecorder {
+	return m.recorder
+}
+
+// GetServiceStats mocks base method.
+func (m *MockAdministrator) GetServiceStats(arg0 context.Context, arg1 uint64) (*statsentity.ServiceStatsModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetServiceStats", arg0, arg1)
+	ret0, _ := ret[0].(*statsentity.ServiceStatsModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetServiceStats indicates an expected call of GetServiceStats.
+func (mr *MockAdministratorMockRecorder) GetServiceStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServiceStats", reflect.TypeOf((*MockAdministrator)(nil).GetServiceStats), arg0, arg1)
+}
+
+// PurgeAlias mocks base method.
+func (m *MockAdministrator) PurgeAlias(arg0 context.Context, arg1 uint64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeAlias", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PurgeAlias indicates an expected call of PurgeAlias.
+func (mr *MockAdministratorMockRecorder) PurgeAlias(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeAlias", reflect.TypeOf((*MockAdministrator)(nil).PurgeAlias), arg0, arg1, arg2)
+}
+
+// SearchAliases mocks base method.
+func (m *MockAdministrator) SearchAliases(arg0 context.Context, arg1 uint64, arg2 aliasentity.Filter) ([]aliasentity.AliasURLModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchAliases", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]aliasentity.AliasURLModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchAliases indicates an expected call of SearchAliases.
+func (mr *MockAdministratorMockRecorder) SearchAliases(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchAliases", reflect.TypeOf((*MockAdministrator)(nil).SearchAliases), arg0, arg1, arg2)
+}
+
+// SetAliasDisabled mocks base method.
+func (m *MockAdministrator) SetAliasDisabled(arg0 context.Context, arg1 uint64, arg2 string, arg3 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAliasDisabled", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetAliasDisabled indicates an expected call of SetAliasDisabled.
+func (mr *MockAdministratorMockRecorder) SetAliasDisabled(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAliasDisabled", reflect.TypeOf((*MockAdministrator)(nil).SetAliasDisabled), arg0, arg1, arg2, arg3)
+}
+
+// ViewUserAliases mocks base method.
+func (m *MockAdministrator) ViewUserAliases(arg0 context.Context, arg1, arg2 uint64) ([]aliasentity.AliasURLModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ViewUserAliases", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]aliasentity.AliasURLModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ViewUserAliases indicates an expected call of ViewUserAliases.
+func (mr *MockAdministratorMockRecorder) ViewUserAliases(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ViewUserAliases", reflect.TypeOf((*MockAdministrator)(nil).ViewUserAliases), arg0, arg1, arg2)
+}
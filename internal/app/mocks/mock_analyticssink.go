@@ -0,0 +1,66 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/Schalure/urlalias/internal/app/aliasmaker (interfaces: AnalyticsSink)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+
+	clickentity "github.com/Schalure/urlalias/internal/app/models/clickentity"
+)
+
+// MockAnalyticsSink is a mock of AnalyticsSink interface.
+type MockAnalyticsSink struct {
+	ctrl     *gomock.Controller
+	recorder *MockAnalyticsSinkMockRecorder
+}
+
+// MockAnalyticsSinkMockRecorder is the mock recorder for MockAnalyticsSink.
+type MockAnalyticsSinkMockRecorder struct {
+	mock *MockAnalyticsSink
+}
+
+// NewMockAnalyticsSink creates a new mock instance.
+func NewMockAnalyticsSink(ctrl *gomock.Controller) *MockAnalyticsSink {
+	mock := &MockAnalyticsSink{ctrl: ctrl}
+	mock.recorder = &MockAnalyticsSinkMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAnalyticsSink) EXPECT() *MockAnalyticsSinkMockRecorder {
+	return m.recorder
+}
+
+// GetClickStats mocks base method.
+func (m *MockAnalyticsSink) GetClickStats(arg0 context.Context, arg1 string) (*clickentity.StatsModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClickStats", arg0, arg1)
+	ret0, _ := ret[0].(*clickentity.StatsModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetClickStats indicates an expected call of GetClickStats.
+func (mr *MockAnalyticsSinkMockRecorder) GetClickStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClickStats", reflect.TypeOf((*MockAnalyticsSink)(nil).GetClickStats), arg0, arg1)
+}
+
+// SaveClicks mocks base method.
+func (m *MockAnalyticsSink) SaveClicks(arg0 context.Context, arg1 []clickentity.ClickModel) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveClicks", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveClicks indicates an expected call of SaveClicks.
+func (mr *MockAnalyticsSinkMockRecorder) SaveClicks(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveClicks", reflect.TypeOf((*MockAnalyticsSink)(nil).SaveClicks), arg0, arg1)
+}
@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/Schalure/urlalias/internal/app/handlers (interfaces: Shortner)
+// Source: github.com/Schalure/urlalias/internal/app/server (interfaces: Shortner)
 
 // Package mocks is a generated GoMock package.
 package mocks
@@ -7,8 +7,11 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+
+	clickentity "github.com/Schalure/urlalias/internal/app/models/clickentity"
 )
 
 // MockShortner is a mock of Shortner interface.
@@ -54,18 +57,18 @@ func (mr *MockShortnerMockRecorder) AddAliasesToDelete(arg0, arg1 interface{}, a
 }
 
 // GetBatchShortURL mocks base method.
-func (m *MockShortner) GetBatchShortURL(arg0 context.Context, arg1 uint64, arg2 []string) ([]string, error) {
+func (m *MockShortner) GetBatchShortURL(arg0 context.Context, arg1 uint64, arg2 []string, arg3 []*time.Time) ([]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetBatchShortURL", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "GetBatchShortURL", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].([]string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetBatchShortURL indicates an expected call of GetBatchShortURL.
-func (mr *MockShortnerMockRecorder) GetBatchShortURL(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockShortnerMockRecorder) GetBatchShortURL(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBatchShortURL", reflect.TypeOf((*MockShortner)(nil).GetBatchShortURL), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBatchShortURL", reflect.TypeOf((*MockShortner)(nil).GetBatchShortURL), arg0, arg1, arg2, arg3)
 }
 
 // GetOriginalURL mocks base method.
@@ -84,30 +87,42 @@ func (mr *MockShortnerMockRecorder) GetOriginalURL(arg0, arg1 interface{}) *gomo
 }
 
 // GetShortKey mocks base method.
-func (m *MockShortner) GetShortKey(arg0 context.Context, arg1 uint64, arg2 string) (string, error) {
+func (m *MockShortner) GetShortKey(arg0 context.Context, arg1 uint64, arg2, arg3 string, arg4 *time.Time) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetShortKey", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "GetShortKey", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetShortKey indicates an expected call of GetShortKey.
-func (mr *MockShortnerMockRecorder) GetShortKey(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockShortnerMockRecorder) GetShortKey(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetShortKey", reflect.TypeOf((*MockShortner)(nil).GetShortKey), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetShortKey", reflect.TypeOf((*MockShortner)(nil).GetShortKey), arg0, arg1, arg2, arg3, arg4)
 }
 
 // IsDatabaseActive mocks base method.
-func (m *MockShortner) IsDatabaseActive() bool {
+func (m *MockShortner) IsDatabaseActive(arg0 context.Context) bool {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "IsDatabaseActive")
+	ret := m.ctrl.Call(m, "IsDatabaseActive", arg0)
 	ret0, _ := ret[0].(bool)
 	return ret0
 }
 
 // IsDatabaseActive indicates an expected call of IsDatabaseActive.
-func (mr *MockShortnerMockRecorder) IsDatabaseActive() *gomock.Call {
+func (mr *MockShortnerMockRecorder) IsDatabaseActive(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsDatabaseActive", reflect.TypeOf((*MockShortner)(nil).IsDatabaseActive), arg0)
+}
+
+// RecordClick mocks base method.
+func (m *MockShortner) RecordClick(arg0 context.Context, arg1 clickentity.ClickModel) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RecordClick", arg0, arg1)
+}
+
+// RecordClick indicates an expected call of RecordClick.
+func (mr *MockShortnerMockRecorder) RecordClick(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsDatabaseActive", reflect.TypeOf((*MockShortner)(nil).IsDatabaseActive))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordClick", reflect.TypeOf((*MockShortner)(nil).RecordClick), arg0, arg1)
 }
@@ -7,10 +7,14 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 
 	aliasentity "github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	apikeyentity "github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+	statsentity "github.com/Schalure/urlalias/internal/app/models/statsentity"
+	userentity "github.com/Schalure/urlalias/internal/app/models/userentity"
 )
 
 // MockStorager is a mock of Storager interface.
@@ -51,18 +55,48 @@ func (mr *MockStoragerMockRecorder) Close() *gomock.Call {
 }
 
 // CreateUser mocks base method.
-func (m *MockStorager) CreateUser() (uint64, error) {
+func (m *MockStorager) CreateUser(arg0 context.Context) (uint64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser")
+	ret := m.ctrl.Call(m, "CreateUser", arg0)
 	ret0, _ := ret[0].(uint64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateUser indicates an expected call of CreateUser.
-func (mr *MockStoragerMockRecorder) CreateUser() *gomock.Call {
+func (mr *MockStoragerMockRecorder) CreateUser(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockStorager)(nil).CreateUser))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockStorager)(nil).CreateUser), arg0)
+}
+
+// FindAPIKeyByHash mocks base method.
+func (m *MockStorager) FindAPIKeyByHash(arg0 context.Context, arg1 string) (*apikeyentity.APIKeyModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAPIKeyByHash", arg0, arg1)
+	ret0, _ := ret[0].(*apikeyentity.APIKeyModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAPIKeyByHash indicates an expected call of FindAPIKeyByHash.
+func (mr *MockStoragerMockRecorder) FindAPIKeyByHash(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAPIKeyByHash", reflect.TypeOf((*MockStorager)(nil).FindAPIKeyByHash), arg0, arg1)
+}
+
+// FindAPIKeysByUserID mocks base method.
+func (m *MockStorager) FindAPIKeysByUserID(arg0 context.Context, arg1 uint64) ([]apikeyentity.APIKeyModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAPIKeysByUserID", arg0, arg1)
+	ret0, _ := ret[0].([]apikeyentity.APIKeyModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAPIKeysByUserID indicates an expected call of FindAPIKeysByUserID.
+func (mr *MockStoragerMockRecorder) FindAPIKeysByUserID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAPIKeysByUserID", reflect.TypeOf((*MockStorager)(nil).FindAPIKeysByUserID), arg0, arg1)
 }
 
 // FindAllByLongURLs mocks base method.
@@ -125,6 +159,36 @@ func (mr *MockStoragerMockRecorder) FindByUserID(arg0, arg1 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByUserID", reflect.TypeOf((*MockStorager)(nil).FindByUserID), arg0, arg1)
 }
 
+// FindUserByID mocks base method.
+func (m *MockStorager) FindUserByID(arg0 context.Context, arg1 uint64) (*userentity.UserModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUserByID", arg0, arg1)
+	ret0, _ := ret[0].(*userentity.UserModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUserByID indicates an expected call of FindUserByID.
+func (mr *MockStoragerMockRecorder) FindUserByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserByID", reflect.TypeOf((*MockStorager)(nil).FindUserByID), arg0, arg1)
+}
+
+// FindUserByLogin mocks base method.
+func (m *MockStorager) FindUserByLogin(arg0 context.Context, arg1 string) (*userentity.UserModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUserByLogin", arg0, arg1)
+	ret0, _ := ret[0].(*userentity.UserModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUserByLogin indicates an expected call of FindUserByLogin.
+func (mr *MockStoragerMockRecorder) FindUserByLogin(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserByLogin", reflect.TypeOf((*MockStorager)(nil).FindUserByLogin), arg0, arg1)
+}
+
 // GetLastShortKey mocks base method.
 func (m *MockStorager) GetLastShortKey() string {
 	m.ctrl.T.Helper()
@@ -139,18 +203,33 @@ func (mr *MockStoragerMockRecorder) GetLastShortKey() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastShortKey", reflect.TypeOf((*MockStorager)(nil).GetLastShortKey))
 }
 
+// GetServiceStats mocks base method.
+func (m *MockStorager) GetServiceStats(arg0 context.Context, arg1 time.Time) (*statsentity.ServiceStatsModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetServiceStats", arg0, arg1)
+	ret0, _ := ret[0].(*statsentity.ServiceStatsModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetServiceStats indicates an expected call of GetServiceStats.
+func (mr *MockStoragerMockRecorder) GetServiceStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServiceStats", reflect.TypeOf((*MockStorager)(nil).GetServiceStats), arg0, arg1)
+}
+
 // IsConnected mocks base method.
-func (m *MockStorager) IsConnected() bool {
+func (m *MockStorager) IsConnected(arg0 context.Context) bool {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "IsConnected")
+	ret := m.ctrl.Call(m, "IsConnected", arg0)
 	ret0, _ := ret[0].(bool)
 	return ret0
 }
 
 // IsConnected indicates an expected call of IsConnected.
-func (mr *MockStoragerMockRecorder) IsConnected() *gomock.Call {
+func (mr *MockStoragerMockRecorder) IsConnected(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsConnected", reflect.TypeOf((*MockStorager)(nil).IsConnected))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsConnected", reflect.TypeOf((*MockStorager)(nil).IsConnected), arg0)
 }
 
 // MarkDeleted mocks base method.
@@ -167,6 +246,81 @@ func (mr *MockStoragerMockRecorder) MarkDeleted(arg0, arg1 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkDeleted", reflect.TypeOf((*MockStorager)(nil).MarkDeleted), arg0, arg1)
 }
 
+// MarkExpired mocks base method.
+func (m *MockStorager) MarkExpired(arg0 context.Context, arg1 time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkExpired", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkExpired indicates an expected call of MarkExpired.
+func (mr *MockStoragerMockRecorder) MarkExpired(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkExpired", reflect.TypeOf((*MockStorager)(nil).MarkExpired), arg0, arg1)
+}
+
+// PurgeAlias mocks base method.
+func (m *MockStorager) PurgeAlias(arg0 context.Context, arg1 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeAlias", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeAlias indicates an expected call of PurgeAlias.
+func (mr *MockStoragerMockRecorder) PurgeAlias(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeAlias", reflect.TypeOf((*MockStorager)(nil).PurgeAlias), arg0, arg1)
+}
+
+// RegisterUser mocks base method.
+func (m *MockStorager) RegisterUser(arg0 context.Context, arg1 *userentity.UserModel) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterUser", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RegisterUser indicates an expected call of RegisterUser.
+func (mr *MockStoragerMockRecorder) RegisterUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterUser", reflect.TypeOf((*MockStorager)(nil).RegisterUser), arg0, arg1)
+}
+
+// ReserveKeyRange mocks base method.
+func (m *MockStorager) ReserveKeyRange(arg0 context.Context, arg1 string, arg2, arg3 uint64) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReserveKeyRange", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReserveKeyRange indicates an expected call of ReserveKeyRange.
+func (mr *MockStoragerMockRecorder) ReserveKeyRange(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveKeyRange", reflect.TypeOf((*MockStorager)(nil).ReserveKeyRange), arg0, arg1, arg2, arg3)
+}
+
+// RevokeAPIKey mocks base method.
+func (m *MockStorager) RevokeAPIKey(arg0 context.Context, arg1, arg2 uint64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockStoragerMockRecorder) RevokeAPIKey(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockStorager)(nil).RevokeAPIKey), arg0, arg1, arg2)
+}
+
 // Save mocks base method.
 func (m *MockStorager) Save(arg0 context.Context, arg1 *aliasentity.AliasURLModel) error {
 	m.ctrl.T.Helper()
@@ -181,6 +335,20 @@ func (mr *MockStoragerMockRecorder) Save(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockStorager)(nil).Save), arg0, arg1)
 }
 
+// SaveAPIKey mocks base method.
+func (m *MockStorager) SaveAPIKey(arg0 context.Context, arg1 *apikeyentity.APIKeyModel) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAPIKey", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAPIKey indicates an expected call of SaveAPIKey.
+func (mr *MockStoragerMockRecorder) SaveAPIKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAPIKey", reflect.TypeOf((*MockStorager)(nil).SaveAPIKey), arg0, arg1)
+}
+
 // SaveAll mocks base method.
 func (m *MockStorager) SaveAll(arg0 context.Context, arg1 []aliasentity.AliasURLModel) error {
 	m.ctrl.T.Helper()
@@ -194,3 +362,47 @@ func (mr *MockStoragerMockRecorder) SaveAll(arg0, arg1 interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAll", reflect.TypeOf((*MockStorager)(nil).SaveAll), arg0, arg1)
 }
+
+// SearchAliases mocks base method.
+func (m *MockStorager) SearchAliases(arg0 context.Context, arg1 aliasentity.Filter) ([]aliasentity.AliasURLModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchAliases", arg0, arg1)
+	ret0, _ := ret[0].([]aliasentity.AliasURLModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchAliases indicates an expected call of SearchAliases.
+func (mr *MockStoragerMockRecorder) SearchAliases(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchAliases", reflect.TypeOf((*MockStorager)(nil).SearchAliases), arg0, arg1)
+}
+
+// SetDisabled mocks base method.
+func (m *MockStorager) SetDisabled(arg0 context.Context, arg1 string, arg2 bool) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDisabled", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetDisabled indicates an expected call of SetDisabled.
+func (mr *MockStoragerMockRecorder) SetDisabled(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDisabled", reflect.TypeOf((*MockStorager)(nil).SetDisabled), arg0, arg1, arg2)
+}
+
+// TouchAPIKey mocks base method.
+func (m *MockStorager) TouchAPIKey(arg0 context.Context, arg1 uint64, arg2 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchAPIKey", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchAPIKey indicates an expected call of TouchAPIKey.
+func (mr *MockStoragerMockRecorder) TouchAPIKey(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchAPIKey", reflect.TypeOf((*MockStorager)(nil).TouchAPIKey), arg0, arg1, arg2)
+}
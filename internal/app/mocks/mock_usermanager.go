@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/Schalure/urlalias/internal/app/handlers (interfaces: UserManager)
+// Source: github.com/Schalure/urlalias/internal/app/server (interfaces: UserManager)
 
 // Package mocks is a generated GoMock package.
 package mocks
@@ -7,10 +7,14 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 
 	aliasentity "github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	apikeyentity "github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+	clickentity "github.com/Schalure/urlalias/internal/app/models/clickentity"
+	userentity "github.com/Schalure/urlalias/internal/app/models/userentity"
 )
 
 // MockUserManager is a mock of UserManager interface.
@@ -36,19 +40,95 @@ func (m *MockUserManager) EXPECT() *MockUserManagerMockRecorder {
 	return m.recorder
 }
 
+// AuthenticateAPIKey mocks base method.
+func (m *MockUserManager) AuthenticateAPIKey(arg0 context.Context, arg1 string) (*apikeyentity.APIKeyModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthenticateAPIKey", arg0, arg1)
+	ret0, _ := ret[0].(*apikeyentity.APIKeyModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthenticateAPIKey indicates an expected call of AuthenticateAPIKey.
+func (mr *MockUserManagerMockRecorder) AuthenticateAPIKey(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateAPIKey", reflect.TypeOf((*MockUserManager)(nil).AuthenticateAPIKey), arg0, arg1)
+}
+
+// CreateAPIKey mocks base method.
+func (m *MockUserManager) CreateAPIKey(arg0 context.Context, arg1 uint64, arg2 string, arg3 []string, arg4 *time.Time) (string, *apikeyentity.APIKeyModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(*apikeyentity.APIKeyModel)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockUserManagerMockRecorder) CreateAPIKey(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockUserManager)(nil).CreateAPIKey), arg0, arg1, arg2, arg3, arg4)
+}
+
 // CreateUser mocks base method.
-func (m *MockUserManager) CreateUser() (uint64, error) {
+func (m *MockUserManager) CreateUser(arg0 context.Context) (uint64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser")
+	ret := m.ctrl.Call(m, "CreateUser", arg0)
 	ret0, _ := ret[0].(uint64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateUser indicates an expected call of CreateUser.
-func (mr *MockUserManagerMockRecorder) CreateUser() *gomock.Call {
+func (mr *MockUserManagerMockRecorder) CreateUser(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserManager)(nil).CreateUser), arg0)
+}
+
+// GetAPIKeys mocks base method.
+func (m *MockUserManager) GetAPIKeys(arg0 context.Context, arg1 uint64) ([]apikeyentity.APIKeyModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeys", arg0, arg1)
+	ret0, _ := ret[0].([]apikeyentity.APIKeyModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeys indicates an expected call of GetAPIKeys.
+func (mr *MockUserManagerMockRecorder) GetAPIKeys(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeys", reflect.TypeOf((*MockUserManager)(nil).GetAPIKeys), arg0, arg1)
+}
+
+// GetAliasStats mocks base method.
+func (m *MockUserManager) GetAliasStats(arg0 context.Context, arg1 uint64, arg2 string) (*clickentity.StatsModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAliasStats", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*clickentity.StatsModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAliasStats indicates an expected call of GetAliasStats.
+func (mr *MockUserManagerMockRecorder) GetAliasStats(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAliasStats", reflect.TypeOf((*MockUserManager)(nil).GetAliasStats), arg0, arg1, arg2)
+}
+
+// GetUser mocks base method.
+func (m *MockUserManager) GetUser(arg0 context.Context, arg1 uint64) (*userentity.UserModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUser", arg0, arg1)
+	ret0, _ := ret[0].(*userentity.UserModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUser indicates an expected call of GetUser.
+func (mr *MockUserManagerMockRecorder) GetUser(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserManager)(nil).CreateUser))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockUserManager)(nil).GetUser), arg0, arg1)
 }
 
 // GetUserAliases mocks base method.
@@ -65,3 +145,47 @@ func (mr *MockUserManagerMockRecorder) GetUserAliases(arg0, arg1 interface{}) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserAliases", reflect.TypeOf((*MockUserManager)(nil).GetUserAliases), arg0, arg1)
 }
+
+// Login mocks base method.
+func (m *MockUserManager) Login(arg0 context.Context, arg1, arg2 string) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Login", arg0, arg1, arg2)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Login indicates an expected call of Login.
+func (mr *MockUserManagerMockRecorder) Login(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockUserManager)(nil).Login), arg0, arg1, arg2)
+}
+
+// Register mocks base method.
+func (m *MockUserManager) Register(arg0 context.Context, arg1, arg2 string, arg3 *uint64) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Register", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Register indicates an expected call of Register.
+func (mr *MockUserManagerMockRecorder) Register(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockUserManager)(nil).Register), arg0, arg1, arg2, arg3)
+}
+
+// RevokeAPIKey mocks base method.
+func (m *MockUserManager) RevokeAPIKey(arg0 context.Context, arg1, arg2 uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockUserManagerMockRecorder) RevokeAPIKey(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockUserManager)(nil).RevokeAPIKey), arg0, arg1, arg2)
+}
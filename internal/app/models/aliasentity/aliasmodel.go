@@ -1,10 +1,41 @@
 package aliasentity
 
+import (
+	"strings"
+	"time"
+)
+
 // Storage model for long URL and their alias keys
 type AliasURLModel struct {
-	ID          uint64 `json:"uuid" db:"uuid"`
-	UserID      uint64 `json:"user_id" db:"user_id"`
-	ShortKey    string `json:"short_url" db:"short_url"`
-	LongURL     string `json:"original_url" db:"original_url"`
-	DeletedFlag bool   `json:"is_deleted" db:"is_deleted"`
+	ID           uint64     `json:"uuid" db:"uuid"`
+	UserID       uint64     `json:"user_id" db:"user_id"`
+	ShortKey     string     `json:"short_url" db:"short_url"`
+	LongURL      string     `json:"original_url" db:"original_url"`
+	DeletedFlag  bool       `json:"is_deleted" db:"is_deleted"`
+	IsCustom     bool       `json:"is_custom,omitempty" db:"is_custom"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	ExpiredFlag  bool       `json:"is_expired,omitempty" db:"is_expired"`
+	DisabledFlag bool       `json:"is_disabled,omitempty" db:"is_disabled"`
+}
+
+// IsExpired reports whether the alias was marked expired or its expiry time has passed
+func (m *AliasURLModel) IsExpired(now time.Time) bool {
+	return m.ExpiredFlag || (m.ExpiresAt != nil && !now.Before(*m.ExpiresAt))
+}
+
+// Filter of alias search. Aliases are returned in the order of their IDs
+type Filter struct {
+	Query  string  //	Query - substring of short key or original URL, empty - any alias
+	UserID *uint64 //	UserID - owner of aliases, nil - any user
+	Offset int     //	Offset - count of matched aliases to skip
+	Limit  int     //	Limit - maximum count of returned aliases, 0 - no limit
+}
+
+// Match reports whether the alias matches the filter. Offset and Limit are not checked
+func (f *Filter) Match(m *AliasURLModel) bool {
+
+	if f.UserID != nil && m.UserID != *f.UserID {
+		return false
+	}
+	return f.Query == "" || strings.Contains(m.ShortKey, f.Query) || strings.Contains(m.LongURL, f.Query)
 }
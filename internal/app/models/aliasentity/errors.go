@@ -0,0 +1,10 @@
+package aliasentity
+
+import "errors"
+
+// Errors of alias storages
+var (
+	ErrNotFound      = errors.New("alias not found")            //	there is no alias with the short key or the long URL
+	ErrShortKeyTaken = errors.New("short key is already taken") //	Save or SaveAll found an alias with the same short key, nothing is saved
+	ErrLongURLTaken  = errors.New("URL already has an alias")   //	Save or SaveAll found a live alias of the same URL, nothing is saved
+)
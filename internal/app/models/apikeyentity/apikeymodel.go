@@ -0,0 +1,43 @@
+package apikeyentity
+
+import "time"
+
+// Scopes of API keys
+const (
+	ScopeShorten = "shorten" //	create aliases
+	ScopeRead    = "read"    //	read aliases of the user and their statistics
+	ScopeDelete  = "delete"  //	delete aliases of the user
+)
+
+// Scopes is the list of all scopes. A key created without scopes gets all of them
+var Scopes = []string{ScopeShorten, ScopeRead, ScopeDelete}
+
+// Storage model for API keys of users. The key itself is never stored, only its hash
+type APIKeyModel struct {
+	ID          uint64     `json:"id" db:"id"`
+	UserID      uint64     `json:"user_id" db:"user_id"`
+	Name        string     `json:"name" db:"name"`
+	Prefix      string     `json:"prefix" db:"prefix"`
+	Hash        string     `json:"hash" db:"key_hash"`
+	Scopes      []string   `json:"scopes" db:"scopes"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedFlag bool       `json:"is_revoked" db:"is_revoked"`
+}
+
+// HasScope reports whether the key grants the scope
+func (m *APIKeyModel) HasScope(scope string) bool {
+
+	for _, s := range m.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsExpired reports whether the expiry time of the key has passed
+func (m *APIKeyModel) IsExpired(now time.Time) bool {
+	return m.ExpiresAt != nil && !now.Before(*m.ExpiresAt)
+}
@@ -0,0 +1,78 @@
+package clickentity
+
+import (
+	"sort"
+	"time"
+)
+
+// Storage model for a redirect by short key
+type ClickModel struct {
+	ShortKey  string    `json:"short_key" db:"short_key"`
+	ClickedAt time.Time `json:"clicked_at" db:"clicked_at"`
+	Referrer  string    `json:"referrer" db:"referrer"`
+	UserAgent string    `json:"user_agent" db:"user_agent"`
+	ClientIP  string    `json:"client_ip" db:"client_ip"`
+}
+
+// Statistics of redirects by short key
+type StatsModel struct {
+	TotalClicks    uint64
+	UniqueVisitors uint64
+	Daily          []DailyStatsModel
+}
+
+// Statistics of redirects by short key for one day (UTC)
+type DailyStatsModel struct {
+	Date           time.Time
+	Clicks         uint64
+	UniqueVisitors uint64
+}
+
+// visitor identifies a unique visitor by client IP and user agent
+type visitor struct {
+	clientIP  string
+	userAgent string
+}
+
+// NewStats aggregates clicks into statistics. Daily buckets are sorted by date
+func NewStats(clicks []ClickModel) *StatsModel {
+
+	type bucket struct {
+		clicks   uint64
+		visitors map[visitor]struct{}
+	}
+
+	stats := &StatsModel{}
+	visitors := make(map[visitor]struct{})
+	buckets := make(map[time.Time]*bucket)
+
+	for _, click := range clicks {
+		v := visitor{clientIP: click.ClientIP, userAgent: click.UserAgent}
+		day := click.ClickedAt.UTC().Truncate(24 * time.Hour)
+
+		b, ok := buckets[day]
+		if !ok {
+			b = &bucket{visitors: make(map[visitor]struct{})}
+			buckets[day] = b
+		}
+		b.clicks++
+		b.visitors[v] = struct{}{}
+
+		visitors[v] = struct{}{}
+		stats.TotalClicks++
+	}
+
+	stats.UniqueVisitors = uint64(len(visitors))
+	stats.Daily = make([]DailyStatsModel, 0, len(buckets))
+	for day, b := range buckets {
+		stats.Daily = append(stats.Daily, DailyStatsModel{
+			Date:           day,
+			Clicks:         b.clicks,
+			UniqueVisitors: uint64(len(b.visitors)),
+		})
+	}
+	sort.Slice(stats.Daily, func(i, j int) bool {
+		return stats.Daily[i].Date.Before(stats.Daily[j].Date)
+	})
+	return stats
+}
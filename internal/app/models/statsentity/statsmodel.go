@@ -0,0 +1,32 @@
+package statsentity
+
+import (
+	"time"
+
+	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+)
+
+// Service-wide counts of users and aliases
+type ServiceStatsModel struct {
+	Users           uint64 `json:"users"`
+	RegisteredUsers uint64 `json:"registered_users"`
+	Aliases         uint64 `json:"aliases"`
+	DeletedAliases  uint64 `json:"deleted_aliases"`
+	ExpiredAliases  uint64 `json:"expired_aliases"`
+	DisabledAliases uint64 `json:"disabled_aliases"`
+}
+
+// AddAlias counts the alias and its flags. An alias can be counted as deleted, expired and disabled at once
+func (m *ServiceStatsModel) AddAlias(node *aliasentity.AliasURLModel, now time.Time) {
+
+	m.Aliases++
+	if node.DeletedFlag {
+		m.DeletedAliases++
+	}
+	if node.IsExpired(now) {
+		m.ExpiredAliases++
+	}
+	if node.DisabledFlag {
+		m.DisabledAliases++
+	}
+}
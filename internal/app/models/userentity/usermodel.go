@@ -1,6 +1,23 @@
 package userentity
 
-// Storage model for users
+// RoleAdmin is the role of users who can moderate aliases of all users.
+// Users without role are ordinary users
+const RoleAdmin = "admin"
+
+// Storage model for users. Anonymous users have no login
 type UserModel struct {
-	UserID uint64 `json:"user_id"`
+	UserID       uint64 `json:"user_id" db:"user_id"`
+	Login        string `json:"login,omitempty" db:"login"`
+	PasswordHash string `json:"password_hash,omitempty" db:"password_hash"`
+	Role         string `json:"-"` //	Role is given by the service from its settings, it is not stored
+}
+
+// IsRegistered reports whether the user has a login and a password
+func (m *UserModel) IsRegistered() bool {
+	return m.Login != ""
+}
+
+// IsAdmin reports whether the user has the admin role
+func (m *UserModel) IsAdmin() bool {
+	return m.Role == RoleAdmin
 }
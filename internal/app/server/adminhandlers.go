@@ -0,0 +1,258 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+)
+
+const (
+	adminPageSizeDefault int = 100  //	count of aliases in a page of search by default
+	adminPageSizeMax     int = 1000 //	maximum count of aliases in a page of search
+)
+
+// adminAliasJSON is alias of any user in responses of admin API
+type adminAliasJSON struct {
+	ID          uint64     `json:"id"`
+	UserID      uint64     `json:"user_id"`
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	IsCustom    bool       `json:"is_custom"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Deleted     bool       `json:"is_deleted"`
+	Expired     bool       `json:"is_expired"`
+	Disabled    bool       `json:"is_disabled"`
+}
+
+// newAdminAliasesJSON converts stored models of aliases to the response
+func (h *Server) newAdminAliasesJSON(nodes []aliasentity.AliasURLModel) []adminAliasJSON {
+
+	now := time.Now()
+	aliases := make([]adminAliasJSON, len(nodes))
+	for i, node := range nodes {
+		aliases[i] = adminAliasJSON{
+			ID:          node.ID,
+			UserID:      node.UserID,
+			ShortURL:    h.baseURL + "/" + node.ShortKey,
+			OriginalURL: node.LongURL,
+			IsCustom:    node.IsCustom,
+			ExpiresAt:   node.ExpiresAt,
+			Deleted:     node.DeletedFlag,
+			Expired:     node.IsExpired(now),
+			Disabled:    node.DisabledFlag,
+		}
+	}
+	return aliases
+}
+
+// Handler returns aliases of all users, including deleted, expired and disabled ones.
+// Query parameters: "q" - substring of short key or original URL, "user_id" - owner of aliases,
+// "offset" and "limit" - the page of aliases, 100 aliases by default and 1000 at most.
+// Handler can returns three HTTP statuses:
+// 1. StatusOK (200) - aliases are returned, the list may be empty;
+// 2. StatusBadRequest (400) - if the query parameters are invalid;
+// 3. StatusInternalServerError (500) - if an internal service error occurred.
+func (h *Server) adminSearchAliases(w http.ResponseWriter, r *http.Request) {
+
+	adminID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, errors.New("can't parsed user id").Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := aliasentity.Filter{
+		Query: query.Get("q"),
+		Limit: adminPageSizeDefault,
+	}
+	if v := query.Get("user_id"); v != "" {
+		userID, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("user ID \"%s\" is not valid", v), http.StatusBadRequest)
+			return
+		}
+		filter.UserID = &userID
+	}
+	if v := query.Get("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil || filter.Offset < 0 {
+			http.Error(w, fmt.Sprintf("offset \"%s\" is not valid", v), http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit <= 0 || filter.Limit > adminPageSizeMax {
+			http.Error(w, fmt.Sprintf("limit \"%s\" is not valid, it must be from 1 to %d", v, adminPageSizeMax), http.StatusBadRequest)
+			return
+		}
+	}
+
+	nodes, err := h.administrator.SearchAliases(r.Context(), adminID, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, h.newAdminAliasesJSON(nodes))
+}
+
+// Handler returns all aliases of the user as the user sees them, including deleted, expired and disabled ones.
+// Handler can returns four HTTP statuses:
+// 1. StatusOK (200) - aliases are returned, the list may be empty;
+// 2. StatusBadRequest (400) - if the user ID is not a number;
+// 3. StatusNotFound (404) - if the user is not found;
+// 4. StatusInternalServerError (500) - if an internal service error occurred.
+func (h *Server) adminGetUserAliases(w http.ResponseWriter, r *http.Request) {
+
+	adminID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, errors.New("can't parsed user id").Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("user ID \"%s\" is not valid", chi.URLParam(r, "id")), http.StatusBadRequest)
+		return
+	}
+
+	nodes, err := h.administrator.ViewUserAliases(r.Context(), adminID, userID)
+	if err != nil {
+		if errors.Is(err, aliasmaker.ErrUserNotFound) {
+			http.Error(w, fmt.Sprintf("user %d not found", userID), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, h.newAdminAliasesJSON(nodes))
+}
+
+// Handler disables the alias of any user if disabled is true, otherwise re-enables it.
+// Redirects of disabled aliases return StatusGone (410). Handler can returns three HTTP statuses:
+// 1. StatusNoContent (204) - the alias is disabled or re-enabled;
+// 2. StatusNotFound (404) - if the alias is not found;
+// 3. StatusInternalServerError (500) - if an internal service error occurred.
+func (h *Server) adminSetAliasDisabled(disabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		adminID, err := h.getUserIDFromContext(r.Context())
+		if err != nil {
+			http.Error(w, errors.New("can't parsed user id").Error(), http.StatusBadRequest)
+			return
+		}
+
+		shortKey := chi.URLParam(r, "shortkey")
+		if err = h.administrator.SetAliasDisabled(r.Context(), adminID, shortKey, disabled); err != nil {
+			if errors.Is(err, aliasmaker.ErrURLNotFound) {
+				http.Error(w, fmt.Sprintf("the url alias not found by key \"%s\"", shortKey), http.StatusNotFound)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Handler removes the alias of any user from the storage, so its short key and original URL can be used again.
+// Handler can returns three HTTP statuses:
+// 1. StatusNoContent (204) - the alias is removed;
+// 2. StatusNotFound (404) - if the alias is not found;
+// 3. StatusInternalServerError (500) - if an internal service error occurred.
+func (h *Server) adminPurgeAlias(w http.ResponseWriter, r *http.Request) {
+
+	adminID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, errors.New("can't parsed user id").Error(), http.StatusBadRequest)
+		return
+	}
+
+	shortKey := chi.URLParam(r, "shortkey")
+	if err = h.administrator.PurgeAlias(r.Context(), adminID, shortKey); err != nil {
+		if errors.Is(err, aliasmaker.ErrURLNotFound) {
+			http.Error(w, fmt.Sprintf("the url alias not found by key \"%s\"", shortKey), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Handler returns service-wide counts of users and aliases. Handler can returns two HTTP statuses:
+// 1. StatusOK (200) - counts are returned;
+// 2. StatusInternalServerError (500) - if an internal service error occurred.
+func (h *Server) adminGetStats(w http.ResponseWriter, r *http.Request) {
+
+	adminID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, errors.New("can't parsed user id").Error(), http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.administrator.GetServiceStats(r.Context(), adminID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, stats)
+}
+
+// Handler reloads the configuration file and applies the settings which can be changed while the service is running.
+// The response lists keys of the changed settings, which are applied and which need a restart.
+// Handler can returns two HTTP statuses:
+// 1. StatusOK (200) - the configuration is reloaded;
+// 2. StatusInternalServerError (500) - if the configuration is not valid, nothing is applied then.
+func (h *Server) adminReloadConfig(w http.ResponseWriter, r *http.Request) {
+
+	adminID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, errors.New("can't parsed user id").Error(), http.StatusBadRequest)
+		return
+	}
+
+	type ResponseJSON struct {
+		Applied         []string `json:"applied"`
+		RestartRequired []string `json:"restart_required"`
+	}
+
+	applied, restartRequired, err := h.reloader.Reload(r.Context())
+	h.logger.Infow("admin audit", "admin id", adminID, "action", "reload config", "error", err)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, &ResponseJSON{
+		Applied:         append([]string{}, applied...),
+		RestartRequired: append([]string{}, restartRequired...),
+	})
+}
+
+// ------------------------------------------------------------
+//
+//	Write v as JSON response with statusCode
+func (h *Server) writeJSON(w http.ResponseWriter, statusCode int, v any) {
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", appJSON)
+	w.WriteHeader(statusCode)
+	w.Write(buf)
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/mocks"
+	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+	"github.com/Schalure/urlalias/internal/app/models/statsentity"
+	"github.com/Schalure/urlalias/internal/app/models/userentity"
+)
+
+func Test_adminAPI(t *testing.T) {
+
+	testLocalHost := "http://localhost"
+	adminID := uint64(1)
+	userID := uint64(2)
+	apiKey := "ua_admin"
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	userManager := mocks.NewMockUserManager(mockController)
+	shortner := mocks.NewMockShortner(mockController)
+	administrator := mocks.NewMockAdministrator(mockController)
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	testServer := httptest.NewServer(NewRouter(New(userManager, shortner, testAuth, logger, testLocalHost, WithAdministrator(administrator))))
+	defer testServer.Close()
+
+	userManager.EXPECT().GetUser(gomock.Any(), adminID).Return(&userentity.UserModel{UserID: adminID, Login: "root", Role: userentity.RoleAdmin}, nil).AnyTimes()
+	userManager.EXPECT().GetUser(gomock.Any(), userID).Return(&userentity.UserModel{UserID: userID, Login: "alice"}, nil).AnyTimes()
+	userManager.EXPECT().AuthenticateAPIKey(gomock.Any(), apiKey).Return(&apikeyentity.APIKeyModel{ID: 1, UserID: adminID, Scopes: apikeyentity.Scopes}, nil).AnyTimes()
+
+	adminToken, err := testAuth.CreateToken(adminID)
+	require.NoError(t, err)
+	userToken, err := testAuth.CreateToken(userID)
+	require.NoError(t, err)
+
+	do := func(method, path, token string) (*http.Response, string) {
+		request, err := http.NewRequest(method, testServer.URL+path, nil)
+		require.NoError(t, err)
+		if token == apiKey {
+			request.Header.Set(apiKeyHeader, apiKey)
+		} else if token != "" {
+			request.Header.Set(authorization, bearerPrefix+token)
+		}
+
+		response, err := testServer.Client().Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+
+		data, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return response, string(data)
+	}
+
+	//	only admins pass, and only with the token
+	response, _ := do(http.MethodGet, "/api/admin/stats", "")
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	response, _ = do(http.MethodGet, "/api/admin/stats", userToken)
+	assert.Equal(t, http.StatusForbidden, response.StatusCode)
+	response, _ = do(http.MethodGet, "/api/admin/stats", apiKey)
+	assert.Equal(t, http.StatusForbidden, response.StatusCode)
+
+	stats := &statsentity.ServiceStatsModel{Users: 2, RegisteredUsers: 2, Aliases: 3, DisabledAliases: 1}
+	administrator.EXPECT().GetServiceStats(gomock.Any(), adminID).Return(stats, nil)
+	response, body := do(http.MethodGet, "/api/admin/stats", adminToken)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	var gotStats statsentity.ServiceStatsModel
+	require.NoError(t, json.Unmarshal([]byte(body), &gotStats))
+	assert.Equal(t, *stats, gotStats)
+
+	//	search
+	nodes := []aliasentity.AliasURLModel{{ID: 5, UserID: userID, ShortKey: "abc", LongURL: "https://example.com", DisabledFlag: true}}
+	administrator.EXPECT().SearchAliases(gomock.Any(), adminID, aliasentity.Filter{Query: "example", UserID: &userID, Offset: 10, Limit: 20}).Return(nodes, nil)
+	response, body = do(http.MethodGet, "/api/admin/urls?q=example&user_id=2&offset=10&limit=20", adminToken)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	var aliases []adminAliasJSON
+	require.NoError(t, json.Unmarshal([]byte(body), &aliases))
+	require.Len(t, aliases, 1)
+	assert.Equal(t, testLocalHost+"/abc", aliases[0].ShortURL)
+	assert.Equal(t, userID, aliases[0].UserID)
+	assert.True(t, aliases[0].Disabled)
+
+	administrator.EXPECT().SearchAliases(gomock.Any(), adminID, aliasentity.Filter{Limit: adminPageSizeDefault}).Return(nil, nil)
+	response, body = do(http.MethodGet, "/api/admin/urls", adminToken)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.JSONEq(t, `[]`, body)
+
+	for _, query := range []string{"limit=0", "limit=1001", "offset=-1", "user_id=alice"} {
+		response, _ = do(http.MethodGet, "/api/admin/urls?"+query, adminToken)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode, query)
+	}
+
+	//	disable, enable and purge
+	administrator.EXPECT().SetAliasDisabled(gomock.Any(), adminID, "abc", true).Return(nil)
+	response, _ = do(http.MethodPost, "/api/admin/urls/abc/disable", adminToken)
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+
+	administrator.EXPECT().SetAliasDisabled(gomock.Any(), adminID, "abc", false).Return(nil)
+	response, _ = do(http.MethodPost, "/api/admin/urls/abc/enable", adminToken)
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+
+	administrator.EXPECT().SetAliasDisabled(gomock.Any(), adminID, "xyz", true).Return(aliasmaker.ErrURLNotFound)
+	response, _ = do(http.MethodPost, "/api/admin/urls/xyz/disable", adminToken)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+	administrator.EXPECT().PurgeAlias(gomock.Any(), adminID, "abc").Return(nil)
+	response, _ = do(http.MethodDelete, "/api/admin/urls/abc", adminToken)
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+
+	administrator.EXPECT().PurgeAlias(gomock.Any(), adminID, "abc").Return(aliasmaker.ErrURLNotFound)
+	response, _ = do(http.MethodDelete, "/api/admin/urls/abc", adminToken)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+	//	links of the user
+	administrator.EXPECT().ViewUserAliases(gomock.Any(), adminID, userID).Return(nodes, nil)
+	response, body = do(http.MethodGet, "/api/admin/users/2/urls", adminToken)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.NoError(t, json.Unmarshal([]byte(body), &aliases))
+	assert.Len(t, aliases, 1)
+
+	administrator.EXPECT().ViewUserAliases(gomock.Any(), adminID, uint64(100)).Return(nil, aliasmaker.ErrUserNotFound)
+	response, _ = do(http.MethodGet, "/api/admin/users/100/urls", adminToken)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
+
+func Test_adminReloadConfig(t *testing.T) {
+
+	testLocalHost := "http://localhost"
+	adminID := uint64(1)
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	userManager := mocks.NewMockUserManager(mockController)
+	shortner := mocks.NewMockShortner(mockController)
+	administrator := mocks.NewMockAdministrator(mockController)
+	reloader := mocks.NewMockReloader(mockController)
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	userManager.EXPECT().GetUser(gomock.Any(), adminID).Return(&userentity.UserModel{UserID: adminID, Login: "root", Role: userentity.RoleAdmin}, nil).AnyTimes()
+	adminToken, err := testAuth.CreateToken(adminID)
+	require.NoError(t, err)
+
+	reload := func(testServer *httptest.Server) (*http.Response, string) {
+		request, err := http.NewRequest(http.MethodPost, testServer.URL+"/api/admin/config/reload", nil)
+		require.NoError(t, err)
+		request.Header.Set(authorization, bearerPrefix+adminToken)
+
+		response, err := testServer.Client().Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+
+		data, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return response, string(data)
+	}
+
+	//	without reloader there is no route
+	withoutReloader := httptest.NewServer(NewRouter(New(userManager, shortner, testAuth, logger, testLocalHost, WithAdministrator(administrator))))
+	defer withoutReloader.Close()
+	response, _ := reload(withoutReloader)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+	testServer := httptest.NewServer(NewRouter(New(userManager, shortner, testAuth, logger, testLocalHost, WithAdministrator(administrator), WithReloader(reloader))))
+	defer testServer.Close()
+
+	reloader.EXPECT().Reload(gomock.Any()).Return([]string{"log_level"}, []string{"server_address"}, nil)
+	response, body := reload(testServer)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.JSONEq(t, `{"applied":["log_level"],"restart_required":["server_address"]}`, body)
+
+	reloader.EXPECT().Reload(gomock.Any()).Return(nil, nil, nil)
+	response, body = reload(testServer)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.JSONEq(t, `{"applied":[],"restart_required":[]}`, body)
+
+	reloader.EXPECT().Reload(gomock.Any()).Return(nil, nil, errors.New("invalid configuration"))
+	response, _ = reload(testServer)
+	assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+}
@@ -2,12 +2,14 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -17,6 +19,7 @@ import (
 	"github.com/Schalure/urlalias/internal/app/aliasmaker"
 	"github.com/Schalure/urlalias/internal/app/mocks"
 	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/models/clickentity"
 )
 
 func Test_apiGetShortURL(t *testing.T) {
@@ -34,7 +37,7 @@ func Test_apiGetShortURL(t *testing.T) {
 	logger, err := zaplogger.NewZapLogger("")
 	require.NoError(t, err)
 
-	testServer := httptest.NewServer(NewRouter(New(userManager, shortner, logger, testLocalHost)))
+	testServer := httptest.NewServer(NewRouter(New(userManager, shortner, testAuth, logger, testLocalHost)))
 	defer testServer.Close()
 
 	testCases := []struct {
@@ -42,6 +45,7 @@ func Test_apiGetShortURL(t *testing.T) {
 		requestBody    string
 		getShortKeyOut struct {
 			requestURL string
+			alias      string
 			shortKey   string
 			err        error
 		}
@@ -55,6 +59,7 @@ func Test_apiGetShortURL(t *testing.T) {
 			requestBody: `{"url": "https://ya.ru"}`,
 			getShortKeyOut: struct {
 				requestURL string
+				alias      string
 				shortKey   string
 				err        error
 			}{
@@ -75,6 +80,7 @@ func Test_apiGetShortURL(t *testing.T) {
 			requestBody: `{"url": "https://ya.ru"}`,
 			getShortKeyOut: struct {
 				requestURL string
+				alias      string
 				shortKey   string
 				err        error
 			}{
@@ -95,6 +101,7 @@ func Test_apiGetShortURL(t *testing.T) {
 			requestBody: `{"url": "https://ya.ru"}`,
 			getShortKeyOut: struct {
 				requestURL string
+				alias      string
 				shortKey   string
 				err        error
 			}{
@@ -110,13 +117,79 @@ func Test_apiGetShortURL(t *testing.T) {
 				responseBody: aliasmaker.ErrInternal.Error(),
 			},
 		},
+		{
+			name:        "custom alias test",
+			requestBody: `{"url": "https://ya.ru", "alias": "spring-sale"}`,
+			getShortKeyOut: struct {
+				requestURL string
+				alias      string
+				shortKey   string
+				err        error
+			}{
+				requestURL: "https://ya.ru",
+				alias:      "spring-sale",
+				shortKey:   "spring-sale",
+				err:        nil,
+			},
+			want: struct {
+				statusCode   int
+				responseBody string
+			}{
+				statusCode:   http.StatusCreated,
+				responseBody: `{"result":"` + testLocalHost + `/spring-sale"}`,
+			},
+		},
+		{
+			name:        "custom alias conflict test",
+			requestBody: `{"url": "https://ya.ru", "alias": "spring-sale"}`,
+			getShortKeyOut: struct {
+				requestURL string
+				alias      string
+				shortKey   string
+				err        error
+			}{
+				requestURL: "https://ya.ru",
+				alias:      "spring-sale",
+				shortKey:   "",
+				err:        aliasmaker.ErrConflictAlias,
+			},
+			want: struct {
+				statusCode   int
+				responseBody string
+			}{
+				statusCode:   http.StatusConflict,
+				responseBody: aliasmaker.ErrConflictAlias.Error() + "\n",
+			},
+		},
+		{
+			name:        "invalid custom alias test",
+			requestBody: `{"url": "https://ya.ru", "alias": "a/b"}`,
+			getShortKeyOut: struct {
+				requestURL string
+				alias      string
+				shortKey   string
+				err        error
+			}{
+				requestURL: "https://ya.ru",
+				alias:      "a/b",
+				shortKey:   "",
+				err:        aliasmaker.ErrInvalidAlias,
+			},
+			want: struct {
+				statusCode   int
+				responseBody string
+			}{
+				statusCode:   http.StatusBadRequest,
+				responseBody: "",
+			},
+		},
 	}
 
 	for _, test := range testCases {
 		t.Run(test.name, func(t *testing.T) {
 
-			userManager.EXPECT().CreateUser().Return(userID, nil)
-			shortner.EXPECT().GetShortKey(gomock.Any(), userID, test.getShortKeyOut.requestURL).Return(test.getShortKeyOut.shortKey, test.getShortKeyOut.err)
+			userManager.EXPECT().CreateUser(gomock.Any()).Return(userID, nil)
+			shortner.EXPECT().GetShortKey(gomock.Any(), userID, test.getShortKeyOut.requestURL, test.getShortKeyOut.alias, nil).Return(test.getShortKeyOut.shortKey, test.getShortKeyOut.err)
 
 			request, err := http.NewRequest(testMethod, testServer.URL+testURL, strings.NewReader(test.requestBody))
 			require.NoError(t, err)
@@ -159,8 +232,8 @@ func Benchmark_apiGetShortURL(b *testing.B) {
 
 	storage := mocks.NewMockStorager(mockController)
 	storage.EXPECT().GetLastShortKey().Return("000000001").AnyTimes()
-	storage.EXPECT().CreateUser().Return(userID, nil).AnyTimes()
-	storage.EXPECT().FindByLongURL(gomock.Any(), "https://ya.ru").Return(nil, errors.New("")).AnyTimes()
+	storage.EXPECT().CreateUser(gomock.Any()).Return(userID, nil).AnyTimes()
+	storage.EXPECT().FindByLongURL(gomock.Any(), "https://ya.ru").Return(nil, aliasentity.ErrNotFound).AnyTimes()
 	storage.EXPECT().Save(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
 	logger, err := zaplogger.NewZapLogger("")
@@ -176,7 +249,7 @@ func Benchmark_apiGetShortURL(b *testing.B) {
 		request.Header.Add("Content-type", "application/json")
 
 		recorder := httptest.NewRecorder()
-		h := New(service, service, logger, testLocalHost).apiGetShortURL
+		h := New(service, service, testAuth, logger, testLocalHost).apiGetShortURL
 
 		h(recorder, request.WithContext(context.WithValue(request.Context(), UserID, userID)))
 	}
@@ -197,7 +270,7 @@ func Test_apiGetBatchShortURL(t *testing.T) {
 	logger, err := zaplogger.NewZapLogger("")
 	require.NoError(t, err)
 
-	testServer := httptest.NewServer(NewRouter(New(userManager, shortner, logger, testLocalHost)))
+	testServer := httptest.NewServer(NewRouter(New(userManager, shortner, testAuth, logger, testLocalHost)))
 	defer testServer.Close()
 
 	testCases := []struct {
@@ -258,8 +331,8 @@ func Test_apiGetBatchShortURL(t *testing.T) {
 	for _, test := range testCases {
 		t.Run(test.name, func(t *testing.T) {
 
-			userManager.EXPECT().CreateUser().Return(userID, nil)
-			shortner.EXPECT().GetBatchShortURL(gomock.Any(), userID, test.getBatchShortURLOut.batchRequestURL).Return(test.getBatchShortURLOut.batchRsponseKey, test.getBatchShortURLOut.err)
+			userManager.EXPECT().CreateUser(gomock.Any()).Return(userID, nil)
+			shortner.EXPECT().GetBatchShortURL(gomock.Any(), userID, test.getBatchShortURLOut.batchRequestURL, gomock.Any()).Return(test.getBatchShortURLOut.batchRsponseKey, test.getBatchShortURLOut.err)
 
 			request, err := http.NewRequest(testMethod, testServer.URL+testURL, strings.NewReader(test.requestBody))
 			require.NoError(t, err)
@@ -302,7 +375,7 @@ func Benchmark_apiGetBatchShortURL(b *testing.B) {
 
 	storage := mocks.NewMockStorager(mockController)
 	storage.EXPECT().GetLastShortKey().Return("000000001").AnyTimes()
-	storage.EXPECT().CreateUser().Return(userID, nil).AnyTimes()
+	storage.EXPECT().CreateUser(gomock.Any()).Return(userID, nil).AnyTimes()
 	storage.EXPECT().FindAllByLongURLs(gomock.Any(), gomock.Any()).Return(map[string]*aliasentity.AliasURLModel{}, nil).AnyTimes()
 	storage.EXPECT().SaveAll(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
@@ -319,7 +392,7 @@ func Benchmark_apiGetBatchShortURL(b *testing.B) {
 		request.Header.Add("Content-type", "application/json")
 
 		recorder := httptest.NewRecorder()
-		h := New(service, service, logger, testLocalHost).apiGetBatchShortURL
+		h := New(service, service, testAuth, logger, testLocalHost).apiGetBatchShortURL
 
 		h(recorder, request.WithContext(context.WithValue(request.Context(), UserID, userID)))
 	}
@@ -340,7 +413,7 @@ func Test_apiGetUserAliases(t *testing.T) {
 	logger, err := zaplogger.NewZapLogger("")
 	require.NoError(t, err)
 
-	testServer := httptest.NewServer(NewRouter(New(userManager, shortner, logger, testLocalHost)))
+	testServer := httptest.NewServer(NewRouter(New(userManager, shortner, testAuth, logger, testLocalHost)))
 	defer testServer.Close()
 
 	testCases := []struct {
@@ -407,7 +480,7 @@ func Test_apiGetUserAliases(t *testing.T) {
 			request, err := http.NewRequest(testMethod, testServer.URL+testURL, nil)
 			require.NoError(t, err)
 			request.Header.Add("Content-type", "application/json")
-			tokenString, err := createTokenJWT(userID)
+			tokenString, err := testAuth.CreateToken(userID)
 			require.NoError(t, err)
 			request.AddCookie(&http.Cookie{
 				Name:  authorization,
@@ -464,7 +537,7 @@ func Benchmark_apiGetUserAliases(b *testing.B) {
 		request, err := http.NewRequest(testMethod, testURL, nil)
 		require.NoError(b, err)
 		request.Header.Add("Content-type", "application/json")
-		tokenString, err := createTokenJWT(userID)
+		tokenString, err := testAuth.CreateToken(userID)
 		require.NoError(b, err)
 		request.AddCookie(&http.Cookie{
 			Name:  authorization,
@@ -472,12 +545,123 @@ func Benchmark_apiGetUserAliases(b *testing.B) {
 		})
 
 		recorder := httptest.NewRecorder()
-		h := New(service, service, logger, testLocalHost).apiGetUserAliases
+		h := New(service, service, testAuth, logger, testLocalHost).apiGetUserAliases
 
 		h(recorder, request.WithContext(context.WithValue(request.Context(), UserID, userID)))
 	}
 }
 
+func Test_apiGetAliasStats(t *testing.T) {
+
+	testLocalHost := "http://localhost"
+	testMethod := "GET"
+	userID := uint64(1)
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	userManager := mocks.NewMockUserManager(mockController)
+	shortner := mocks.NewMockShortner(mockController)
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	testServer := httptest.NewServer(NewRouter(New(userManager, shortner, testAuth, logger, testLocalHost)))
+	defer testServer.Close()
+
+	testCases := []struct {
+		name             string
+		shortKey         string
+		getAliasStatsOut struct {
+			stats *clickentity.StatsModel
+			err   error
+		}
+		want struct {
+			statusCode   int
+			responseBody string
+		}
+	}{
+		{
+			name:     "simple test",
+			shortKey: "000000001",
+			getAliasStatsOut: struct {
+				stats *clickentity.StatsModel
+				err   error
+			}{
+				stats: &clickentity.StatsModel{
+					TotalClicks:    3,
+					UniqueVisitors: 2,
+					Daily: []clickentity.DailyStatsModel{
+						{Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Clicks: 1, UniqueVisitors: 1},
+						{Date: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), Clicks: 2, UniqueVisitors: 2},
+					},
+				},
+				err: nil,
+			},
+			want: struct {
+				statusCode   int
+				responseBody string
+			}{
+				statusCode:   http.StatusOK,
+				responseBody: `{"short_url":"http://localhost/000000001","total_clicks":3,"unique_visitors":2,"daily":[{"date":"2024-03-01","clicks":1,"unique_visitors":1},{"date":"2024-03-02","clicks":2,"unique_visitors":2}]}`,
+			},
+		},
+		{
+			name:     "not found test",
+			shortKey: "000000002",
+			getAliasStatsOut: struct {
+				stats *clickentity.StatsModel
+				err   error
+			}{
+				stats: nil,
+				err:   aliasmaker.ErrURLNotFound,
+			},
+			want: struct {
+				statusCode   int
+				responseBody string
+			}{
+				statusCode:   http.StatusNotFound,
+				responseBody: "the url alias not found by key \"000000002\"\n",
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+
+			userManager.EXPECT().GetAliasStats(gomock.Any(), userID, test.shortKey).Return(test.getAliasStatsOut.stats, test.getAliasStatsOut.err)
+
+			request, err := http.NewRequest(testMethod, testServer.URL+"/api/user/urls/"+test.shortKey+"/stats", nil)
+			require.NoError(t, err)
+			tokenString, err := testAuth.CreateToken(userID)
+			require.NoError(t, err)
+			request.AddCookie(&http.Cookie{
+				Name:  authorization,
+				Value: tokenString,
+			})
+
+			client := testServer.Client()
+			transport := &http.Transport{
+				Proxy:              http.ProxyFromEnvironment,
+				DisableCompression: true,
+			}
+			client.Transport = transport
+
+			response, err := client.Do(request)
+			require.NoError(t, err)
+
+			//	check status code
+			assert.Equal(t, test.want.statusCode, response.StatusCode)
+
+			data, err := io.ReadAll(response.Body)
+			require.NoError(t, err)
+			err = response.Body.Close()
+			require.NoError(t, err)
+
+			assert.Equal(t, test.want.responseBody, string(data))
+		})
+	}
+}
+
 func Test_aipDeleteUserAliases(t *testing.T) {
 
 	testLocalHost := "http://localhost"
@@ -493,7 +677,7 @@ func Test_aipDeleteUserAliases(t *testing.T) {
 	logger, err := zaplogger.NewZapLogger("")
 	require.NoError(t, err)
 
-	testServer := httptest.NewServer(NewRouter(New(userManager, shortner, logger, testLocalHost)))
+	testServer := httptest.NewServer(NewRouter(New(userManager, shortner, testAuth, logger, testLocalHost)))
 	defer testServer.Close()
 
 	testCases := []struct {
@@ -531,7 +715,7 @@ func Test_aipDeleteUserAliases(t *testing.T) {
 			request, err := http.NewRequest(testMethod, testServer.URL+testURL, strings.NewReader(test.requestBody))
 			require.NoError(t, err)
 			request.Header.Add("Content-type", "application/json")
-			tokenString, err := createTokenJWT(userID)
+			tokenString, err := testAuth.CreateToken(userID)
 			require.NoError(t, err)
 			request.AddCookie(&http.Cookie{
 				Name:  authorization,
@@ -554,3 +738,149 @@ func Test_aipDeleteUserAliases(t *testing.T) {
 		})
 	}
 }
+
+func Test_apiGetToken(t *testing.T) {
+
+	testLocalHost := "http://localhost"
+	userID := uint64(5)
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	userManager := mocks.NewMockUserManager(mockController)
+	shortner := mocks.NewMockShortner(mockController)
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	testServer := httptest.NewServer(NewRouter(New(userManager, shortner, testAuth, logger, testLocalHost)))
+	defer testServer.Close()
+
+	type ResponseJSON struct {
+		Token     string `json:"token"`
+		TokenType string `json:"token_type"`
+		ExpiresIn int64  `json:"expires_in"`
+	}
+	getToken := func(bearer string) (int, ResponseJSON, *http.Response) {
+		request, err := http.NewRequest(http.MethodPost, testServer.URL+"/api/auth/token", nil)
+		require.NoError(t, err)
+		if bearer != "" {
+			request.Header.Set(authorization, "Bearer "+bearer)
+		}
+		response, err := testServer.Client().Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+
+		var responseJSON ResponseJSON
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&responseJSON))
+		return response.StatusCode, responseJSON, response
+	}
+
+	//	client without token gets the token of a new user
+	userManager.EXPECT().CreateUser(gomock.Any()).Return(userID, nil)
+	statusCode, first, response := getToken("")
+	assert.Equal(t, http.StatusCreated, statusCode)
+	assert.Equal(t, "Bearer", first.TokenType)
+	assert.Equal(t, int64(testAuth.TokenExp().Seconds()), first.ExpiresIn)
+	assert.Empty(t, response.Cookies())
+
+	tokenUserID, err := testAuth.GetUserID(first.Token)
+	require.NoError(t, err)
+	assert.Equal(t, userID, tokenUserID)
+
+	//	the token is exchanged for a new token of the same user
+	statusCode, second, _ := getToken(first.Token)
+	assert.Equal(t, http.StatusOK, statusCode)
+	tokenUserID, err = testAuth.GetUserID(second.Token)
+	require.NoError(t, err)
+	assert.Equal(t, userID, tokenUserID)
+
+	//	the token identifies the user in API requests
+	userManager.EXPECT().GetUserAliases(gomock.Any(), userID).Return(nil, nil)
+	request, err := http.NewRequest(http.MethodGet, testServer.URL+"/api/user/urls", nil)
+	require.NoError(t, err)
+	request.Header.Set(authorization, "Bearer "+second.Token)
+	response, err = testServer.Client().Do(request)
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+}
+
+func Test_apiRegisterAndLogin(t *testing.T) {
+
+	testLocalHost := "http://localhost"
+	userID := uint64(6)
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	userManager := mocks.NewMockUserManager(mockController)
+	shortner := mocks.NewMockShortner(mockController)
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	testServer := httptest.NewServer(NewRouter(New(userManager, shortner, testAuth, logger, testLocalHost)))
+	defer testServer.Close()
+
+	type ResponseJSON struct {
+		Token string `json:"token"`
+	}
+	do := func(path, body, bearer string) (*http.Response, ResponseJSON) {
+		request, err := http.NewRequest(http.MethodPost, testServer.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		if bearer != "" {
+			request.Header.Set(authorization, "Bearer "+bearer)
+		}
+		response, err := testServer.Client().Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+
+		var responseJSON ResponseJSON
+		json.NewDecoder(response.Body).Decode(&responseJSON)
+		return response, responseJSON
+	}
+	assertToken := func(response *http.Response, responseJSON ResponseJSON) {
+		tokenUserID, err := testAuth.GetUserID(responseJSON.Token)
+		require.NoError(t, err)
+		assert.Equal(t, userID, tokenUserID)
+
+		cookies := response.Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, authorization, cookies[0].Name)
+		assert.Equal(t, responseJSON.Token, cookies[0].Value)
+	}
+
+	//	registration without token
+	userManager.EXPECT().Register(gomock.Any(), "alice", "correct horse", nil).Return(userID, nil)
+	response, responseJSON := do("/api/auth/register", `{"login": "alice", "password": "correct horse"}`, "")
+	require.Equal(t, http.StatusCreated, response.StatusCode)
+	assertToken(response, responseJSON)
+
+	//	registration claims the user of the token
+	anonymousToken, err := testAuth.CreateToken(userID)
+	require.NoError(t, err)
+	userManager.EXPECT().Register(gomock.Any(), "bob", "battery staple", gomock.Eq(&userID)).Return(userID, nil)
+	response, responseJSON = do("/api/auth/register", `{"login": "bob", "password": "battery staple"}`, anonymousToken)
+	require.Equal(t, http.StatusCreated, response.StatusCode)
+	assertToken(response, responseJSON)
+
+	userManager.EXPECT().Register(gomock.Any(), "alice", "correct horse", nil).Return(uint64(0), aliasmaker.ErrConflictLogin)
+	response, _ = do("/api/auth/register", `{"login": "alice", "password": "correct horse"}`, "")
+	assert.Equal(t, http.StatusConflict, response.StatusCode)
+
+	userManager.EXPECT().Register(gomock.Any(), "alice", "short", nil).Return(uint64(0), aliasmaker.ErrInvalidPassword)
+	response, _ = do("/api/auth/register", `{"login": "alice", "password": "short"}`, "")
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	response, _ = do("/api/auth/register", `not json`, "")
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	//	login
+	userManager.EXPECT().Login(gomock.Any(), "alice", "correct horse").Return(userID, nil)
+	response, responseJSON = do("/api/auth/login", `{"login": "alice", "password": "correct horse"}`, "")
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assertToken(response, responseJSON)
+
+	userManager.EXPECT().Login(gomock.Any(), "alice", "wrong horse").Return(uint64(0), aliasmaker.ErrInvalidCredentials)
+	response, _ = do("/api/auth/login", `{"login": "alice", "password": "wrong horse"}`, "")
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/interpreter"
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+)
+
+// apiKeyJSON is API key in responses. The key itself is returned only when it is created
+type apiKeyJSON struct {
+	ID         uint64     `json:"id"`
+	Key        string     `json:"key,omitempty"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Revoked    bool       `json:"revoked"`
+}
+
+// newAPIKeyJSON converts the stored model of API key to the response
+func newAPIKeyJSON(key *apikeyentity.APIKeyModel) apiKeyJSON {
+
+	return apiKeyJSON{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		Revoked:    key.RevokedFlag,
+	}
+}
+
+// Handler creates API key of the user. The optional "scopes" field limits the key to some of
+// "shorten", "read" and "delete" scopes, all of them by default. The optional "expires_at" (RFC 3339)
+// or "ttl_seconds" fields set the expiry time of the key. The key is returned once and can't be shown again.
+// Handler can returns three HTTP statuses:
+// 1. StatusCreated (201) - the key is created;
+// 2. StatusBadRequest (400) - if the request fields are invalid;
+// 3. StatusInternalServerError (500) - if an internal service error occurred.
+func (h *Server) apiCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+
+	type RequestJSON struct {
+		Name       string     `json:"name"`
+		Scopes     []string   `json:"scopes,omitempty"`
+		ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+		TTLSeconds *int64     `json:"ttl_seconds,omitempty"`
+	}
+
+	var i interpreter.InterpreterJSON
+
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, errors.New("can't parsed user id").Error(), http.StatusBadRequest)
+		return
+	}
+
+	var requestJSON RequestJSON
+	if err = i.Unmarshal(r.Body, &requestJSON); err != nil {
+		http.Error(w, "can't decode JSON content", http.StatusBadRequest)
+		return
+	}
+
+	expiresAt, err := GetExpiresAt(requestJSON.ExpiresAt, requestJSON.TTLSeconds)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key, model, err := h.userManager.CreateAPIKey(r.Context(), userID, requestJSON.Name, requestJSON.Scopes, expiresAt)
+	if err != nil {
+		if errors.Is(err, aliasmaker.ErrInvalidScope) || errors.Is(err, aliasmaker.ErrInvalidAPIKeyName) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	responseJSON := newAPIKeyJSON(model)
+	responseJSON.Key = key
+
+	buf, err := json.Marshal(&responseJSON)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", appJSON)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(buf)
+}
+
+// Handler returns all API keys of the user without the keys themselves.
+// Handler can returns three HTTP statuses:
+// 1. StatusOK (200) - keys are returned;
+// 2. StatusNoContent (204) - if the user has no keys;
+// 3. StatusInternalServerError (500) - if an internal service error occurred.
+func (h *Server) apiGetAPIKeys(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, errors.New("can't parsed user id").Error(), http.StatusBadRequest)
+		return
+	}
+
+	keys, err := h.userManager.GetAPIKeys(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if len(keys) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	responseJSON := make([]apiKeyJSON, len(keys))
+	for i := range keys {
+		responseJSON[i] = newAPIKeyJSON(&keys[i])
+	}
+
+	buf, err := json.Marshal(&responseJSON)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", appJSON)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf)
+}
+
+// Handler revokes API key of the user by ID. Handler can returns four HTTP statuses:
+// 1. StatusNoContent (204) - the key is revoked;
+// 2. StatusBadRequest (400) - if the ID is not a number;
+// 3. StatusNotFound (404) - if the key is not found or belongs to other user;
+// 4. StatusInternalServerError (500) - if an internal service error occurred.
+func (h *Server) apiRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := h.getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, errors.New("can't parsed user id").Error(), http.StatusBadRequest)
+		return
+	}
+
+	keyID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("API key ID \"%s\" is not valid", chi.URLParam(r, "id")), http.StatusBadRequest)
+		return
+	}
+
+	if err = h.userManager.RevokeAPIKey(r.Context(), userID, keyID); err != nil {
+		if errors.Is(err, aliasmaker.ErrAPIKeyNotFound) {
+			http.Error(w, fmt.Sprintf("API key %d not found", keyID), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/mocks"
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+)
+
+func Test_apiAPIKeys(t *testing.T) {
+
+	testLocalHost := "http://localhost"
+	userID := uint64(2)
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	userManager := mocks.NewMockUserManager(mockController)
+	shortner := mocks.NewMockShortner(mockController)
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	testServer := httptest.NewServer(NewRouter(New(userManager, shortner, testAuth, logger, testLocalHost)))
+	defer testServer.Close()
+
+	tokenString, err := testAuth.CreateToken(userID)
+	require.NoError(t, err)
+
+	do := func(method, path, body string) (*http.Response, string) {
+		request, err := http.NewRequest(method, testServer.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		request.Header.Set(authorization, bearerPrefix+tokenString)
+
+		response, err := testServer.Client().Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+
+		data, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return response, string(data)
+	}
+
+	createdAt := time.Now().UTC().Truncate(time.Second)
+	model := &apikeyentity.APIKeyModel{
+		ID:        7,
+		UserID:    userID,
+		Name:      "ci",
+		Prefix:    "ua_abcdefgh",
+		Hash:      "hash",
+		Scopes:    []string{apikeyentity.ScopeShorten},
+		CreatedAt: createdAt,
+	}
+
+	//	the key is returned once, when it is created
+	userManager.EXPECT().CreateAPIKey(gomock.Any(), userID, "ci", []string{apikeyentity.ScopeShorten}, nil).Return("ua_abcdefghsecret", model, nil)
+	response, body := do(http.MethodPost, "/api/user/keys", `{"name": "ci", "scopes": ["shorten"]}`)
+	require.Equal(t, http.StatusCreated, response.StatusCode, body)
+	assert.Equal(t, "no-store", response.Header.Get("Cache-Control"))
+
+	var created apiKeyJSON
+	require.NoError(t, json.Unmarshal([]byte(body), &created))
+	assert.Equal(t, "ua_abcdefghsecret", created.Key)
+	assert.Equal(t, uint64(7), created.ID)
+	assert.Equal(t, model.Scopes, created.Scopes)
+	assert.NotContains(t, body, "hash")
+
+	userManager.EXPECT().CreateAPIKey(gomock.Any(), userID, "", []string{"admin"}, nil).Return("", nil, aliasmaker.ErrInvalidScope)
+	response, _ = do(http.MethodPost, "/api/user/keys", `{"scopes": ["admin"]}`)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	response, _ = do(http.MethodPost, "/api/user/keys", `{"ttl_seconds": -1}`)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	//	the list has no keys themselves
+	userManager.EXPECT().GetAPIKeys(gomock.Any(), userID).Return([]apikeyentity.APIKeyModel{*model}, nil)
+	response, body = do(http.MethodGet, "/api/user/keys", "")
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	var listed []apiKeyJSON
+	require.NoError(t, json.Unmarshal([]byte(body), &listed))
+	require.Len(t, listed, 1)
+	assert.Empty(t, listed[0].Key)
+	assert.Equal(t, model.Prefix, listed[0].Prefix)
+
+	userManager.EXPECT().GetAPIKeys(gomock.Any(), userID).Return(nil, nil)
+	response, _ = do(http.MethodGet, "/api/user/keys", "")
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+
+	//	revoke
+	userManager.EXPECT().RevokeAPIKey(gomock.Any(), userID, uint64(7)).Return(nil)
+	response, _ = do(http.MethodDelete, "/api/user/keys/7", "")
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+
+	userManager.EXPECT().RevokeAPIKey(gomock.Any(), userID, uint64(8)).Return(aliasmaker.ErrAPIKeyNotFound)
+	response, _ = do(http.MethodDelete, "/api/user/keys/8", "")
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+	response, _ = do(http.MethodDelete, "/api/user/keys/seven", "")
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ------------------------------------------------------------
+//
+//	Parse addresses of trusted proxies
+//	Input:
+//		proxies []string - IP addresses or networks, for example: 10.0.0.1, 10.1.0.0/16
+//	Output:
+//		[]netip.Prefix
+//		error - if an item is not an IP address or a network
+func ParseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		if prefix, err := netip.ParsePrefix(proxy); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy is not an IP address or a network: %s", proxy)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Get IP address of client. "X-Forwarded-For" header is used only if the request came from a trusted proxy,
+//	so clients can't set their address by the header. Addresses of the header are read from right to left,
+//	the first one which is not a trusted proxy is the client
+func (h *Server) getClientIP(r *http.Request) string {
+
+	remoteIP := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		remoteIP = host
+	}
+	if !h.isTrustedProxy(remoteIP) {
+		return remoteIP
+	}
+
+	var forwardedFor []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		forwardedFor = append(forwardedFor, strings.Split(header, ",")...)
+	}
+
+	clientIP := remoteIP
+	for i := len(forwardedFor) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwardedFor[i])
+		if _, err := netip.ParseAddr(ip); err != nil {
+			//	the proxy appended a broken address, addresses left of it can't be trusted
+			break
+		}
+		clientIP = ip
+		if !h.isTrustedProxy(ip) {
+			break
+		}
+	}
+	return clientIP
+}
+
+// ------------------------------------------------------------
+//
+//	Check if IP address is an address of a trusted proxy
+func (h *Server) isTrustedProxy(ip string) bool {
+
+	if len(h.trustedProxies) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range h.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
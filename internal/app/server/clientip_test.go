@@ -0,0 +1,97 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_getClientIP(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		forwardedFor   []string
+		want           string
+	}{
+		{
+			name:         "header is ignored without trusted proxies",
+			remoteAddr:   "203.0.113.7:5000",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "203.0.113.7",
+		},
+		{
+			name:           "header is ignored from untrusted address",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "203.0.113.7:5000",
+			forwardedFor:   []string{"198.51.100.1"},
+			want:           "203.0.113.7",
+		},
+		{
+			name:           "header is used from trusted proxy",
+			trustedProxies: []string{"10.0.0.1"},
+			remoteAddr:     "10.0.0.1:5000",
+			forwardedFor:   []string{"198.51.100.1"},
+			want:           "198.51.100.1",
+		},
+		{
+			name:           "spoofed addresses left of the client are skipped",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.1:5000",
+			forwardedFor:   []string{"1.1.1.1, 198.51.100.1", "10.0.0.2"},
+			want:           "198.51.100.1",
+		},
+		{
+			name:           "all addresses are trusted",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.1:5000",
+			forwardedFor:   []string{"10.0.0.3, 10.0.0.2"},
+			want:           "10.0.0.3",
+		},
+		{
+			name:           "broken address stops the walk",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.1:5000",
+			forwardedFor:   []string{"198.51.100.1, unknown"},
+			want:           "10.0.0.1",
+		},
+		{
+			name:           "IPv6 proxy",
+			trustedProxies: []string{"::1"},
+			remoteAddr:     "[::1]:5000",
+			forwardedFor:   []string{"2001:db8::1"},
+			want:           "2001:db8::1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxies, err := ParseTrustedProxies(tt.trustedProxies)
+			require.NoError(t, err)
+			s := New(nil, nil, nil, nil, "http://localhost:8080", WithTrustedProxies(proxies...))
+
+			request := httptest.NewRequest("GET", "/abc", nil)
+			request.RemoteAddr = tt.remoteAddr
+			for _, header := range tt.forwardedFor {
+				request.Header.Add("X-Forwarded-For", header)
+			}
+			assert.Equal(t, tt.want, s.getClientIP(request))
+		})
+	}
+}
+
+func Test_ParseTrustedProxies(t *testing.T) {
+
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.1", "10.1.2.3/16", "::ffff:192.168.0.1", "fd00::/8"})
+	require.NoError(t, err)
+	require.Len(t, proxies, 4)
+	assert.Equal(t, "10.0.0.1/32", proxies[0].String())
+	assert.Equal(t, "10.1.0.0/16", proxies[1].String())
+	assert.Equal(t, "192.168.0.1/32", proxies[2].String())
+	assert.Equal(t, "fd00::/8", proxies[3].String())
+
+	_, err = ParseTrustedProxies([]string{"proxy.local"})
+	assert.Error(t, err)
+}
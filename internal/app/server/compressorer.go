@@ -1,6 +1,7 @@
 package server
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/Schalure/urlalias/internal/app/compressor"
@@ -13,12 +14,39 @@ type Compressorer interface {
 	Type() string
 }
 
-// Compressorer constructor
-func NewCompressorer(w io.Writer, compressType CompressType) Compressorer {
+// compressionLevels are ranges and defaults of compression levels
+var compressionLevels = map[CompressType]struct{ min, max, def int }{
+	TypeGZIP:   {compressor.GZIPLevelMin, compressor.GZIPLevelMax, compressor.GZIPLevelDefault},
+	TypeZLIB:   {compressor.DeflateLevelMin, compressor.DeflateLevelMax, compressor.DeflateLevelDefault},
+	TypeBrotli: {compressor.BrotliLevelMin, compressor.BrotliLevelMax, compressor.BrotliLevelDefault},
+	TypeZSTD:   {compressor.ZSTDLevelMin, compressor.ZSTDLevelMax, compressor.ZSTDLevelDefault},
+}
+
+// Compressorer constructor. level out of range of the compression type means the default level
+func NewCompressorer(w io.Writer, compressType CompressType, level int) Compressorer {
 
 	switch compressType {
 	case TypeGZIP:
-		return compressor.NewCompressorGZIP(w)
+		return compressor.NewCompressorGZIP(w, level)
+	case TypeZLIB:
+		return compressor.NewCompressorDeflate(w, level)
+	case TypeBrotli:
+		return compressor.NewCompressorBrotli(w, level)
+	case TypeZSTD:
+		return compressor.NewCompressorZSTD(w, level)
+	}
+	return nil
+}
+
+// CheckCompressionLevel returns error if the compression type is not supported or the level is out of its range
+func CheckCompressionLevel(compressType CompressType, level int) error {
+
+	levels, ok := compressionLevels[compressType]
+	if !ok {
+		return fmt.Errorf("%s compression type is not supported", compressType)
+	}
+	if level < levels.min || level > levels.max {
+		return fmt.Errorf("%s compression level must be from %d to %d", compressType, levels.min, levels.max)
 	}
 	return nil
 }
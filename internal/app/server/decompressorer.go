@@ -16,12 +16,16 @@ const (
 	TypeLZW    CompressType = "compress"
 	TypeZLIB   CompressType = "deflate"
 	TypeBrotli CompressType = "br"
+	TypeZSTD   CompressType = "zstd"
 	TypeNone   CompressType = ""
 )
 
-// PossibleCompressionTypes
+// PossibleCompressionTypes in order of preference, the first one is chosen if a client accepts several equally
 var PossibleCompressionTypes = []CompressType{
+	TypeBrotli,
+	TypeZSTD,
 	TypeGZIP,
+	TypeZLIB,
 }
 
 // Decompressorer interface
@@ -34,14 +38,15 @@ type Decompressorer interface {
 // Decompressorer constructor
 func NewDecompressorer(r io.Reader, compressType CompressType) (Decompressorer, error) {
 
-	var err error
 	switch compressType {
 	case TypeGZIP:
-		if decompressorGZIP, err := compressor.NewDecompressorGZIP(r); err == nil {
-			return decompressorGZIP, nil
-		}
-	default:
-		err = fmt.Errorf("%s compression type is not supported", compressType)
+		return compressor.NewDecompressorGZIP(r)
+	case TypeZLIB:
+		return compressor.NewDecompressorDeflate(r)
+	case TypeBrotli:
+		return compressor.NewDecompressorBrotli(r)
+	case TypeZSTD:
+		return compressor.NewDecompressorZSTD(r)
 	}
-	return nil, err
+	return nil, fmt.Errorf("%s compression type is not supported", compressType)
 }
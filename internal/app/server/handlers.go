@@ -6,17 +6,28 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/netip"
+	"sync/atomic"
+	"time"
 
 	"github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
 	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/jwtauth"
 	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+	"github.com/Schalure/urlalias/internal/app/models/clickentity"
+	"github.com/Schalure/urlalias/internal/app/models/statsentity"
+	"github.com/Schalure/urlalias/internal/app/models/userentity"
 )
 
 const (
 	contentType     string = "Content-Type"
 	contentEncoding string = "Content-Encoding"
+	contentLength   string = "Content-Length"
 	acceptEncoding  string = "Accept-Encoding"
+	vary            string = "Vary"
 	authorization   string = "Authorization"
+	apiKeyHeader    string = "X-API-Key"
 )
 
 const (
@@ -30,38 +41,77 @@ var ContentTypeToCompress = []string{
 	appJSON,
 }
 
-//go:generate mockgen -destination=../mocks/mock_shortner.go -package=mocks github.com/Schalure/urlalias/internal/app/handlers Shortner
+//go:generate mockgen -destination=../mocks/mock_shortner.go -package=mocks github.com/Schalure/urlalias/internal/app/server Shortner
 type Shortner interface {
 	GetOriginalURL(ctx context.Context, shortKey string) (string, error)
-	GetShortKey(ctx context.Context, userID uint64, originalURL string) (string, error)
-	GetBatchShortURL(ctx context.Context, userID uint64, batchOriginalURL []string) ([]string, error)
+	GetShortKey(ctx context.Context, userID uint64, originalURL, alias string, expiresAt *time.Time) (string, error)
+	GetBatchShortURL(ctx context.Context, userID uint64, batchOriginalURL []string, batchExpiresAt []*time.Time) ([]string, error)
 	AddAliasesToDelete(ctx context.Context, userID uint64, aliases ...string) error
-	IsDatabaseActive() bool
+	RecordClick(ctx context.Context, click clickentity.ClickModel)
+	IsDatabaseActive(ctx context.Context) bool
 }
 
-//go:generate mockgen -destination=../mocks/mock_usermanager.go -package=mocks github.com/Schalure/urlalias/internal/app/handlers UserManager
+//go:generate mockgen -destination=../mocks/mock_usermanager.go -package=mocks github.com/Schalure/urlalias/internal/app/server UserManager
 type UserManager interface {
-	CreateUser() (uint64, error)
+	CreateUser(ctx context.Context) (uint64, error)
+	GetUser(ctx context.Context, userID uint64) (*userentity.UserModel, error)
 	GetUserAliases(ctx context.Context, userID uint64) ([]aliasentity.AliasURLModel, error)
+	GetAliasStats(ctx context.Context, userID uint64, shortKey string) (*clickentity.StatsModel, error)
+	CreateAPIKey(ctx context.Context, userID uint64, name string, scopes []string, expiresAt *time.Time) (string, *apikeyentity.APIKeyModel, error)
+	GetAPIKeys(ctx context.Context, userID uint64) ([]apikeyentity.APIKeyModel, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID uint64) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*apikeyentity.APIKeyModel, error)
+	Register(ctx context.Context, login, password string, claimUserID *uint64) (uint64, error)
+	Login(ctx context.Context, login, password string) (uint64, error)
+}
+
+//go:generate mockgen -destination=../mocks/mock_administrator.go -package=mocks github.com/Schalure/urlalias/internal/app/server Administrator
+type Administrator interface {
+	SearchAliases(ctx context.Context, adminID uint64, filter aliasentity.Filter) ([]aliasentity.AliasURLModel, error)
+	ViewUserAliases(ctx context.Context, adminID, userID uint64) ([]aliasentity.AliasURLModel, error)
+	SetAliasDisabled(ctx context.Context, adminID uint64, shortKey string, disabled bool) error
+	PurgeAlias(ctx context.Context, adminID uint64, shortKey string) error
+	GetServiceStats(ctx context.Context, adminID uint64) (*statsentity.ServiceStatsModel, error)
+}
+
+//go:generate mockgen -destination=../mocks/mock_reloader.go -package=mocks github.com/Schalure/urlalias/internal/app/server Reloader
+type Reloader interface {
+	//	Reload returns keys of changed settings: applied to the running service and the ones which need a restart
+	Reload(ctx context.Context) (applied, restartRequired []string, err error)
 }
 
 // Server type
 type Server struct {
-	userManager UserManager
-	shortner    Shortner
-	logger      *zaplogger.ZapLogger
-	baseURL     string
+	userManager   UserManager
+	shortner      Shortner
+	administrator Administrator //	administrator - service of "/api/admin" routes, nil - the routes are not served
+	auth          *jwtauth.Manager
+	reloader      Reloader                         //	reloader - reloads configuration by "/api/admin/config/reload", nil - the route is not served
+	settings      *atomic.Pointer[RuntimeSettings] //	settings - cookie attributes and compression, they are replaced at once by Reconfigure
+	logger        *zaplogger.ZapLogger
+	baseURL       string
+
+	trustedProxies []netip.Prefix //	trustedProxies - proxies whose "X-Forwarded-For" header is used, empty - the header is ignored
 }
 
 // Constructor of Handler type
-func New(userManager UserManager, shortner Shortner, logger *zaplogger.ZapLogger, baseURL string) *Server {
+func New(userManager UserManager, shortner Shortner, auth *jwtauth.Manager, logger *zaplogger.ZapLogger, baseURL string, opts ...Option) *Server {
 
-	return &Server{
+	s := &Server{
 		userManager: userManager,
 		shortner:    shortner,
-		logger:      logger,
-		baseURL:     baseURL,
+		auth:        auth,
+		settings: newRuntimeSettings(RuntimeSettings{
+			Cookie:      CookieAttributesDefault,
+			Compression: CompressionDefault,
+		}),
+		logger:  logger,
+		baseURL: baseURL,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 // Handler retuns original URL by short key in HTTP header "Location" and redirect status code (307).
@@ -80,8 +130,26 @@ func (h *Server) redirect(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, fmt.Sprintf("the url alias was deleted \"%s\"", shortKey), http.StatusGone)
 			return
 		}
+		if errors.Is(err, aliasmaker.ErrURLExpired) {
+			http.Error(w, fmt.Sprintf("the url alias has expired \"%s\"", shortKey), http.StatusGone)
+			return
+		}
+		if errors.Is(err, aliasmaker.ErrURLDisabled) {
+			http.Error(w, fmt.Sprintf("the url alias was disabled \"%s\"", shortKey), http.StatusGone)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
+	h.shortner.RecordClick(r.Context(), clickentity.ClickModel{
+		ShortKey:  shortKey,
+		ClickedAt: time.Now(),
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+		ClientIP:  h.getClientIP(r),
+	})
+
 	w.Header().Add("Location", originalURL)
 	w.WriteHeader(http.StatusTemporaryRedirect)
 }
@@ -105,12 +173,16 @@ func (h *Server) getShortURL(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var statusCode int
-	shortURL, err := h.shortner.GetShortKey(r.Context(), userID, string(originalURL))
+	shortURL, err := h.shortner.GetShortKey(r.Context(), userID, string(originalURL), "", nil)
 	if err != nil {
 		if errors.Is(err, aliasmaker.ErrInternal) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		if errors.Is(err, aliasmaker.ErrKeyspaceExhausted) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
 		if errors.Is(err, aliasmaker.ErrConflictURL) {
 			statusCode = http.StatusConflict
 		}
@@ -126,13 +198,35 @@ func (h *Server) getShortURL(w http.ResponseWriter, r *http.Request) {
 // Get state of database service
 func (h *Server) PingGet(w http.ResponseWriter, r *http.Request) {
 
-	if !h.shortner.IsDatabaseActive() {
+	if !h.shortner.IsDatabaseActive(r.Context()) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
 
+// GetExpiresAt returns expiry time of alias from "expires_at" or "ttl_seconds" request fields.
+// Returns nil if neither is set
+func GetExpiresAt(expiresAt *time.Time, ttlSeconds *int64) (*time.Time, error) {
+
+	if expiresAt != nil && ttlSeconds != nil {
+		return nil, errors.New("only one of \"expires_at\" and \"ttl_seconds\" can be set")
+	}
+
+	if ttlSeconds != nil {
+		if *ttlSeconds <= 0 {
+			return nil, errors.New("\"ttl_seconds\" must be positive")
+		}
+		t := time.Now().Add(time.Duration(*ttlSeconds) * time.Second)
+		return &t, nil
+	}
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, errors.New("\"expires_at\" must be in the future")
+	}
+	return expiresAt, nil
+}
+
 // Get User ID from request context
 func (h *Server) getUserIDFromContext(ctx context.Context) (uint64, error) {
 
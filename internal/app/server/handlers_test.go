@@ -2,12 +2,12 @@ package server
 
 import (
 	"context"
-	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -15,10 +15,25 @@ import (
 
 	"github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
 	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/jwtauth"
 	"github.com/Schalure/urlalias/internal/app/mocks"
 	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
 )
 
+// testAuth issues tokens of the tests
+var testAuth = func() *jwtauth.Manager {
+
+	key, err := jwtauth.GenerateHMACKey("test")
+	if err != nil {
+		panic(err)
+	}
+	auth, err := jwtauth.New([]jwtauth.Key{key}, key.ID)
+	if err != nil {
+		panic(err)
+	}
+	return auth
+}()
+
 func Test_redirect(t *testing.T) {
 
 	mockController := gomock.NewController(t)
@@ -83,6 +98,26 @@ func Test_redirect(t *testing.T) {
 				responseURL: "",
 			},
 		},
+		{
+			name:      "expired test",
+			requesURI: "/000000000",
+			getOriginalURLParams: struct {
+				inpURI string
+				outURL string
+				outErr error
+			}{
+				inpURI: "000000000",
+				outURL: "",
+				outErr: aliasmaker.ErrURLExpired,
+			},
+			want: struct {
+				statusCode  int
+				responseURL string
+			}{
+				statusCode:  http.StatusGone,
+				responseURL: "",
+			},
+		},
 		{
 			name:      "not found test",
 			requesURI: "/000000000",
@@ -109,11 +144,14 @@ func Test_redirect(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 
 			shortner.EXPECT().GetOriginalURL(gomock.Any(), test.getOriginalURLParams.inpURI).Return(test.getOriginalURLParams.outURL, test.getOriginalURLParams.outErr)
+			if test.getOriginalURLParams.outErr == nil {
+				shortner.EXPECT().RecordClick(gomock.Any(), gomock.Any())
+			}
 
 			request := httptest.NewRequest(http.MethodGet, test.requesURI, nil)
 
 			recorder := httptest.NewRecorder()
-			h := New(userManager, shortner, logger, "http://localhost/").redirect
+			h := New(userManager, shortner, testAuth, logger, "http://localhost/").redirect
 			h(recorder, request)
 
 			resp := recorder.Result()
@@ -139,7 +177,7 @@ func Benchmark_redirect(b *testing.B) {
 
 	storage := mocks.NewMockStorager(mockController)
 	storage.EXPECT().GetLastShortKey().Return("000000001").AnyTimes()
-	storage.EXPECT().CreateUser().Return(userID, nil).AnyTimes()
+	storage.EXPECT().CreateUser(gomock.Any()).Return(userID, nil).AnyTimes()
 	storage.EXPECT().FindByShortKey(gomock.Any(), "000000002").Return(&aliasentity.AliasURLModel{
 		ID:          1,
 		UserID:      userID,
@@ -158,7 +196,7 @@ func Benchmark_redirect(b *testing.B) {
 	request.Header.Add("Content-type", "text/plain")
 
 	recorder := httptest.NewRecorder()
-	h := New(service, service, logger, testLocalHost).redirect
+	h := New(service, service, testAuth, logger, testLocalHost).redirect
 
 	for i := 0; i < b.N; i++ {
 
@@ -181,7 +219,7 @@ func Test_getShortURL(t *testing.T) {
 	logger, err := zaplogger.NewZapLogger("")
 	require.NoError(t, err)
 
-	testServer := httptest.NewServer(NewRouter(New(userManager, shortner, logger, testLocalHost)))
+	testServer := httptest.NewServer(NewRouter(New(userManager, shortner, testAuth, logger, testLocalHost)))
 	defer testServer.Close()
 
 	testCases := []struct {
@@ -255,8 +293,8 @@ func Test_getShortURL(t *testing.T) {
 	for _, test := range testCases {
 		t.Run(test.name, func(t *testing.T) {
 
-			userManager.EXPECT().CreateUser().Return(userID, nil)
-			shortner.EXPECT().GetShortKey(gomock.Any(), userID, test.requestURL).Return(test.getShortKeyOut.shortKey, test.getShortKeyOut.err)
+			userManager.EXPECT().CreateUser(gomock.Any()).Return(userID, nil)
+			shortner.EXPECT().GetShortKey(gomock.Any(), userID, test.requestURL, "", nil).Return(test.getShortKeyOut.shortKey, test.getShortKeyOut.err)
 
 			request, err := http.NewRequest(testMethod, testServer.URL+testURL, strings.NewReader(test.requestURL))
 			require.NoError(t, err)
@@ -287,6 +325,47 @@ func Test_getShortURL(t *testing.T) {
 	}
 }
 
+func Test_GetExpiresAt(t *testing.T) {
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	ttl := int64(60)
+	badTTL := int64(0)
+
+	testCases := []struct {
+		name       string
+		expiresAt  *time.Time
+		ttlSeconds *int64
+		wantNil    bool
+		wantErr    bool
+	}{
+		{name: "no expiry", wantNil: true},
+		{name: "absolute expiry", expiresAt: &future},
+		{name: "ttl expiry", ttlSeconds: &ttl},
+		{name: "expiry in the past", expiresAt: &past, wantErr: true},
+		{name: "not positive ttl", ttlSeconds: &badTTL, wantErr: true},
+		{name: "both fields", expiresAt: &future, ttlSeconds: &ttl, wantErr: true},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+
+			expiresAt, err := GetExpiresAt(test.expiresAt, test.ttlSeconds)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if test.wantNil {
+				assert.Nil(t, expiresAt)
+				return
+			}
+			require.NotNil(t, expiresAt)
+			assert.True(t, expiresAt.After(time.Now()))
+		})
+	}
+}
+
 func Benchmark_getShortURL(b *testing.B) {
 
 	testLocalHost := "http://localhost"
@@ -299,8 +378,8 @@ func Benchmark_getShortURL(b *testing.B) {
 
 	storage := mocks.NewMockStorager(mockController)
 	storage.EXPECT().GetLastShortKey().Return("000000001").AnyTimes()
-	storage.EXPECT().CreateUser().Return(userID, nil).AnyTimes()
-	storage.EXPECT().FindByLongURL(gomock.Any(), "https://ya.ru").Return(nil, errors.New("")).AnyTimes()
+	storage.EXPECT().CreateUser(gomock.Any()).Return(userID, nil).AnyTimes()
+	storage.EXPECT().FindByLongURL(gomock.Any(), "https://ya.ru").Return(nil, aliasentity.ErrNotFound).AnyTimes()
 	storage.EXPECT().Save(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
 	logger, err := zaplogger.NewZapLogger("")
@@ -315,7 +394,7 @@ func Benchmark_getShortURL(b *testing.B) {
 		request.Header.Add("Content-type", "text/plain")
 
 		recorder := httptest.NewRecorder()
-		h := New(service, service, logger, testLocalHost).getShortURL
+		h := New(service, service, testAuth, logger, testLocalHost).getShortURL
 
 		h(recorder, request.WithContext(context.WithValue(request.Context(), UserID, userID)))
 	}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ------------------------------------------------------------
+//
+//	Handler of plain HTTP listener which redirects requests to the HTTPS server.
+//	Host of the request is kept, the port is changed to the port of HTTPS server.
+//	Requests without host are redirected to the host of base URL
+//	Input:
+//		httpsAddress string - address of HTTPS server, for example: localhost:8443
+//		baseURL string - base URL of the service, for example: https://example.com
+//	Output:
+//		http.Handler
+//		error - if the address has no port or base URL has no host
+//
+//	Handler can returns 2 HTTP statuses:
+//		1. "301 Moved Permanently" for GET and HEAD requests
+//		2. "308 Permanent Redirect" for other requests, clients repeat them with the same method and body
+func NewHTTPSRedirect(httpsAddress, baseURL string) (http.Handler, error) {
+
+	_, port, err := net.SplitHostPort(httpsAddress)
+	if err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	baseHost := base.Hostname()
+	if baseHost == "" {
+		return nil, errors.New("base URL has no host")
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		host := baseHost
+		if r.Host != "" {
+			host = requestHostname(r.Host)
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		statusCode := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			statusCode = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), statusCode)
+	}), nil
+}
+
+// requestHostname returns the host of "Host" header without port and brackets of IPv6 address
+func requestHostname(host string) string {
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NewHTTPSRedirect(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		httpsAddress string
+		method       string
+		target       string
+		host         string //	host overrides the host of target
+		noHost       bool
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			name:         "GET to other port",
+			httpsAddress: "localhost:8443",
+			method:       http.MethodGet,
+			target:       "http://example.com:8080/abc?x=1",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "https://example.com:8443/abc?x=1",
+		},
+		{
+			name:         "default HTTPS port",
+			httpsAddress: ":443",
+			method:       http.MethodHead,
+			target:       "http://example.com/abc",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "https://example.com/abc",
+		},
+		{
+			name:         "POST keeps the method",
+			httpsAddress: "0.0.0.0:8443",
+			method:       http.MethodPost,
+			target:       "http://127.0.0.1:8080/api/shorten",
+			wantStatus:   http.StatusPermanentRedirect,
+			wantLocation: "https://127.0.0.1:8443/api/shorten",
+		},
+		{
+			name:         "IPv6 host without port",
+			httpsAddress: ":8443",
+			method:       http.MethodGet,
+			target:       "/abc",
+			host:         "[::1]",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "https://[::1]:8443/abc",
+		},
+		{
+			name:         "IPv6 host to default HTTPS port",
+			httpsAddress: ":443",
+			method:       http.MethodGet,
+			target:       "/abc",
+			host:         "[::1]:8080",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "https://[::1]/abc",
+		},
+		{
+			name:         "empty host goes to base URL",
+			httpsAddress: ":8443",
+			method:       http.MethodGet,
+			target:       "/abc",
+			noHost:       true,
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "https://short.example:8443/abc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := NewHTTPSRedirect(tt.httpsAddress, "http://short.example:8080")
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.host != "" {
+				request.Host = tt.host
+			}
+			if tt.noHost {
+				request.Host = ""
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tt.wantStatus, recorder.Code)
+			assert.Equal(t, tt.wantLocation, recorder.Header().Get("Location"))
+		})
+	}
+
+	_, err := NewHTTPSRedirect("localhost", "http://short.example")
+	assert.Error(t, err)
+	_, err = NewHTTPSRedirect(":8443", "/relative")
+	assert.Error(t, err)
+}
@@ -1,10 +1,19 @@
 package server
 
-import "github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
+	"github.com/Schalure/urlalias/internal/app/jwtauth"
+)
 
 // Middleware type
 type Middleware struct {
 	userManager UserManager
+	auth        *jwtauth.Manager
+	settings    *atomic.Pointer[RuntimeSettings] //	settings - cookie attributes and compression, they are shared with Server
 	logger      *zaplogger.ZapLogger
 }
 
@@ -12,13 +21,52 @@ type Middleware struct {
 //
 //	Constructor of middleware
 //	Input:
-//		logger *slog.Logger
+//		userManager UserManager
+//		auth *jwtauth.Manager - issues and verifies tokens of users
+//		cookie CookieAttributes - attributes of the token cookie
+//		compression CompressionSettings - settings of response compression
+//		logger *zaplogger.ZapLogger
 //	Output:
 //		*Middleware
-func NewMiddleware(userManager UserManager, logger *zaplogger.ZapLogger) *Middleware {
+func NewMiddleware(userManager UserManager, auth *jwtauth.Manager, cookie CookieAttributes, compression CompressionSettings, logger *zaplogger.ZapLogger) *Middleware {
+
+	return newMiddleware(userManager, auth, newRuntimeSettings(RuntimeSettings{Cookie: cookie, Compression: compression}), logger)
+}
+
+// ------------------------------------------------------------
+//
+//	Constructor of middleware which uses runtime settings of the server
+func newMiddleware(userManager UserManager, auth *jwtauth.Manager, settings *atomic.Pointer[RuntimeSettings], logger *zaplogger.ZapLogger) *Middleware {
 
 	return &Middleware{
 		userManager: userManager,
+		auth:        auth,
+		settings:    settings,
 		logger:      logger,
 	}
 }
+
+// ------------------------------------------------------------
+//
+//	Create the cookie which keeps the token
+func (m *Middleware) tokenCookie(tokenString string) *http.Cookie {
+	return newTokenCookie(tokenString, m.auth.TokenExp(), m.settings.Load().Cookie)
+}
+
+// ------------------------------------------------------------
+//
+//	Create the cookie which keeps the token. The cookie lives as long as the token
+func newTokenCookie(tokenString string, tokenExp time.Duration, attributes CookieAttributes) *http.Cookie {
+
+	return &http.Cookie{
+		Name:     authorization,
+		Value:    tokenString,
+		Path:     "/",
+		Domain:   attributes.Domain,
+		Expires:  time.Now().Add(tokenExp),
+		MaxAge:   int(tokenExp.Seconds()),
+		HttpOnly: attributes.HTTPOnly,
+		Secure:   attributes.Secure,
+		SameSite: attributes.SameSite,
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"net/http"
+	"net/netip"
+)
+
+// Option configures Server
+type Option func(*Server)
+
+// CookieAttributes are attributes of the cookie which keeps the token of the user
+type CookieAttributes struct {
+	HTTPOnly bool          //	HTTPOnly - the cookie is not available to scripts
+	Secure   bool          //	Secure - the cookie is sent over HTTPS only
+	SameSite http.SameSite //	SameSite - the cookie is not sent with cross-site requests
+	Domain   string        //	Domain - the cookie is sent to subdomains of Domain, empty - to the host only
+}
+
+// CookieAttributesDefault are attributes of the token cookie by default
+var CookieAttributesDefault = CookieAttributes{
+	HTTPOnly: true,
+	SameSite: http.SameSiteLaxMode,
+}
+
+// CompressionSettings are settings of response compression
+type CompressionSettings struct {
+	MinSize int                  //	MinSize - responses shorter than MinSize bytes are not compressed
+	Levels  map[CompressType]int //	Levels - compression levels by type, the default level of the type if it is missing
+}
+
+// CompressMinSizeDefault is the size of response content in bytes from which the content is compressed by default
+const CompressMinSizeDefault = 1024
+
+// CompressionDefault are settings of response compression by default
+var CompressionDefault = CompressionSettings{
+	MinSize: CompressMinSizeDefault,
+}
+
+// level returns compression level of the type
+func (c CompressionSettings) level(compressType CompressType) int {
+
+	if level, ok := c.Levels[compressType]; ok {
+		return level
+	}
+	return compressionLevels[compressType].def
+}
+
+// WithAdministrator sets the service of "/api/admin" routes. The routes are served only if it is set
+func WithAdministrator(administrator Administrator) Option {
+	return func(s *Server) {
+		s.administrator = administrator
+	}
+}
+
+// WithReloader sets the reloader of configuration of "/api/admin/config/reload" route. The route is served only if it is set
+func WithReloader(reloader Reloader) Option {
+	return func(s *Server) {
+		s.reloader = reloader
+	}
+}
+
+// WithTrustedProxies sets proxies whose "X-Forwarded-For" header gives the address of the client.
+// Without them the header is ignored, the remote address of the request is used
+func WithTrustedProxies(proxies ...netip.Prefix) Option {
+	return func(s *Server) {
+		s.trustedProxies = proxies
+	}
+}
+
+// WithCompression sets settings of response compression
+func WithCompression(settings CompressionSettings) Option {
+	return func(s *Server) {
+		s.updateSettings(func(rs *RuntimeSettings) {
+			rs.Compression = settings
+		})
+	}
+}
+
+// WithCookieAttributes sets attributes of the cookie which keeps the token of the user
+func WithCookieAttributes(attributes CookieAttributes) Option {
+	return func(s *Server) {
+		s.updateSettings(func(rs *RuntimeSettings) {
+			rs.Cookie = attributes
+		})
+	}
+}
@@ -4,22 +4,29 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+	"github.com/Schalure/urlalias/internal/app/models/userentity"
 )
 
-// New router constructor
-func NewRouter(handler *Server) http.Handler /*chi.Mux*/ {
+// New router constructor. middlewares are used before the others, for example to collect metrics
+func NewRouter(handler *Server, middlewares ...func(http.Handler) http.Handler) http.Handler /*chi.Mux*/ {
 
 	r := chi.NewRouter()
-	m := NewMiddleware(handler.userManager, handler.logger)
+	m := newMiddleware(handler.userManager, handler.auth, handler.settings, handler.logger)
 
+	r.Use(middlewares...)
 	r.Use(m.WithLogging, m.WithCompress)
 
 	r.Get("/{shortkey}", handler.redirect)
 	r.Get("/ping", handler.PingGet)
+	r.Post("/api/auth/token", handler.apiGetToken)
+	r.Post("/api/auth/register", handler.apiRegister)
+	r.Post("/api/auth/login", handler.apiLogin)
 
 	r.Group(func(r chi.Router) {
 
-		r.Use(m.WithAuthentication)
+		r.Use(m.WithAuthentication, m.WithScope(apikeyentity.ScopeShorten))
 		r.Post("/", handler.getShortURL)
 		r.Post("/api/shorten", handler.apiGetShortURL)
 		r.Post("/api/shorten/batch", handler.apiGetBatchShortURL)
@@ -28,9 +35,34 @@ func NewRouter(handler *Server) http.Handler /*chi.Mux*/ {
 	r.Group(func(r chi.Router) {
 
 		r.Use(m.WithVerification)
-		r.Get("/api/user/urls", handler.apiGetUserAliases)
-		r.Delete("/api/user/urls", handler.aipDeleteUserAliases)
+		r.With(m.WithScope(apikeyentity.ScopeRead)).Get("/api/user/urls", handler.apiGetUserAliases)
+		r.With(m.WithScope(apikeyentity.ScopeRead)).Get("/api/user/urls/{shortkey}/stats", handler.apiGetAliasStats)
+		r.With(m.WithScope(apikeyentity.ScopeDelete)).Delete("/api/user/urls", handler.aipDeleteUserAliases)
+
+		r.Group(func(r chi.Router) {
+
+			r.Use(m.WithoutAPIKey)
+			r.Post("/api/user/keys", handler.apiCreateAPIKey)
+			r.Get("/api/user/keys", handler.apiGetAPIKeys)
+			r.Delete("/api/user/keys/{id}", handler.apiRevokeAPIKey)
+		})
 	})
 
+	if handler.administrator != nil {
+		r.Route("/api/admin", func(r chi.Router) {
+
+			r.Use(m.WithVerification, m.WithRole(userentity.RoleAdmin))
+			r.Get("/urls", handler.adminSearchAliases)
+			r.Post("/urls/{shortkey}/disable", handler.adminSetAliasDisabled(true))
+			r.Post("/urls/{shortkey}/enable", handler.adminSetAliasDisabled(false))
+			r.Delete("/urls/{shortkey}", handler.adminPurgeAlias)
+			r.Get("/users/{id}/urls", handler.adminGetUserAliases)
+			r.Get("/stats", handler.adminGetStats)
+			if handler.reloader != nil {
+				r.Post("/config/reload", handler.adminReloadConfig)
+			}
+		})
+	}
+
 	return r
 }
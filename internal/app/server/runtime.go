@@ -0,0 +1,36 @@
+package server
+
+import "sync/atomic"
+
+// RuntimeSettings are settings of the server which can be changed while it is running
+type RuntimeSettings struct {
+	Cookie      CookieAttributes    //	Cookie - attributes of the cookie which keeps the token of the user
+	Compression CompressionSettings //	Compression - settings of response compression
+}
+
+// newRuntimeSettings returns holder of the settings, it is shared by Server and its Middleware
+func newRuntimeSettings(settings RuntimeSettings) *atomic.Pointer[RuntimeSettings] {
+
+	holder := new(atomic.Pointer[RuntimeSettings])
+	holder.Store(&settings)
+	return holder
+}
+
+// RuntimeSettings returns the settings which are used by the server now
+func (h *Server) RuntimeSettings() RuntimeSettings {
+	return *h.settings.Load()
+}
+
+// Reconfigure replaces the runtime settings of the running server and its middlewares at once.
+// Requests which are in progress finish with the settings they have started with
+func (h *Server) Reconfigure(settings RuntimeSettings) {
+	h.settings.Store(&settings)
+}
+
+// updateSettings changes a copy of the runtime settings and replaces them
+func (h *Server) updateSettings(update func(settings *RuntimeSettings)) {
+
+	settings := h.RuntimeSettings()
+	update(&settings)
+	h.Reconfigure(settings)
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+)
+
+// APIKey ContextKey of the API key which authenticated the request
+const APIKey ContextKey = "apiKey"
+
+// ------------------------------------------------------------
+//
+//	Authenticate the request by API key of "X-API-Key" header and call h with the owner of the key.
+//	Requests with a key which is unknown, revoked or expired are rejected, a new user is never created.
+//	The cookie is not set
+func (m *Middleware) serveAPIKey(w http.ResponseWriter, r *http.Request, h http.Handler, apiKey string) {
+
+	key, err := m.userManager.AuthenticateAPIKey(r.Context(), apiKey)
+	if err != nil {
+		m.logger.Infow(
+			"serveAPIKey: key, err := m.userManager.AuthenticateAPIKey(r.Context(), apiKey)",
+			"error", err,
+		)
+		http.Error(w, errors.New("Unauthorized").Error(), http.StatusUnauthorized)
+		return
+	}
+
+	m.logger.Infow(
+		"Request from user",
+		"userID", key.UserID,
+		"API key", key.ID,
+	)
+
+	ctx := context.WithValue(r.Context(), UserID, key.UserID)
+	ctx = context.WithValue(ctx, APIKey, key)
+	h.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// WithScope middleware. Requests authenticated by API key without the scope are forbidden,
+// requests authenticated by token are passed
+func (m *Middleware) WithScope(scope string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			key, ok := r.Context().Value(APIKey).(*apikeyentity.APIKeyModel)
+			if ok && !key.HasScope(scope) {
+				m.logger.Infow(
+					"WithScope: API key has no scope",
+					"API key", key.ID,
+					"scope", scope,
+				)
+				http.Error(w, "API key has no \""+scope+"\" scope", http.StatusForbidden)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WithoutAPIKey middleware. Requests authenticated by API key are forbidden,
+// so a leaked key can't be used to create or revoke other keys
+func (m *Middleware) WithoutAPIKey(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if _, ok := r.Context().Value(APIKey).(*apikeyentity.APIKeyModel); ok {
+			http.Error(w, "API keys are managed with the token of the user", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
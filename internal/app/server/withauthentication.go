@@ -3,11 +3,8 @@ package server
 import (
 	"context"
 	"errors"
-	"fmt"
 	"net/http"
-	"time"
-
-	"github.com/golang-jwt/jwt/v4"
+	"strings"
 )
 
 // ContextKey type
@@ -16,72 +13,45 @@ type ContextKey string
 // UserID ContextKey
 const UserID ContextKey = "userID"
 
-const tokenExp = time.Hour * 3
-const secretKey = "supersecretkey"
-
-// Claims type
-type Claims struct {
-	jwt.RegisteredClaims
-	UserID uint64
-}
+// bearerPrefix is the prefix of token in "Authorization" header
+const bearerPrefix = "Bearer "
 
-// WithAuthentication middleware
+// WithAuthentication middleware. The token is taken from "Authorization: Bearer" header or from the cookie.
+// If the token is missing or the token of the cookie is not valid, a new user is created and its token is returned
+// in the cookie and in "Authorization" response header. Not valid token of the header is rejected,
+// clients which send it expect their own user, not a new one.
+// A request with "X-API-Key" header is authenticated by the API key only
 func (m *Middleware) WithAuthentication(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		var tokenString string
-		var userID uint64
+		if apiKey := r.Header.Get(apiKeyHeader); apiKey != "" {
+			m.serveAPIKey(w, r, h, apiKey)
+			return
+		}
 
-		tokenCookie, err := r.Cookie(authorization)
+		tokenString, bearer := getRequestToken(r)
+		userID, err := m.auth.GetUserID(tokenString)
 		if err != nil {
 			m.logger.Infow(
-				"WithAuthentication: tokenCookie, err := r.Cookie(authorization)",
+				"WithAuthentication: userID, err = m.auth.GetUserID(tokenString)",
 				"error", err,
 			)
-			if userID, err = m.userManager.CreateUser(); err != nil {
-				m.logger.Infow(
-					"WithAuthentication: userID, err = m.service.CreateUser()",
-					"error", err,
-				)
-				http.Error(w, errors.New("internal error").Error(), http.StatusInternalServerError)
+			if bearer {
+				http.Error(w, errors.New("Unauthorized").Error(), http.StatusUnauthorized)
 				return
 			}
-			tokenString, err = createTokenJWT(userID)
-			if err != nil {
-				m.logger.Infow(
-					"WithAuthentication: tokenString, err = createTokenJWT(userID)",
-					"error", err,
-				)
-				http.Error(w, errors.New("internal error").Error(), http.StatusInternalServerError)
-				return
-			}
-
-			m.logger.Infow(
-				"Add new user",
-				"userID", userID,
-			)
-			http.SetCookie(w, &http.Cookie{
-				Name:  authorization,
-				Value: tokenString,
-			})
-
-		} else if userID, err = getUserID(tokenCookie.Value); err != nil {
-			m.logger.Infow(
-				"WithAuthentication: userID, err = getUserID(tokenCookie.Value)",
-				"error", err,
-			)
-			if userID, err = m.userManager.CreateUser(); err != nil {
+			if userID, err = m.userManager.CreateUser(r.Context()); err != nil {
 				m.logger.Infow(
-					"WithAuthentication: userID, err = m.service.CreateUser()",
+					"WithAuthentication: userID, err = m.service.CreateUser(r.Context())",
 					"error", err,
 				)
 				http.Error(w, errors.New("internal error").Error(), http.StatusInternalServerError)
 				return
 			}
-			tokenString, err = createTokenJWT(userID)
+			tokenString, err = m.auth.CreateToken(userID)
 			if err != nil {
 				m.logger.Infow(
-					"WithAuthentication: tokenString, err = createTokenJWT(userID)",
+					"WithAuthentication: tokenString, err = m.auth.CreateToken(userID)",
 					"error", err,
 				)
 				http.Error(w, errors.New("internal error").Error(), http.StatusInternalServerError)
@@ -92,13 +62,11 @@ func (m *Middleware) WithAuthentication(h http.Handler) http.Handler {
 				"Add new user",
 				"userID", userID,
 			)
-			http.SetCookie(w, &http.Cookie{
-				Name:  authorization,
-				Value: tokenString,
-			})
-		} else {
-			authCookie, _ := r.Cookie(authorization)
-			http.SetCookie(w, authCookie)
+			w.Header().Set(authorization, bearerPrefix+tokenString)
+			http.SetCookie(w, m.tokenCookie(tokenString))
+
+		} else if !bearer {
+			http.SetCookie(w, m.tokenCookie(tokenString))
 		}
 
 		m.logger.Infow(
@@ -110,39 +78,21 @@ func (m *Middleware) WithAuthentication(h http.Handler) http.Handler {
 	})
 }
 
-// get user id from JWT token string
-func getUserID(tokenString string) (uint64, error) {
-
-	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+// ------------------------------------------------------------
+//
+//	Get token of the request. "Authorization: Bearer" header has priority over the cookie
+//	Output:
+//		string - token, empty if the request has no token
+//		bool - true if the token is taken from the header
+func getRequestToken(r *http.Request) (string, bool) {
+
+	if header := r.Header.Get(authorization); header != "" {
+		if len(header) > len(bearerPrefix) && strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+			return strings.TrimSpace(header[len(bearerPrefix):]), true
 		}
-		return []byte(secretKey), nil
-	})
-	if err != nil {
-		return 0, errors.New("can't parse token string")
-	}
-	if !token.Valid {
-		return 0, errors.New("token not valid")
 	}
-	return claims.UserID, nil
-}
-
-func createTokenJWT(userID uint64) (string, error) {
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenExp)),
-		},
-		UserID: userID,
-	})
-
-	tokenString, err := token.SignedString([]byte(secretKey))
-	if err != nil {
-		return "", err
+	if tokenCookie, err := r.Cookie(authorization); err == nil {
+		return tokenCookie.Value, false
 	}
-
-	// возвращаем строку токена
-	return tokenString, nil
+	return "", false
 }
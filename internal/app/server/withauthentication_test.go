@@ -0,0 +1,264 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/jwtauth"
+	"github.com/Schalure/urlalias/internal/app/mocks"
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+)
+
+func Test_WithAuthenticationCookie(t *testing.T) {
+
+	userID := uint64(7)
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	userManager := mocks.NewMockUserManager(mockController)
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	attributes := CookieAttributes{
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+		Domain:   "example.com",
+	}
+	m := NewMiddleware(userManager, testAuth, attributes, CompressionDefault, logger)
+
+	var gotUserID interface{}
+	h := m.WithAuthentication(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID = r.Context().Value(UserID)
+	}))
+
+	//	new user gets the token cookie with configured attributes
+	userManager.EXPECT().CreateUser(gomock.Any()).Return(userID, nil)
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/shorten", nil))
+
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+	cookie := cookies[0]
+	assert.Equal(t, authorization, cookie.Name)
+	assert.Equal(t, "/", cookie.Path)
+	assert.Equal(t, "example.com", cookie.Domain)
+	assert.True(t, cookie.HttpOnly)
+	assert.True(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+	assert.Equal(t, int(testAuth.TokenExp().Seconds()), cookie.MaxAge)
+	assert.Equal(t, userID, gotUserID)
+
+	//	token signed by a retired key still identifies the user
+	retiredKey, err := jwtauth.GenerateHMACKey("retired")
+	require.NoError(t, err)
+	retired, err := jwtauth.New([]jwtauth.Key{retiredKey}, retiredKey.ID)
+	require.NoError(t, err)
+	tokenString, err := retired.CreateToken(userID + 1)
+	require.NoError(t, err)
+
+	activeKey, err := jwtauth.GenerateHMACKey("active")
+	require.NoError(t, err)
+	rotated, err := jwtauth.New([]jwtauth.Key{activeKey, retiredKey}, activeKey.ID)
+	require.NoError(t, err)
+	m = NewMiddleware(userManager, rotated, attributes, CompressionDefault, logger)
+	h = m.WithAuthentication(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID = r.Context().Value(UserID)
+	}))
+
+	request := httptest.NewRequest(http.MethodPost, "/api/shorten", nil)
+	request.AddCookie(&http.Cookie{Name: authorization, Value: tokenString})
+	h.ServeHTTP(httptest.NewRecorder(), request)
+	assert.Equal(t, userID+1, gotUserID)
+}
+
+func Test_BearerToken(t *testing.T) {
+
+	userID := uint64(3)
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	userManager := mocks.NewMockUserManager(mockController)
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	m := NewMiddleware(userManager, testAuth, CookieAttributesDefault, CompressionDefault, logger)
+	var gotUserID interface{}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID = r.Context().Value(UserID)
+	})
+
+	//	new user gets the token in the response header too
+	userManager.EXPECT().CreateUser(gomock.Any()).Return(userID, nil)
+	recorder := httptest.NewRecorder()
+	m.WithAuthentication(next).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/shorten", nil))
+
+	header := recorder.Header().Get(authorization)
+	require.True(t, strings.HasPrefix(header, "Bearer "), header)
+	tokenUserID, err := testAuth.GetUserID(strings.TrimPrefix(header, "Bearer "))
+	require.NoError(t, err)
+	assert.Equal(t, userID, tokenUserID)
+
+	//	the token is accepted in the header, the cookie flow is not used
+	for _, h := range []http.Handler{m.WithAuthentication(next), m.WithVerification(next)} {
+		gotUserID = nil
+		request := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+		request.Header.Set(authorization, header)
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, request)
+
+		assert.Equal(t, userID, gotUserID)
+		assert.Empty(t, recorder.Result().Cookies())
+	}
+
+	//	the header has priority over the cookie
+	otherToken, err := testAuth.CreateToken(userID + 1)
+	require.NoError(t, err)
+	request := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+	request.Header.Set(authorization, "bearer "+otherToken)
+	request.AddCookie(&http.Cookie{Name: authorization, Value: strings.TrimPrefix(header, "Bearer ")})
+	m.WithVerification(next).ServeHTTP(httptest.NewRecorder(), request)
+	assert.Equal(t, userID+1, gotUserID)
+
+	//	not valid token is rejected by verification
+	request = httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+	request.Header.Set(authorization, "Bearer not.a.token")
+	recorder = httptest.NewRecorder()
+	m.WithVerification(next).ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func Test_APIKey(t *testing.T) {
+
+	testLocalHost := "http://localhost"
+	userID := uint64(4)
+	apiKey := "ua_readonly"
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	userManager := mocks.NewMockUserManager(mockController)
+	shortner := mocks.NewMockShortner(mockController)
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	testServer := httptest.NewServer(NewRouter(New(userManager, shortner, testAuth, logger, testLocalHost)))
+	defer testServer.Close()
+
+	userManager.EXPECT().AuthenticateAPIKey(gomock.Any(), apiKey).Return(&apikeyentity.APIKeyModel{
+		ID:     1,
+		UserID: userID,
+		Scopes: []string{apikeyentity.ScopeRead},
+	}, nil).AnyTimes()
+	userManager.EXPECT().AuthenticateAPIKey(gomock.Any(), gomock.Not(apiKey)).Return(nil, aliasmaker.ErrAPIKeyNotValid).AnyTimes()
+
+	do := func(method, path, key string) *http.Response {
+		request, err := http.NewRequest(method, testServer.URL+path, strings.NewReader(`[]`))
+		require.NoError(t, err)
+		request.Header.Set(apiKeyHeader, key)
+
+		response, err := testServer.Client().Do(request)
+		require.NoError(t, err)
+		require.NoError(t, response.Body.Close())
+		return response
+	}
+
+	//	the key resolves to its owner, the cookie is not set
+	userManager.EXPECT().GetUserAliases(gomock.Any(), userID).Return(nil, nil)
+	response := do(http.MethodGet, "/api/user/urls", apiKey)
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+	assert.Empty(t, response.Cookies())
+
+	//	scopes of the key are enforced
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/api/shorten", apiKey).StatusCode)
+	assert.Equal(t, http.StatusForbidden, do(http.MethodDelete, "/api/user/urls", apiKey).StatusCode)
+
+	//	keys are not managed by keys
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/api/user/keys", apiKey).StatusCode)
+
+	//	not valid key is rejected, a new user is not created
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/api/shorten", "ua_unknown").StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/user/urls", "ua_unknown").StatusCode)
+}
+
+func Test_BearerTokenNotValid(t *testing.T) {
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	//	no user is created for a not valid bearer token
+	userManager := mocks.NewMockUserManager(mockController)
+	shortner := mocks.NewMockShortner(mockController)
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	secret := []byte("secret of the tests, 32 bytes at least")
+	key, err := jwtauth.NewHMACKey("test", secret)
+	require.NoError(t, err)
+	auth, err := jwtauth.New([]jwtauth.Key{key}, key.ID)
+	require.NoError(t, err)
+	otherKey, err := jwtauth.GenerateHMACKey("test")
+	require.NoError(t, err)
+	forged, err := jwtauth.New([]jwtauth.Key{otherKey}, otherKey.ID)
+	require.NoError(t, err)
+
+	expired := jwt.NewWithClaims(jwt.SigningMethodHS256, jwtauth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))},
+		UserID:           1,
+	})
+	expired.Header["kid"] = key.ID
+	expiredToken, err := expired.SignedString(secret)
+	require.NoError(t, err)
+	forgedToken, err := forged.CreateToken(1)
+	require.NoError(t, err)
+
+	m := NewMiddleware(userManager, auth, CookieAttributesDefault, CompressionDefault, logger)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request with not valid bearer token is served")
+	})
+	testServer := httptest.NewServer(NewRouter(New(userManager, shortner, auth, logger, "http://localhost")))
+	defer testServer.Close()
+
+	for name, tokenString := range map[string]string{"expired": expiredToken, "badly signed": forgedToken, "malformed": "not.a.token"} {
+		t.Run(name, func(t *testing.T) {
+
+			request := httptest.NewRequest(http.MethodPost, "/api/shorten", nil)
+			request.Header.Set(authorization, "Bearer "+tokenString)
+			recorder := httptest.NewRecorder()
+			m.WithAuthentication(next).ServeHTTP(recorder, request)
+			assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+			assert.Empty(t, recorder.Result().Cookies())
+			assert.Empty(t, recorder.Header().Get(authorization))
+
+			tokenRequest, err := http.NewRequest(http.MethodPost, testServer.URL+"/api/auth/token", nil)
+			require.NoError(t, err)
+			tokenRequest.Header.Set(authorization, "Bearer "+tokenString)
+			response, err := testServer.Client().Do(tokenRequest)
+			require.NoError(t, err)
+			response.Body.Close()
+			assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+		})
+	}
+
+	//	not valid token of the cookie still gets a new user
+	userManager.EXPECT().CreateUser(gomock.Any()).Return(uint64(2), nil)
+	var gotUserID interface{}
+	request := httptest.NewRequest(http.MethodPost, "/api/shorten", nil)
+	request.AddCookie(&http.Cookie{Name: authorization, Value: expiredToken})
+	m.WithAuthentication(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID = r.Context().Value(UserID)
+	})).ServeHTTP(httptest.NewRecorder(), request)
+	assert.Equal(t, uint64(2), gotUserID)
+}
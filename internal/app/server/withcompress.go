@@ -1,6 +1,7 @@
 package server
 
 import (
+	"fmt"
 	"io"
 	"net/http"
 	"strconv"
@@ -9,10 +10,17 @@ import (
 
 // ========================================================================================================================
 //
-//	Compress writer type
+//	Compress writer type. It holds the content back until MinSize bytes are written or the handler
+//	has finished, then it decides to compress the content by its status code, type and size
 type compressWriter struct {
 	w          http.ResponseWriter
-	compressor Compressorer
+	encoding   CompressType //	encoding - the coding accepted by the client, TypeNone - the content is not compressed
+	level      int
+	minSize    int
+	statusCode int
+	buf        []byte
+	decided    bool
+	compressor Compressorer //	compressor - nil if the content is written as is
 }
 
 // ------------------------------------------------------------
@@ -20,15 +28,17 @@ type compressWriter struct {
 //	Constructor of compressWriter type
 //	Input:
 //		w http.ResponseWriter
-//		compressType CompressType
+//		encoding CompressType - the coding accepted by the client
+//		settings CompressionSettings
 //	Output:
 //		*compressWriter
-func newCompressWriter(w http.ResponseWriter, compressType CompressType) *compressWriter {
+func newCompressWriter(w http.ResponseWriter, encoding CompressType, settings CompressionSettings) *compressWriter {
 
-	compressor := NewCompressorer(w, compressType)
 	return &compressWriter{
-		w:          w,
-		compressor: compressor,
+		w:        w,
+		encoding: encoding,
+		level:    settings.level(encoding),
+		minSize:  settings.MinSize,
 	}
 }
 
@@ -50,19 +60,79 @@ func (c *compressWriter) Header() http.Header {
 //		n int - count of write bytes
 //		error
 func (c *compressWriter) Write(p []byte) (int, error) {
-	return c.compressor.Write(p)
+
+	if c.decided {
+		if c.compressor != nil {
+			return c.compressor.Write(p)
+		}
+		return c.w.Write(p)
+	}
+
+	if c.statusCode == 0 {
+		c.statusCode = http.StatusOK
+	}
+	c.buf = append(c.buf, p...)
+	if len(c.buf) > 0 && len(c.buf) >= c.minSize {
+		if err := c.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
 }
 
 // ------------------------------------------------------------
 //
-//	WriteHeader method of compressWriter type
+//	WriteHeader method of compressWriter type. The status code is sent with the first part of the content
 //	Input:
 //		statusCode int
 func (c *compressWriter) WriteHeader(statusCode int) {
-	if statusCode < 300 {
-		c.w.Header().Set(contentEncoding, c.compressor.Type())
+
+	if c.decided || c.statusCode != 0 {
+		return
+	}
+	if statusCode < http.StatusOK {
+		c.w.WriteHeader(statusCode)
+		return
+	}
+	c.statusCode = statusCode
+}
+
+// ------------------------------------------------------------
+//
+//	decide method of compressWriter type. It sends the status code and the held content,
+//	compressed if the client accepts compression and the content is worth compressing
+//	Output:
+//		error
+func (c *compressWriter) decide() error {
+
+	c.decided = true
+	header := c.w.Header()
+
+	if header.Get(contentEncoding) == "" && isCompressionPossible(header.Get(contentType)) {
+
+		//	the content might be compressed for other clients, so caches must keep them apart
+		header.Add(vary, acceptEncoding)
+
+		if c.encoding != TypeNone && c.statusCode < 300 && len(c.buf) > 0 && len(c.buf) >= c.minSize {
+			header.Set(contentEncoding, string(c.encoding))
+			header.Del(contentLength)
+			c.compressor = NewCompressorer(c.w, c.encoding, c.level)
+		}
+	}
+
+	c.w.WriteHeader(c.statusCode)
+
+	buf := c.buf
+	c.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if c.compressor != nil {
+		_, err := c.compressor.Write(buf)
+		return err
 	}
-	c.w.WriteHeader(statusCode)
+	_, err := c.w.Write(buf)
+	return err
 }
 
 // ------------------------------------------------------------
@@ -72,7 +142,20 @@ func (c *compressWriter) WriteHeader(statusCode int) {
 //		Output:
 //			error
 func (c *compressWriter) Close() error {
-	return c.compressor.Close()
+
+	if !c.decided {
+		//	the handler has written nothing, the response is up to http.Server
+		if c.statusCode == 0 {
+			return nil
+		}
+		if err := c.decide(); err != nil {
+			return err
+		}
+	}
+	if c.compressor != nil {
+		return c.compressor.Close()
+	}
+	return nil
 }
 
 // ========================================================================================================================
@@ -131,7 +214,8 @@ func (c *compressReader) Close() error {
 // ------------------------------------------------------------
 //
 //	WithCompress middleware - method of Middleware type
-//	Compress and decompress data
+//	Compress and decompress data. A request body which can't be decoded by its "Content-Encoding"
+//	gets StatusBadRequest (400), here or from the handler which reads it
 //	Receiver:
 //		m* Middleware
 //	Input:
@@ -141,30 +225,15 @@ func (c *compressReader) Close() error {
 func (m *Middleware) WithCompress(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		origWriter := w
+		encodingWriter := newCompressWriter(w, getEncodingMethod(r), m.settings.Load().Compression)
+		defer encodingWriter.Close()
 
-		if isCompressionPossible(r.Header.Get(contentType)) {
-			encodingMethod := getEncodingMethod(r)
-			if encodingMethod != TypeNone {
-
-				encodingWriter := newCompressWriter(w, encodingMethod)
-				origWriter = encodingWriter
-				defer encodingWriter.Close()
-			}
-		}
-
-		contentEncodingType := r.Header.Get(contentEncoding)
-		if contentEncodingType != "" && func() bool {
-			for _, enc := range PossibleCompressionTypes {
-				if CompressType(contentEncodingType) == enc {
-					return true
-				}
-			}
-			return false
-		}() {
-			cr, err := newCompressReader(r.Body, CompressType(contentEncodingType))
+		contentEncodingType := CompressType(strings.ToLower(strings.TrimSpace(r.Header.Get(contentEncoding))))
+		if contentEncodingType != TypeNone && isCompressionTypePossible(contentEncodingType) {
+			cr, err := newCompressReader(r.Body, contentEncodingType)
 			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
+				//	the body does not match its "Content-Encoding", it is the fault of the client
+				http.Error(w, fmt.Sprintf("can't decode %s content: %s", contentEncodingType, err), http.StatusBadRequest)
 				return
 			}
 			// меняем тело запроса на новое
@@ -172,7 +241,7 @@ func (m *Middleware) WithCompress(h http.Handler) http.Handler {
 			defer cr.Close()
 		}
 
-		h.ServeHTTP(origWriter, r)
+		h.ServeHTTP(encodingWriter, r)
 	})
 }
 
@@ -188,42 +257,103 @@ func isCompressionPossible(contentType string) bool {
 }
 
 // ------------------------------------------------------------
-func getEncodingMethod(r *http.Request) CompressType {
+func isCompressionTypePossible(compressType CompressType) bool {
 
-	var (
-		methodType string
-		q          float64
-		err        error
-		qMax       = -1.0
-	)
+	for _, t := range PossibleCompressionTypes {
+		if compressType == t {
+			return true
+		}
+	}
+	return false
+}
 
-	acceptEncodingList := r.Header.Values(acceptEncoding)
+// ------------------------------------------------------------
+//
+//	Choose content coding of the response by "Accept-Encoding" header (RFC 9110, section 12.5.3).
+//	The coding with the highest weight is chosen, equal weights are resolved by PossibleCompressionTypes order.
+//	"*" sets the weight of codings which are not listed, weight 0 means "not acceptable".
+//	The content is not compressed if the header is missing or "identity" has a higher weight than any coding
+//	Input:
+//		r *http.Request
+//	Output:
+//		CompressType - TypeNone if the content must not be compressed
+func getEncodingMethod(r *http.Request) CompressType {
 
+	acceptEncodingList := r.Header.Values(acceptEncoding)
 	if len(acceptEncodingList) == 0 {
 		return TypeNone
 	}
 
-	for _, method := range acceptEncodingList {
+	weights := parseAcceptEncoding(acceptEncodingList)
+	weight := func(coding string) float64 {
+		if q, ok := weights[coding]; ok {
+			return q
+		}
+		return weights["*"]
+	}
 
-		s := strings.Split(method, ";")
+	methodType, qMax := TypeNone, 0.0
+	for _, t := range PossibleCompressionTypes {
+		if q := weight(string(t)); q > qMax {
+			methodType, qMax = t, q
+		}
+	}
 
-		if len(s) == 2 {
-			q, err = strconv.ParseFloat(strings.TrimPrefix(s[1], "q="), 32)
-			if err != nil {
-				q = 0
+	if weight("identity") > qMax {
+		return TypeNone
+	}
+	return methodType
+}
+
+// ------------------------------------------------------------
+//
+//	Parse values of "Accept-Encoding" header to weights of codings.
+//	Codings are case-insensitive, elements with invalid weight are skipped
+//	Input:
+//		values []string - for example: "gzip, br;q=0.8", "identity;q=0"
+//	Output:
+//		map[string]float64 - weights of codings from 0 to 1
+func parseAcceptEncoding(values []string) map[string]float64 {
+
+	weights := make(map[string]float64)
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+
+			params := strings.Split(element, ";")
+			coding := strings.ToLower(strings.TrimSpace(params[0]))
+			if coding == "" {
+				continue
 			}
-		}
 
-		if q > qMax && func() bool {
-			for _, t := range PossibleCompressionTypes {
-				if CompressType(s[0]) == t {
-					return true
+			q, ok := 1.0, true
+			for _, param := range params[1:] {
+				name, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.EqualFold(strings.TrimSpace(name), "q") {
+					q, ok = parseQValue(strings.TrimSpace(v))
 				}
 			}
-			return false
-		}() {
-			methodType = s[0]
+			if !ok {
+				continue
+			}
+			if prev, found := weights[coding]; !found || q > prev {
+				weights[coding] = q
+			}
 		}
 	}
-	return CompressType(methodType)
+	return weights
+}
+
+// ------------------------------------------------------------
+//
+//	Parse weight of "Accept-Encoding" element: "0" to "1" with up to three digits after the point
+func parseQValue(s string) (float64, bool) {
+
+	if s == "" || len(s) > 5 || (s[0] != '0' && s[0] != '1') {
+		return 0, false
+	}
+	q, err := strconv.ParseFloat(s, 64)
+	if err != nil || q < 0 || q > 1 {
+		return 0, false
+	}
+	return q, true
 }
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Schalure/urlalias/internal/app/aliaslogger/zaplogger"
+)
+
+func Test_getEncodingMethod(t *testing.T) {
+
+	testCases := []struct {
+		name           string
+		acceptEncoding []string
+		want           CompressType
+	}{
+		{name: "no header", acceptEncoding: nil, want: TypeNone},
+		{name: "empty header", acceptEncoding: []string{""}, want: TypeNone},
+		{name: "single coding", acceptEncoding: []string{"gzip"}, want: TypeGZIP},
+		{name: "list in one header", acceptEncoding: []string{"gzip, deflate, br"}, want: TypeBrotli},
+		{name: "several headers", acceptEncoding: []string{"gzip", "zstd"}, want: TypeZSTD},
+		{name: "weights", acceptEncoding: []string{"br;q=0.5, gzip;q=0.8, zstd;q=0.1"}, want: TypeGZIP},
+		{name: "weight with spaces and case", acceptEncoding: []string{"GZIP ; Q=0.9, br ;q=0.2"}, want: TypeGZIP},
+		{name: "not acceptable", acceptEncoding: []string{"br;q=0, gzip"}, want: TypeGZIP},
+		{name: "nothing acceptable", acceptEncoding: []string{"gzip;q=0, br;q=0"}, want: TypeNone},
+		{name: "unknown codings", acceptEncoding: []string{"compress, exi"}, want: TypeNone},
+		{name: "wildcard", acceptEncoding: []string{"*"}, want: TypeBrotli},
+		{name: "wildcard excludes others", acceptEncoding: []string{"gzip;q=0.5, *;q=0"}, want: TypeGZIP},
+		{name: "identity is preferred", acceptEncoding: []string{"gzip;q=0.5, identity"}, want: TypeNone},
+		{name: "identity is excluded", acceptEncoding: []string{"identity;q=0, deflate"}, want: TypeZLIB},
+		{name: "invalid weight is skipped", acceptEncoding: []string{"br;q=high, gzip;q=1.5, deflate;q=0.3"}, want: TypeZLIB},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, v := range test.acceptEncoding {
+				r.Header.Add(acceptEncoding, v)
+			}
+			assert.Equal(t, test.want, getEncodingMethod(r))
+		})
+	}
+}
+
+func Test_WithCompress(t *testing.T) {
+
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	settings := CompressionSettings{MinSize: 100, Levels: map[CompressType]int{TypeGZIP: 1}}
+	m := NewMiddleware(nil, testAuth, CookieAttributesDefault, settings, logger)
+
+	long := strings.Repeat(`{"result": "http://localhost/abc"}`, 10)
+	short := `{"result": "http://localhost/abc"}`
+
+	handler := m.WithCompress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			w.Header().Set("Location", "https://example.com")
+			w.WriteHeader(http.StatusTemporaryRedirect)
+			return
+		case "/html":
+			w.Header().Set(contentType, "text/html")
+		default:
+			w.Header().Set(contentType, appJSON)
+		}
+		w.WriteHeader(http.StatusCreated)
+		body := long
+		if r.URL.Path == "/short" {
+			body = short
+		}
+		//	the content is written in parts to check it is held until the decision
+		w.Write([]byte(body[:10]))
+		w.Write([]byte(body[10:]))
+	}))
+
+	do := func(path, accept string) *http.Response {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			r.Header.Set(acceptEncoding, accept)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	readAll := func(r io.Reader) string {
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	response := do("/long", "gzip, br")
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	assert.Equal(t, "br", response.Header.Get(contentEncoding))
+	assert.Equal(t, acceptEncoding, response.Header.Get(vary))
+	assert.Equal(t, long, readAll(brotli.NewReader(response.Body)))
+
+	response = do("/long", "gzip")
+	defer response.Body.Close()
+	assert.Equal(t, "gzip", response.Header.Get(contentEncoding))
+	zr, err := gzip.NewReader(response.Body)
+	require.NoError(t, err)
+	assert.Equal(t, long, readAll(zr))
+
+	//	short content, content types which are not compressed and clients without compression
+	response = do("/short", "gzip")
+	defer response.Body.Close()
+	assert.Empty(t, response.Header.Get(contentEncoding))
+	assert.Equal(t, acceptEncoding, response.Header.Get(vary))
+	assert.Equal(t, short, readAll(response.Body))
+
+	response = do("/long", "")
+	defer response.Body.Close()
+	assert.Empty(t, response.Header.Get(contentEncoding))
+	assert.Equal(t, acceptEncoding, response.Header.Get(vary))
+	assert.Equal(t, long, readAll(response.Body))
+
+	response = do("/html", "gzip")
+	defer response.Body.Close()
+	assert.Empty(t, response.Header.Get(contentEncoding))
+	assert.Empty(t, response.Header.Get(vary))
+	assert.Equal(t, long, readAll(response.Body))
+
+	response = do("/redirect", "gzip")
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusTemporaryRedirect, response.StatusCode)
+	assert.Empty(t, response.Header.Get(contentEncoding))
+}
+
+func Test_WithCompressCorruptBody(t *testing.T) {
+
+	logger, err := zaplogger.NewZapLogger("")
+	require.NoError(t, err)
+
+	m := NewMiddleware(nil, testAuth, CookieAttributesDefault, CompressionSettings{}, logger)
+
+	//	the handler reads the body like getShortURL
+	handler := m.WithCompress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	var gzipBody bytes.Buffer
+	zw := gzip.NewWriter(&gzipBody)
+	_, err = zw.Write([]byte(strings.Repeat("https://example.com/", 20)))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	tornGzip := gzipBody.Bytes()[:gzipBody.Len()/2]
+
+	testCases := []struct {
+		name     string
+		encoding CompressType
+		body     []byte
+		want     int
+	}{
+		{name: "valid gzip", encoding: TypeGZIP, body: gzipBody.Bytes(), want: http.StatusCreated},
+		{name: "not gzip", encoding: TypeGZIP, body: []byte("https://example.com/"), want: http.StatusBadRequest},
+		{name: "torn gzip", encoding: TypeGZIP, body: tornGzip, want: http.StatusBadRequest},
+		{name: "not zstd", encoding: TypeZSTD, body: []byte("https://example.com/"), want: http.StatusBadRequest},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(test.body))
+			r.Header.Set(contentEncoding, string(test.encoding))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			response := w.Result()
+			defer response.Body.Close()
+			assert.Equal(t, test.want, response.StatusCode)
+		})
+	}
+}
@@ -3,6 +3,8 @@ package server
 import (
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type (
@@ -73,7 +75,11 @@ func (m *Middleware) WithLogging(h http.Handler) http.Handler {
 			responseData:   responseData,
 		}
 
+		//	the trace ID joins log records of the request with its spans
+		traceID := trace.SpanContextFromContext(r.Context()).TraceID()
+
 		m.logger.Infow("Information about request",
+			"Trace ID", traceID,
 			"Request URI", r.RequestURI,
 			"Request method", r.Method,
 			"Request headers", r.Header,
@@ -86,6 +92,7 @@ func (m *Middleware) WithLogging(h http.Handler) http.Handler {
 
 		m.logger.Infow(
 			"Information about response",
+			"Trace ID", traceID,
 			"Response status", responseData.status,
 			"Response headers", lw.ResponseWriter.Header(),
 			"Response cookie", r.Cookies(),
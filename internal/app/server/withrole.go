@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+)
+
+// WithRole middleware. Only users with the role pass, the others are forbidden.
+// The user is read on every request, so the role follows the current configuration.
+// It is used after WithVerification. Requests authenticated by API key are forbidden,
+// so a leaked key can't be used to act on behalf of the user with the role
+func (m *Middleware) WithRole(role string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			if _, ok := r.Context().Value(APIKey).(*apikeyentity.APIKeyModel); ok {
+				http.Error(w, "the role is checked with the token of the user", http.StatusForbidden)
+				return
+			}
+
+			userID, ok := r.Context().Value(UserID).(uint64)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			user, err := m.userManager.GetUser(r.Context(), userID)
+			if err != nil || user.Role != role {
+				m.logger.Infow(
+					"WithRole: user has no role",
+					"userID", userID,
+					"role", role,
+				)
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
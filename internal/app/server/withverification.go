@@ -6,34 +6,41 @@ import (
 	"net/http"
 )
 
-// WithVerification middleware
+// WithVerification middleware. The token is taken from "Authorization: Bearer" header or from the cookie.
+// A request with "X-API-Key" header is authenticated by the API key only
 func (m *Middleware) WithVerification(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		tokenCookie, err := r.Cookie(authorization)
-		if err != nil {
+		if apiKey := r.Header.Get(apiKeyHeader); apiKey != "" {
+			m.serveAPIKey(w, r, h, apiKey)
+			return
+		}
+
+		tokenString, bearer := getRequestToken(r)
+		if tokenString == "" {
 			m.logger.Infow(
-				"WithVerification: tokenCookie, err := r.Cookie(authorization)",
-				"error", err,
+				"WithVerification: tokenString, bearer := getRequestToken(r)",
+				"error", "token not found",
 			)
 			http.Error(w, errors.New("Unauthorized").Error(), http.StatusUnauthorized)
 			return
 		}
 
-		userID, err := getUserID(tokenCookie.Value)
+		userID, err := m.auth.GetUserID(tokenString)
 		if err != nil {
 			m.logger.Infow(
-				"WithVerification: userID, err = getUserID(tokenCookie.Value)",
+				"WithVerification: userID, err = m.auth.GetUserID(tokenString)",
 				"error", err,
 				"user", userID,
-				"token", tokenCookie.Value,
+				"token", tokenString,
 			)
 			http.Error(w, errors.New("Unauthorized").Error(), http.StatusUnauthorized)
 			return
 		}
 
-		authCookie, _ := r.Cookie(authorization)
-		http.SetCookie(w, authCookie)
+		if !bearer {
+			http.SetCookie(w, m.tokenCookie(tokenString))
+		}
 
 		m.logger.Infow(
 			"Request from user",
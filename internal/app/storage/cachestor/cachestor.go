@@ -0,0 +1,404 @@
+// Package cachestor describes a read-through cache of redirect lookups.
+//
+// Type "Storage" wraps any "aliasmaker.Storager" and keeps the results of
+// "FindByShortKey" in an LRU list limited by size and TTL. Short keys which
+// are not found are remembered too, so scanning of keys does not reach the
+// backend. Cached aliases are dropped when they are saved, marked as deleted,
+// marked as expired, disabled or purged through the cache, and lookups which
+// were reading the backend at that moment do not cache the old alias.
+// Other instances sharing the backend do not invalidate the cache, their
+// changes are seen when entries expire.
+package cachestor
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/models/clickentity"
+)
+
+// Default limits of the cache
+const (
+	TTLDefault         = time.Minute
+	NegativeTTLDefault = 10 * time.Second
+)
+
+// Stats is a snapshot of cache counters
+type Stats struct {
+	Hits         uint64 //	lookups answered with a cached alias
+	NegativeHits uint64 //	lookups answered with a cached miss
+	Misses       uint64 //	lookups passed to the backend
+	Len          int    //	count of cached entries
+}
+
+// entry is a cached lookup. node is nil for a short key which is not found
+type entry struct {
+	shortKey  string
+	node      *aliasentity.AliasURLModel
+	expiresAt time.Time
+}
+
+// fill is a backend lookup of a short key in progress. generation is bumped when the key is invalidated,
+// so a lookup which started before the change does not cache the old alias
+type fill struct {
+	generation uint64
+	readers    int
+}
+
+// Storage is a cache of redirect lookups over a backend storage.
+// Methods which are not overridden go directly to the backend.
+// Storage is safe for concurrent use
+type Storage struct {
+	aliasmaker.Storager
+
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+
+	mx      sync.Mutex
+	order   *list.List               //	entries from the most to the least recently used
+	entries map[string]*list.Element //	[key, value] = [ShortKey, element of order]
+	byID    map[uint64]string        //	[key, value] = [alias ID, ShortKey] of cached aliases
+	fills   map[string]*fill         //	[key, value] = [ShortKey, backend lookup in progress]
+
+	hits         atomic.Uint64
+	negativeHits atomic.Uint64
+	misses       atomic.Uint64
+}
+
+// ------------------------------------------------------------
+//
+//	Storage constructor
+//	Input:
+//		backend - storage the cache reads from
+//		size - maximum count of cached entries
+func NewStorage(backend aliasmaker.Storager, size int, opts ...Option) (*Storage, error) {
+
+	if size <= 0 {
+		return nil, fmt.Errorf("cache size must be positive")
+	}
+
+	s := &Storage{
+		Storager:    backend,
+		size:        size,
+		ttl:         TTLDefault,
+		negativeTTL: NegativeTTLDefault,
+		now:         time.Now,
+		order:       list.New(),
+		entries:     make(map[string]*list.Element, size),
+		byID:        make(map[uint64]string, size),
+		fills:       make(map[string]*fill),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Find "urlAliasNode models.AliasURLModel" by short key.
+//	The cached alias is returned if it is fresh, otherwise the backend is asked.
+//	Misses are remembered unless the context is done.
+//	The result is not cached if the short key was changed through the cache during the lookup
+func (s *Storage) FindByShortKey(ctx context.Context, shortKey string) (*aliasentity.AliasURLModel, error) {
+
+	if node, found, ok := s.get(shortKey); ok {
+		if !found {
+			s.negativeHits.Add(1)
+			return nil, aliasentity.ErrNotFound
+		}
+		s.hits.Add(1)
+		return node, nil
+	}
+	s.misses.Add(1)
+
+	generation := s.startFill(shortKey)
+	node, err := s.Storager.FindByShortKey(ctx, shortKey)
+	if err != nil {
+		//	only a real miss is remembered, a timeout or an outage says nothing about the key
+		if errors.Is(err, aliasentity.ErrNotFound) && ctx.Err() == nil && s.negativeTTL > 0 {
+			s.put(shortKey, nil, s.negativeTTL, generation)
+		} else {
+			s.cancelFill(shortKey)
+		}
+		return nil, err
+	}
+
+	s.put(shortKey, node, s.ttl, generation)
+	return node, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Save pair "shortKey, longURL" to db.
+//	A remembered miss of the short key is dropped
+func (s *Storage) Save(ctx context.Context, urlAliasNode *aliasentity.AliasURLModel) error {
+
+	err := s.Storager.Save(ctx, urlAliasNode)
+	s.invalidate(urlAliasNode.ShortKey)
+	return err
+}
+
+// ------------------------------------------------------------
+//
+//	Save array of pairs "shortKey, longURL" to db.
+//	Remembered misses of the short keys are dropped
+func (s *Storage) SaveAll(ctx context.Context, urlAliasNodes []aliasentity.AliasURLModel) error {
+
+	err := s.Storager.SaveAll(ctx, urlAliasNodes)
+	for _, node := range urlAliasNodes {
+		s.invalidate(node.ShortKey)
+	}
+	return err
+}
+
+// ------------------------------------------------------------
+//
+//	Mark aliases like "deleted" by aliasesID.
+//	The aliases are dropped from the cache
+func (s *Storage) MarkDeleted(ctx context.Context, aliasesID []uint64) error {
+
+	err := s.Storager.MarkDeleted(ctx, aliasesID)
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for _, id := range aliasesID {
+		if shortKey, ok := s.byID[id]; ok {
+			s.remove(s.entries[shortKey])
+		}
+	}
+	//	short keys of aliases which are not cached are unknown, so no lookup in progress is cached
+	s.staleFills()
+	return err
+}
+
+// ------------------------------------------------------------
+//
+//	Mark aliases like "expired" if their expiry time has passed.
+//	Cached aliases which have expired are dropped
+func (s *Storage) MarkExpired(ctx context.Context, now time.Time) (int, error) {
+
+	count, err := s.Storager.MarkExpired(ctx, now)
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for element := s.order.Front(); element != nil; {
+		next := element.Next()
+		if node := element.Value.(*entry).node; node != nil && node.ExpiresAt != nil && !now.Before(*node.ExpiresAt) {
+			s.remove(element)
+		}
+		element = next
+	}
+	s.staleFills()
+	return count, err
+}
+
+// ------------------------------------------------------------
+//
+//	Disable or re-enable alias by short key.
+//	The alias is dropped from the cache
+func (s *Storage) SetDisabled(ctx context.Context, shortKey string, disabled bool) (bool, error) {
+
+	ok, err := s.Storager.SetDisabled(ctx, shortKey, disabled)
+	s.invalidate(shortKey)
+	return ok, err
+}
+
+// ------------------------------------------------------------
+//
+//	Remove alias by short key.
+//	The alias is dropped from the cache
+func (s *Storage) PurgeAlias(ctx context.Context, shortKey string) (bool, error) {
+
+	ok, err := s.Storager.PurgeAlias(ctx, shortKey)
+	s.invalidate(shortKey)
+	return ok, err
+}
+
+// ------------------------------------------------------------
+//
+//	Save batch of redirects to the backend
+//	This is interfase method of "AnalyticsSink" interface
+func (s *Storage) SaveClicks(ctx context.Context, clicks []clickentity.ClickModel) error {
+
+	sink, ok := s.Storager.(aliasmaker.AnalyticsSink)
+	if !ok {
+		return errors.New("storage does not keep redirect statistics")
+	}
+	return sink.SaveClicks(ctx, clicks)
+}
+
+// ------------------------------------------------------------
+//
+//	Get statistics of redirects by short key from the backend
+//	This is interfase method of "AnalyticsSink" interface
+func (s *Storage) GetClickStats(ctx context.Context, shortKey string) (*clickentity.StatsModel, error) {
+
+	sink, ok := s.Storager.(aliasmaker.AnalyticsSink)
+	if !ok {
+		return nil, errors.New("storage does not keep redirect statistics")
+	}
+	return sink.GetClickStats(ctx, shortKey)
+}
+
+// ------------------------------------------------------------
+//
+//	Backend storage, so optional methods of the backend can be found
+func (s *Storage) Unwrap() aliasmaker.Storager {
+	return s.Storager
+}
+
+// ------------------------------------------------------------
+//
+//	Snapshot of cache counters
+func (s *Storage) Stats() Stats {
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return Stats{
+		Hits:         s.hits.Load(),
+		NegativeHits: s.negativeHits.Load(),
+		Misses:       s.misses.Load(),
+		Len:          s.order.Len(),
+	}
+}
+
+// get returns a copy of the fresh cached alias. found is false for a cached miss, ok is false if nothing is cached
+func (s *Storage) get(shortKey string) (node *aliasentity.AliasURLModel, found, ok bool) {
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	element, ok := s.entries[shortKey]
+	if !ok {
+		return nil, false, false
+	}
+
+	cached := element.Value.(*entry)
+	if !s.now().Before(cached.expiresAt) {
+		s.remove(element)
+		return nil, false, false
+	}
+
+	s.order.MoveToFront(element)
+	if cached.node == nil {
+		return nil, false, true
+	}
+	copied := *cached.node
+	return &copied, true, true
+}
+
+// put caches the lookup result, the least recently used entry is dropped if the cache is full.
+// Nothing is cached if the short key was invalidated after the lookup had started
+func (s *Storage) put(shortKey string, node *aliasentity.AliasURLModel, ttl time.Duration, generation uint64) {
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.finishFill(shortKey) != generation {
+		return
+	}
+
+	if element, ok := s.entries[shortKey]; ok {
+		s.remove(element)
+	}
+
+	cached := &entry{
+		shortKey:  shortKey,
+		expiresAt: s.now().Add(ttl),
+	}
+	if node != nil {
+		copied := *node
+		cached.node = &copied
+		s.byID[node.ID] = shortKey
+	}
+	s.entries[shortKey] = s.order.PushFront(cached)
+
+	for s.order.Len() > s.size {
+		s.remove(s.order.Back())
+	}
+}
+
+// invalidate drops the cached entry of the short key, lookups of the key in progress are not cached
+func (s *Storage) invalidate(shortKey string) {
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if element, ok := s.entries[shortKey]; ok {
+		s.remove(element)
+	}
+	if f, ok := s.fills[shortKey]; ok {
+		f.generation++
+	}
+}
+
+// startFill registers a backend lookup of the short key and returns the generation of the key
+func (s *Storage) startFill(shortKey string) uint64 {
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	f, ok := s.fills[shortKey]
+	if !ok {
+		f = &fill{}
+		s.fills[shortKey] = f
+	}
+	f.readers++
+	return f.generation
+}
+
+// cancelFill ends the backend lookup of the short key whose result is not cached
+func (s *Storage) cancelFill(shortKey string) {
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.finishFill(shortKey)
+}
+
+// finishFill ends the backend lookup of the short key and returns the current generation of the key.
+// The caller must hold s.mx
+func (s *Storage) finishFill(shortKey string) uint64 {
+
+	f := s.fills[shortKey]
+	f.readers--
+	if f.readers == 0 {
+		delete(s.fills, shortKey)
+	}
+	return f.generation
+}
+
+// staleFills bumps generations of all lookups in progress. The caller must hold s.mx
+func (s *Storage) staleFills() {
+
+	for _, f := range s.fills {
+		f.generation++
+	}
+}
+
+// remove drops the element from the cache. The caller must hold s.mx
+func (s *Storage) remove(element *list.Element) {
+
+	if element == nil {
+		return
+	}
+
+	cached := s.order.Remove(element).(*entry)
+	delete(s.entries, cached.shortKey)
+	if cached.node != nil && s.byID[cached.node.ID] == cached.shortKey {
+		delete(s.byID, cached.node.ID)
+	}
+}
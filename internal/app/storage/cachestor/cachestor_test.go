@@ -0,0 +1,257 @@
+package cachestor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/storage/memstor"
+	"github.com/Schalure/urlalias/internal/app/storage/storagetest"
+)
+
+func TestStorager(t *testing.T) {
+
+	storagetest.Run(t, func(t *testing.T) aliasmaker.Storager {
+		backend, err := memstor.NewStorage()
+		require.NoError(t, err)
+
+		stor, err := NewStorage(backend, 100)
+		require.NoError(t, err)
+		return stor
+	})
+}
+
+// newTestStorage returns the cache over a memory storage and a clock the cache uses
+func newTestStorage(t *testing.T, size int) (*Storage, *memstor.Storage, *time.Time) {
+
+	backend, err := memstor.NewStorage()
+	require.NoError(t, err)
+
+	stor, err := NewStorage(backend, size, WithTTL(time.Minute), WithNegativeTTL(10*time.Second))
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	stor.now = func() time.Time { return now }
+	return stor, backend, &now
+}
+
+func TestStorage_FindByShortKey(t *testing.T) {
+
+	ctx := context.Background()
+	stor, backend, now := newTestStorage(t, 10)
+
+	require.NoError(t, stor.Save(ctx, &aliasentity.AliasURLModel{ShortKey: "000000001", LongURL: "https://qqq.ru/1"}))
+
+	//	the first lookup reads the backend, the next ones are served from the cache
+	for i := 0; i < 3; i++ {
+		node, err := stor.FindByShortKey(ctx, "000000001")
+		require.NoError(t, err)
+		assert.Equal(t, "https://qqq.ru/1", node.LongURL)
+	}
+	assert.Equal(t, Stats{Hits: 2, Misses: 1, Len: 1}, stor.Stats())
+
+	//	callers can't change cached aliases
+	node, err := stor.FindByShortKey(ctx, "000000001")
+	require.NoError(t, err)
+	node.LongURL = "https://changed.ru"
+	node, err = stor.FindByShortKey(ctx, "000000001")
+	require.NoError(t, err)
+	assert.Equal(t, "https://qqq.ru/1", node.LongURL)
+
+	//	the backend is read again when the entry has expired
+	require.NoError(t, backend.MarkDeleted(ctx, []uint64{node.ID}))
+	*now = now.Add(time.Minute)
+	node, err = stor.FindByShortKey(ctx, "000000001")
+	require.NoError(t, err)
+	assert.True(t, node.DeletedFlag)
+	assert.Equal(t, uint64(2), stor.Stats().Misses)
+}
+
+func TestStorage_NegativeCache(t *testing.T) {
+
+	ctx := context.Background()
+	stor, _, now := newTestStorage(t, 10)
+
+	for i := 0; i < 3; i++ {
+		_, err := stor.FindByShortKey(ctx, "unknown")
+		assert.Error(t, err)
+	}
+	assert.Equal(t, Stats{NegativeHits: 2, Misses: 1, Len: 1}, stor.Stats())
+
+	//	misses are remembered for a shorter time
+	*now = now.Add(10 * time.Second)
+	_, err := stor.FindByShortKey(ctx, "unknown")
+	assert.Error(t, err)
+	assert.Equal(t, uint64(2), stor.Stats().Misses)
+
+	//	a saved alias replaces the remembered miss
+	require.NoError(t, stor.Save(ctx, &aliasentity.AliasURLModel{ShortKey: "unknown", LongURL: "https://qqq.ru"}))
+	node, err := stor.FindByShortKey(ctx, "unknown")
+	require.NoError(t, err)
+	assert.Equal(t, "https://qqq.ru", node.LongURL)
+
+	//	misses of a done context are not remembered
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = stor.FindByShortKey(cancelled, "timeout")
+	assert.Error(t, err)
+	_, ok := stor.entries["timeout"]
+	assert.False(t, ok)
+}
+
+func TestStorage_Eviction(t *testing.T) {
+
+	ctx := context.Background()
+	stor, _, _ := newTestStorage(t, 2)
+
+	require.NoError(t, stor.SaveAll(ctx, []aliasentity.AliasURLModel{
+		{ShortKey: "000000001", LongURL: "https://qqq.ru/1"},
+		{ShortKey: "000000002", LongURL: "https://qqq.ru/2"},
+		{ShortKey: "000000003", LongURL: "https://qqq.ru/3"},
+	}))
+
+	for _, key := range []string{"000000001", "000000002", "000000001", "000000003"} {
+		_, err := stor.FindByShortKey(ctx, key)
+		require.NoError(t, err)
+	}
+
+	//	"000000002" is the least recently used
+	assert.Equal(t, 2, stor.Stats().Len)
+	assert.Contains(t, stor.entries, "000000001")
+	assert.Contains(t, stor.entries, "000000003")
+	assert.NotContains(t, stor.entries, "000000002")
+	assert.Len(t, stor.byID, 2)
+}
+
+func TestStorage_Invalidation(t *testing.T) {
+
+	ctx := context.Background()
+	stor, _, now := newTestStorage(t, 10)
+
+	expiresAt := now.Add(time.Second)
+	require.NoError(t, stor.SaveAll(ctx, []aliasentity.AliasURLModel{
+		{ShortKey: "000000001", LongURL: "https://qqq.ru/1"},
+		{ShortKey: "000000002", LongURL: "https://qqq.ru/2", ExpiresAt: &expiresAt},
+	}))
+
+	deleted, err := stor.FindByShortKey(ctx, "000000001")
+	require.NoError(t, err)
+	_, err = stor.FindByShortKey(ctx, "000000002")
+	require.NoError(t, err)
+
+	//	deleted alias is read from the backend again
+	require.NoError(t, stor.MarkDeleted(ctx, []uint64{deleted.ID}))
+	node, err := stor.FindByShortKey(ctx, "000000001")
+	require.NoError(t, err)
+	assert.True(t, node.DeletedFlag)
+
+	//	expired alias is read from the backend again
+	*now = now.Add(time.Second)
+	count, err := stor.MarkExpired(ctx, *now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	node, err = stor.FindByShortKey(ctx, "000000002")
+	require.NoError(t, err)
+	assert.True(t, node.ExpiredFlag)
+
+	assert.Equal(t, uint64(4), stor.Stats().Misses)
+}
+
+// slowBackend is a memory storage whose lookups wait until they are released
+type slowBackend struct {
+	*memstor.Storage
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *slowBackend) FindByShortKey(ctx context.Context, shortKey string) (*aliasentity.AliasURLModel, error) {
+
+	node, err := b.Storage.FindByShortKey(ctx, shortKey)
+	b.started <- struct{}{}
+	<-b.release
+	return node, err
+}
+
+func TestStorage_StaleFill(t *testing.T) {
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		change func(stor *Storage, node *aliasentity.AliasURLModel) error
+		want   func(t *testing.T, node *aliasentity.AliasURLModel, err error)
+	}{
+		{
+			name: "deleted",
+			change: func(stor *Storage, node *aliasentity.AliasURLModel) error {
+				return stor.MarkDeleted(ctx, []uint64{node.ID})
+			},
+			want: func(t *testing.T, node *aliasentity.AliasURLModel, err error) {
+				require.NoError(t, err)
+				assert.True(t, node.DeletedFlag)
+			},
+		},
+		{
+			name: "disabled",
+			change: func(stor *Storage, node *aliasentity.AliasURLModel) error {
+				_, err := stor.SetDisabled(ctx, node.ShortKey, true)
+				return err
+			},
+			want: func(t *testing.T, node *aliasentity.AliasURLModel, err error) {
+				require.NoError(t, err)
+				assert.True(t, node.DisabledFlag)
+			},
+		},
+		{
+			name: "purged",
+			change: func(stor *Storage, node *aliasentity.AliasURLModel) error {
+				_, err := stor.PurgeAlias(ctx, node.ShortKey)
+				return err
+			},
+			want: func(t *testing.T, node *aliasentity.AliasURLModel, err error) {
+				assert.ErrorIs(t, err, aliasentity.ErrNotFound)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			memory, err := memstor.NewStorage()
+			require.NoError(t, err)
+			backend := &slowBackend{Storage: memory, started: make(chan struct{}), release: make(chan struct{})}
+			stor, err := NewStorage(backend, 10)
+			require.NoError(t, err)
+
+			require.NoError(t, memory.Save(ctx, &aliasentity.AliasURLModel{ShortKey: "000000001", LongURL: "https://qqq.ru/1"}))
+			saved, err := memory.FindByShortKey(ctx, "000000001")
+			require.NoError(t, err)
+
+			//	the lookup reads the alias before the change and returns after it
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				_, err := stor.FindByShortKey(ctx, "000000001")
+				assert.NoError(t, err)
+			}()
+			<-backend.started
+			require.NoError(t, tt.change(stor, saved))
+			backend.release <- struct{}{}
+			<-done
+
+			//	the old alias is not cached, the next lookup sees the change
+			go func() {
+				<-backend.started
+				backend.release <- struct{}{}
+			}()
+			node, err := stor.FindByShortKey(ctx, "000000001")
+			tt.want(t, node, err)
+			assert.Equal(t, uint64(0), stor.Stats().Hits)
+			assert.Empty(t, stor.fills)
+		})
+	}
+}
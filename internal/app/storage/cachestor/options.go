@@ -0,0 +1,20 @@
+package cachestor
+
+import "time"
+
+// Option configures Storage
+type Option func(*Storage)
+
+// WithTTL sets how long a found alias is kept in the cache
+func WithTTL(ttl time.Duration) Option {
+	return func(s *Storage) {
+		s.ttl = ttl
+	}
+}
+
+// WithNegativeTTL sets how long a short key which is not found is remembered. Zero disables caching of misses
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(s *Storage) {
+		s.negativeTTL = ttl
+	}
+}
@@ -0,0 +1,183 @@
+package filestor
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/models/statsentity"
+)
+
+// disabledSwitch is appended to the aliases file when an alias is disabled or re-enabled
+type disabledSwitch struct {
+	ID       uint64 `json:"uuid"`
+	Disabled bool   `json:"is_disabled"`
+	Switch   bool   `json:"switch"`
+}
+
+// purge is appended to the aliases file when an alias is removed
+type purge struct {
+	ID       uint64 `json:"uuid"`
+	UserID   uint64 `json:"user_id"`
+	ShortKey string `json:"short_url"`
+	Purge    bool   `json:"purge"`
+}
+
+// ------------------------------------------------------------
+//
+//	Find aliases of all users by filter in the order of their IDs
+//	This is interfase method of "Storager" interface
+func (s *Storage) SearchAliases(ctx context.Context, filter aliasentity.Filter) ([]aliasentity.AliasURLModel, error) {
+
+	s.aliasesMx.RLock()
+	defer s.aliasesMx.RUnlock()
+
+	var nodes []aliasentity.AliasURLModel
+	skip := filter.Offset
+	for _, pos := range s.index.live() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		node, err := s.readAlias(pos)
+		if err != nil {
+			return nil, err
+		}
+		if !filter.Match(node) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		if filter.Limit > 0 && len(nodes) == filter.Limit {
+			break
+		}
+		nodes = append(nodes, *node)
+	}
+	return nodes, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Disable or re-enable alias by short key.
+//	A switch is appended to the aliases file, switches are replayed by NewStorage
+//	This is interfase method of "Storager" interface
+//	Output:
+//		bool - false if the alias is not found
+func (s *Storage) SetDisabled(ctx context.Context, shortKey string, disabled bool) (bool, error) {
+
+	s.aliasesMx.Lock()
+	defer s.aliasesMx.Unlock()
+
+	pos, ok := s.index.byShortKey[shortKey]
+	if !ok {
+		return false, nil
+	}
+	node, err := s.readAlias(pos)
+	if err != nil {
+		return false, err
+	}
+
+	record := aliasRecord{AliasURLModel: aliasentity.AliasURLModel{ID: node.ID, DisabledFlag: disabled}, Switch: true}
+	if err = s.appendAliasRecord(disabledSwitch{ID: node.ID, Disabled: disabled, Switch: true}, &record); err != nil {
+		return false, err
+	}
+	s.compactIfNeeded()
+	return true, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Remove alias by short key and its clicks.
+//	A purge is appended to the aliases file and the file is compacted at once,
+//	so the record of the alias does not stay on the disk
+//	This is interfase method of "Storager" interface
+//	Output:
+//		bool - false if the alias is not found
+func (s *Storage) PurgeAlias(ctx context.Context, shortKey string) (bool, error) {
+
+	s.aliasesMx.Lock()
+	defer s.aliasesMx.Unlock()
+
+	pos, ok := s.index.byShortKey[shortKey]
+	if !ok {
+		return false, nil
+	}
+	node, err := s.readAlias(pos)
+	if err != nil {
+		return false, err
+	}
+
+	//	clicks go first, the next owner of the short key must not get them
+	if err = s.purgeClicks(node.ShortKey); err != nil {
+		return false, err
+	}
+
+	record := aliasRecord{AliasURLModel: aliasentity.AliasURLModel{ID: node.ID, UserID: node.UserID, ShortKey: node.ShortKey}, Purge: true}
+	if err = s.appendAliasRecord(purge{ID: node.ID, UserID: node.UserID, ShortKey: node.ShortKey, Purge: true}, &record); err != nil {
+		return false, err
+	}
+
+	//	the purge is already replayable, a failed compaction is retried later
+	if err = s.compact(ctx); err != nil {
+		log.Printf("file storage: can't compact \"%s\": %s", s.aliasesFileName, err)
+	}
+	return true, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Count users and aliases
+//	This is interfase method of "Storager" interface
+func (s *Storage) GetServiceStats(ctx context.Context, now time.Time) (*statsentity.ServiceStatsModel, error) {
+
+	stats := new(statsentity.ServiceStatsModel)
+
+	s.usersMx.RLock()
+	stats.Users = s.lastUserID
+	stats.RegisteredUsers = uint64(len(s.registered))
+	s.usersMx.RUnlock()
+
+	s.aliasesMx.RLock()
+	defer s.aliasesMx.RUnlock()
+
+	for _, pos := range s.index.live() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		node, err := s.readAlias(pos)
+		if err != nil {
+			return nil, err
+		}
+		stats.AddAlias(node, now)
+	}
+	return stats, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Append v to the aliases file and add record, which is v as it is read, to the index.
+//	The caller must hold s.aliasesMx
+func (s *Storage) appendAliasRecord(v any, record *aliasRecord) error {
+
+	file, err := os.OpenFile(s.aliasesFileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	s.index.add(record, len(data))
+	return s.sync(file)
+}
@@ -0,0 +1,186 @@
+package filestor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+)
+
+// apiKeys keeps API keys of the file in memory. Every change of a key appends
+// the whole key to the file, the last record of the key wins on reading
+type apiKeys struct {
+	byID   map[uint64]*apikeyentity.APIKeyModel
+	byHash map[string]uint64 //	[key, value] = [hash of the key, ID of the key]
+	lastID uint64
+}
+
+// ------------------------------------------------------------
+//
+//	Read API keys from the file
+func readAPIKeys(fileName string) (*apiKeys, error) {
+
+	keys := &apiKeys{
+		byID:   make(map[uint64]*apikeyentity.APIKeyModel),
+		byHash: make(map[string]uint64),
+	}
+
+	err := readRecords(fileName, func(data []byte) error {
+		var key apikeyentity.APIKeyModel
+		if err := json.Unmarshal(data, &key); err != nil {
+			return err
+		}
+		keys.set(&key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// set adds the key or replaces its previous version
+func (k *apiKeys) set(key *apikeyentity.APIKeyModel) {
+
+	k.byID[key.ID] = key
+	k.byHash[key.Hash] = key.ID
+	if key.ID > k.lastID {
+		k.lastID = key.ID
+	}
+}
+
+// ------------------------------------------------------------
+//
+//	Save new API key and set its ID
+//	This is interfase method of "Storager" interface
+func (s *Storage) SaveAPIKey(ctx context.Context, key *apikeyentity.APIKeyModel) error {
+
+	s.apiKeysMx.Lock()
+	defer s.apiKeysMx.Unlock()
+
+	node := copyAPIKey(key)
+	node.ID = s.apiKeys.lastID + 1
+	if err := s.writeAPIKey(&node); err != nil {
+		return err
+	}
+	key.ID = node.ID
+	return nil
+}
+
+// ------------------------------------------------------------
+//
+//	Find API key by hash of the key
+//	This is interfase method of "Storager" interface
+func (s *Storage) FindAPIKeyByHash(ctx context.Context, hash string) (*apikeyentity.APIKeyModel, error) {
+
+	s.apiKeysMx.RLock()
+	defer s.apiKeysMx.RUnlock()
+
+	id, ok := s.apiKeys.byHash[hash]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+	key := copyAPIKey(s.apiKeys.byID[id])
+	return &key, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Find all API keys of the user ordered by ID
+//	This is interfase method of "Storager" interface
+func (s *Storage) FindAPIKeysByUserID(ctx context.Context, userID uint64) ([]apikeyentity.APIKeyModel, error) {
+
+	s.apiKeysMx.RLock()
+	defer s.apiKeysMx.RUnlock()
+
+	var keys []apikeyentity.APIKeyModel
+	for _, key := range s.apiKeys.byID {
+		if key.UserID == userID {
+			keys = append(keys, copyAPIKey(key))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Mark API key of the user like "revoked"
+//	This is interfase method of "Storager" interface
+//	Output:
+//		bool - false if the user has no key with keyID
+func (s *Storage) RevokeAPIKey(ctx context.Context, userID, keyID uint64) (bool, error) {
+
+	s.apiKeysMx.Lock()
+	defer s.apiKeysMx.Unlock()
+
+	key, ok := s.apiKeys.byID[keyID]
+	if !ok || key.UserID != userID {
+		return false, nil
+	}
+	if key.RevokedFlag {
+		return true, nil
+	}
+
+	node := copyAPIKey(key)
+	node.RevokedFlag = true
+	return true, s.writeAPIKey(&node)
+}
+
+// ------------------------------------------------------------
+//
+//	Set last used time of API key
+//	This is interfase method of "Storager" interface
+func (s *Storage) TouchAPIKey(ctx context.Context, keyID uint64, usedAt time.Time) error {
+
+	s.apiKeysMx.Lock()
+	defer s.apiKeysMx.Unlock()
+
+	key, ok := s.apiKeys.byID[keyID]
+	if !ok {
+		return fmt.Errorf("not found")
+	}
+
+	node := copyAPIKey(key)
+	node.LastUsedAt = &usedAt
+	return s.writeAPIKey(&node)
+}
+
+// ------------------------------------------------------------
+//
+//	Append the key to the file and keep it in memory.
+//	The caller must hold s.apiKeysMx
+func (s *Storage) writeAPIKey(key *apikeyentity.APIKeyModel) error {
+
+	file, err := os.OpenFile(s.apiKeysFileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	data, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if err = s.sync(file); err != nil {
+		return err
+	}
+
+	s.apiKeys.set(key)
+	return nil
+}
+
+// copyAPIKey returns a copy of the key which doesn't share scopes with it
+func copyAPIKey(key *apikeyentity.APIKeyModel) apikeyentity.APIKeyModel {
+
+	c := *key
+	c.Scopes = append([]string(nil), key.Scopes...)
+	return c
+}
@@ -0,0 +1,181 @@
+package filestor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// ------------------------------------------------------------
+//
+//	Compact rewrites the aliases file without superseded records and tombstones.
+//	Deleted aliases are kept with "is_deleted" flag. The storage stays available,
+//	requests wait while the file is rewritten
+func (s *Storage) Compact(ctx context.Context) error {
+
+	s.aliasesMx.Lock()
+	defer s.aliasesMx.Unlock()
+
+	return s.compact(ctx)
+}
+
+// ------------------------------------------------------------
+//
+//	Compact the aliases file if its garbage has reached the threshold.
+//	The caller must hold s.aliasesMx
+func (s *Storage) compactIfNeeded() {
+
+	if s.compactThreshold <= 0 || s.index.garbage < s.compactThreshold {
+		return
+	}
+
+	if err := s.compact(context.Background()); err != nil {
+		log.Printf("file storage: can't compact \"%s\": %s", s.aliasesFileName, err)
+	}
+}
+
+// ------------------------------------------------------------
+//
+//	Write live records to a temporary file and replace the aliases file by it.
+//	The caller must hold s.aliasesMx
+func (s *Storage) compact(ctx context.Context) error {
+
+	dir := filepath.Dir(s.aliasesFileName)
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.aliasesFileName)+".compact-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	writer := bufio.NewWriter(tmp)
+	index := newAliasIndex()
+
+	for _, pos := range s.index.live() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		node, err := s.readAlias(pos)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(node)
+		if err != nil {
+			return err
+		}
+		if _, err = writer.Write(append(data, '\n')); err != nil {
+			return err
+		}
+		index.add(&aliasRecord{AliasURLModel: *node}, len(data))
+	}
+
+	//	the new file must be on the disk before it replaces the old one
+	if err = writer.Flush(); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), s.aliasesFileName); err != nil {
+		return err
+	}
+	if err = syncDir(dir); err != nil {
+		return err
+	}
+
+	reader, err := os.Open(s.aliasesFileName)
+	if err != nil {
+		return err
+	}
+	s.aliasesReader.Close()
+	s.aliasesReader = reader
+	s.index = index
+	return nil
+}
+
+// ------------------------------------------------------------
+//
+//	Flush file to the disk if fsync option is on
+func (s *Storage) sync(file *os.File) error {
+
+	if !s.fsync {
+		return nil
+	}
+	return file.Sync()
+}
+
+// ------------------------------------------------------------
+//
+//	Flush directory entries to the disk, so the rename survives a crash
+func syncDir(dir string) error {
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
+// ------------------------------------------------------------
+//
+//	Call parse for every record of the file, the file is created if it does not exist.
+//	A broken last record is a write torn by a crash: it is cut off the file
+func readRecords(fileName string, parse func(data []byte) error) error {
+
+	file, err := os.OpenFile(fileName, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				return cutTornRecord(file, offset)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := parse(line[:len(line)-1]); err != nil {
+			if _, err := reader.Peek(1); errors.Is(err, io.EOF) {
+				return cutTornRecord(file, offset)
+			}
+			return errors.New("invalid file format")
+		}
+		offset += int64(len(line))
+	}
+}
+
+// ------------------------------------------------------------
+//
+//	Truncate file to size
+func cutTornRecord(file *os.File, size int64) error {
+
+	log.Printf("file storage: partial record at the end of \"%s\" is cut off at offset %d", file.Name(), size)
+
+	if err := file.Truncate(size); err != nil {
+		return err
+	}
+	return file.Sync()
+}
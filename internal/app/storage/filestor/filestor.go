@@ -7,18 +7,60 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/models/clickentity"
 	"github.com/Schalure/urlalias/internal/app/models/userentity"
 )
 
-// Storage type
+// Storage type. Storage is safe for concurrent use
 type Storage struct {
-	aliasesFileName string
-	usersFileName   string
-	lastKey         string
-	lastID          uint64
-	lastUserID      uint64
+	aliasesFileName   string
+	usersFileName     string
+	clicksFileName    string
+	keyRangesFileName string
+	apiKeysFileName   string
+	aliasesReader     *os.File     //	aliases file opened for random access reads
+	aliasesMx         sync.RWMutex //	guards aliases file, index, lastKey and lastID
+	usersMx           sync.RWMutex //	guards users file, registered, logins and lastUserID
+	clicksMx          sync.Mutex   //	clicks are written by the background worker
+	keyRangesMx       sync.Mutex   //	guards key ranges file in the process, file lock guards it between processes
+	apiKeysMx         sync.RWMutex //	guards API keys file and apiKeys
+	index             *aliasIndex  //	positions of alias records in aliases file
+	apiKeys           *apiKeys     //	API keys read from API keys file
+	lastKey           string
+	lastID            uint64
+	lastUserID        uint64
+	registered        map[uint64]userentity.UserModel //	registered users, other users up to lastUserID are anonymous
+	logins            map[string]uint64               //	[key, value] = [login, user ID]
+
+	fsync            bool  //	flush every write to the disk
+	compactThreshold int64 //	size of garbage in aliases file which triggers compaction, 0 - never
+}
+
+// aliasRecord is a line of the aliases file: an alias, a tombstone of a deleted alias,
+// a switch of disabled flag or a purge of an alias
+type aliasRecord struct {
+	aliasentity.AliasURLModel
+	Tombstone bool `json:"tombstone,omitempty"`
+	Switch    bool `json:"switch,omitempty"`
+	Purge     bool `json:"purge,omitempty"`
+	Expire    bool `json:"expire,omitempty"`
+}
+
+// tombstone is appended to the aliases file when an alias is marked as deleted
+type tombstone struct {
+	ID        uint64 `json:"uuid"`
+	Tombstone bool   `json:"tombstone"`
+}
+
+// expiry is appended to the aliases file when an alias is marked as expired
+type expiry struct {
+	ID     uint64 `json:"uuid"`
+	Expire bool   `json:"expire"`
 }
 
 // ------------------------------------------------------------
@@ -26,61 +68,103 @@ type Storage struct {
 //	FileStorage constructor
 //	Output:
 //		*FileStorage
-func NewStorage(aliasesFileName, usersFileName string) (*Storage, error) {
-
-	aliasesFile, err := os.OpenFile(aliasesFileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
-	if err != nil {
-		return nil, err
-	}
-	defer aliasesFile.Close()
-
-	scanner := bufio.NewScanner(aliasesFile)
+func NewStorage(aliasesFileName, usersFileName, clicksFileName, keyRangesFileName, apiKeysFileName string, opts ...Option) (*Storage, error) {
 
 	var lastKey string
 	var lastID uint64
+	index := newAliasIndex()
 
-	for i := 0; scanner.Scan(); i++ {
-		var node aliasentity.AliasURLModel
-		if err := json.Unmarshal([]byte(scanner.Text()), &node); err != nil {
-			return nil, errors.New("invalid file format")
+	err := readRecords(aliasesFileName, func(data []byte) error {
+		var record aliasRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return err
 		}
 
-		lastID = node.ID
-		lastKey = node.ShortKey
-	}
+		//	tombstones, switches, purges and expiries replay changes of aliases
+		index.add(&record, len(data))
+		if record.Tombstone || record.Switch || record.Purge || record.Expire {
+			return nil
+		}
 
-	usersFile, err := os.OpenFile(usersFileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		lastID = record.ID
+		if !record.IsCustom {
+			lastKey = record.ShortKey
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer usersFile.Close()
-
-	scanner = bufio.NewScanner(usersFile)
 
 	var lastUserID uint64
-
-	for i := 0; scanner.Scan(); i++ {
+	registered := make(map[uint64]userentity.UserModel)
+	logins := make(map[string]uint64)
+	err = readRecords(usersFileName, func(data []byte) error {
 		var node userentity.UserModel
-		if err := json.Unmarshal([]byte(scanner.Text()), &node); err != nil {
-			return nil, errors.New("invalid file format")
+		if err := json.Unmarshal(data, &node); err != nil {
+			return err
 		}
 
-		lastUserID = node.UserID
+		//	registration appends the user again with login
+		if node.IsRegistered() {
+			registered[node.UserID] = node
+			logins[node.Login] = node.UserID
+		}
+		if node.UserID > lastUserID {
+			lastUserID = node.UserID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return &Storage{
-		aliasesFileName: aliasesFileName,
-		usersFileName:   usersFileName,
-		lastKey:         lastKey,
-		lastID:          lastID,
-		lastUserID:      lastUserID,
-	}, nil
+	err = readRecords(clicksFileName, func(data []byte) error {
+		var click clickentity.ClickModel
+		return json.Unmarshal(data, &click)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys, err := readAPIKeys(apiKeysFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	aliasesReader, err := os.Open(aliasesFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Storage{
+		aliasesFileName:   aliasesFileName,
+		usersFileName:     usersFileName,
+		clicksFileName:    clicksFileName,
+		keyRangesFileName: keyRangesFileName,
+		apiKeysFileName:   apiKeysFileName,
+		aliasesReader:     aliasesReader,
+		index:             index,
+		apiKeys:           apiKeys,
+		lastKey:           lastKey,
+		lastID:            lastID,
+		lastUserID:        lastUserID,
+		registered:        registered,
+		logins:            logins,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
 }
 
 // ------------------------------------------------------------
 //
 //	Create new user
-func (s *Storage) CreateUser() (uint64, error) {
+func (s *Storage) CreateUser(ctx context.Context) (uint64, error) {
+
+	s.usersMx.Lock()
+	defer s.usersMx.Unlock()
 
 	var data []byte
 
@@ -102,11 +186,93 @@ func (s *Storage) CreateUser() (uint64, error) {
 	if _, err = file.Write(append(data, '\n')); err != nil {
 		return 0, err
 	}
+	if err = s.sync(file); err != nil {
+		return 0, err
+	}
 
 	s.lastUserID = newUserID
 	return s.lastUserID, nil
 }
 
+// ------------------------------------------------------------
+//
+//	Find user by ID
+//	This is interfase method of "Storager" interface
+func (s *Storage) FindUserByID(ctx context.Context, userID uint64) (*userentity.UserModel, error) {
+
+	s.usersMx.RLock()
+	defer s.usersMx.RUnlock()
+
+	if user, ok := s.registered[userID]; ok {
+		return &user, nil
+	}
+	if userID == 0 || userID > s.lastUserID {
+		return nil, fmt.Errorf("not found")
+	}
+	return &userentity.UserModel{UserID: userID}, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Find registered user by login
+//	This is interfase method of "Storager" interface
+func (s *Storage) FindUserByLogin(ctx context.Context, login string) (*userentity.UserModel, error) {
+
+	s.usersMx.RLock()
+	defer s.usersMx.RUnlock()
+
+	userID, ok := s.logins[login]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+	user := s.registered[userID]
+	return &user, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Set login and password hash of the anonymous user.
+//	The user is appended to the users file again, the last record of the user wins
+//	This is interfase method of "Storager" interface
+//	Output:
+//		bool - false if the user is already registered or the login is taken
+func (s *Storage) RegisterUser(ctx context.Context, user *userentity.UserModel) (bool, error) {
+
+	s.usersMx.Lock()
+	defer s.usersMx.Unlock()
+
+	if user.UserID == 0 || user.UserID > s.lastUserID {
+		return false, fmt.Errorf("not found")
+	}
+	if _, ok := s.registered[user.UserID]; ok {
+		return false, nil
+	}
+	if _, ok := s.logins[user.Login]; ok {
+		return false, nil
+	}
+
+	file, err := os.OpenFile(s.usersFileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	data, err := json.Marshal(user)
+	if err != nil {
+		return false, err
+	}
+	if _, err = file.Write(append(data, '\n')); err != nil {
+		return false, err
+	}
+	if err = s.sync(file); err != nil {
+		return false, err
+	}
+
+	s.registered[user.UserID] = *user
+	s.logins[user.Login] = user.UserID
+	return true, nil
+}
+
 // ------------------------------------------------------------
 //
 //	Save pair "shortKey, longURL" to db
@@ -114,9 +280,17 @@ func (s *Storage) CreateUser() (uint64, error) {
 //	Input:
 //		urlAliasNode *repositories.AliasURLModel
 //	Output:
-//		error - if not nil, can not save "urlAliasNode" because duplicate key
+//		error - aliasentity.ErrShortKeyTaken if there is an alias with the short key
 func (s *Storage) Save(ctx context.Context, urlAliasNode *aliasentity.AliasURLModel) error {
 
+	s.aliasesMx.Lock()
+	defer s.aliasesMx.Unlock()
+
+	//	the index keeps the first record of a short key, so the check is under the same lock as the write
+	if _, ok := s.index.byShortKey[urlAliasNode.ShortKey]; ok {
+		return aliasentity.ErrShortKeyTaken
+	}
+
 	var data []byte
 	file, err := os.OpenFile(s.aliasesFileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
 	if err != nil {
@@ -133,9 +307,16 @@ func (s *Storage) Save(ctx context.Context, urlAliasNode *aliasentity.AliasURLMo
 		return err
 	}
 
+	s.index.add(&aliasRecord{AliasURLModel: *urlAliasNode}, len(data))
 	s.lastID++
-	s.lastKey = urlAliasNode.ShortKey
+	if !urlAliasNode.IsCustom {
+		s.lastKey = urlAliasNode.ShortKey
+	}
 
+	if err = s.sync(file); err != nil {
+		return err
+	}
+	s.compactIfNeeded()
 	return nil
 }
 
@@ -146,9 +327,23 @@ func (s *Storage) Save(ctx context.Context, urlAliasNode *aliasentity.AliasURLMo
 //	Input:
 //		urlAliasNode []repositories.AliasURLModel
 //	Output:
-//		error - if not nil, can not save "[]storage.AliasURLModel"
+//		error - aliasentity.ErrShortKeyTaken if a short key is taken or repeated, nothing is saved
 func (s *Storage) SaveAll(ctx context.Context, urlAliasNodes []aliasentity.AliasURLModel) error {
 
+	s.aliasesMx.Lock()
+	defer s.aliasesMx.Unlock()
+
+	batchKeys := make(map[string]struct{}, len(urlAliasNodes))
+	for _, node := range urlAliasNodes {
+		if _, ok := batchKeys[node.ShortKey]; ok {
+			return aliasentity.ErrShortKeyTaken
+		}
+		if _, ok := s.index.byShortKey[node.ShortKey]; ok {
+			return aliasentity.ErrShortKeyTaken
+		}
+		batchKeys[node.ShortKey] = struct{}{}
+	}
+
 	var data []byte
 	file, err := os.OpenFile(s.aliasesFileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
 	if err != nil {
@@ -166,9 +361,17 @@ func (s *Storage) SaveAll(ctx context.Context, urlAliasNodes []aliasentity.Alias
 			return err
 		}
 
+		s.index.add(&aliasRecord{AliasURLModel: node}, len(data))
 		s.lastID++
-		s.lastKey = node.ShortKey
+		if !node.IsCustom {
+			s.lastKey = node.ShortKey
+		}
 	}
+
+	if err = s.sync(file); err != nil {
+		return err
+	}
+	s.compactIfNeeded()
 	return nil
 }
 
@@ -183,26 +386,14 @@ func (s *Storage) SaveAll(ctx context.Context, urlAliasNodes []aliasentity.Alias
 //		error - if can not find "urlAliasNode" by short key
 func (s *Storage) FindByShortKey(ctx context.Context, shortKey string) (*aliasentity.AliasURLModel, error) {
 
-	file, err := os.OpenFile(s.aliasesFileName, os.O_RDONLY, 0644)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-
-	for i := 0; scanner.Scan(); i++ {
-		var node aliasentity.AliasURLModel
-		if err := json.Unmarshal([]byte(scanner.Text()), &node); err != nil {
-			return nil, err
-		}
-
-		if shortKey == node.ShortKey {
-			return &node, nil
-		}
+	s.aliasesMx.RLock()
+	defer s.aliasesMx.RUnlock()
 
+	pos, ok := s.index.byShortKey[shortKey]
+	if !ok {
+		return nil, aliasentity.ErrNotFound
 	}
-	return nil, fmt.Errorf("not found")
+	return s.readAlias(pos)
 }
 
 // ------------------------------------------------------------
@@ -216,64 +407,285 @@ func (s *Storage) FindByShortKey(ctx context.Context, shortKey string) (*aliasen
 //		error - if can not find "urlAliasNode" by long URL
 func (s *Storage) FindByLongURL(ctx context.Context, longURL string) (*aliasentity.AliasURLModel, error) {
 
-	file, err := os.OpenFile(s.aliasesFileName, os.O_RDONLY, 0644)
+	s.aliasesMx.RLock()
+	defer s.aliasesMx.RUnlock()
+
+	pos, ok := s.index.byLongURL[longURL]
+	if !ok {
+		return nil, aliasentity.ErrNotFound
+	}
+	return s.readAlias(pos)
+}
+
+// FindAllByLongURLs find all aliases by slice of original URL and return map[original_url] aliasentity.AliasURLModel or error
+func (s *Storage) FindAllByLongURLs(ctx context.Context, longURL []string) (map[string]*aliasentity.AliasURLModel, error) {
+
+	s.aliasesMx.RLock()
+	defer s.aliasesMx.RUnlock()
+
+	nodes := make(map[string]*aliasentity.AliasURLModel)
+	for _, u := range longURL {
+		pos, ok := s.index.byLongURL[u]
+		if !ok {
+			continue
+		}
+
+		node, err := s.readAlias(pos)
+		if err != nil {
+			return nil, err
+		}
+		nodes[u] = node
+	}
+	return nodes, nil
+}
+
+// FindByUserID
+func (s *Storage) FindByUserID(ctx context.Context, userID uint64) ([]aliasentity.AliasURLModel, error) {
+
+	s.aliasesMx.RLock()
+	defer s.aliasesMx.RUnlock()
+
+	var nodes []aliasentity.AliasURLModel
+	for _, pos := range s.index.byUserID[userID] {
+		node, err := s.readAlias(pos)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, *node)
+	}
+	return nodes, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Mark aliases like "deleted" by aliasesID.
+//	A tombstone is appended to the aliases file for every alias,
+//	tombstones are replayed by NewStorage
+func (s *Storage) MarkDeleted(ctx context.Context, aliasesID []uint64) error {
+
+	s.aliasesMx.Lock()
+	defer s.aliasesMx.Unlock()
+
+	file, err := os.OpenFile(s.aliasesFileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
+	for _, aliasID := range aliasesID {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Storage MarkDeleted: context deadline")
+		default:
+		}
 
-	for i := 0; scanner.Scan(); i++ {
-		var node aliasentity.AliasURLModel
-		if err := json.Unmarshal([]byte(scanner.Text()), &node); err != nil {
-			return nil, err
+		if _, ok := s.index.deleted[aliasID]; ok {
+			continue
 		}
 
-		if longURL == node.LongURL {
-			return &node, nil
+		data, err := json.Marshal(tombstone{ID: aliasID, Tombstone: true})
+		if err != nil {
+			return err
 		}
+		if _, err = file.Write(append(data, '\n')); err != nil {
+			return err
+		}
+		s.index.add(&aliasRecord{AliasURLModel: aliasentity.AliasURLModel{ID: aliasID}, Tombstone: true}, len(data))
+	}
 
+	if err = s.sync(file); err != nil {
+		return err
 	}
-	return nil, fmt.Errorf("not found")
+	s.compactIfNeeded()
+	return nil
 }
 
-// FindAllByLongURLs find all aliases by slice of original URL and return map[original_url] aliasentity.AliasURLModel or error
-func (s *Storage) FindAllByLongURLs(ctx context.Context, longURL []string) (map[string]*aliasentity.AliasURLModel, error) {
-	return nil, fmt.Errorf("no implemented")
+// ------------------------------------------------------------
+//
+//	Mark aliases like "expired" if their expiry time has passed.
+//	An expiry is appended to the aliases file for every alias, expiries are replayed by NewStorage
+//	Output:
+//		int - count of aliases marked now
+//		error
+func (s *Storage) MarkExpired(ctx context.Context, now time.Time) (int, error) {
+
+	s.aliasesMx.Lock()
+	defer s.aliasesMx.Unlock()
+
+	aliasesID := s.index.expiredBy(now)
+	if len(aliasesID) == 0 {
+		return 0, nil
+	}
+
+	file, err := os.OpenFile(s.aliasesFileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	count := 0
+	for _, aliasID := range aliasesID {
+		select {
+		case <-ctx.Done():
+			//	expiries which are written already are kept, the rest are marked by the next sweep
+			return count, errors.Join(ctx.Err(), s.sync(file))
+		default:
+		}
+
+		data, err := json.Marshal(expiry{ID: aliasID, Expire: true})
+		if err != nil {
+			return count, err
+		}
+		if _, err = file.Write(append(data, '\n')); err != nil {
+			return count, err
+		}
+		s.index.add(&aliasRecord{AliasURLModel: aliasentity.AliasURLModel{ID: aliasID}, Expire: true}, len(data))
+		count++
+	}
+
+	if err = s.sync(file); err != nil {
+		return count, err
+	}
+	s.compactIfNeeded()
+	return count, nil
 }
 
-// FindByUserID
-func (s *Storage) FindByUserID(ctx context.Context, userID uint64) ([]aliasentity.AliasURLModel, error) {
+// ------------------------------------------------------------
+//
+//	Save batch of redirects
+//	This is interfase method of "AnalyticsSink" interface
+func (s *Storage) SaveClicks(ctx context.Context, clicks []clickentity.ClickModel) error {
+
+	s.clicksMx.Lock()
+	defer s.clicksMx.Unlock()
 
-	file, err := os.OpenFile(s.aliasesFileName, os.O_RDONLY, 0644)
+	file, err := os.OpenFile(s.clicksFileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, click := range clicks {
+		if err := encoder.Encode(&click); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return s.sync(file)
+}
+
+// ------------------------------------------------------------
+//
+//	Get statistics of redirects by short key
+//	This is interfase method of "AnalyticsSink" interface
+func (s *Storage) GetClickStats(ctx context.Context, shortKey string) (*clickentity.StatsModel, error) {
+
+	s.clicksMx.Lock()
+	defer s.clicksMx.Unlock()
+
+	file, err := os.OpenFile(s.clicksFileName, os.O_RDONLY, 0644)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	var nodes []aliasentity.AliasURLModel
+	var clicks []clickentity.ClickModel
 	scanner := bufio.NewScanner(file)
 
-	for i := 0; scanner.Scan(); i++ {
-		var node aliasentity.AliasURLModel
-		if err := json.Unmarshal([]byte(scanner.Text()), &node); err != nil {
+	for scanner.Scan() {
+		var click clickentity.ClickModel
+		if err := json.Unmarshal(scanner.Bytes(), &click); err != nil {
 			return nil, err
 		}
 
-		if node.UserID == userID {
-			nodes = append(nodes, node)
+		if click.ShortKey == shortKey {
+			clicks = append(clicks, click)
 		}
 	}
-	return nodes, nil
+	return clickentity.NewStats(clicks), nil
 }
 
 // ------------------------------------------------------------
 //
-//	Mark aliases like "deleted" by aliasesID
-func (s *Storage) MarkDeleted(ctx context.Context, aliasesID []uint64) error {
+//	Rewrite clicks file without redirects of the short key
+func (s *Storage) purgeClicks(shortKey string) error {
 
-	return nil
+	s.clicksMx.Lock()
+	defer s.clicksMx.Unlock()
+
+	file, err := os.OpenFile(s.clicksFileName, os.O_RDONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	dir := filepath.Dir(s.clicksFileName)
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.clicksFileName)+".purge-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	writer := bufio.NewWriter(tmp)
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		var click clickentity.ClickModel
+		if err := json.Unmarshal(scanner.Bytes(), &click); err != nil {
+			return err
+		}
+
+		if click.ShortKey == shortKey {
+			continue
+		}
+		if _, err := writer.Write(append(scanner.Bytes(), '\n')); err != nil {
+			return err
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+
+	//	the new file must be on the disk before it replaces the old one
+	if err = writer.Flush(); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), s.clicksFileName); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// ------------------------------------------------------------
+//
+//	Read alias record at pos of aliases file.
+//	The caller must hold s.aliasesMx
+func (s *Storage) readAlias(pos recordPos) (*aliasentity.AliasURLModel, error) {
+
+	data := make([]byte, pos.size)
+	if _, err := s.aliasesReader.ReadAt(data, pos.offset); err != nil {
+		return nil, err
+	}
+
+	var node aliasentity.AliasURLModel
+	if err := json.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	node.DeletedFlag = node.DeletedFlag || s.index.isDeleted(&node)
+	node.ExpiredFlag = node.ExpiredFlag || s.index.isExpired(&node)
+	node.DisabledFlag = s.index.isDisabled(&node)
+	return &node, nil
 }
 
 // ------------------------------------------------------------
@@ -283,6 +695,10 @@ func (s *Storage) MarkDeleted(ctx context.Context, aliasesID []uint64) error {
 //	Output:
 //		string - last saved key
 func (s *Storage) GetLastShortKey() string {
+
+	s.aliasesMx.RLock()
+	defer s.aliasesMx.RUnlock()
+
 	return s.lastKey
 }
 
@@ -294,7 +710,7 @@ func (s *Storage) GetLastShortKey() string {
 //		bool - true: connection is
 //			   false: connection isn't
 //		error - if can not find "urlAliasNode" by long URL
-func (s *Storage) IsConnected() bool {
+func (s *Storage) IsConnected(ctx context.Context) bool {
 	return true
 }
 
@@ -305,5 +721,5 @@ func (s *Storage) IsConnected() bool {
 //	Output:
 //		error
 func (s *Storage) Close() error {
-	return nil
+	return s.aliasesReader.Close()
 }
@@ -3,13 +3,21 @@ package filestor
 import (
 	"bufio"
 	"context"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
 	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+	"github.com/Schalure/urlalias/internal/app/models/userentity"
+	"github.com/Schalure/urlalias/internal/app/storage/storagetest"
 )
 
 func TestFileStorage_Save(t *testing.T) {
@@ -24,7 +32,22 @@ func TestFileStorage_Save(t *testing.T) {
 	aliasesFile.Close()
 	defer os.Remove(usersFile.Name())
 
-	stor, _ := NewStorage(aliasesFile.Name(), usersFile.Name())
+	clicksFile, err := os.CreateTemp("", "storage*.json")
+	require.NoError(t, err)
+	clicksFile.Close()
+	defer os.Remove(clicksFile.Name())
+
+	keyRangesFile, err := os.CreateTemp("", "storage*.json")
+	require.NoError(t, err)
+	keyRangesFile.Close()
+	defer os.Remove(keyRangesFile.Name())
+
+	apiKeysFile, err := os.CreateTemp("", "storage*.json")
+	require.NoError(t, err)
+	apiKeysFile.Close()
+	defer os.Remove(apiKeysFile.Name())
+
+	stor, _ := NewStorage(aliasesFile.Name(), usersFile.Name(), clicksFile.Name(), keyRangesFile.Name(), apiKeysFile.Name())
 
 	testCases := []struct {
 		testName string
@@ -74,3 +97,546 @@ func TestFileStorage_Save(t *testing.T) {
 		})
 	}
 }
+
+func TestStorager(t *testing.T) {
+
+	storagetest.Run(t, func(t *testing.T) aliasmaker.Storager {
+		dir := t.TempDir()
+		stor, err := NewStorage(
+			filepath.Join(dir, "aliases.json"),
+			filepath.Join(dir, "users.json"),
+			filepath.Join(dir, "clicks.json"),
+			filepath.Join(dir, "keys.json"),
+			filepath.Join(dir, "apikeys.json"),
+		)
+		require.NoError(t, err)
+		return stor
+	})
+}
+
+func TestStoragerRestart(t *testing.T) {
+
+	storagetest.RunRestart(t, func(t *testing.T) aliasmaker.Storager {
+		dir := t.TempDir()
+		stor, err := NewStorage(
+			filepath.Join(dir, "aliases.json"),
+			filepath.Join(dir, "users.json"),
+			filepath.Join(dir, "clicks.json"),
+			filepath.Join(dir, "keys.json"),
+			filepath.Join(dir, "apikeys.json"),
+		)
+		require.NoError(t, err)
+		return stor
+	}, func(t *testing.T, stor aliasmaker.Storager) aliasmaker.Storager {
+		closed := stor.(*Storage)
+		require.NoError(t, closed.Close())
+		reopened, err := NewStorage(closed.aliasesFileName, closed.usersFileName, closed.clicksFileName, closed.keyRangesFileName, closed.apiKeysFileName)
+		require.NoError(t, err)
+		return reopened
+	})
+}
+
+func TestFileStorage_RegisterUserReplay(t *testing.T) {
+
+	dir := t.TempDir()
+	aliasesFile := filepath.Join(dir, "aliases.json")
+	usersFile := filepath.Join(dir, "users.json")
+	clicksFile := filepath.Join(dir, "clicks.json")
+	keyRangesFile := filepath.Join(dir, "keys.json")
+	apiKeysFile := filepath.Join(dir, "apikeys.json")
+
+	stor, err := NewStorage(aliasesFile, usersFile, clicksFile, keyRangesFile, apiKeysFile)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	first, err := stor.CreateUser(ctx)
+	require.NoError(t, err)
+	second, err := stor.CreateUser(ctx)
+	require.NoError(t, err)
+	ok, err := stor.RegisterUser(ctx, &userentity.UserModel{UserID: first, Login: "alice", PasswordHash: "hash"})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	//	reopen storage: the registration of the first user is the last record of the file
+	require.NoError(t, stor.Close())
+	stor, err = NewStorage(aliasesFile, usersFile, clicksFile, keyRangesFile, apiKeysFile)
+	require.NoError(t, err)
+	defer stor.Close()
+
+	user, err := stor.FindUserByLogin(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, first, user.UserID)
+
+	third, err := stor.CreateUser(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, second+1, third)
+}
+
+func TestFileStorage_APIKeysReplay(t *testing.T) {
+
+	dir := t.TempDir()
+	aliasesFile := filepath.Join(dir, "aliases.json")
+	usersFile := filepath.Join(dir, "users.json")
+	clicksFile := filepath.Join(dir, "clicks.json")
+	keyRangesFile := filepath.Join(dir, "keys.json")
+	apiKeysFile := filepath.Join(dir, "apikeys.json")
+
+	stor, err := NewStorage(aliasesFile, usersFile, clicksFile, keyRangesFile, apiKeysFile)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	first := apikeyentity.APIKeyModel{UserID: 1, Hash: "hash-of-first", Scopes: apikeyentity.Scopes}
+	second := apikeyentity.APIKeyModel{UserID: 1, Hash: "hash-of-second", Scopes: apikeyentity.Scopes}
+	require.NoError(t, stor.SaveAPIKey(ctx, &first))
+	require.NoError(t, stor.SaveAPIKey(ctx, &second))
+	_, err = stor.RevokeAPIKey(ctx, 1, first.ID)
+	require.NoError(t, err)
+	require.NoError(t, stor.TouchAPIKey(ctx, second.ID, time.Now()))
+
+	//	reopen storage: the last record of every key wins
+	require.NoError(t, stor.Close())
+	stor, err = NewStorage(aliasesFile, usersFile, clicksFile, keyRangesFile, apiKeysFile)
+	require.NoError(t, err)
+	defer stor.Close()
+
+	keys, err := stor.FindAPIKeysByUserID(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.True(t, keys[0].RevokedFlag)
+	assert.NotNil(t, keys[1].LastUsedAt)
+
+	//	changes of keys do not take IDs
+	third := apikeyentity.APIKeyModel{UserID: 1, Hash: "hash-of-third", Scopes: apikeyentity.Scopes}
+	require.NoError(t, stor.SaveAPIKey(ctx, &third))
+	assert.Equal(t, second.ID+1, third.ID)
+}
+
+func TestFileStorage_MarkDeletedReplay(t *testing.T) {
+
+	dir := t.TempDir()
+	aliasesFile := filepath.Join(dir, "aliases.json")
+	usersFile := filepath.Join(dir, "users.json")
+	clicksFile := filepath.Join(dir, "clicks.json")
+	keyRangesFile := filepath.Join(dir, "keys.json")
+	apiKeysFile := filepath.Join(dir, "apikeys.json")
+
+	stor, err := NewStorage(aliasesFile, usersFile, clicksFile, keyRangesFile, apiKeysFile)
+	require.NoError(t, err)
+
+	require.NoError(t, stor.SaveAll(context.Background(), []aliasentity.AliasURLModel{
+		{UserID: 1, ShortKey: "000000001", LongURL: "https://qqq.ru/1"},
+		{UserID: 1, ShortKey: "000000002", LongURL: "https://qqq.ru/2"},
+	}))
+
+	node, err := stor.FindByShortKey(context.Background(), "000000001")
+	require.NoError(t, err)
+	require.NoError(t, stor.MarkDeleted(context.Background(), []uint64{node.ID}))
+
+	//	reopen storage: tombstones must be replayed
+	require.NoError(t, stor.Close())
+	stor, err = NewStorage(aliasesFile, usersFile, clicksFile, keyRangesFile, apiKeysFile)
+	require.NoError(t, err)
+	defer stor.Close()
+
+	node, err = stor.FindByShortKey(context.Background(), "000000001")
+	require.NoError(t, err)
+	assert.True(t, node.DeletedFlag)
+
+	node, err = stor.FindByShortKey(context.Background(), "000000002")
+	require.NoError(t, err)
+	assert.False(t, node.DeletedFlag)
+
+	//	tombstones do not take IDs
+	require.NoError(t, stor.Save(context.Background(), &aliasentity.AliasURLModel{UserID: 1, ShortKey: "000000003", LongURL: "https://qqq.ru/3"}))
+	node, err = stor.FindByShortKey(context.Background(), "000000003")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), node.ID)
+	assert.Equal(t, "000000003", stor.GetLastShortKey())
+}
+
+func TestFileStorage_MarkExpiredReplay(t *testing.T) {
+
+	dir := t.TempDir()
+	aliasesFile := filepath.Join(dir, "aliases.json")
+	usersFile := filepath.Join(dir, "users.json")
+	clicksFile := filepath.Join(dir, "clicks.json")
+	keyRangesFile := filepath.Join(dir, "keys.json")
+	apiKeysFile := filepath.Join(dir, "apikeys.json")
+
+	stor, err := NewStorage(aliasesFile, usersFile, clicksFile, keyRangesFile, apiKeysFile)
+	require.NoError(t, err)
+
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	require.NoError(t, stor.SaveAll(context.Background(), []aliasentity.AliasURLModel{
+		{UserID: 1, ShortKey: "000000001", LongURL: "https://qqq.ru/1", ExpiresAt: &past},
+		{UserID: 1, ShortKey: "000000002", LongURL: "https://qqq.ru/2", ExpiresAt: &future},
+	}))
+	count, err := stor.MarkExpired(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	//	reopen storage: expiries must be replayed, also after compaction
+	for _, opts := range [][]Option{nil, {WithCompactThreshold(1)}} {
+		require.NoError(t, stor.Close())
+		stor, err = NewStorage(aliasesFile, usersFile, clicksFile, keyRangesFile, apiKeysFile, opts...)
+		require.NoError(t, err)
+		require.NoError(t, stor.Compact(context.Background()))
+
+		node, err := stor.FindByShortKey(context.Background(), "000000001")
+		require.NoError(t, err)
+		assert.True(t, node.ExpiredFlag)
+
+		node, err = stor.FindByShortKey(context.Background(), "000000002")
+		require.NoError(t, err)
+		assert.False(t, node.ExpiredFlag)
+
+		count, err = stor.MarkExpired(context.Background(), now)
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+	}
+	require.NoError(t, stor.Close())
+}
+
+// BenchmarkFileStorage_Find shows that lookup cost does not depend on the size of the aliases file
+func TestFileStorage_AdminReplay(t *testing.T) {
+
+	dir := t.TempDir()
+	aliasesFile := filepath.Join(dir, "aliases.json")
+	usersFile := filepath.Join(dir, "users.json")
+	clicksFile := filepath.Join(dir, "clicks.json")
+	keyRangesFile := filepath.Join(dir, "keys.json")
+	apiKeysFile := filepath.Join(dir, "apikeys.json")
+
+	stor, err := NewStorage(aliasesFile, usersFile, clicksFile, keyRangesFile, apiKeysFile)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	userID, err := stor.CreateUser(ctx)
+	require.NoError(t, err)
+	ok, err := stor.RegisterUser(ctx, &userentity.UserModel{UserID: userID, Login: "alice", PasswordHash: "hash"})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, stor.SaveAll(ctx, []aliasentity.AliasURLModel{
+		{UserID: userID, ShortKey: "000000001", LongURL: "https://qqq.ru/1"},
+		{UserID: userID, ShortKey: "000000002", LongURL: "https://qqq.ru/2"},
+		{UserID: userID, ShortKey: "000000003", LongURL: "https://qqq.ru/3"},
+		{UserID: userID, ShortKey: "000000004", LongURL: "https://qqq.ru/4"},
+	}))
+
+	//	the purge compacts the file, the disabled flag of the first alias is kept in its record
+	_, err = stor.SetDisabled(ctx, "000000001", true)
+	require.NoError(t, err)
+	_, err = stor.PurgeAlias(ctx, "000000002")
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(aliasesFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "https://qqq.ru/2")
+
+	_, err = stor.SetDisabled(ctx, "000000003", true)
+	require.NoError(t, err)
+
+	//	a purge which was not compacted is replayed too
+	node, err := stor.FindByShortKey(ctx, "000000004")
+	require.NoError(t, err)
+	require.NoError(t, stor.Close())
+
+	file, err := os.OpenFile(aliasesFile, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = fmt.Fprintf(file, `{"uuid":%d,"user_id":%d,"short_url":"000000004","purge":true}`+"\n", node.ID, userID)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	stor, err = NewStorage(aliasesFile, usersFile, clicksFile, keyRangesFile, apiKeysFile)
+	require.NoError(t, err)
+	defer stor.Close()
+
+	for shortKey, disabled := range map[string]bool{"000000001": true, "000000003": true} {
+		node, err := stor.FindByShortKey(ctx, shortKey)
+		require.NoError(t, err)
+		assert.Equal(t, disabled, node.DisabledFlag, shortKey)
+	}
+	for _, shortKey := range []string{"000000002", "000000004"} {
+		_, err := stor.FindByShortKey(ctx, shortKey)
+		assert.Error(t, err, shortKey)
+	}
+
+	nodes, err := stor.FindByUserID(ctx, userID)
+	require.NoError(t, err)
+	assert.Len(t, nodes, 2)
+}
+
+func BenchmarkFileStorage_Find(b *testing.B) {
+
+	const aliasesPerUser = 10
+
+	for _, records := range []int{1000, 10000, 100000} {
+
+		dir := b.TempDir()
+		stor, err := NewStorage(
+			filepath.Join(dir, "aliases.json"),
+			filepath.Join(dir, "users.json"),
+			filepath.Join(dir, "clicks.json"),
+			filepath.Join(dir, "keys.json"),
+			filepath.Join(dir, "apikeys.json"),
+		)
+		require.NoError(b, err)
+
+		nodes := make([]aliasentity.AliasURLModel, records)
+		for i := range nodes {
+			nodes[i] = aliasentity.AliasURLModel{
+				UserID:   uint64(i / aliasesPerUser),
+				ShortKey: fmt.Sprintf("%09d", i+1),
+				LongURL:  fmt.Sprintf("https://qqq.ru/%d", i+1),
+			}
+		}
+		require.NoError(b, stor.SaveAll(context.Background(), nodes))
+
+		b.Run(fmt.Sprintf("FindByShortKey/records=%d", records), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := stor.FindByShortKey(context.Background(), nodes[i%records].ShortKey); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("FindByLongURL/records=%d", records), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := stor.FindByLongURL(context.Background(), nodes[i%records].LongURL); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("FindByUserID/records=%d", records), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := stor.FindByUserID(context.Background(), nodes[i%records].UserID); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		stor.Close()
+	}
+}
+
+func TestFileStorage_ReserveKeyRangeShared(t *testing.T) {
+
+	dir := t.TempDir()
+	newStorage := func() *Storage {
+		stor, err := NewStorage(
+			filepath.Join(dir, "aliases.json"),
+			filepath.Join(dir, "users.json"),
+			filepath.Join(dir, "clicks.json"),
+			filepath.Join(dir, "keys.json"),
+			filepath.Join(dir, "apikeys.json"),
+		)
+		require.NoError(t, err)
+		return stor
+	}
+
+	//	storages opened on the same files act like instances sharing a volume
+	first, second := newStorage(), newStorage()
+	defer first.Close()
+	defer second.Close()
+
+	start, err := first.ReserveKeyRange(context.Background(), "sequential-9", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), start)
+
+	start, err = second.ReserveKeyRange(context.Background(), "sequential-9", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), start)
+
+	start, err = first.ReserveKeyRange(context.Background(), "sequential-9", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(20), start)
+
+	//	reserved ranges survive restart
+	restarted := newStorage()
+	defer restarted.Close()
+
+	start, err = restarted.ReserveKeyRange(context.Background(), "sequential-9", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(30), start)
+}
+
+func TestFileStorage_ReserveKeyRangeTornRecord(t *testing.T) {
+
+	dir := t.TempDir()
+	keyRangesFile := filepath.Join(dir, "keys.json")
+	stor, err := NewStorage(
+		filepath.Join(dir, "aliases.json"),
+		filepath.Join(dir, "users.json"),
+		filepath.Join(dir, "clicks.json"),
+		keyRangesFile,
+		filepath.Join(dir, "apikeys.json"),
+	)
+	require.NoError(t, err)
+	defer stor.Close()
+
+	ctx := context.Background()
+	starts := make(map[uint64]bool)
+	start, err := stor.ReserveKeyRange(ctx, "sequential-9", 0, 10)
+	require.NoError(t, err)
+	starts[start] = true
+
+	//	a reservation torn by a crash
+	file, err := os.OpenFile(keyRangesFile, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"name":"sequential-9","ne`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	//	ranges reserved after the torn record don't overlap
+	for i := 0; i < 2; i++ {
+		start, err = stor.ReserveKeyRange(ctx, "sequential-9", 0, 10)
+		require.NoError(t, err)
+		assert.False(t, starts[start], "range from %d is reserved twice", start)
+		starts[start] = true
+	}
+	assert.Equal(t, map[uint64]bool{0: true, 10: true, 20: true}, starts)
+
+	data, err := os.ReadFile(keyRangesFile)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `"ne{`)
+}
+
+func TestFileStorage_TornRecord(t *testing.T) {
+
+	testCases := []struct {
+		testName string
+		tail     string
+		wantErr  bool
+	}{
+		{testName: "partial last record", tail: `{"uuid":3,"user_id":1,"short_u`},
+		{testName: "complete last record without line break", tail: `{"uuid":3,"user_id":1,"short_url":"000000003","original_url":"https://qqq.ru/3"}`},
+		{testName: "garbage last line", tail: "\x00\x00\x00\n"},
+		{testName: "broken record in the middle", tail: "{\"uuid\":3\n" + `{"uuid":4,"user_id":1,"short_url":"000000004","original_url":"https://qqq.ru/4"}` + "\n", wantErr: true},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.testName, func(t *testing.T) {
+
+			dir := t.TempDir()
+			aliasesFile := filepath.Join(dir, "aliases.json")
+			usersFile := filepath.Join(dir, "users.json")
+			clicksFile := filepath.Join(dir, "clicks.json")
+			keyRangesFile := filepath.Join(dir, "keys.json")
+			apiKeysFile := filepath.Join(dir, "apikeys.json")
+
+			stor, err := NewStorage(aliasesFile, usersFile, clicksFile, keyRangesFile, apiKeysFile)
+			require.NoError(t, err)
+			require.NoError(t, stor.SaveAll(context.Background(), []aliasentity.AliasURLModel{
+				{UserID: 1, ShortKey: "000000001", LongURL: "https://qqq.ru/1"},
+				{UserID: 1, ShortKey: "000000002", LongURL: "https://qqq.ru/2"},
+			}))
+			require.NoError(t, stor.Close())
+
+			info, err := os.Stat(aliasesFile)
+			require.NoError(t, err)
+			validSize := info.Size()
+
+			f, err := os.OpenFile(aliasesFile, os.O_WRONLY|os.O_APPEND, 0644)
+			require.NoError(t, err)
+			_, err = f.WriteString(test.tail)
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+
+			stor, err = NewStorage(aliasesFile, usersFile, clicksFile, keyRangesFile, apiKeysFile)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer stor.Close()
+
+			info, err = os.Stat(aliasesFile)
+			require.NoError(t, err)
+			assert.Equal(t, validSize, info.Size())
+
+			require.NoError(t, stor.Save(context.Background(), &aliasentity.AliasURLModel{UserID: 1, ShortKey: "000000003", LongURL: "https://qqq.ru/3"}))
+			for _, shortKey := range []string{"000000001", "000000002", "000000003"} {
+				_, err := stor.FindByShortKey(context.Background(), shortKey)
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestFileStorage_Compact(t *testing.T) {
+
+	dir := t.TempDir()
+	aliasesFile := filepath.Join(dir, "aliases.json")
+	usersFile := filepath.Join(dir, "users.json")
+	clicksFile := filepath.Join(dir, "clicks.json")
+	keyRangesFile := filepath.Join(dir, "keys.json")
+	apiKeysFile := filepath.Join(dir, "apikeys.json")
+
+	readLines := func() []string {
+		data, err := os.ReadFile(aliasesFile)
+		require.NoError(t, err)
+		return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+
+	stor, err := NewStorage(aliasesFile, usersFile, clicksFile, keyRangesFile, apiKeysFile, WithFsync(true))
+	require.NoError(t, err)
+
+	require.NoError(t, stor.SaveAll(context.Background(), []aliasentity.AliasURLModel{
+		{UserID: 1, ShortKey: "000000001", LongURL: "https://qqq.ru/1"},
+		{UserID: 1, ShortKey: "000000002", LongURL: "https://qqq.ru/2"},
+		{UserID: 2, ShortKey: "000000003", LongURL: "https://qqq.ru/3"},
+	}))
+	require.NoError(t, stor.MarkDeleted(context.Background(), []uint64{1, 3}))
+	require.Len(t, readLines(), 5)
+
+	require.NoError(t, stor.Compact(context.Background()))
+	lines := readLines()
+	require.Len(t, lines, 3)
+	for _, line := range lines {
+		assert.NotContains(t, line, "tombstone")
+	}
+
+	check := func(stor *Storage) {
+		for _, want := range []struct {
+			shortKey string
+			deleted  bool
+		}{
+			{shortKey: "000000001", deleted: true},
+			{shortKey: "000000002", deleted: false},
+			{shortKey: "000000003", deleted: true},
+		} {
+			node, err := stor.FindByShortKey(context.Background(), want.shortKey)
+			require.NoError(t, err)
+			assert.Equal(t, want.deleted, node.DeletedFlag, want.shortKey)
+		}
+
+		nodes, err := stor.FindByUserID(context.Background(), 1)
+		require.NoError(t, err)
+		assert.Len(t, nodes, 2)
+		assert.Equal(t, "000000003", stor.GetLastShortKey())
+	}
+	check(stor)
+
+	//	compacted file is read after restart
+	require.NoError(t, stor.Close())
+	stor, err = NewStorage(aliasesFile, usersFile, clicksFile, keyRangesFile, apiKeysFile, WithCompactThreshold(1))
+	require.NoError(t, err)
+	defer stor.Close()
+	check(stor)
+
+	//	deleting compacts the file automatically when threshold is reached
+	require.NoError(t, stor.MarkDeleted(context.Background(), []uint64{2}))
+	assert.Len(t, readLines(), 3)
+
+	node, err := stor.FindByShortKey(context.Background(), "000000002")
+	require.NoError(t, err)
+	assert.True(t, node.DeletedFlag)
+
+	require.NoError(t, stor.Save(context.Background(), &aliasentity.AliasURLModel{UserID: 1, ShortKey: "000000004", LongURL: "https://qqq.ru/4"}))
+	node, err = stor.FindByShortKey(context.Background(), "000000004")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), node.ID)
+}
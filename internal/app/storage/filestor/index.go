@@ -0,0 +1,204 @@
+package filestor
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+)
+
+// recordPos is the position of a record in the aliases file
+type recordPos struct {
+	offset int64 //	offset of the first byte of the record
+	size   int   //	size of the record without line break
+}
+
+// aliasIndex keeps positions of alias records in the aliases file,
+// so a lookup costs one map access and one read of the file
+type aliasIndex struct {
+	byShortKey map[string]recordPos
+	byLongURL  map[string]recordPos
+	byUserID   map[uint64][]recordPos
+	deleted    map[uint64]struct{}  //	IDs of aliases marked as deleted
+	disabled   map[uint64]struct{}  //	IDs of disabled aliases
+	expired    map[uint64]struct{}  //	IDs of aliases marked as expired
+	expiring   map[uint64]time.Time //	expiry times of aliases which are not marked as expired yet
+	size       int64                //	size of the indexed part of the file
+	garbage    int64                //	size of superseded records and tombstones
+}
+
+// ------------------------------------------------------------
+//
+//	aliasIndex constructor
+func newAliasIndex() *aliasIndex {
+
+	return &aliasIndex{
+		byShortKey: make(map[string]recordPos),
+		byLongURL:  make(map[string]recordPos),
+		byUserID:   make(map[uint64][]recordPos),
+		deleted:    make(map[uint64]struct{}),
+		disabled:   make(map[uint64]struct{}),
+		expired:    make(map[uint64]struct{}),
+		expiring:   make(map[uint64]time.Time),
+	}
+}
+
+// ------------------------------------------------------------
+//
+//	Add the record of size bytes appended to the end of the file.
+//	The first record of a short key wins, later ones are superseded
+func (i *aliasIndex) add(record *aliasRecord, size int) {
+
+	pos := recordPos{offset: i.size, size: size}
+	i.size += int64(size) + 1
+
+	if record.Tombstone {
+		i.deleted[record.ID] = struct{}{}
+		i.garbage += int64(size) + 1
+		return
+	}
+
+	if record.Switch {
+		i.setDisabled(record.ID, record.DisabledFlag)
+		i.garbage += int64(size) + 1
+		return
+	}
+
+	if record.Purge {
+		i.purge(record)
+		i.garbage += int64(size) + 1
+		return
+	}
+
+	if record.Expire {
+		i.expired[record.ID] = struct{}{}
+		delete(i.expiring, record.ID)
+		i.garbage += int64(size) + 1
+		return
+	}
+
+	if _, ok := i.byShortKey[record.ShortKey]; ok {
+		i.garbage += int64(size) + 1
+		return
+	}
+	i.byShortKey[record.ShortKey] = pos
+
+	if record.DeletedFlag {
+		i.deleted[record.ID] = struct{}{}
+	}
+	i.setDisabled(record.ID, record.DisabledFlag)
+	switch {
+	case record.ExpiredFlag:
+		i.expired[record.ID] = struct{}{}
+	case record.ExpiresAt != nil:
+		i.expiring[record.ID] = *record.ExpiresAt
+	}
+	//	the newest alias of the URL is found, older ones are deleted or expired
+	i.byLongURL[record.LongURL] = pos
+	i.byUserID[record.UserID] = append(i.byUserID[record.UserID], pos)
+}
+
+// ------------------------------------------------------------
+//
+//	Positions of the records which are not superseded, in the order of the file
+func (i *aliasIndex) live() []recordPos {
+
+	positions := make([]recordPos, 0, len(i.byShortKey))
+	for _, pos := range i.byShortKey {
+		positions = append(positions, pos)
+	}
+	sort.Slice(positions, func(a, b int) bool { return positions[a].offset < positions[b].offset })
+	return positions
+}
+
+// ------------------------------------------------------------
+//
+//	Check if alias is marked as deleted
+func (i *aliasIndex) isDeleted(node *aliasentity.AliasURLModel) bool {
+
+	_, ok := i.deleted[node.ID]
+	return ok
+}
+
+// ------------------------------------------------------------
+//
+//	Check if alias is marked as expired
+func (i *aliasIndex) isExpired(node *aliasentity.AliasURLModel) bool {
+
+	_, ok := i.expired[node.ID]
+	return ok
+}
+
+// ------------------------------------------------------------
+//
+//	IDs of aliases whose expiry time has passed by now, but which are not marked as expired yet.
+//	IDs are sorted, so expiries are appended in the order of aliases
+func (i *aliasIndex) expiredBy(now time.Time) []uint64 {
+
+	var aliasesID []uint64
+	for aliasID, expiresAt := range i.expiring {
+		if !now.Before(expiresAt) {
+			aliasesID = append(aliasesID, aliasID)
+		}
+	}
+	sort.Slice(aliasesID, func(a, b int) bool { return aliasesID[a] < aliasesID[b] })
+	return aliasesID
+}
+
+// ------------------------------------------------------------
+//
+//	Check if alias is disabled
+func (i *aliasIndex) isDisabled(node *aliasentity.AliasURLModel) bool {
+
+	_, ok := i.disabled[node.ID]
+	return ok
+}
+
+// ------------------------------------------------------------
+//
+//	Set or clear disabled flag of alias
+func (i *aliasIndex) setDisabled(aliasID uint64, disabled bool) {
+
+	if disabled {
+		i.disabled[aliasID] = struct{}{}
+	} else {
+		delete(i.disabled, aliasID)
+	}
+}
+
+// ------------------------------------------------------------
+//
+//	Drop the alias of the purge record from the index, its record becomes garbage.
+//	Purges are rare, so the original URL of the alias is looked for by position
+func (i *aliasIndex) purge(record *aliasRecord) {
+
+	pos, ok := i.byShortKey[record.ShortKey]
+	if !ok {
+		return
+	}
+	delete(i.byShortKey, record.ShortKey)
+	i.garbage += int64(pos.size) + 1
+
+	for longURL, p := range i.byLongURL {
+		if p == pos {
+			delete(i.byLongURL, longURL)
+			break
+		}
+	}
+
+	positions := i.byUserID[record.UserID]
+	for n, p := range positions {
+		if p == pos {
+			i.byUserID[record.UserID] = append(positions[:n:n], positions[n+1:]...)
+			break
+		}
+	}
+	if len(i.byUserID[record.UserID]) == 0 {
+		delete(i.byUserID, record.UserID)
+	}
+
+	delete(i.deleted, record.ID)
+	delete(i.disabled, record.ID)
+	delete(i.expired, record.ID)
+	delete(i.expiring, record.ID)
+}
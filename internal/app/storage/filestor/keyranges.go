@@ -0,0 +1,89 @@
+package filestor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+)
+
+// keyRangeRecord is a line of the key ranges file: the next free value of a key counter
+type keyRangeRecord struct {
+	Name string `json:"name"`
+	Next uint64 `json:"next"`
+}
+
+// ------------------------------------------------------------
+//
+//	Reserve range of key counter values
+//	This is interfase method of "Storager" interface.
+//	The file is locked while the range is reserved, so processes
+//	sharing the file never get the same range
+//	Input:
+//		name string - name of the counter
+//		start uint64 - value of a new counter
+//		size uint64 - count of reserved values
+//	Output:
+//		uint64 - the first value of the range
+func (s *Storage) ReserveKeyRange(ctx context.Context, name string, start, size uint64) (uint64, error) {
+
+	s.keyRangesMx.Lock()
+	defer s.keyRangesMx.Unlock()
+
+	file, err := os.OpenFile(s.keyRangesFileName, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	if err = lockFile(file); err != nil {
+		return 0, err
+	}
+	defer unlockFile(file)
+
+	//	other processes could move the counter, so it is read from the file every time
+	next := start
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			//	a torn last record is not a reservation, it is cut off,
+			//	so the new record is not appended to its line
+			if len(line) > 0 {
+				if err = cutTornRecord(file, offset); err != nil {
+					return 0, err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		offset += int64(len(line))
+
+		var record keyRangeRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			continue
+		}
+		if record.Name == name {
+			next = record.Next
+		}
+	}
+
+	data, err := json.Marshal(keyRangeRecord{Name: name, Next: next + size})
+	if err != nil {
+		return 0, err
+	}
+	if _, err = file.Write(append(data, '\n')); err != nil {
+		return 0, err
+	}
+
+	//	a range handed out twice after a crash gives duplicate keys, so the record is always flushed
+	if err = file.Sync(); err != nil {
+		return 0, err
+	}
+	return next, nil
+}
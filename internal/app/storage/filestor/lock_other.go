@@ -0,0 +1,16 @@
+//go:build !unix
+
+package filestor
+
+import "os"
+
+// lockFile does nothing where flock is not available: the file
+// must not be shared by several processes on such systems
+func lockFile(file *os.File) error {
+	return nil
+}
+
+// unlockFile does nothing where flock is not available
+func unlockFile(file *os.File) error {
+	return nil
+}
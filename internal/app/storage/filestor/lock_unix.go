@@ -0,0 +1,18 @@
+//go:build unix
+
+package filestor
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes exclusive lock of the file, it waits while other process holds it
+func lockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+}
+
+// unlockFile releases the lock taken by lockFile
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,19 @@
+package filestor
+
+// Option configures Storage
+type Option func(*Storage)
+
+// WithFsync makes every write to the files flushed to the disk before it is reported as done
+func WithFsync(enabled bool) Option {
+	return func(s *Storage) {
+		s.fsync = enabled
+	}
+}
+
+// WithCompactThreshold sets the size in bytes of superseded records and tombstones
+// in the aliases file after which the file is compacted. Zero disables automatic compaction
+func WithCompactThreshold(size int64) Option {
+	return func(s *Storage) {
+		s.compactThreshold = size
+	}
+}
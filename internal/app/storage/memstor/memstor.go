@@ -9,18 +9,35 @@ package memstor
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+	"github.com/Schalure/urlalias/internal/app/models/clickentity"
+	"github.com/Schalure/urlalias/internal/app/models/statsentity"
 	"github.com/Schalure/urlalias/internal/app/models/userentity"
 )
 
-// Type for storage long URL and their alias keys
+// Type for storage long URL and their alias keys.
+// Storage is safe for concurrent use
 type Storage struct {
 	//	[key, value] = [ShortKey, LongURL]
 	aliases []aliasentity.AliasURLModel
 	users   []userentity.UserModel
+	clicks  []clickentity.ClickModel
+
+	mx       sync.RWMutex //	guards aliases, users, lastKey and lastID
+	clicksMx sync.Mutex   //	clicks are written by the background worker
+
+	keyRangesMx sync.Mutex        //	guards keyRanges
+	keyRanges   map[string]uint64 //	[key, value] = [counter name, next value]
+
+	apiKeysMx sync.RWMutex //	guards apiKeys
+	apiKeys   []apikeyentity.APIKeyModel
 
 	lastKey string
+	lastID  uint64
 }
 
 // ------------------------------------------------------------
@@ -31,6 +48,9 @@ func NewStorage() (*Storage, error) {
 	var s Storage
 	s.aliases = make([]aliasentity.AliasURLModel, 0)
 	s.users = make([]userentity.UserModel, 0)
+	s.clicks = make([]clickentity.ClickModel, 0)
+	s.keyRanges = make(map[string]uint64)
+	s.apiKeys = make([]apikeyentity.APIKeyModel, 0)
 
 	return &s, nil
 }
@@ -38,7 +58,10 @@ func NewStorage() (*Storage, error) {
 // ------------------------------------------------------------
 //
 //	Create new user
-func (s *Storage) CreateUser() (uint64, error) {
+func (s *Storage) CreateUser(ctx context.Context) (uint64, error) {
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
 
 	user := userentity.UserModel{
 		UserID: uint64(len(s.users)),
@@ -48,13 +71,87 @@ func (s *Storage) CreateUser() (uint64, error) {
 	return user.UserID, nil
 }
 
+// ------------------------------------------------------------
+//
+//	Find user by ID
+//	This is interfase method of "Storager" interface
+func (s *Storage) FindUserByID(ctx context.Context, userID uint64) (*userentity.UserModel, error) {
+
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	if userID >= uint64(len(s.users)) {
+		return nil, fmt.Errorf("not found")
+	}
+	user := s.users[userID]
+	return &user, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Find registered user by login
+//	This is interfase method of "Storager" interface
+func (s *Storage) FindUserByLogin(ctx context.Context, login string) (*userentity.UserModel, error) {
+
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	for _, user := range s.users {
+		if user.IsRegistered() && user.Login == login {
+			return &user, nil
+		}
+	}
+	return nil, fmt.Errorf("not found")
+}
+
+// ------------------------------------------------------------
+//
+//	Set login and password hash of the anonymous user
+//	This is interfase method of "Storager" interface
+//	Output:
+//		bool - false if the user is already registered or the login is taken
+func (s *Storage) RegisterUser(ctx context.Context, user *userentity.UserModel) (bool, error) {
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if user.UserID >= uint64(len(s.users)) {
+		return false, fmt.Errorf("not found")
+	}
+	if s.users[user.UserID].IsRegistered() {
+		return false, nil
+	}
+	for _, u := range s.users {
+		if u.Login == user.Login {
+			return false, nil
+		}
+	}
+
+	s.users[user.UserID].Login = user.Login
+	s.users[user.UserID].PasswordHash = user.PasswordHash
+	return true, nil
+}
+
 // ------------------------------------------------------------
 //
 //	Save pair "shortKey, longURL" to db
+//	Output:
+//		error - aliasentity.ErrShortKeyTaken if there is an alias with the short key
 func (s *Storage) Save(ctx context.Context, urlAliasNode *aliasentity.AliasURLModel) error {
 
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.hasShortKey(urlAliasNode.ShortKey) {
+		return aliasentity.ErrShortKeyTaken
+	}
+
+	s.lastID++
+	urlAliasNode.ID = s.lastID
 	s.aliases = append(s.aliases, *urlAliasNode)
-	s.lastKey = urlAliasNode.ShortKey
+	if !urlAliasNode.IsCustom {
+		s.lastKey = urlAliasNode.ShortKey
+	}
 
 	return nil
 }
@@ -66,13 +163,28 @@ func (s *Storage) Save(ctx context.Context, urlAliasNode *aliasentity.AliasURLMo
 //	Input:
 //		urlAliasNode []repositories.AliasURLModel
 //	Output:
-//		error - if not nil, can not save "[]storage.AliasURLModel"
+//		error - aliasentity.ErrShortKeyTaken if a short key is taken or repeated, nothing is saved
 func (s *Storage) SaveAll(ctx context.Context, urlAliasNodes []aliasentity.AliasURLModel) error {
 
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	batchKeys := make(map[string]struct{}, len(urlAliasNodes))
+	for _, node := range urlAliasNodes {
+		if _, ok := batchKeys[node.ShortKey]; ok || s.hasShortKey(node.ShortKey) {
+			return aliasentity.ErrShortKeyTaken
+		}
+		batchKeys[node.ShortKey] = struct{}{}
+	}
+
 	for _, node := range urlAliasNodes {
 
+		s.lastID++
+		node.ID = s.lastID
 		s.aliases = append(s.aliases, node)
-		s.lastKey = node.ShortKey
+		if !node.IsCustom {
+			s.lastKey = node.ShortKey
+		}
 	}
 	return nil
 }
@@ -88,12 +200,26 @@ func (s *Storage) SaveAll(ctx context.Context, urlAliasNodes []aliasentity.Alias
 //		error - if can not find "urlAliasNode" by short key
 func (s *Storage) FindByShortKey(ctx context.Context, shortKey string) (*aliasentity.AliasURLModel, error) {
 
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
 	for _, node := range s.aliases {
 		if node.ShortKey == shortKey {
 			return &node, nil
 		}
 	}
-	return nil, fmt.Errorf("not found")
+	return nil, aliasentity.ErrNotFound
+}
+
+// hasShortKey reports whether there is an alias with the short key. The caller holds the lock
+func (s *Storage) hasShortKey(shortKey string) bool {
+
+	for i := range s.aliases {
+		if s.aliases[i].ShortKey == shortKey {
+			return true
+		}
+	}
+	return false
 }
 
 // ------------------------------------------------------------
@@ -107,17 +233,38 @@ func (s *Storage) FindByShortKey(ctx context.Context, shortKey string) (*aliasen
 //		error - if can not find "urlAliasNode" by long URL
 func (s *Storage) FindByLongURL(ctx context.Context, longURL string) (*aliasentity.AliasURLModel, error) {
 
-	for _, node := range s.aliases {
-		if node.LongURL == longURL {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	//	the newest alias of the URL is found, older ones are deleted or expired
+	for i := len(s.aliases) - 1; i >= 0; i-- {
+		if s.aliases[i].LongURL == longURL {
+			node := s.aliases[i]
 			return &node, nil
 		}
 	}
-	return nil, fmt.Errorf("not found")
+	return nil, aliasentity.ErrNotFound
 }
 
 // FindAllByLongURLs find all aliases by slice of original URL and return map[original_url] aliasentity.AliasURLModel or error
 func (s *Storage) FindAllByLongURLs(ctx context.Context, longURL []string) (map[string]*aliasentity.AliasURLModel, error) {
-	return nil, fmt.Errorf("no implemented")
+
+	wanted := make(map[string]bool, len(longURL))
+	for _, u := range longURL {
+		wanted[u] = true
+	}
+
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	nodes := make(map[string]*aliasentity.AliasURLModel)
+	for _, node := range s.aliases {
+		if wanted[node.LongURL] {
+			node := node
+			nodes[node.LongURL] = &node
+		}
+	}
+	return nodes, nil
 }
 
 // ------------------------------------------------------------
@@ -125,6 +272,9 @@ func (s *Storage) FindAllByLongURLs(ctx context.Context, longURL []string) (map[
 //	Find all "urlAliasNode models.AliasURLModel" by UserID
 func (s *Storage) FindByUserID(ctx context.Context, userID uint64) ([]aliasentity.AliasURLModel, error) {
 
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
 	var nodes []aliasentity.AliasURLModel
 
 	for _, node := range s.aliases {
@@ -140,6 +290,9 @@ func (s *Storage) FindByUserID(ctx context.Context, userID uint64) ([]aliasentit
 //	Mark aliases like "deleted" by aliasesID
 func (s *Storage) MarkDeleted(ctx context.Context, aliasesID []uint64) error {
 
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
 	for _, aliasID := range aliasesID {
 		select {
 		case <-ctx.Done():
@@ -155,6 +308,167 @@ func (s *Storage) MarkDeleted(ctx context.Context, aliasesID []uint64) error {
 	return nil
 }
 
+// ------------------------------------------------------------
+//
+//	Mark aliases like "expired" if their expiry time has passed
+//	Output:
+//		int - count of marked aliases
+//		error
+func (s *Storage) MarkExpired(ctx context.Context, now time.Time) (int, error) {
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	count := 0
+	for i := range s.aliases {
+		node := &s.aliases[i]
+		if !node.ExpiredFlag && node.ExpiresAt != nil && !now.Before(*node.ExpiresAt) {
+			node.ExpiredFlag = true
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Find aliases of all users by filter in the order of their IDs
+//	This is interfase method of "Storager" interface
+func (s *Storage) SearchAliases(ctx context.Context, filter aliasentity.Filter) ([]aliasentity.AliasURLModel, error) {
+
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	var nodes []aliasentity.AliasURLModel
+	skip := filter.Offset
+	for i := range s.aliases {
+		if !filter.Match(&s.aliases[i]) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		if filter.Limit > 0 && len(nodes) == filter.Limit {
+			break
+		}
+		nodes = append(nodes, s.aliases[i])
+	}
+	return nodes, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Disable or re-enable alias by short key
+//	This is interfase method of "Storager" interface
+//	Output:
+//		bool - false if the alias is not found
+func (s *Storage) SetDisabled(ctx context.Context, shortKey string, disabled bool) (bool, error) {
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for i := range s.aliases {
+		if s.aliases[i].ShortKey == shortKey {
+			s.aliases[i].DisabledFlag = disabled
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Remove alias by short key and its clicks
+//	This is interfase method of "Storager" interface
+//	Output:
+//		bool - false if the alias is not found
+func (s *Storage) PurgeAlias(ctx context.Context, shortKey string) (bool, error) {
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for i := range s.aliases {
+		if s.aliases[i].ShortKey == shortKey {
+			s.aliases = append(s.aliases[:i], s.aliases[i+1:]...)
+			s.purgeClicks(shortKey)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Remove redirects of the short key
+func (s *Storage) purgeClicks(shortKey string) {
+
+	s.clicksMx.Lock()
+	defer s.clicksMx.Unlock()
+
+	clicks := s.clicks[:0]
+	for _, click := range s.clicks {
+		if click.ShortKey != shortKey {
+			clicks = append(clicks, click)
+		}
+	}
+	s.clicks = clicks
+}
+
+// ------------------------------------------------------------
+//
+//	Count users and aliases
+//	This is interfase method of "Storager" interface
+func (s *Storage) GetServiceStats(ctx context.Context, now time.Time) (*statsentity.ServiceStatsModel, error) {
+
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	stats := &statsentity.ServiceStatsModel{
+		Users: uint64(len(s.users)),
+	}
+	for i := range s.users {
+		if s.users[i].IsRegistered() {
+			stats.RegisteredUsers++
+		}
+	}
+	for i := range s.aliases {
+		stats.AddAlias(&s.aliases[i], now)
+	}
+	return stats, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Save batch of redirects
+//	This is interfase method of "AnalyticsSink" interface
+func (s *Storage) SaveClicks(ctx context.Context, clicks []clickentity.ClickModel) error {
+
+	s.clicksMx.Lock()
+	defer s.clicksMx.Unlock()
+
+	s.clicks = append(s.clicks, clicks...)
+	return nil
+}
+
+// ------------------------------------------------------------
+//
+//	Get statistics of redirects by short key
+//	This is interfase method of "AnalyticsSink" interface
+func (s *Storage) GetClickStats(ctx context.Context, shortKey string) (*clickentity.StatsModel, error) {
+
+	s.clicksMx.Lock()
+	defer s.clicksMx.Unlock()
+
+	var clicks []clickentity.ClickModel
+	for _, click := range s.clicks {
+		if click.ShortKey == shortKey {
+			clicks = append(clicks, click)
+		}
+	}
+	return clickentity.NewStats(clicks), nil
+}
+
 // ------------------------------------------------------------
 //
 //	Get the last saved key
@@ -162,9 +476,132 @@ func (s *Storage) MarkDeleted(ctx context.Context, aliasesID []uint64) error {
 //	Output:
 //		string - last saved key
 func (s *Storage) GetLastShortKey() string {
+
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
 	return s.lastKey
 }
 
+// ------------------------------------------------------------
+//
+//	Reserve range of key counter values
+//	This is interfase method of "Storager" interface
+//	Input:
+//		name string - name of the counter
+//		start uint64 - value of a new counter
+//		size uint64 - count of reserved values
+//	Output:
+//		uint64 - the first value of the range
+func (s *Storage) ReserveKeyRange(ctx context.Context, name string, start, size uint64) (uint64, error) {
+
+	s.keyRangesMx.Lock()
+	defer s.keyRangesMx.Unlock()
+
+	next, ok := s.keyRanges[name]
+	if !ok {
+		next = start
+	}
+	s.keyRanges[name] = next + size
+	return next, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Save new API key and set its ID
+//	This is interfase method of "Storager" interface
+func (s *Storage) SaveAPIKey(ctx context.Context, key *apikeyentity.APIKeyModel) error {
+
+	s.apiKeysMx.Lock()
+	defer s.apiKeysMx.Unlock()
+
+	key.ID = uint64(len(s.apiKeys)) + 1
+	s.apiKeys = append(s.apiKeys, copyAPIKey(key))
+	return nil
+}
+
+// ------------------------------------------------------------
+//
+//	Find API key by hash of the key
+//	This is interfase method of "Storager" interface
+func (s *Storage) FindAPIKeyByHash(ctx context.Context, hash string) (*apikeyentity.APIKeyModel, error) {
+
+	s.apiKeysMx.RLock()
+	defer s.apiKeysMx.RUnlock()
+
+	for i := range s.apiKeys {
+		if s.apiKeys[i].Hash == hash {
+			key := copyAPIKey(&s.apiKeys[i])
+			return &key, nil
+		}
+	}
+	return nil, fmt.Errorf("not found")
+}
+
+// ------------------------------------------------------------
+//
+//	Find all API keys of the user
+//	This is interfase method of "Storager" interface
+func (s *Storage) FindAPIKeysByUserID(ctx context.Context, userID uint64) ([]apikeyentity.APIKeyModel, error) {
+
+	s.apiKeysMx.RLock()
+	defer s.apiKeysMx.RUnlock()
+
+	var keys []apikeyentity.APIKeyModel
+	for i := range s.apiKeys {
+		if s.apiKeys[i].UserID == userID {
+			keys = append(keys, copyAPIKey(&s.apiKeys[i]))
+		}
+	}
+	return keys, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Mark API key of the user like "revoked"
+//	This is interfase method of "Storager" interface
+//	Output:
+//		bool - false if the user has no key with keyID
+func (s *Storage) RevokeAPIKey(ctx context.Context, userID, keyID uint64) (bool, error) {
+
+	s.apiKeysMx.Lock()
+	defer s.apiKeysMx.Unlock()
+
+	for i := range s.apiKeys {
+		if s.apiKeys[i].ID == keyID && s.apiKeys[i].UserID == userID {
+			s.apiKeys[i].RevokedFlag = true
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Set last used time of API key
+//	This is interfase method of "Storager" interface
+func (s *Storage) TouchAPIKey(ctx context.Context, keyID uint64, usedAt time.Time) error {
+
+	s.apiKeysMx.Lock()
+	defer s.apiKeysMx.Unlock()
+
+	for i := range s.apiKeys {
+		if s.apiKeys[i].ID == keyID {
+			s.apiKeys[i].LastUsedAt = &usedAt
+			return nil
+		}
+	}
+	return fmt.Errorf("not found")
+}
+
+// copyAPIKey returns a copy of the key which doesn't share scopes with it
+func copyAPIKey(key *apikeyentity.APIKeyModel) apikeyentity.APIKeyModel {
+
+	c := *key
+	c.Scopes = append([]string(nil), key.Scopes...)
+	return c
+}
+
 // ------------------------------------------------------------
 //
 //	Check connection to DB
@@ -173,7 +610,7 @@ func (s *Storage) GetLastShortKey() string {
 //		bool - true: connection is
 //			   false: connection isn't
 //		error - if can not find "urlAliasNode" by long URL
-func (s *Storage) IsConnected() bool {
+func (s *Storage) IsConnected(ctx context.Context) bool {
 	return true
 }
 
@@ -0,0 +1,19 @@
+package memstor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/storage/storagetest"
+)
+
+func TestStorager(t *testing.T) {
+
+	storagetest.Run(t, func(t *testing.T) aliasmaker.Storager {
+		stor, err := NewStorage()
+		require.NoError(t, err)
+		return stor
+	})
+}
@@ -0,0 +1,105 @@
+package postgrestor
+
+import (
+	"context"
+	"time"
+
+	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/models/statsentity"
+)
+
+// ------------------------------------------------------------
+//
+//	Find aliases of all users by filter in the order of their IDs
+//	This is interfase method of "Storager" interface
+func (s *Storage) SearchAliases(ctx context.Context, filter aliasentity.Filter) ([]aliasentity.AliasURLModel, error) {
+
+	var userID, limit any
+	if filter.UserID != nil {
+		userID = int64(*filter.UserID)
+	}
+	if filter.Limit > 0 {
+		limit = filter.Limit
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT `+aliasColumns+` FROM aliases
+		WHERE ($1 = '' OR strpos(short_key, $1) > 0 OR strpos(original_url, $1) > 0)
+			AND ($2::bigint IS NULL OR user_id = $2::bigint)
+		ORDER BY id OFFSET $3 LIMIT $4::integer;
+	`, filter.Query, userID, filter.Offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanAliases(rows)
+}
+
+// ------------------------------------------------------------
+//
+//	Disable or re-enable alias by short key
+//	This is interfase method of "Storager" interface
+//	Output:
+//		bool - false if the alias is not found
+func (s *Storage) SetDisabled(ctx context.Context, shortKey string, disabled bool) (bool, error) {
+
+	tag, err := s.db.Exec(ctx, `UPDATE aliases SET is_disabled = $2 WHERE short_key = $1;`, shortKey, disabled)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Remove alias by short key and its clicks
+//	This is interfase method of "Storager" interface
+//	Output:
+//		bool - false if the alias is not found
+func (s *Storage) PurgeAlias(ctx context.Context, shortKey string) (bool, error) {
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM aliases WHERE short_key = $1;`, shortKey)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	//	the next owner of the short key must not get the clicks
+	if _, err = tx.Exec(ctx, `DELETE FROM clicks WHERE short_key = $1;`, shortKey); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// ------------------------------------------------------------
+//
+//	Count users and aliases
+//	This is interfase method of "Storager" interface
+func (s *Storage) GetServiceStats(ctx context.Context, now time.Time) (*statsentity.ServiceStatsModel, error) {
+
+	stats := new(statsentity.ServiceStatsModel)
+
+	row := s.db.QueryRow(ctx, `SELECT count(*), count(login) FROM users;`)
+	if err := row.Scan(&stats.Users, &stats.RegisteredUsers); err != nil {
+		return nil, err
+	}
+
+	row = s.db.QueryRow(ctx, `
+		SELECT count(*),
+			count(*) FILTER (WHERE is_deleted),
+			count(*) FILTER (WHERE is_expired OR expires_at <= $1),
+			count(*) FILTER (WHERE is_disabled)
+		FROM aliases;
+	`, now)
+	if err := row.Scan(&stats.Aliases, &stats.DeletedAliases, &stats.ExpiredAliases, &stats.DisabledAliases); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,243 @@
+package postgrestor
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationFileName is "<version>_<name>.<up|down>.sql"
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// migrationsLockID is the key of the advisory lock which keeps instances from migrating at the same time
+const migrationsLockID = int64(0x75726c616c696173)
+
+// Migration is a versioned change of the database schema
+type Migration struct {
+	Version int
+	Name    string
+	Up      string //	SQL applying the migration
+	Down    string //	SQL reverting the migration
+}
+
+// Migrator applies and reverts migrations of the database schema
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+// ------------------------------------------------------------
+//
+//	Migrator constructor
+func NewMigrator(db *pgxpool.Pool) (*Migrator, error) {
+
+	migrations, err := loadMigrations(migrationsFS)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Apply all migrations which are not applied yet
+//	Output:
+//		[]Migration - applied migrations
+//		error
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+
+	var applied []Migration
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		version, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if migration.Version <= version {
+				continue
+			}
+
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Up); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, `INSERT INTO schema_migrations(version, name) VALUES($1, $2);`, migration.Version, migration.Name)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// ------------------------------------------------------------
+//
+//	Revert the last steps applied migrations
+//	Output:
+//		[]Migration - reverted migrations
+//		error
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+
+	var reverted []Migration
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		version, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if migration.Version > version {
+				continue
+			}
+
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Down); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1;`, migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// ------------------------------------------------------------
+//
+//	Version of the last applied migration, 0 - no migrations are applied
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+
+	var version int
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		var err error
+		version, err = currentVersion(ctx, conn)
+		return err
+	})
+	return version, err
+}
+
+// ------------------------------------------------------------
+//
+//	All known migrations in the order of versions
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// ------------------------------------------------------------
+//
+//	Run f holding the advisory lock, so concurrent instances migrate one by one.
+//	The lock belongs to a session, so f gets the connection which holds it
+func (m *Migrator) withLock(ctx context.Context, f func(conn *pgxpool.Conn) error) error {
+
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, `SELECT pg_advisory_lock($1);`, migrationsLockID); err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1);`, migrationsLockID)
+
+	if _, err = conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations(
+		version integer PRIMARY KEY,
+		name text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+		);
+	`); err != nil {
+		return err
+	}
+
+	return f(conn)
+}
+
+// currentVersion returns the version of the last applied migration
+func currentVersion(ctx context.Context, conn *pgxpool.Conn) (int, error) {
+
+	var version int
+	err := conn.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations;`).Scan(&version)
+	return version, err
+}
+
+// loadMigrations reads pairs of up and down migrations from fsys and sorts them by version
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+
+	files, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, file := range files {
+		match := migrationFileName.FindStringSubmatch(file[len("migrations/"):])
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", file)
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version: %s", file)
+		}
+
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has different names: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS aliases;
+DROP TABLE IF EXISTS users;
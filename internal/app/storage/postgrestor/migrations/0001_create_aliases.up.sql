@@ -0,0 +1,28 @@
+-- Schema of aliases and users. Statements are idempotent: databases created
+-- before migrations were introduced already have these tables.
+CREATE TABLE IF NOT EXISTS users(
+	user_id serial PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS aliases(
+	id serial PRIMARY KEY,
+	user_id integer NOT NULL REFERENCES users(user_id),
+	original_url text NOT NULL,
+	short_key varchar(64) NOT NULL,
+	is_deleted boolean NOT NULL DEFAULT false
+);
+
+ALTER TABLE aliases ALTER COLUMN short_key TYPE varchar(64);
+ALTER TABLE aliases ADD COLUMN IF NOT EXISTS is_custom boolean NOT NULL DEFAULT false;
+ALTER TABLE aliases ADD COLUMN IF NOT EXISTS expires_at timestamptz;
+ALTER TABLE aliases ADD COLUMN IF NOT EXISTS is_expired boolean NOT NULL DEFAULT false;
+
+-- An original URL has one live alias. Deleted and expired aliases keep their
+-- URL, so the URL can get a new alias.
+ALTER TABLE aliases DROP CONSTRAINT IF EXISTS aliases_original_url_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS aliases_short_key_idx ON aliases(short_key);
+CREATE UNIQUE INDEX IF NOT EXISTS aliases_live_original_url_idx ON aliases(original_url) WHERE NOT is_deleted AND NOT is_expired;
+CREATE INDEX IF NOT EXISTS aliases_original_url_idx ON aliases(original_url);
+CREATE INDEX IF NOT EXISTS aliases_user_id_idx ON aliases(user_id);
+CREATE INDEX IF NOT EXISTS aliases_expires_at_idx ON aliases(expires_at) WHERE expires_at IS NOT NULL AND NOT is_expired;
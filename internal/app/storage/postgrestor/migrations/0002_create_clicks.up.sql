@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS clicks(
+	id bigserial PRIMARY KEY,
+	short_key varchar(64) NOT NULL,
+	clicked_at timestamptz NOT NULL,
+	referrer text NOT NULL DEFAULT '',
+	user_agent text NOT NULL DEFAULT '',
+	client_ip text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS clicks_short_key_idx ON clicks(short_key, clicked_at);
@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS key_ranges(
+	name text PRIMARY KEY,
+	next bigint NOT NULL
+);
@@ -0,0 +1,15 @@
+-- API keys of users. Only the SHA-256 hash of a key is stored
+CREATE TABLE IF NOT EXISTS api_keys(
+	id bigserial PRIMARY KEY,
+	user_id integer NOT NULL REFERENCES users(user_id),
+	name text NOT NULL DEFAULT '',
+	prefix text NOT NULL,
+	key_hash text NOT NULL UNIQUE,
+	scopes text[] NOT NULL,
+	created_at timestamptz NOT NULL,
+	expires_at timestamptz,
+	last_used_at timestamptz,
+	is_revoked boolean NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys(user_id);
@@ -0,0 +1,3 @@
+-- Login and password hash of registered users. Anonymous users have no login
+ALTER TABLE users ADD COLUMN IF NOT EXISTS login text UNIQUE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash text;
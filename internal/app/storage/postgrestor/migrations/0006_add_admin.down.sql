@@ -0,0 +1,2 @@
+ALTER TABLE aliases DROP COLUMN IF EXISTS is_disabled;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,3 @@
+-- Role of users and disabled flag of aliases, which are used for moderation
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role text NOT NULL DEFAULT '';
+ALTER TABLE aliases ADD COLUMN IF NOT EXISTS is_disabled boolean NOT NULL DEFAULT false;
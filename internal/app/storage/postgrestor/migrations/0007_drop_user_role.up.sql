@@ -0,0 +1,2 @@
+-- The admin role is given by IDs of users in the configuration, stored roles are not used
+ALTER TABLE users DROP COLUMN IF EXISTS role;
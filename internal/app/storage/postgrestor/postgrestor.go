@@ -9,10 +9,14 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/jackc/pgx/v5/stdlib"
 
 	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+	"github.com/Schalure/urlalias/internal/app/models/clickentity"
+	"github.com/Schalure/urlalias/internal/app/models/userentity"
 )
 
 // Storage type
@@ -20,7 +24,7 @@ type Storage struct {
 	db *pgxpool.Pool
 }
 
-// Storage constructor
+// Storage constructor. The database schema is migrated to the last version
 func NewStorage(dbConnectionString string) (*Storage, error) {
 
 	db, err := pgxpool.New(context.Background(), dbConnectionString)
@@ -29,25 +33,13 @@ func NewStorage(dbConnectionString string) (*Storage, error) {
 		log.Panicln(err)
 	}
 
-	if _, err = db.Exec(context.Background(),
-		`
-		CREATE TABLE IF NOT EXISTS users(
-		user_id serial PRIMARY KEY
-		);
-	`); err != nil {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		db.Close()
 		return nil, err
 	}
-
-	if _, err = db.Exec(context.Background(),
-		`
-		CREATE TABLE IF NOT EXISTS aliases(
-		id serial PRIMARY KEY,
-		user_id integer NOT NULL REFERENCES users(user_id),
-		original_url text NOT NULL UNIQUE,
-		short_key varchar(9) NOT NULL,
-		is_deleted boolean NOT NULL DEFAULT false
-		);
-	`); err != nil {
+	if _, err = migrator.Up(context.Background()); err != nil {
+		db.Close()
 		return nil, err
 	}
 
@@ -57,10 +49,10 @@ func NewStorage(dbConnectionString string) (*Storage, error) {
 }
 
 // CreateUser
-func (s *Storage) CreateUser() (uint64, error) {
+func (s *Storage) CreateUser(ctx context.Context) (uint64, error) {
 
 	lastID := 0
-	err := s.db.QueryRow(context.Background(), `insert into users default values returning user_id`).Scan(&lastID)
+	err := s.db.QueryRow(ctx, `insert into users default values returning user_id`).Scan(&lastID)
 	if err != nil {
 		return 0, errors.New("can't create new user")
 	}
@@ -68,6 +60,97 @@ func (s *Storage) CreateUser() (uint64, error) {
 	return uint64(lastID), nil
 }
 
+// uniqueViolation is the code of PostgreSQL error "unique_violation"
+const uniqueViolation = "23505"
+
+// userColumns are columns of "users" table in the order of scanUser
+const userColumns = `user_id, login, password_hash`
+
+// scanUser scans a row of userColumns
+func scanUser(row pgx.Row) (*userentity.UserModel, error) {
+
+	var user userentity.UserModel
+	var login, passwordHash *string
+	if err := row.Scan(&user.UserID, &login, &passwordHash); err != nil {
+		return nil, err
+	}
+	if login != nil && passwordHash != nil {
+		user.Login = *login
+		user.PasswordHash = *passwordHash
+	}
+	return &user, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Find user by ID
+//	This is interfase method of "Storager" interface
+func (s *Storage) FindUserByID(ctx context.Context, userID uint64) (*userentity.UserModel, error) {
+
+	return scanUser(s.db.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE user_id = $1;`, userID))
+}
+
+// ------------------------------------------------------------
+//
+//	Find registered user by login
+//	This is interfase method of "Storager" interface
+func (s *Storage) FindUserByLogin(ctx context.Context, login string) (*userentity.UserModel, error) {
+
+	return scanUser(s.db.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE login = $1;`, login))
+}
+
+// ------------------------------------------------------------
+//
+//	Set login and password hash of the anonymous user
+//	This is interfase method of "Storager" interface
+//	Output:
+//		bool - false if the user is already registered or the login is taken
+func (s *Storage) RegisterUser(ctx context.Context, user *userentity.UserModel) (bool, error) {
+
+	tag, err := s.db.Exec(ctx, `UPDATE users SET login = $2, password_hash = $3 WHERE user_id = $1 AND login IS NULL;`, user.UserID, user.Login, user.PasswordHash)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return false, nil
+		}
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+// aliasColumns are columns of "aliases" table in the order of scanAlias
+const aliasColumns = `id, user_id, original_url, short_key, is_deleted, is_custom, expires_at, is_expired, is_disabled`
+
+// scanAlias scans a row of aliasColumns
+func scanAlias(row pgx.Row) (*aliasentity.AliasURLModel, error) {
+
+	var node aliasentity.AliasURLModel
+	err := row.Scan(&node.ID, &node.UserID, &node.LongURL, &node.ShortKey, &node.DeletedFlag, &node.IsCustom, &node.ExpiresAt, &node.ExpiredFlag, &node.DisabledFlag)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, aliasentity.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// scanAliases scans all rows of aliasColumns and closes rows
+func scanAliases(rows pgx.Rows) ([]aliasentity.AliasURLModel, error) {
+
+	defer rows.Close()
+
+	var nodes []aliasentity.AliasURLModel
+	for rows.Next() {
+		node, err := scanAlias(rows)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, *node)
+	}
+	return nodes, rows.Err()
+}
+
 // ------------------------------------------------------------
 //
 //	Save pair "shortKey, longURL" to db
@@ -75,15 +158,34 @@ func (s *Storage) CreateUser() (uint64, error) {
 //	Input:
 //		urlAliasNode *repositories.AliasURLModel
 //	Output:
-//		error - if not nil, can not save "urlAliasNode" because duplicate key
+//		error - aliasentity.ErrShortKeyTaken if there is an alias with the short key
 func (s *Storage) Save(ctx context.Context, urlAliasNode *aliasentity.AliasURLModel) error {
 
-	_, err := s.db.Exec(ctx, `INSERT INTO aliases(user_id, original_url, short_key) VALUES($1, $2, $3);`, urlAliasNode.UserID, urlAliasNode.LongURL, urlAliasNode.ShortKey)
+	_, err := s.db.Exec(ctx, `INSERT INTO aliases(user_id, original_url, short_key, is_custom, expires_at) VALUES($1, $2, $3, $4, $5);`, urlAliasNode.UserID, urlAliasNode.LongURL, urlAliasNode.ShortKey, urlAliasNode.IsCustom, urlAliasNode.ExpiresAt)
+	return aliasSaveError(err)
+}
 
-	if err != nil {
+// Unique indexes of aliases
+const (
+	shortKeyIndex        = "aliases_short_key_idx"         //	short keys
+	liveOriginalURLIndex = "aliases_live_original_url_idx" //	URLs of aliases which are not deleted or expired
+)
+
+// aliasSaveError maps violation of unique short keys to aliasentity.ErrShortKeyTaken
+// and violation of unique live URLs to aliasentity.ErrLongURLTaken
+func aliasSaveError(err error) error {
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
 		return err
 	}
-	return nil
+	switch pgErr.ConstraintName {
+	case shortKeyIndex:
+		return aliasentity.ErrShortKeyTaken
+	case liveOriginalURLIndex:
+		return aliasentity.ErrLongURLTaken
+	}
+	return err
 }
 
 // ------------------------------------------------------------
@@ -93,7 +195,7 @@ func (s *Storage) Save(ctx context.Context, urlAliasNode *aliasentity.AliasURLMo
 //	Input:
 //		urlAliasNode []repositories.AliasURLModel
 //	Output:
-//		error - if not nil, can not save "[]storage.AliasURLModel"
+//		error - aliasentity.ErrShortKeyTaken if a short key is taken or repeated, nothing is saved
 func (s *Storage) SaveAll(ctx context.Context, urlAliasNodes []aliasentity.AliasURLModel) error {
 
 	tx, err := s.db.Begin(ctx)
@@ -104,9 +206,9 @@ func (s *Storage) SaveAll(ctx context.Context, urlAliasNodes []aliasentity.Alias
 
 	for _, node := range urlAliasNodes {
 
-		_, err := tx.Exec(ctx, `insert into aliases(user_id, original_url, short_key) VALUES($1, $2, $3);`, node.UserID, node.LongURL, node.ShortKey)
+		_, err := tx.Exec(ctx, `insert into aliases(user_id, original_url, short_key, is_custom, expires_at) VALUES($1, $2, $3, $4, $5);`, node.UserID, node.LongURL, node.ShortKey, node.IsCustom, node.ExpiresAt)
 		if err != nil {
-			return err
+			return aliasSaveError(err)
 		}
 	}
 	return tx.Commit(ctx)
@@ -123,13 +225,7 @@ func (s *Storage) SaveAll(ctx context.Context, urlAliasNodes []aliasentity.Alias
 //		error - if can not find "urlAliasNode" by short key
 func (s *Storage) FindByShortKey(ctx context.Context, shortKey string) (*aliasentity.AliasURLModel, error) {
 
-	var aliasNode = new(aliasentity.AliasURLModel)
-
-	row := s.db.QueryRow(ctx, `SELECT id, user_id, original_url, short_key, is_deleted FROM aliases WHERE short_key = $1;`, shortKey)
-	if err := row.Scan(&aliasNode.ID, &aliasNode.UserID, &aliasNode.LongURL, &aliasNode.ShortKey, &aliasNode.DeletedFlag); err != nil {
-		return nil, err
-	}
-	return aliasNode, nil
+	return scanAlias(s.db.QueryRow(ctx, `SELECT `+aliasColumns+` FROM aliases WHERE short_key = $1;`, shortKey))
 }
 
 // ------------------------------------------------------------
@@ -143,81 +239,234 @@ func (s *Storage) FindByShortKey(ctx context.Context, shortKey string) (*aliasen
 //		error - if can not find "urlAliasNode" by long URL
 func (s *Storage) FindByLongURL(ctx context.Context, longURL string) (*aliasentity.AliasURLModel, error) {
 
-	var aliasNode = new(aliasentity.AliasURLModel)
-
-	row := s.db.QueryRow(ctx, `SELECT id, user_id, original_url, short_key, is_deleted FROM aliases WHERE original_url=$1;`, longURL)
-	if err := row.Scan(&aliasNode.ID, &aliasNode.UserID, &aliasNode.LongURL, &aliasNode.ShortKey, &aliasNode.DeletedFlag); err != nil {
-		return nil, err
-	}
-	return aliasNode, nil
+	//	the newest alias of the URL is found, older ones are deleted or expired
+	return scanAlias(s.db.QueryRow(ctx, `SELECT `+aliasColumns+` FROM aliases WHERE original_url=$1 ORDER BY id DESC LIMIT 1;`, longURL))
 }
 
 // FindAllByLongURLs find all aliases by slice of original URL and return map[original_url] aliasentity.AliasURLModel or error
 func (s *Storage) FindAllByLongURLs(ctx context.Context, longURL []string) (map[string]*aliasentity.AliasURLModel, error) {
 
+	nodes := map[string]*aliasentity.AliasURLModel{}
+	if len(longURL) == 0 {
+		return nodes, nil
+	}
+
 	paramsString := make([]string, len(longURL))
 	params := make([]interface{}, len(longURL))
 	for i, u := range longURL {
 		paramsString[i] = fmt.Sprintf("$%d", i+1)
 		params[i] = u
 	}
-	stmt := fmt.Sprintf("SELECT original_url, short_key FROM aliases where original_url IN (%s);", strings.Join(paramsString, ","))
+	stmt := fmt.Sprintf("SELECT DISTINCT ON (original_url) "+aliasColumns+" FROM aliases where original_url IN (%s) ORDER BY original_url, id DESC;", strings.Join(paramsString, ","))
 	rows, err := s.db.Query(ctx, stmt, params...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	node := aliasentity.AliasURLModel{}
-	nodes := map[string]*aliasentity.AliasURLModel{}
 	for rows.Next() {
-		err = rows.Scan(&node.LongURL, &node.ShortKey)
+		node, err := scanAlias(rows)
 		if err != nil {
 			return nil, err
 		}
-		nodes[node.LongURL] = &node
+		nodes[node.LongURL] = node
 	}
-	return nodes, nil
+	return nodes, rows.Err()
 }
 
 // FindByUserID
 func (s *Storage) FindByUserID(ctx context.Context, userID uint64) ([]aliasentity.AliasURLModel, error) {
 
-	rows, err := s.db.Query(ctx, `select original_url, short_key from aliases where user_id=$1;`, userID)
+	rows, err := s.db.Query(ctx, `select `+aliasColumns+` from aliases where user_id=$1;`, userID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return scanAliases(rows)
+}
 
-	var nodes []aliasentity.AliasURLModel
-	var node aliasentity.AliasURLModel
+// ------------------------------------------------------------
+//
+//	Mark aliases like "deleted" by aliasesID
+func (s *Storage) MarkDeleted(ctx context.Context, aliasesID []uint64) error {
 
+	query := `UPDATE aliases SET is_deleted = TRUE where id=$1;`
+	batch := &pgx.Batch{}
+	for _, ID := range aliasesID {
+		batch.Queue(query, ID)
+	}
+	results := s.db.SendBatch(ctx, batch)
+	return results.Close()
+}
+
+// ------------------------------------------------------------
+//
+//	Mark aliases like "expired" if their expiry time has passed
+func (s *Storage) MarkExpired(ctx context.Context, now time.Time) (int, error) {
+
+	tag, err := s.db.Exec(ctx, `UPDATE aliases SET is_expired = TRUE WHERE NOT is_expired AND expires_at <= $1;`, now)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// ------------------------------------------------------------
+//
+//	Reserve range of key counter values
+//	This is interfase method of "Storager" interface.
+//	The row of the counter is locked by the update, so instances
+//	sharing the database never get the same range
+//	Input:
+//		name string - name of the counter
+//		start uint64 - value of a new counter
+//		size uint64 - count of reserved values
+//	Output:
+//		uint64 - the first value of the range
+func (s *Storage) ReserveKeyRange(ctx context.Context, name string, start, size uint64) (uint64, error) {
+
+	var next int64
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO key_ranges(name, next) VALUES($1, $2::bigint + $3::bigint)
+		ON CONFLICT (name) DO UPDATE SET next = key_ranges.next + $3::bigint
+		RETURNING next - $3::bigint;
+	`, name, int64(start), int64(size)).Scan(&next)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(next), nil
+}
+
+// ------------------------------------------------------------
+//
+//	Save batch of redirects
+//	This is interfase method of "AnalyticsSink" interface
+func (s *Storage) SaveClicks(ctx context.Context, clicks []clickentity.ClickModel) error {
+
+	_, err := s.db.CopyFrom(
+		ctx,
+		pgx.Identifier{"clicks"},
+		[]string{"short_key", "clicked_at", "referrer", "user_agent", "client_ip"},
+		pgx.CopyFromSlice(len(clicks), func(i int) ([]any, error) {
+			return []any{clicks[i].ShortKey, clicks[i].ClickedAt, clicks[i].Referrer, clicks[i].UserAgent, clicks[i].ClientIP}, nil
+		}),
+	)
+	return err
+}
+
+// ------------------------------------------------------------
+//
+//	Get statistics of redirects by short key
+//	This is interfase method of "AnalyticsSink" interface
+func (s *Storage) GetClickStats(ctx context.Context, shortKey string) (*clickentity.StatsModel, error) {
+
+	stats := new(clickentity.StatsModel)
+
+	row := s.db.QueryRow(ctx, `SELECT count(*), count(DISTINCT (client_ip, user_agent)) FROM clicks WHERE short_key = $1;`, shortKey)
+	if err := row.Scan(&stats.TotalClicks, &stats.UniqueVisitors); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT date_trunc('day', clicked_at AT TIME ZONE 'UTC') AS day, count(*), count(DISTINCT (client_ip, user_agent))
+		FROM clicks WHERE short_key = $1 GROUP BY day ORDER BY day;`, shortKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats.Daily = make([]clickentity.DailyStatsModel, 0)
 	for rows.Next() {
-		err = rows.Scan(&node.LongURL, &node.ShortKey)
-		if err != nil {
+		var daily clickentity.DailyStatsModel
+		if err = rows.Scan(&daily.Date, &daily.Clicks, &daily.UniqueVisitors); err != nil {
 			return nil, err
 		}
-		nodes = append(nodes, node)
+		daily.Date = time.Date(daily.Date.Year(), daily.Date.Month(), daily.Date.Day(), 0, 0, 0, 0, time.UTC)
+		stats.Daily = append(stats.Daily, daily)
 	}
+	return stats, rows.Err()
+}
+
+// apiKeyColumns are columns of "api_keys" table in the order of scanAPIKey
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, created_at, expires_at, last_used_at, is_revoked`
+
+// scanAPIKey scans a row of apiKeyColumns
+func scanAPIKey(row pgx.Row) (*apikeyentity.APIKeyModel, error) {
 
-	if err = rows.Err(); err != nil {
+	var key apikeyentity.APIKeyModel
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.Hash, &key.Scopes, &key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt, &key.RevokedFlag)
+	if err != nil {
 		return nil, err
 	}
-	return nodes, nil
+	return &key, nil
 }
 
 // ------------------------------------------------------------
 //
-//	Mark aliases like "deleted" by aliasesID
-func (s *Storage) MarkDeleted(ctx context.Context, aliasesID []uint64) error {
+//	Save new API key and set its ID
+//	This is interfase method of "Storager" interface
+func (s *Storage) SaveAPIKey(ctx context.Context, key *apikeyentity.APIKeyModel) error {
 
-	query := `UPDATE aliases SET is_deleted = TRUE where id=$1;`
-	batch := &pgx.Batch{}
-	for _, ID := range aliasesID {
-		batch.Queue(query, ID)
+	return s.db.QueryRow(ctx, `
+		INSERT INTO api_keys(user_id, name, prefix, key_hash, scopes, created_at, expires_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id;
+	`, key.UserID, key.Name, key.Prefix, key.Hash, key.Scopes, key.CreatedAt, key.ExpiresAt).Scan(&key.ID)
+}
+
+// ------------------------------------------------------------
+//
+//	Find API key by hash of the key
+//	This is interfase method of "Storager" interface
+func (s *Storage) FindAPIKeyByHash(ctx context.Context, hash string) (*apikeyentity.APIKeyModel, error) {
+
+	return scanAPIKey(s.db.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1;`, hash))
+}
+
+// ------------------------------------------------------------
+//
+//	Find all API keys of the user ordered by ID
+//	This is interfase method of "Storager" interface
+func (s *Storage) FindAPIKeysByUserID(ctx context.Context, userID uint64) ([]apikeyentity.APIKeyModel, error) {
+
+	rows, err := s.db.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = $1 ORDER BY id;`, userID)
+	if err != nil {
+		return nil, err
 	}
-	results := s.db.SendBatch(ctx, batch)
-	return results.Close()
+	defer rows.Close()
+
+	var keys []apikeyentity.APIKeyModel
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// ------------------------------------------------------------
+//
+//	Mark API key of the user like "revoked"
+//	This is interfase method of "Storager" interface
+//	Output:
+//		bool - false if the user has no key with keyID
+func (s *Storage) RevokeAPIKey(ctx context.Context, userID, keyID uint64) (bool, error) {
+
+	tag, err := s.db.Exec(ctx, `UPDATE api_keys SET is_revoked = TRUE WHERE id = $1 AND user_id = $2;`, keyID, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() != 0, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Set last used time of API key
+//	This is interfase method of "Storager" interface
+func (s *Storage) TouchAPIKey(ctx context.Context, keyID uint64, usedAt time.Time) error {
+
+	_, err := s.db.Exec(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1;`, keyID, usedAt)
+	return err
 }
 
 // ------------------------------------------------------------
@@ -227,7 +476,7 @@ func (s *Storage) GetLastShortKey() string {
 
 	var shortKey string
 
-	row := s.db.QueryRow(context.Background(), `select short_key from aliases where id=(select max(id) from aliases);`)
+	row := s.db.QueryRow(context.Background(), `select short_key from aliases where id=(select max(id) from aliases where not is_custom);`)
 	if err := row.Scan(&shortKey); err != nil {
 		return ""
 	}
@@ -242,9 +491,9 @@ func (s *Storage) GetLastShortKey() string {
 //		bool - true: connection is
 //			   false: connection isn't
 //		error - if can not find "urlAliasNode" by long URL
-func (s *Storage) IsConnected() bool {
+func (s *Storage) IsConnected(ctx context.Context) bool {
 
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
 	defer cancel()
 
 	if err := s.db.Ping(ctx); err != nil {
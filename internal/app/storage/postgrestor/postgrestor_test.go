@@ -1,3 +1,184 @@
 package postgrestor
 
-//	TODO make postgrestor tests
+import (
+	"context"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/storage/storagetest"
+)
+
+// testDSNEnvKey is the environment variable with the connection string of a test database.
+// All data of the database is removed by the tests
+const testDSNEnvKey = "TEST_DATABASE_DSN"
+
+// testDSN returns connection string of the test database or skips the test
+func testDSN(t *testing.T) string {
+
+	dsn, ok := os.LookupEnv(testDSNEnvKey)
+	if !ok || dsn == "" {
+		t.Skipf("%s is not set", testDSNEnvKey)
+	}
+	return dsn
+}
+
+// newTestStorage returns the storage over the empty test database
+func newTestStorage(t *testing.T, dsn string) aliasmaker.Storager {
+
+	stor, err := NewStorage(dsn)
+	require.NoError(t, err)
+
+	_, err = stor.db.Exec(context.Background(), `TRUNCATE aliases, users, clicks, key_ranges, api_keys RESTART IDENTITY;`)
+	require.NoError(t, err)
+	return stor
+}
+
+func TestStorager(t *testing.T) {
+
+	dsn := testDSN(t)
+
+	storagetest.Run(t, func(t *testing.T) aliasmaker.Storager {
+		return newTestStorage(t, dsn)
+	})
+}
+
+func TestStoragerRestart(t *testing.T) {
+
+	dsn := testDSN(t)
+
+	storagetest.RunRestart(t, func(t *testing.T) aliasmaker.Storager {
+		return newTestStorage(t, dsn)
+	}, func(t *testing.T, stor aliasmaker.Storager) aliasmaker.Storager {
+		require.NoError(t, stor.Close())
+		reopened, err := NewStorage(dsn)
+		require.NoError(t, err)
+		return reopened
+	})
+}
+
+func Test_loadMigrations(t *testing.T) {
+
+	testCases := []struct {
+		testName     string
+		fsys         fstest.MapFS
+		wantVersions []int
+		wantErr      bool
+	}{
+		{
+			testName: "sorted by version",
+			fsys: fstest.MapFS{
+				"migrations/0010_third.up.sql":    {Data: []byte("up 10")},
+				"migrations/0010_third.down.sql":  {Data: []byte("down 10")},
+				"migrations/0002_second.up.sql":   {Data: []byte("up 2")},
+				"migrations/0002_second.down.sql": {Data: []byte("down 2")},
+				"migrations/0001_first.up.sql":    {Data: []byte("up 1")},
+				"migrations/0001_first.down.sql":  {Data: []byte("down 1")},
+			},
+			wantVersions: []int{1, 2, 10},
+		},
+		{
+			testName: "down is missing",
+			fsys: fstest.MapFS{
+				"migrations/0001_first.up.sql": {Data: []byte("up 1")},
+			},
+			wantErr: true,
+		},
+		{
+			testName: "different names of one version",
+			fsys: fstest.MapFS{
+				"migrations/0001_first.up.sql":   {Data: []byte("up 1")},
+				"migrations/0001_other.down.sql": {Data: []byte("down 1")},
+			},
+			wantErr: true,
+		},
+		{
+			testName: "invalid file name",
+			fsys: fstest.MapFS{
+				"migrations/first.up.sql": {Data: []byte("up 1")},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.testName, func(t *testing.T) {
+
+			migrations, err := loadMigrations(test.fsys)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var versions []int
+			for _, migration := range migrations {
+				versions = append(versions, migration.Version)
+				assert.Contains(t, migration.Up, "up")
+				assert.Contains(t, migration.Down, "down")
+			}
+			assert.Equal(t, test.wantVersions, versions)
+		})
+	}
+
+	//	embedded migrations must be valid
+	migrations, err := loadMigrations(migrationsFS)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	assert.Equal(t, 1, migrations[0].Version)
+}
+
+func TestMigrator(t *testing.T) {
+
+	dsn := testDSN(t)
+	ctx := context.Background()
+
+	db, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	migrator, err := NewMigrator(db)
+	require.NoError(t, err)
+	last := migrator.Migrations()[len(migrator.Migrations())-1].Version
+
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	version, err := migrator.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, last, version)
+
+	//	nothing to apply twice
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	reverted, err := migrator.Down(ctx, len(migrator.Migrations()))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(migrator.Migrations()))
+
+	version, err = migrator.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+
+	//	instances started at the same time migrate one by one
+	errs := make(chan error, 3)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := migrator.Up(ctx)
+			errs <- err
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		assert.NoError(t, <-errs)
+	}
+
+	version, err = migrator.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, last, version)
+}
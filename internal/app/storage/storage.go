@@ -3,21 +3,70 @@ package storage
 import (
 	"github.com/Schalure/urlalias/cmd/shortener/config"
 	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/metrics"
+	"github.com/Schalure/urlalias/internal/app/storage/cachestor"
 	"github.com/Schalure/urlalias/internal/app/storage/filestor"
 	"github.com/Schalure/urlalias/internal/app/storage/memstor"
 	"github.com/Schalure/urlalias/internal/app/storage/postgrestor"
+	"github.com/Schalure/urlalias/internal/app/tracing"
 )
 
 // --------------------------------------------------
 //
-//	Choose storage for service
-func NewStorage(c *config.Configuration) (aliasmaker.Storager, error) {
+//	Choose storage for service. The storage is wrapped by the cache of redirect lookups if it is enabled.
+//	Calls to the storage are observed by m if it is not nil.
+//	Calls of the service, including ones answered by the cache, are traced by t if it is not nil
+func NewStorage(c *config.Configuration, m *metrics.Metrics, t *tracing.Tracing) (aliasmaker.Storager, error) {
+
+	stor, err := newBackend(c)
+	if err != nil {
+		return nil, err
+	}
+	if m != nil {
+		stor = m.InstrumentStorage(stor)
+	}
+
+	if c.CacheSize() == 0 {
+		return traceStorage(stor, t), nil
+	}
+
+	cached, err := cachestor.NewStorage(stor, c.CacheSize(),
+		cachestor.WithTTL(c.CacheTTL()),
+		cachestor.WithNegativeTTL(c.CacheNegativeTTL()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if m != nil {
+		m.RegisterCache(cached.Stats)
+	}
+	return traceStorage(cached, t), nil
+}
+
+// --------------------------------------------------
+//
+//	Wrap the storage, so its calls are traced by t if it is not nil
+func traceStorage(stor aliasmaker.Storager, t *tracing.Tracing) aliasmaker.Storager {
+
+	if t == nil {
+		return stor
+	}
+	return t.InstrumentStorage(stor)
+}
+
+// --------------------------------------------------
+//
+//	Create storage of the configured type
+func newBackend(c *config.Configuration) (aliasmaker.Storager, error) {
 
 	switch c.StorageType() {
 	case config.DataBaseStor:
 		return postgrestor.NewStorage(c.DBConnection())
 	case config.FileStor:
-		return filestor.NewStorage(c.AliasesFile(), c.UsersFile())
+		return filestor.NewStorage(c.AliasesFile(), c.UsersFile(), c.ClicksFile(), c.KeysFile(), c.APIKeysFile(),
+			filestor.WithFsync(c.FileFsync()),
+			filestor.WithCompactThreshold(c.FileCompactThreshold()),
+		)
 	default:
 		return memstor.NewStorage()
 	}
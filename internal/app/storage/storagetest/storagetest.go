@@ -0,0 +1,937 @@
+/*
+Package storagetest contains a behavioral test suite that every
+implementation of "aliasmaker.Storager" must pass.
+
+A storage package runs the suite from its own tests:
+
+	func TestStorager(t *testing.T) {
+		storagetest.Run(t, func(t *testing.T) aliasmaker.Storager {
+			stor, err := NewStorage()
+			require.NoError(t, err)
+			return stor
+		})
+	}
+
+The suite is meant to be run with the "-race" flag.
+*/
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+	"github.com/Schalure/urlalias/internal/app/models/clickentity"
+	"github.com/Schalure/urlalias/internal/app/models/statsentity"
+	"github.com/Schalure/urlalias/internal/app/models/userentity"
+)
+
+// Factory returns a new empty storage. It is called once for every test of the suite
+type Factory func(t *testing.T) aliasmaker.Storager
+
+// Reopener closes the storage and opens its data again, like the service does after restart
+type Reopener func(t *testing.T, stor aliasmaker.Storager) aliasmaker.Storager
+
+// ------------------------------------------------------------
+//
+//	Run runs the suite against storages made by newStorage.
+//	Tests named in notImplemented are skipped
+func Run(t *testing.T, newStorage Factory, notImplemented ...string) {
+
+	tests := []struct {
+		name string
+		test func(t *testing.T, stor aliasmaker.Storager)
+	}{
+		{name: "CreateUser", test: testCreateUser},
+		{name: "RegisterUser", test: testRegisterUser},
+		{name: "Save", test: testSave},
+		{name: "SaveAll", test: testSaveAll},
+		{name: "SaveTakenShortKey", test: testSaveTakenShortKey},
+		{name: "FindAllByLongURLs", test: testFindAllByLongURLs},
+		{name: "FindByUserID", test: testFindByUserID},
+		{name: "MarkDeleted", test: testMarkDeleted},
+		{name: "MarkExpired", test: testMarkExpired},
+		{name: "GetLastShortKey", test: testGetLastShortKey},
+		{name: "ReserveKeyRange", test: testReserveKeyRange},
+		{name: "APIKeys", test: testAPIKeys},
+		{name: "SearchAliases", test: testSearchAliases},
+		{name: "SetDisabled", test: testSetDisabled},
+		{name: "PurgeAlias", test: testPurgeAlias},
+		{name: "PurgeAliasClicks", test: testPurgeAliasClicks},
+		{name: "GetServiceStats", test: testGetServiceStats},
+		{name: "Concurrency", test: testConcurrency},
+	}
+
+	skip := make(map[string]bool, len(notImplemented))
+	for _, name := range notImplemented {
+		skip[name] = true
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if skip[test.name] {
+				t.Skip("not implemented by this storage")
+			}
+
+			stor := newStorage(t)
+			defer stor.Close()
+
+			test.test(t, stor)
+		})
+	}
+}
+
+// ------------------------------------------------------------
+//
+//	RunRestart runs the tests of data which must survive restart against
+//	storages made by newStorage. Storages which keep nothing after restart don't run them
+func RunRestart(t *testing.T, newStorage Factory, reopen Reopener) {
+
+	tests := []struct {
+		name string
+		test func(t *testing.T, stor aliasmaker.Storager, reopen Reopener)
+	}{
+		{name: "MarkExpired", test: testRestartMarkExpired},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, newStorage(t), reopen)
+		})
+	}
+}
+
+// testCreateUser checks that every new user gets a unique ID
+func testCreateUser(t *testing.T, stor aliasmaker.Storager) {
+
+	ctx := context.Background()
+	first, err := stor.CreateUser(ctx)
+	require.NoError(t, err)
+
+	second, err := stor.CreateUser(ctx)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, first, second)
+}
+
+// testRegisterUser checks that an anonymous user is registered once and found by login
+func testRegisterUser(t *testing.T, stor aliasmaker.Storager) {
+
+	ctx := context.Background()
+	userID := createUser(t, stor)
+	otherUserID := createUser(t, stor)
+
+	user, err := stor.FindUserByID(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, userID, user.UserID)
+	assert.False(t, user.IsRegistered())
+
+	_, err = stor.FindUserByLogin(ctx, "alice")
+	assert.Error(t, err)
+
+	ok, err := stor.RegisterUser(ctx, &userentity.UserModel{UserID: userID, Login: "alice", PasswordHash: "hash"})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	for _, find := range []func() (*userentity.UserModel, error){
+		func() (*userentity.UserModel, error) { return stor.FindUserByID(ctx, userID) },
+		func() (*userentity.UserModel, error) { return stor.FindUserByLogin(ctx, "alice") },
+	} {
+		user, err := find()
+		require.NoError(t, err)
+		assert.Equal(t, userentity.UserModel{UserID: userID, Login: "alice", PasswordHash: "hash"}, *user)
+	}
+
+	//	the login is taken
+	ok, err = stor.RegisterUser(ctx, &userentity.UserModel{UserID: otherUserID, Login: "alice", PasswordHash: "other"})
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	//	the user is already registered
+	ok, err = stor.RegisterUser(ctx, &userentity.UserModel{UserID: userID, Login: "bob", PasswordHash: "other"})
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	user, err = stor.FindUserByID(ctx, otherUserID)
+	require.NoError(t, err)
+	assert.False(t, user.IsRegistered())
+}
+
+// testSave checks that a saved alias can be found by short key and by long URL
+func testSave(t *testing.T, stor aliasmaker.Storager) {
+
+	ctx := context.Background()
+	userID := createUser(t, stor)
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond)
+
+	want := aliasentity.AliasURLModel{
+		UserID:    userID,
+		ShortKey:  "000000001",
+		LongURL:   "https://example.com/save",
+		ExpiresAt: &expiresAt,
+	}
+	require.NoError(t, stor.Save(ctx, &aliasentity.AliasURLModel{
+		UserID:    want.UserID,
+		ShortKey:  want.ShortKey,
+		LongURL:   want.LongURL,
+		ExpiresAt: want.ExpiresAt,
+	}))
+
+	node, err := stor.FindByShortKey(ctx, want.ShortKey)
+	require.NoError(t, err)
+	assertAlias(t, want, node)
+	assert.NotZero(t, node.ID)
+
+	byURL, err := stor.FindByLongURL(ctx, want.LongURL)
+	require.NoError(t, err)
+	assertAlias(t, want, byURL)
+	assert.Equal(t, node.ID, byURL.ID)
+
+	_, err = stor.FindByShortKey(ctx, "000000002")
+	assert.ErrorIs(t, err, aliasentity.ErrNotFound)
+
+	_, err = stor.FindByLongURL(ctx, "https://example.com/unknown")
+	assert.ErrorIs(t, err, aliasentity.ErrNotFound)
+}
+
+// testSaveAll checks that every alias of a batch is saved with its own ID
+func testSaveAll(t *testing.T, stor aliasmaker.Storager) {
+
+	ctx := context.Background()
+	userID := createUser(t, stor)
+
+	batch := makeAliases(userID, 1, 5)
+	require.NoError(t, stor.SaveAll(ctx, batch))
+
+	ids := make(map[uint64]bool, len(batch))
+	for _, want := range batch {
+		node, err := stor.FindByShortKey(ctx, want.ShortKey)
+		require.NoError(t, err)
+		assertAlias(t, want, node)
+		ids[node.ID] = true
+	}
+	assert.Len(t, ids, len(batch))
+}
+
+// testSaveTakenShortKey checks that a short key is saved once, even by concurrent writers,
+// and a batch with a taken short key is not saved at all
+func testSaveTakenShortKey(t *testing.T, stor aliasmaker.Storager) {
+
+	const writers = 8
+	ctx := context.Background()
+	userID := createUser(t, stor)
+
+	var (
+		wg      sync.WaitGroup
+		mx      sync.Mutex
+		winners []string
+	)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			longURL := fmt.Sprintf("https://example.com/taken/%d", w)
+			err := stor.Save(ctx, &aliasentity.AliasURLModel{UserID: userID, ShortKey: "spring-sale", LongURL: longURL, IsCustom: true})
+			if err != nil {
+				assert.ErrorIs(t, err, aliasentity.ErrShortKeyTaken)
+				return
+			}
+			mx.Lock()
+			winners = append(winners, longURL)
+			mx.Unlock()
+		}(w)
+	}
+	wg.Wait()
+
+	//	the only writer which succeeded owns the short key
+	require.Len(t, winners, 1)
+	node, err := stor.FindByShortKey(ctx, "spring-sale")
+	require.NoError(t, err)
+	assert.Equal(t, winners[0], node.LongURL)
+
+	batch := makeAliases(userID, 1, 3)
+	batch[1].ShortKey = "spring-sale"
+	assert.ErrorIs(t, stor.SaveAll(ctx, batch), aliasentity.ErrShortKeyTaken)
+	_, err = stor.FindByShortKey(ctx, batch[0].ShortKey)
+	assert.ErrorIs(t, err, aliasentity.ErrNotFound)
+
+	batch = makeAliases(userID, 1, 3)
+	batch[2].ShortKey = batch[0].ShortKey
+	assert.ErrorIs(t, stor.SaveAll(ctx, batch), aliasentity.ErrShortKeyTaken)
+	_, err = stor.FindByShortKey(ctx, batch[1].ShortKey)
+	assert.ErrorIs(t, err, aliasentity.ErrNotFound)
+}
+
+// testFindAllByLongURLs checks that only saved URLs are returned, each with its own alias
+func testFindAllByLongURLs(t *testing.T, stor aliasmaker.Storager) {
+
+	ctx := context.Background()
+	userID := createUser(t, stor)
+
+	batch := makeAliases(userID, 1, 3)
+	require.NoError(t, stor.SaveAll(ctx, batch))
+
+	nodes, err := stor.FindAllByLongURLs(ctx, []string{
+		batch[0].LongURL,
+		"https://example.com/unknown",
+		batch[2].LongURL,
+	})
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+
+	for _, want := range []aliasentity.AliasURLModel{batch[0], batch[2]} {
+		require.Contains(t, nodes, want.LongURL)
+		assertAlias(t, want, nodes[want.LongURL])
+	}
+
+	nodes, err = stor.FindAllByLongURLs(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, nodes)
+}
+
+// testFindByUserID checks that a user gets only his own aliases
+func testFindByUserID(t *testing.T, stor aliasmaker.Storager) {
+
+	ctx := context.Background()
+	firstUserID := createUser(t, stor)
+	secondUserID := createUser(t, stor)
+	thirdUserID := createUser(t, stor)
+
+	firstAliases := makeAliases(firstUserID, 1, 3)
+	secondAliases := makeAliases(secondUserID, 4, 2)
+	require.NoError(t, stor.SaveAll(ctx, firstAliases))
+	require.NoError(t, stor.SaveAll(ctx, secondAliases))
+
+	for _, want := range []struct {
+		userID  uint64
+		aliases []aliasentity.AliasURLModel
+	}{
+		{userID: firstUserID, aliases: firstAliases},
+		{userID: secondUserID, aliases: secondAliases},
+		{userID: thirdUserID, aliases: nil},
+	} {
+		nodes, err := stor.FindByUserID(ctx, want.userID)
+		require.NoError(t, err)
+
+		var shortKeys []string
+		for _, node := range nodes {
+			assert.Equal(t, want.userID, node.UserID)
+			shortKeys = append(shortKeys, node.ShortKey)
+		}
+		assert.ElementsMatch(t, shortKeysOf(want.aliases), shortKeys)
+	}
+}
+
+// testMarkDeleted checks that only the requested aliases are marked and the mark is visible to every lookup
+func testMarkDeleted(t *testing.T, stor aliasmaker.Storager) {
+
+	ctx := context.Background()
+	userID := createUser(t, stor)
+
+	batch := makeAliases(userID, 1, 3)
+	require.NoError(t, stor.SaveAll(ctx, batch))
+
+	deleted, err := stor.FindByShortKey(ctx, batch[1].ShortKey)
+	require.NoError(t, err)
+	require.NoError(t, stor.MarkDeleted(ctx, []uint64{deleted.ID}))
+
+	for i, want := range batch {
+		node, err := stor.FindByShortKey(ctx, want.ShortKey)
+		require.NoError(t, err)
+		assert.Equal(t, i == 1, node.DeletedFlag, node.ShortKey)
+
+		node, err = stor.FindByLongURL(ctx, want.LongURL)
+		require.NoError(t, err)
+		assert.Equal(t, i == 1, node.DeletedFlag, node.ShortKey)
+	}
+
+	nodes, err := stor.FindByUserID(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, nodes, len(batch))
+	for _, node := range nodes {
+		assert.Equal(t, node.ShortKey == batch[1].ShortKey, node.DeletedFlag, node.ShortKey)
+	}
+
+	//	repeated marking is not an error
+	require.NoError(t, stor.MarkDeleted(ctx, []uint64{deleted.ID}))
+}
+
+// testMarkExpired checks that only aliases whose expiry time has passed are marked, once,
+// and their URL can get a new alias
+func testMarkExpired(t *testing.T, stor aliasmaker.Storager) {
+
+	ctx := context.Background()
+	userID := createUser(t, stor)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	batch := makeAliases(userID, 1, 3)
+	batch[0].ExpiresAt = &past
+	batch[1].ExpiresAt = &future
+	require.NoError(t, stor.SaveAll(ctx, batch))
+
+	count, err := stor.MarkExpired(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	for i, want := range batch {
+		node, err := stor.FindByShortKey(ctx, want.ShortKey)
+		require.NoError(t, err)
+		assert.Equal(t, i == 0, node.ExpiredFlag, node.ShortKey)
+	}
+
+	//	marked aliases are not counted again
+	count, err = stor.MarkExpired(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	count, err = stor.MarkExpired(ctx, future)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	//	the newest alias of the URL is found
+	renewed := aliasentity.AliasURLModel{UserID: userID, ShortKey: shortKey(10), LongURL: batch[0].LongURL}
+	require.NoError(t, stor.Save(ctx, &aliasentity.AliasURLModel{UserID: renewed.UserID, ShortKey: renewed.ShortKey, LongURL: renewed.LongURL}))
+
+	node, err := stor.FindByLongURL(ctx, batch[0].LongURL)
+	require.NoError(t, err)
+	assertAlias(t, renewed, node)
+
+	nodes, err := stor.FindAllByLongURLs(ctx, []string{batch[0].LongURL})
+	require.NoError(t, err)
+	require.Contains(t, nodes, batch[0].LongURL)
+	assertAlias(t, renewed, nodes[batch[0].LongURL])
+
+	node, err = stor.FindByShortKey(ctx, batch[0].ShortKey)
+	require.NoError(t, err)
+	assert.True(t, node.ExpiredFlag)
+}
+
+// testRestartMarkExpired checks that marks of expired aliases don't change IDs and keys of aliases after restart
+func testRestartMarkExpired(t *testing.T, stor aliasmaker.Storager, reopen Reopener) {
+
+	ctx := context.Background()
+	userID := createUser(t, stor)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	past := now.Add(-time.Hour)
+
+	batch := makeAliases(userID, 1, 3)
+	batch[0].ExpiresAt = &past
+	require.NoError(t, stor.SaveAll(ctx, batch))
+
+	count, err := stor.MarkExpired(ctx, now)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	stor = reopen(t, stor)
+	defer stor.Close()
+	assert.Equal(t, batch[2].ShortKey, stor.GetLastShortKey())
+
+	//	the new alias gets its own ID, so its deletion touches nothing else
+	saved := makeAliases(userID, 4, 1)[0]
+	require.NoError(t, stor.Save(ctx, &saved))
+	node, err := stor.FindByShortKey(ctx, saved.ShortKey)
+	require.NoError(t, err)
+	require.NoError(t, stor.MarkDeleted(ctx, []uint64{node.ID}))
+
+	ids := make(map[uint64]bool)
+	for i, want := range append(batch, saved) {
+		node, err := stor.FindByShortKey(ctx, want.ShortKey)
+		require.NoError(t, err)
+		assert.Equal(t, want.LongURL, node.LongURL)
+		assert.Equal(t, i == 0, node.ExpiredFlag, node.ShortKey)
+		assert.Equal(t, i == 3, node.DeletedFlag, node.ShortKey)
+		assert.False(t, ids[node.ID], "ID %d is used twice", node.ID)
+		ids[node.ID] = true
+	}
+}
+
+// testGetLastShortKey checks that the last generated key is returned and custom aliases are ignored
+func testGetLastShortKey(t *testing.T, stor aliasmaker.Storager) {
+
+	ctx := context.Background()
+	userID := createUser(t, stor)
+
+	assert.Equal(t, "", stor.GetLastShortKey())
+
+	require.NoError(t, stor.SaveAll(ctx, makeAliases(userID, 1, 2)))
+	assert.Equal(t, "000000002", stor.GetLastShortKey())
+
+	require.NoError(t, stor.Save(ctx, &aliasentity.AliasURLModel{
+		UserID:   userID,
+		ShortKey: "my-custom-alias",
+		LongURL:  "https://example.com/custom",
+		IsCustom: true,
+	}))
+	assert.Equal(t, "000000002", stor.GetLastShortKey())
+
+	require.NoError(t, stor.Save(ctx, &aliasentity.AliasURLModel{
+		UserID:   userID,
+		ShortKey: "000000003",
+		LongURL:  "https://example.com/3",
+	}))
+	assert.Equal(t, "000000003", stor.GetLastShortKey())
+}
+
+// testReserveKeyRange checks that reserved ranges of a counter never overlap, even when they are reserved concurrently
+func testReserveKeyRange(t *testing.T, stor aliasmaker.Storager) {
+
+	const (
+		reservers   = 8
+		perReserver = 10
+		size        = 100
+	)
+	ctx := context.Background()
+
+	//	a new counter starts at the given value, later the value is ignored
+	first, err := stor.ReserveKeyRange(ctx, "sequential-9", 500, size)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(500), first)
+
+	second, err := stor.ReserveKeyRange(ctx, "sequential-9", 0, size)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(500+size), second)
+
+	//	counters are independent
+	other, err := stor.ReserveKeyRange(ctx, "obfuscated-9", 0, size)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), other)
+
+	var (
+		mx     sync.Mutex
+		starts = map[uint64]bool{first: true, second: true}
+		wg     sync.WaitGroup
+	)
+	for r := 0; r < reservers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perReserver; i++ {
+				start, err := stor.ReserveKeyRange(ctx, "sequential-9", 0, size)
+				if !assert.NoError(t, err) {
+					return
+				}
+				mx.Lock()
+				assert.False(t, starts[start], "range %d is reserved twice", start)
+				starts[start] = true
+				mx.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	//	ranges follow each other without gaps
+	assert.Len(t, starts, reservers*perReserver+2)
+	for i := 0; i < reservers*perReserver+2; i++ {
+		assert.True(t, starts[uint64(500+i*size)], "range %d is not reserved", 500+i*size)
+	}
+}
+
+// testAPIKeys checks that API keys are found by hash, listed by owner, revoked by owner only and touched
+func testAPIKeys(t *testing.T, stor aliasmaker.Storager) {
+
+	ctx := context.Background()
+	userID := createUser(t, stor)
+	otherUserID := createUser(t, stor)
+	createdAt := time.Now().UTC().Truncate(time.Millisecond)
+	expiresAt := createdAt.Add(time.Hour)
+
+	first := apikeyentity.APIKeyModel{
+		UserID:    userID,
+		Name:      "ci",
+		Prefix:    "ua_first",
+		Hash:      "hash-of-first",
+		Scopes:    []string{apikeyentity.ScopeShorten, apikeyentity.ScopeRead},
+		CreatedAt: createdAt,
+		ExpiresAt: &expiresAt,
+	}
+	second := apikeyentity.APIKeyModel{
+		UserID:    userID,
+		Prefix:    "ua_secon",
+		Hash:      "hash-of-second",
+		Scopes:    []string{apikeyentity.ScopeDelete},
+		CreatedAt: createdAt,
+	}
+	require.NoError(t, stor.SaveAPIKey(ctx, &first))
+	require.NoError(t, stor.SaveAPIKey(ctx, &second))
+	assert.NotZero(t, first.ID)
+	assert.NotEqual(t, first.ID, second.ID)
+
+	key, err := stor.FindAPIKeyByHash(ctx, first.Hash)
+	require.NoError(t, err)
+	assertAPIKey(t, first, key)
+
+	_, err = stor.FindAPIKeyByHash(ctx, "hash-of-unknown")
+	assert.Error(t, err)
+
+	keys, err := stor.FindAPIKeysByUserID(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assertAPIKey(t, first, &keys[0])
+	assertAPIKey(t, second, &keys[1])
+
+	keys, err = stor.FindAPIKeysByUserID(ctx, otherUserID)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	//	only the owner revokes the key
+	ok, err := stor.RevokeAPIKey(ctx, otherUserID, first.ID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = stor.RevokeAPIKey(ctx, userID, first.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = stor.RevokeAPIKey(ctx, userID, second.ID+100)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	usedAt := createdAt.Add(time.Minute)
+	require.NoError(t, stor.TouchAPIKey(ctx, second.ID, usedAt))
+
+	key, err = stor.FindAPIKeyByHash(ctx, first.Hash)
+	require.NoError(t, err)
+	assert.True(t, key.RevokedFlag)
+	assert.Nil(t, key.LastUsedAt)
+
+	key, err = stor.FindAPIKeyByHash(ctx, second.Hash)
+	require.NoError(t, err)
+	assert.False(t, key.RevokedFlag)
+	require.NotNil(t, key.LastUsedAt)
+	assert.True(t, usedAt.Equal(*key.LastUsedAt))
+}
+
+// testSearchAliases checks that aliases of all users are filtered and paged in the order of IDs
+func testSearchAliases(t *testing.T, stor aliasmaker.Storager) {
+
+	ctx := context.Background()
+	userID := createUser(t, stor)
+	otherUserID := createUser(t, stor)
+
+	batch := makeAliases(userID, 1, 3)
+	otherBatch := makeAliases(otherUserID, 11, 2)
+	require.NoError(t, stor.SaveAll(ctx, batch))
+	require.NoError(t, stor.SaveAll(ctx, otherBatch))
+
+	//	deleted aliases are found too
+	deleted, err := stor.FindByShortKey(ctx, batch[0].ShortKey)
+	require.NoError(t, err)
+	require.NoError(t, stor.MarkDeleted(ctx, []uint64{deleted.ID}))
+
+	all := append(append([]aliasentity.AliasURLModel{}, batch...), otherBatch...)
+	testCases := []struct {
+		name   string
+		filter aliasentity.Filter
+		want   []aliasentity.AliasURLModel
+	}{
+		{name: "all", filter: aliasentity.Filter{}, want: all},
+		{name: "by user", filter: aliasentity.Filter{UserID: &otherUserID}, want: otherBatch},
+		{name: "by short key", filter: aliasentity.Filter{Query: batch[1].ShortKey}, want: batch[1:2]},
+		{name: "by original URL", filter: aliasentity.Filter{Query: "example.com/1"}, want: []aliasentity.AliasURLModel{batch[0], otherBatch[0], otherBatch[1]}},
+		{name: "by query and user", filter: aliasentity.Filter{Query: "example.com/1", UserID: &userID}, want: batch[0:1]},
+		{name: "page", filter: aliasentity.Filter{Offset: 1, Limit: 2}, want: all[1:3]},
+		{name: "last page", filter: aliasentity.Filter{Offset: 4, Limit: 2}, want: all[4:]},
+		{name: "nothing", filter: aliasentity.Filter{Query: "example.org"}, want: nil},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			nodes, err := stor.SearchAliases(ctx, test.filter)
+			require.NoError(t, err)
+			require.Equal(t, shortKeysOf(test.want), shortKeysOf(nodes))
+			for i := range nodes {
+				assertAlias(t, test.want[i], &nodes[i])
+				assert.Equal(t, nodes[i].ShortKey == batch[0].ShortKey, nodes[i].DeletedFlag)
+			}
+		})
+	}
+}
+
+// testSetDisabled checks that the disabled flag is switched and visible to every lookup
+func testSetDisabled(t *testing.T, stor aliasmaker.Storager) {
+
+	ctx := context.Background()
+	userID := createUser(t, stor)
+
+	batch := makeAliases(userID, 1, 2)
+	require.NoError(t, stor.SaveAll(ctx, batch))
+
+	assertDisabled := func(disabled bool) {
+		t.Helper()
+
+		node, err := stor.FindByShortKey(ctx, batch[0].ShortKey)
+		require.NoError(t, err)
+		assert.Equal(t, disabled, node.DisabledFlag)
+
+		node, err = stor.FindByLongURL(ctx, batch[0].LongURL)
+		require.NoError(t, err)
+		assert.Equal(t, disabled, node.DisabledFlag)
+
+		nodes, err := stor.FindByUserID(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, nodes, len(batch))
+		for _, node := range nodes {
+			assert.Equal(t, disabled && node.ShortKey == batch[0].ShortKey, node.DisabledFlag, node.ShortKey)
+		}
+	}
+
+	ok, err := stor.SetDisabled(ctx, batch[0].ShortKey, true)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assertDisabled(true)
+
+	ok, err = stor.SetDisabled(ctx, batch[0].ShortKey, false)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assertDisabled(false)
+
+	ok, err = stor.SetDisabled(ctx, "unknown", true)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+// testPurgeAlias checks that a purged alias is not found anymore and its short key and URL are free
+func testPurgeAlias(t *testing.T, stor aliasmaker.Storager) {
+
+	ctx := context.Background()
+	userID := createUser(t, stor)
+
+	batch := makeAliases(userID, 1, 3)
+	require.NoError(t, stor.SaveAll(ctx, batch))
+
+	ok, err := stor.PurgeAlias(ctx, batch[1].ShortKey)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = stor.FindByShortKey(ctx, batch[1].ShortKey)
+	assert.Error(t, err)
+	_, err = stor.FindByLongURL(ctx, batch[1].LongURL)
+	assert.Error(t, err)
+
+	nodes, err := stor.FindByUserID(ctx, userID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{batch[0].ShortKey, batch[2].ShortKey}, shortKeysOf(nodes))
+
+	ok, err = stor.PurgeAlias(ctx, batch[1].ShortKey)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	//	the short key and the URL can be saved again
+	require.NoError(t, stor.Save(ctx, &batch[1]))
+	node, err := stor.FindByShortKey(ctx, batch[1].ShortKey)
+	require.NoError(t, err)
+	assertAlias(t, batch[1], node)
+	node, err = stor.FindByLongURL(ctx, batch[1].LongURL)
+	require.NoError(t, err)
+	assertAlias(t, batch[1], node)
+}
+
+// testPurgeAliasClicks checks that the next owner of a purged short key does not get its clicks
+func testPurgeAliasClicks(t *testing.T, stor aliasmaker.Storager) {
+
+	sink, ok := stor.(aliasmaker.AnalyticsSink)
+	if !ok {
+		t.Skip("storage does not save clicks")
+	}
+
+	ctx := context.Background()
+	userID := createUser(t, stor)
+
+	batch := makeAliases(userID, 1, 2)
+	require.NoError(t, stor.SaveAll(ctx, batch))
+
+	now := time.Now().UTC()
+	require.NoError(t, sink.SaveClicks(ctx, []clickentity.ClickModel{
+		{ShortKey: batch[0].ShortKey, ClickedAt: now, ClientIP: "127.0.0.1"},
+		{ShortKey: batch[1].ShortKey, ClickedAt: now, ClientIP: "127.0.0.1"},
+	}))
+
+	ok, err := stor.PurgeAlias(ctx, batch[0].ShortKey)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	batch[0].LongURL += "/next"
+	require.NoError(t, stor.Save(ctx, &batch[0]))
+
+	stats, err := sink.GetClickStats(ctx, batch[0].ShortKey)
+	require.NoError(t, err)
+	assert.Zero(t, stats.TotalClicks)
+	assert.Empty(t, stats.Daily)
+
+	//	clicks of other aliases stay
+	stats, err = sink.GetClickStats(ctx, batch[1].ShortKey)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), stats.TotalClicks)
+}
+
+// testGetServiceStats checks counts of users and aliases by their flags
+func testGetServiceStats(t *testing.T, stor aliasmaker.Storager) {
+
+	ctx := context.Background()
+	now := time.Now()
+	userID := createUser(t, stor)
+	createUser(t, stor)
+
+	ok, err := stor.RegisterUser(ctx, &userentity.UserModel{UserID: userID, Login: "alice", PasswordHash: "hash"})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	batch := makeAliases(userID, 1, 4)
+	expiresAt := now.Add(-time.Minute)
+	batch[2].ExpiresAt = &expiresAt
+	require.NoError(t, stor.SaveAll(ctx, batch))
+
+	deleted, err := stor.FindByShortKey(ctx, batch[0].ShortKey)
+	require.NoError(t, err)
+	require.NoError(t, stor.MarkDeleted(ctx, []uint64{deleted.ID}))
+	_, err = stor.SetDisabled(ctx, batch[1].ShortKey, true)
+	require.NoError(t, err)
+
+	stats, err := stor.GetServiceStats(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, statsentity.ServiceStatsModel{
+		Users:           2,
+		RegisteredUsers: 1,
+		Aliases:         4,
+		DeletedAliases:  1,
+		ExpiredAliases:  1,
+		DisabledAliases: 1,
+	}, *stats)
+}
+
+// testConcurrency runs writers and readers at the same time, like the service does while deleting aliases
+func testConcurrency(t *testing.T, stor aliasmaker.Storager) {
+
+	const (
+		writers   = 8
+		perWriter = 20
+	)
+	ctx := context.Background()
+	userID := createUser(t, stor)
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for _, node := range makeAliases(userID, w*perWriter+1, perWriter) {
+				node := node
+				assert.NoError(t, stor.Save(ctx, &node))
+			}
+		}(w)
+
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				_, err := stor.CreateUser(ctx)
+				assert.NoError(t, err)
+
+				node, err := stor.FindByShortKey(ctx, shortKey(w*perWriter+i+1))
+				if err == nil {
+					assert.NoError(t, stor.MarkDeleted(ctx, []uint64{node.ID}))
+				}
+				_, err = stor.FindByUserID(ctx, userID)
+				assert.NoError(t, err)
+				stor.GetLastShortKey()
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	nodes, err := stor.FindByUserID(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, nodes, writers*perWriter)
+
+	ids := make(map[uint64]bool, len(nodes))
+	for _, node := range nodes {
+		found, err := stor.FindByShortKey(ctx, node.ShortKey)
+		require.NoError(t, err)
+		ids[found.ID] = true
+	}
+	assert.Len(t, ids, writers*perWriter)
+}
+
+// assertAPIKey compares the stored fields of API keys
+func assertAPIKey(t *testing.T, want apikeyentity.APIKeyModel, got *apikeyentity.APIKeyModel) {
+
+	t.Helper()
+	require.NotNil(t, got)
+	assert.Equal(t, want.ID, got.ID)
+	assert.Equal(t, want.UserID, got.UserID)
+	assert.Equal(t, want.Name, got.Name)
+	assert.Equal(t, want.Prefix, got.Prefix)
+	assert.Equal(t, want.Hash, got.Hash)
+	assert.Equal(t, want.Scopes, got.Scopes)
+	assert.True(t, want.CreatedAt.Equal(got.CreatedAt), "created at %s, want %s", got.CreatedAt, want.CreatedAt)
+	if want.ExpiresAt == nil {
+		assert.Nil(t, got.ExpiresAt)
+	} else if assert.NotNil(t, got.ExpiresAt) {
+		assert.True(t, want.ExpiresAt.Equal(*got.ExpiresAt), "expires at %s, want %s", *got.ExpiresAt, *want.ExpiresAt)
+	}
+}
+
+// createUser creates a new user or fails the test
+func createUser(t *testing.T, stor aliasmaker.Storager) uint64 {
+
+	userID, err := stor.CreateUser(context.Background())
+	require.NoError(t, err)
+	return userID
+}
+
+// makeAliases returns count aliases of the user with generated keys starting from first
+func makeAliases(userID uint64, first, count int) []aliasentity.AliasURLModel {
+
+	aliases := make([]aliasentity.AliasURLModel, count)
+	for i := range aliases {
+		aliases[i] = aliasentity.AliasURLModel{
+			UserID:   userID,
+			ShortKey: shortKey(first + i),
+			LongURL:  fmt.Sprintf("https://example.com/%d", first+i),
+		}
+	}
+	return aliases
+}
+
+// shortKey returns a generator-shaped key for n
+func shortKey(n int) string {
+	return fmt.Sprintf("%09d", n)
+}
+
+// shortKeysOf returns the short keys of aliases
+func shortKeysOf(aliases []aliasentity.AliasURLModel) []string {
+
+	var shortKeys []string
+	for _, node := range aliases {
+		shortKeys = append(shortKeys, node.ShortKey)
+	}
+	return shortKeys
+}
+
+// assertAlias compares the stored fields of an alias, ignoring its ID
+func assertAlias(t *testing.T, want aliasentity.AliasURLModel, got *aliasentity.AliasURLModel) {
+
+	t.Helper()
+	require.NotNil(t, got)
+
+	assert.Equal(t, want.UserID, got.UserID)
+	assert.Equal(t, want.ShortKey, got.ShortKey)
+	assert.Equal(t, want.LongURL, got.LongURL)
+	assert.Equal(t, want.IsCustom, got.IsCustom)
+
+	if want.ExpiresAt == nil {
+		assert.Nil(t, got.ExpiresAt)
+		return
+	}
+	if assert.NotNil(t, got.ExpiresAt) {
+		assert.True(t, want.ExpiresAt.Equal(*got.ExpiresAt), "expires_at: want %s, got %s", want.ExpiresAt, got.ExpiresAt)
+	}
+}
@@ -0,0 +1,105 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"time"
+)
+
+// SelfSignedValidForDefault - lifetime of self-signed certificates by default
+const SelfSignedValidForDefault = 365 * 24 * time.Hour
+
+// ------------------------------------------------------------
+//
+//	Generate self-signed certificate for local development. The certificate is its own CA,
+//	so it can be added to trusted ones of a client, or used as client CA and client certificate for mTLS.
+//	Key is ECDSA P-256
+//	Input:
+//		hosts []string - DNS names and IP addresses of the certificate, for example: localhost, 127.0.0.1
+//		validFor time.Duration - lifetime of the certificate
+//	Output:
+//		certPEM []byte - PEM certificate
+//		keyPEM []byte - PEM PKCS #8 private key
+//		err error
+func GenerateSelfSigned(hosts []string, validFor time.Duration) (certPEM, keyPEM []byte, err error) {
+
+	if len(hosts) == 0 {
+		return nil, nil, errors.New("hosts of certificate are not set")
+	}
+	if validFor <= 0 {
+		return nil, nil, errors.New("lifetime of certificate must be positive")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	notBefore := time.Now().Add(-time.Minute)
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{Organization: []string{"urlalias development"}, CommonName: hosts[0]},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(validFor),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Generate self-signed certificate and write it to the files. Key file is readable by the owner only
+//	Input:
+//		certFile string - file of PEM certificate
+//		keyFile string - file of PEM private key
+//		hosts []string - DNS names and IP addresses of the certificate
+//		validFor time.Duration - lifetime of the certificate
+func WriteSelfSigned(certFile, keyFile string, hosts []string, validFor time.Duration) error {
+
+	certPEM, keyPEM, err := GenerateSelfSigned(hosts, validFor)
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		return fmt.Errorf("can't write key file: %w", err)
+	}
+	if err = os.WriteFile(certFile, certPEM, 0644); err != nil {
+		return fmt.Errorf("can't write certificate file: %w", err)
+	}
+	return nil
+}
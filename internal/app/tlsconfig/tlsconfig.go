@@ -0,0 +1,189 @@
+/*
+Package tlsconfig builds TLS configuration of the HTTPS server and generates
+self-signed certificates for local development.
+
+Minimum TLS version, cipher suites policy and verification of client certificates (mTLS)
+are set by Settings.
+*/
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Minimum TLS versions
+const (
+	Version12      = "1.2"     //	TLS 1.2 and TLS 1.3
+	Version13      = "1.3"     //	TLS 1.3 only
+	VersionDefault = Version12 //	default minimum version
+)
+
+// Cipher suites policies. They are applied to TLS 1.2 only, suites of TLS 1.3 are not configurable
+const (
+	CipherPolicyDefault = "default" //	suites of crypto/tls by default
+	CipherPolicyModern  = "modern"  //	ECDHE key exchange with AEAD ciphers only, all of them have forward secrecy
+)
+
+// Verification of client certificates
+const (
+	ClientAuthNone     = "none"     //	client certificates are not requested
+	ClientAuthOptional = "optional" //	client certificate is verified if it is given
+	ClientAuthRequire  = "require"  //	client must give a valid certificate
+)
+
+// modernCipherSuites - TLS 1.2 suites of CipherPolicyModern
+var modernCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// Settings of TLS
+type Settings struct {
+	CertFile     string //	CertFile - PEM file of the server certificate, it may contain intermediate certificates
+	KeyFile      string //	KeyFile - PEM file of the server private key
+	MinVersion   string //	MinVersion - Version12 or Version13, empty - VersionDefault
+	CipherPolicy string //	CipherPolicy - CipherPolicyDefault or CipherPolicyModern, empty - CipherPolicyDefault
+	ClientAuth   string //	ClientAuth - ClientAuthNone, ClientAuthOptional or ClientAuthRequire, empty - ClientAuthNone
+	ClientCAFile string //	ClientCAFile - PEM file of CA certificates which sign client certificates
+}
+
+// ------------------------------------------------------------
+//
+//	Build TLS configuration of the server. Certificate, key and client CA files are read once
+//	Input:
+//		settings Settings
+//	Output:
+//		*tls.Config
+//		error - if settings are not valid or files can't be read
+func New(settings Settings) (*tls.Config, error) {
+
+	minVersion, err := ParseVersion(settings.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	cipherSuites, err := cipherSuites(settings.CipherPolicy)
+	if err != nil {
+		return nil, err
+	}
+	clientAuth, err := ParseClientAuth(settings.ClientAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.CertFile == "" || settings.KeyFile == "" {
+		return nil, errors.New("certificate and key files must be set")
+	}
+	certificate, err := tls.LoadX509KeyPair(settings.CertFile, settings.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't load certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   minVersion,
+		CipherSuites: cipherSuites,
+		ClientAuth:   clientAuth,
+	}
+
+	if clientAuth != tls.NoClientCert {
+		if settings.ClientCAFile == "" {
+			return nil, errors.New("client CA file must be set to verify client certificates")
+		}
+		if config.ClientCAs, err = loadCertPool(settings.ClientCAFile); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// ------------------------------------------------------------
+//
+//	Parse minimum TLS version
+//	Input:
+//		version string - Version12 or Version13, empty - VersionDefault
+//	Output:
+//		uint16 - version of crypto/tls
+//		error
+func ParseVersion(version string) (uint16, error) {
+
+	switch version {
+	case "":
+		return ParseVersion(VersionDefault)
+	case Version12:
+		return tls.VersionTLS12, nil
+	case Version13:
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported minimum TLS version: %s, expected %s or %s", version, Version12, Version13)
+	}
+}
+
+// ------------------------------------------------------------
+//
+//	Check the name of cipher suites policy
+func CheckCipherPolicy(policy string) error {
+
+	_, err := cipherSuites(policy)
+	return err
+}
+
+// ------------------------------------------------------------
+//
+//	Parse verification of client certificates
+//	Input:
+//		clientAuth string - ClientAuthNone, ClientAuthOptional or ClientAuthRequire, empty - ClientAuthNone
+//	Output:
+//		tls.ClientAuthType
+//		error
+func ParseClientAuth(clientAuth string) (tls.ClientAuthType, error) {
+
+	switch clientAuth {
+	case "", ClientAuthNone:
+		return tls.NoClientCert, nil
+	case ClientAuthOptional:
+		return tls.VerifyClientCertIfGiven, nil
+	case ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return tls.NoClientCert, fmt.Errorf("unknown client certificates verification: %s", clientAuth)
+	}
+}
+
+// ------------------------------------------------------------
+//
+//	TLS 1.2 cipher suites of the policy, nil - suites of crypto/tls by default
+func cipherSuites(policy string) ([]uint16, error) {
+
+	switch policy {
+	case "", CipherPolicyDefault:
+		return nil, nil
+	case CipherPolicyModern:
+		return append([]uint16{}, modernCipherSuites...), nil
+	default:
+		return nil, fmt.Errorf("unknown cipher suites policy: %s", policy)
+	}
+}
+
+// ------------------------------------------------------------
+//
+//	Load pool of PEM certificates from the file
+func loadCertPool(fileName string) (*x509.CertPool, error) {
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("can't read CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("there are no PEM certificates in CA file %s", fileName)
+	}
+	return pool, nil
+}
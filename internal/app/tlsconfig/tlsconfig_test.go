@@ -0,0 +1,170 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSelfSigned writes self-signed certificate for localhost to the temp dir and returns its files
+func writeSelfSigned(t *testing.T, name string) (certFile, keyFile string) {
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, name+".pem")
+	keyFile = filepath.Join(dir, name+"-key.pem")
+	require.NoError(t, WriteSelfSigned(certFile, keyFile, []string{"localhost", "127.0.0.1"}, time.Hour))
+	return certFile, keyFile
+}
+
+// newTLSServer starts HTTPS server with the settings
+func newTLSServer(t *testing.T, settings Settings) *httptest.Server {
+
+	config, err := New(settings)
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = config
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newClient returns client which trusts the certificate, with client certificate if it is set
+func newClient(t *testing.T, caFile string, clientCert *tls.Certificate, maxVersion uint16) *http.Client {
+
+	data, err := os.ReadFile(caFile)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(data))
+
+	config := &tls.Config{RootCAs: pool, MaxVersion: maxVersion}
+	if clientCert != nil {
+		config.Certificates = []tls.Certificate{*clientCert}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+}
+
+func Test_GenerateSelfSigned(t *testing.T) {
+
+	certPEM, keyPEM, err := GenerateSelfSigned([]string{"localhost", "127.0.0.1"}, time.Hour)
+	require.NoError(t, err)
+
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"localhost"}, leaf.DNSNames)
+	require.Len(t, leaf.IPAddresses, 1)
+	assert.Equal(t, "127.0.0.1", leaf.IPAddresses[0].String())
+	assert.NoError(t, leaf.VerifyHostname("localhost"))
+	assert.True(t, leaf.NotAfter.Before(time.Now().Add(time.Hour)))
+
+	_, _, err = GenerateSelfSigned(nil, time.Hour)
+	assert.Error(t, err)
+	_, _, err = GenerateSelfSigned([]string{"localhost"}, 0)
+	assert.Error(t, err)
+
+	_, keyFile := writeSelfSigned(t, "server")
+	info, err := os.Stat(keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func Test_New(t *testing.T) {
+
+	certFile, keyFile := writeSelfSigned(t, "server")
+
+	tests := []struct {
+		name     string
+		settings Settings
+		wantErr  bool
+	}{
+		{name: "defaults", settings: Settings{CertFile: certFile, KeyFile: keyFile}},
+		{name: "all settings", settings: Settings{CertFile: certFile, KeyFile: keyFile, MinVersion: Version13, CipherPolicy: CipherPolicyModern, ClientAuth: ClientAuthRequire, ClientCAFile: certFile}},
+		{name: "no certificate", settings: Settings{KeyFile: keyFile}, wantErr: true},
+		{name: "certificate is not found", settings: Settings{CertFile: certFile + ".none", KeyFile: keyFile}, wantErr: true},
+		{name: "unknown version", settings: Settings{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.1"}, wantErr: true},
+		{name: "unknown cipher policy", settings: Settings{CertFile: certFile, KeyFile: keyFile, CipherPolicy: "legacy"}, wantErr: true},
+		{name: "unknown client auth", settings: Settings{CertFile: certFile, KeyFile: keyFile, ClientAuth: "always"}, wantErr: true},
+		{name: "client auth without CA", settings: Settings{CertFile: certFile, KeyFile: keyFile, ClientAuth: ClientAuthOptional}, wantErr: true},
+		{name: "CA file without certificates", settings: Settings{CertFile: certFile, KeyFile: keyFile, ClientAuth: ClientAuthOptional, ClientCAFile: keyFile}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := New(tt.settings)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, config.Certificates, 1)
+		})
+	}
+}
+
+func Test_handshake(t *testing.T) {
+
+	certFile, keyFile := writeSelfSigned(t, "server")
+	clientCertFile, clientKeyFile := writeSelfSigned(t, "client")
+	clientCert, err := tls.LoadX509KeyPair(clientCertFile, clientKeyFile)
+	require.NoError(t, err)
+
+	t.Run("minimum version", func(t *testing.T) {
+		server := newTLSServer(t, Settings{CertFile: certFile, KeyFile: keyFile, MinVersion: Version13})
+
+		response, err := newClient(t, certFile, nil, 0).Get(server.URL)
+		require.NoError(t, err)
+		response.Body.Close()
+		assert.Equal(t, uint16(tls.VersionTLS13), response.TLS.Version)
+
+		_, err = newClient(t, certFile, nil, tls.VersionTLS12).Get(server.URL)
+		assert.Error(t, err)
+	})
+
+	t.Run("modern cipher suites", func(t *testing.T) {
+		server := newTLSServer(t, Settings{CertFile: certFile, KeyFile: keyFile, CipherPolicy: CipherPolicyModern})
+
+		response, err := newClient(t, certFile, nil, tls.VersionTLS12).Get(server.URL)
+		require.NoError(t, err)
+		response.Body.Close()
+		assert.Contains(t, modernCipherSuites, response.TLS.CipherSuite)
+	})
+
+	t.Run("client certificate is required", func(t *testing.T) {
+		server := newTLSServer(t, Settings{CertFile: certFile, KeyFile: keyFile, ClientAuth: ClientAuthRequire, ClientCAFile: clientCertFile})
+
+		_, err := newClient(t, certFile, nil, tls.VersionTLS12).Get(server.URL)
+		assert.Error(t, err)
+
+		response, err := newClient(t, certFile, &clientCert, 0).Get(server.URL)
+		require.NoError(t, err)
+		response.Body.Close()
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+
+		//	certificate which is not signed by client CA
+		serverCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		require.NoError(t, err)
+		_, err = newClient(t, certFile, &serverCert, tls.VersionTLS12).Get(server.URL)
+		assert.Error(t, err)
+	})
+
+	t.Run("client certificate is optional", func(t *testing.T) {
+		server := newTLSServer(t, Settings{CertFile: certFile, KeyFile: keyFile, ClientAuth: ClientAuthOptional, ClientCAFile: clientCertFile})
+
+		response, err := newClient(t, certFile, nil, 0).Get(server.URL)
+		require.NoError(t, err)
+		response.Body.Close()
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+	})
+}
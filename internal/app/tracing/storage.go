@@ -0,0 +1,278 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/models/aliasentity"
+	"github.com/Schalure/urlalias/internal/app/models/apikeyentity"
+	"github.com/Schalure/urlalias/internal/app/models/clickentity"
+	"github.com/Schalure/urlalias/internal/app/models/statsentity"
+	"github.com/Schalure/urlalias/internal/app/models/userentity"
+)
+
+// Attributes of storage spans
+const (
+	shortKeyKey = attribute.Key("urlalias.short_key")
+	userIDKey   = attribute.Key("urlalias.user_id")
+	countKey    = attribute.Key("urlalias.count")
+)
+
+// Storage starts a span of every call to the wrapped storage
+type Storage struct {
+	storage aliasmaker.Storager
+	tracer  trace.Tracer
+}
+
+// ------------------------------------------------------------
+//
+//	Wrap the storage, so its calls are traced
+func (t *Tracing) InstrumentStorage(storage aliasmaker.Storager) *Storage {
+
+	return &Storage{
+		storage: storage,
+		tracer:  t.tracer,
+	}
+}
+
+// start starts a client span of the storage method
+func (s *Storage) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+
+	return s.tracer.Start(ctx, "Storager."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// CreateUser is traced "Storager.CreateUser"
+func (s *Storage) CreateUser(ctx context.Context) (userID uint64, err error) {
+
+	ctx, span := s.start(ctx, "CreateUser")
+	defer func() { endSpan(span, err) }()
+	return s.storage.CreateUser(ctx)
+}
+
+// FindUserByID is traced "Storager.FindUserByID"
+func (s *Storage) FindUserByID(ctx context.Context, userID uint64) (user *userentity.UserModel, err error) {
+
+	ctx, span := s.start(ctx, "FindUserByID", userIDKey.Int64(int64(userID)))
+	defer func() { endSpan(span, err) }()
+	return s.storage.FindUserByID(ctx, userID)
+}
+
+// FindUserByLogin is traced "Storager.FindUserByLogin". The login is not recorded
+func (s *Storage) FindUserByLogin(ctx context.Context, login string) (user *userentity.UserModel, err error) {
+
+	ctx, span := s.start(ctx, "FindUserByLogin")
+	defer func() { endSpan(span, err) }()
+	return s.storage.FindUserByLogin(ctx, login)
+}
+
+// RegisterUser is traced "Storager.RegisterUser"
+func (s *Storage) RegisterUser(ctx context.Context, user *userentity.UserModel) (ok bool, err error) {
+
+	ctx, span := s.start(ctx, "RegisterUser", userIDKey.Int64(int64(user.UserID)))
+	defer func() { endSpan(span, err) }()
+	return s.storage.RegisterUser(ctx, user)
+}
+
+// Save is traced "Storager.Save"
+func (s *Storage) Save(ctx context.Context, urlAliasNode *aliasentity.AliasURLModel) (err error) {
+
+	ctx, span := s.start(ctx, "Save", shortKeyKey.String(urlAliasNode.ShortKey))
+	defer func() { endSpan(span, err) }()
+	return s.storage.Save(ctx, urlAliasNode)
+}
+
+// SaveAll is traced "Storager.SaveAll"
+func (s *Storage) SaveAll(ctx context.Context, urlAliasNodes []aliasentity.AliasURLModel) (err error) {
+
+	ctx, span := s.start(ctx, "SaveAll", countKey.Int(len(urlAliasNodes)))
+	defer func() { endSpan(span, err) }()
+	return s.storage.SaveAll(ctx, urlAliasNodes)
+}
+
+// FindByShortKey is traced "Storager.FindByShortKey"
+func (s *Storage) FindByShortKey(ctx context.Context, shortKey string) (node *aliasentity.AliasURLModel, err error) {
+
+	ctx, span := s.start(ctx, "FindByShortKey", shortKeyKey.String(shortKey))
+	defer func() { endSpan(span, err) }()
+	return s.storage.FindByShortKey(ctx, shortKey)
+}
+
+// FindByLongURL is traced "Storager.FindByLongURL"
+func (s *Storage) FindByLongURL(ctx context.Context, longURL string) (node *aliasentity.AliasURLModel, err error) {
+
+	ctx, span := s.start(ctx, "FindByLongURL")
+	defer func() { endSpan(span, err) }()
+	return s.storage.FindByLongURL(ctx, longURL)
+}
+
+// FindAllByLongURLs is traced "Storager.FindAllByLongURLs"
+func (s *Storage) FindAllByLongURLs(ctx context.Context, longURL []string) (nodes map[string]*aliasentity.AliasURLModel, err error) {
+
+	ctx, span := s.start(ctx, "FindAllByLongURLs", countKey.Int(len(longURL)))
+	defer func() { endSpan(span, err) }()
+	return s.storage.FindAllByLongURLs(ctx, longURL)
+}
+
+// FindByUserID is traced "Storager.FindByUserID"
+func (s *Storage) FindByUserID(ctx context.Context, userID uint64) (nodes []aliasentity.AliasURLModel, err error) {
+
+	ctx, span := s.start(ctx, "FindByUserID", userIDKey.Int64(int64(userID)))
+	defer func() { endSpan(span, err) }()
+	return s.storage.FindByUserID(ctx, userID)
+}
+
+// MarkDeleted is traced "Storager.MarkDeleted"
+func (s *Storage) MarkDeleted(ctx context.Context, aliasesID []uint64) (err error) {
+
+	ctx, span := s.start(ctx, "MarkDeleted", countKey.Int(len(aliasesID)))
+	defer func() { endSpan(span, err) }()
+	return s.storage.MarkDeleted(ctx, aliasesID)
+}
+
+// MarkExpired is traced "Storager.MarkExpired"
+func (s *Storage) MarkExpired(ctx context.Context, now time.Time) (count int, err error) {
+
+	ctx, span := s.start(ctx, "MarkExpired")
+	defer func() { endSpan(span, err) }()
+	return s.storage.MarkExpired(ctx, now)
+}
+
+// ReserveKeyRange is traced "Storager.ReserveKeyRange"
+func (s *Storage) ReserveKeyRange(ctx context.Context, name string, start, size uint64) (first uint64, err error) {
+
+	ctx, span := s.start(ctx, "ReserveKeyRange", attribute.String("urlalias.key_range", name))
+	defer func() { endSpan(span, err) }()
+	return s.storage.ReserveKeyRange(ctx, name, start, size)
+}
+
+// SaveAPIKey is traced "Storager.SaveAPIKey"
+func (s *Storage) SaveAPIKey(ctx context.Context, key *apikeyentity.APIKeyModel) (err error) {
+
+	ctx, span := s.start(ctx, "SaveAPIKey", userIDKey.Int64(int64(key.UserID)))
+	defer func() { endSpan(span, err) }()
+	return s.storage.SaveAPIKey(ctx, key)
+}
+
+// FindAPIKeyByHash is traced "Storager.FindAPIKeyByHash". The hash is not recorded
+func (s *Storage) FindAPIKeyByHash(ctx context.Context, hash string) (key *apikeyentity.APIKeyModel, err error) {
+
+	ctx, span := s.start(ctx, "FindAPIKeyByHash")
+	defer func() { endSpan(span, err) }()
+	return s.storage.FindAPIKeyByHash(ctx, hash)
+}
+
+// FindAPIKeysByUserID is traced "Storager.FindAPIKeysByUserID"
+func (s *Storage) FindAPIKeysByUserID(ctx context.Context, userID uint64) (keys []apikeyentity.APIKeyModel, err error) {
+
+	ctx, span := s.start(ctx, "FindAPIKeysByUserID", userIDKey.Int64(int64(userID)))
+	defer func() { endSpan(span, err) }()
+	return s.storage.FindAPIKeysByUserID(ctx, userID)
+}
+
+// RevokeAPIKey is traced "Storager.RevokeAPIKey"
+func (s *Storage) RevokeAPIKey(ctx context.Context, userID, keyID uint64) (ok bool, err error) {
+
+	ctx, span := s.start(ctx, "RevokeAPIKey", userIDKey.Int64(int64(userID)))
+	defer func() { endSpan(span, err) }()
+	return s.storage.RevokeAPIKey(ctx, userID, keyID)
+}
+
+// TouchAPIKey is traced "Storager.TouchAPIKey"
+func (s *Storage) TouchAPIKey(ctx context.Context, keyID uint64, usedAt time.Time) (err error) {
+
+	ctx, span := s.start(ctx, "TouchAPIKey")
+	defer func() { endSpan(span, err) }()
+	return s.storage.TouchAPIKey(ctx, keyID, usedAt)
+}
+
+// SearchAliases is traced "Storager.SearchAliases"
+func (s *Storage) SearchAliases(ctx context.Context, filter aliasentity.Filter) (nodes []aliasentity.AliasURLModel, err error) {
+
+	ctx, span := s.start(ctx, "SearchAliases")
+	defer func() { endSpan(span, err) }()
+	return s.storage.SearchAliases(ctx, filter)
+}
+
+// SetDisabled is traced "Storager.SetDisabled"
+func (s *Storage) SetDisabled(ctx context.Context, shortKey string, disabled bool) (ok bool, err error) {
+
+	ctx, span := s.start(ctx, "SetDisabled", shortKeyKey.String(shortKey))
+	defer func() { endSpan(span, err) }()
+	return s.storage.SetDisabled(ctx, shortKey, disabled)
+}
+
+// PurgeAlias is traced "Storager.PurgeAlias"
+func (s *Storage) PurgeAlias(ctx context.Context, shortKey string) (ok bool, err error) {
+
+	ctx, span := s.start(ctx, "PurgeAlias", shortKeyKey.String(shortKey))
+	defer func() { endSpan(span, err) }()
+	return s.storage.PurgeAlias(ctx, shortKey)
+}
+
+// GetServiceStats is traced "Storager.GetServiceStats"
+func (s *Storage) GetServiceStats(ctx context.Context, now time.Time) (stats *statsentity.ServiceStatsModel, err error) {
+
+	ctx, span := s.start(ctx, "GetServiceStats")
+	defer func() { endSpan(span, err) }()
+	return s.storage.GetServiceStats(ctx, now)
+}
+
+// GetLastShortKey is traced "Storager.GetLastShortKey"
+func (s *Storage) GetLastShortKey() string {
+
+	_, span := s.start(context.Background(), "GetLastShortKey")
+	defer span.End()
+	return s.storage.GetLastShortKey()
+}
+
+// IsConnected is traced "Storager.IsConnected"
+func (s *Storage) IsConnected(ctx context.Context) bool {
+
+	ctx, span := s.start(ctx, "IsConnected")
+	defer span.End()
+	return s.storage.IsConnected(ctx)
+}
+
+// Close closes the wrapped storage
+func (s *Storage) Close() error {
+	return s.storage.Close()
+}
+
+// SaveClicks is traced "AnalyticsSink.SaveClicks"
+func (s *Storage) SaveClicks(ctx context.Context, clicks []clickentity.ClickModel) (err error) {
+
+	sink, ok := s.storage.(aliasmaker.AnalyticsSink)
+	if !ok {
+		return errors.New("storage does not keep redirect statistics")
+	}
+
+	ctx, span := s.start(ctx, "SaveClicks", countKey.Int(len(clicks)))
+	defer func() { endSpan(span, err) }()
+	return sink.SaveClicks(ctx, clicks)
+}
+
+// GetClickStats is traced "AnalyticsSink.GetClickStats"
+func (s *Storage) GetClickStats(ctx context.Context, shortKey string) (stats *clickentity.StatsModel, err error) {
+
+	sink, ok := s.storage.(aliasmaker.AnalyticsSink)
+	if !ok {
+		return nil, errors.New("storage does not keep redirect statistics")
+	}
+
+	ctx, span := s.start(ctx, "GetClickStats", shortKeyKey.String(shortKey))
+	defer func() { endSpan(span, err) }()
+	return sink.GetClickStats(ctx, shortKey)
+}
+
+// Unwrap returns the wrapped storage, so optional methods of the storage can be found
+func (s *Storage) Unwrap() aliasmaker.Storager {
+	return s.storage
+}
@@ -0,0 +1,130 @@
+/*
+Package tracing traces requests through the HTTP and gRPC servers, the service
+and the storage with OpenTelemetry.
+
+Trace context of incoming requests is taken from W3C "traceparent" and
+"tracestate" headers, so spans of the service join traces started by clients.
+Spans are written as JSON to stdout or to a file, so tracing works without a collector.
+*/
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters of spans
+const (
+	ExporterNone   = "none"   //	spans are not recorded
+	ExporterStdout = "stdout" //	spans are written to stdout
+	ExporterFile   = "file"   //	spans are appended to a file
+)
+
+// instrumentationName is the name of the tracer of the package
+const instrumentationName = "github.com/Schalure/urlalias/internal/app/tracing"
+
+// Tracing of the service. Tracing is safe for concurrent use
+type Tracing struct {
+	provider   *sdktrace.TracerProvider
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	output     io.Closer //	output - file of spans, nil for stdout
+}
+
+// ------------------------------------------------------------
+//
+//	Tracing constructor. The tracer provider and the propagator are set as global ones,
+//	so packages which use otel.Tracer get spans of the same traces
+//	Input:
+//		serviceName - name of the service in spans
+//		exporter - ExporterStdout or ExporterFile
+//		fileName - file of spans for ExporterFile
+func New(serviceName, exporter, fileName string) (*Tracing, error) {
+
+	var (
+		output io.Writer
+		closer io.Closer
+	)
+	switch exporter {
+	case ExporterStdout:
+		output = os.Stdout
+	case ExporterFile:
+		if fileName == "" {
+			return nil, errors.New("file of spans is not set")
+		}
+		file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("can't open file of spans: %w", err)
+		}
+		output, closer = file, file
+	default:
+		return nil, fmt.Errorf("unknown exporter of spans \"%s\"", exporter)
+	}
+
+	spanExporter, err := stdouttrace.New(stdouttrace.WithWriter(output))
+	if err != nil {
+		if closer != nil {
+			closer.Close()
+		}
+		return nil, err
+	}
+
+	t := newTracing(serviceName, sdktrace.WithBatcher(spanExporter))
+	t.output = closer
+
+	otel.SetTracerProvider(t.provider)
+	otel.SetTextMapPropagator(t.propagator)
+	return t, nil
+}
+
+// newTracing creates Tracing with the provider built from opts
+func newTracing(serviceName string, opts ...sdktrace.TracerProviderOption) *Tracing {
+
+	opts = append(opts, sdktrace.WithResource(resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	)))
+	provider := sdktrace.NewTracerProvider(opts...)
+
+	return &Tracing{
+		provider:   provider,
+		tracer:     provider.Tracer(instrumentationName),
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	}
+}
+
+// ------------------------------------------------------------
+//
+//	Shutdown writes the spans which are not exported yet and closes the file of spans
+func (t *Tracing) Shutdown(ctx context.Context) error {
+
+	err := t.provider.Shutdown(ctx)
+	if t.output != nil {
+		if errClose := t.output.Close(); err == nil {
+			err = errClose
+		}
+	}
+	return err
+}
+
+// endSpan records err in the span if it is not nil and ends the span
+func endSpan(span trace.Span, err error) {
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,167 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/Schalure/urlalias/internal/app/aliasmaker"
+	"github.com/Schalure/urlalias/internal/app/storage/memstor"
+	"github.com/Schalure/urlalias/internal/app/storage/storagetest"
+)
+
+// traceparent of the client span all requests of the tests belong to
+const (
+	traceparent  = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	clientTrace  = "4bf92f3577b34da6a3ce929d0e0e4736"
+	clientSpanID = "00f067aa0ba902b7"
+)
+
+// newRecordedTracing returns Tracing which keeps spans in memory
+func newRecordedTracing() (*Tracing, *tracetest.SpanRecorder) {
+
+	recorder := tracetest.NewSpanRecorder()
+	return newTracing("test", sdktrace.WithSpanProcessor(recorder)), recorder
+}
+
+func TestTracing_HTTPMiddleware(t *testing.T) {
+
+	tr, recorder := newRecordedTracing()
+
+	var handlerSpan trace.SpanContext
+	r := chi.NewRouter()
+	r.Use(tr.HTTPMiddleware)
+	r.Get("/{shortkey}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+	r.Get("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/000000001", nil)
+	request.Header.Set("traceparent", traceparent)
+	r.ServeHTTP(httptest.NewRecorder(), request)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	//	the span continues the trace of the client and is named by route pattern
+	redirect := spans[0]
+	assert.Equal(t, "GET /{shortkey}", redirect.Name())
+	assert.Equal(t, trace.SpanKindServer, redirect.SpanKind())
+	assert.Equal(t, clientTrace, redirect.SpanContext().TraceID().String())
+	assert.Equal(t, clientSpanID, redirect.Parent().SpanID().String())
+	assert.True(t, redirect.Parent().IsRemote())
+	assert.Contains(t, redirect.Attributes(), semconv.HTTPRoute("/{shortkey}"))
+	assert.Contains(t, redirect.Attributes(), semconv.HTTPResponseStatusCode(http.StatusTemporaryRedirect))
+
+	//	handlers get the span of the request
+	assert.Equal(t, redirect.SpanContext().SpanID(), handlerSpan.SpanID())
+
+	//	request without traceparent starts a new trace, server errors mark the span
+	ping := spans[1]
+	assert.False(t, ping.Parent().IsValid())
+	assert.NotEqual(t, clientTrace, ping.SpanContext().TraceID().String())
+	assert.Equal(t, codes.Error, ping.Status().Code)
+}
+
+func TestTracing_UnaryServerInterceptor(t *testing.T) {
+
+	tr, recorder := newRecordedTracing()
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/shortener.Shortener/Expand"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(grpccodes.NotFound, "not found")
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", traceparent))
+	_, err := tr.UnaryServerInterceptor(ctx, nil, info, handler)
+	assert.Equal(t, grpccodes.NotFound, status.Code(err))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "shortener.Shortener/Expand", spans[0].Name())
+	assert.Equal(t, clientTrace, spans[0].SpanContext().TraceID().String())
+	assert.Contains(t, spans[0].Attributes(), semconv.RPCMethod("Expand"))
+	assert.Contains(t, spans[0].Attributes(), semconv.RPCGRPCStatusCodeKey.Int(int(grpccodes.NotFound)))
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
+
+func TestTracing_Storage(t *testing.T) {
+
+	tr, recorder := newRecordedTracing()
+
+	backend, err := memstor.NewStorage()
+	require.NoError(t, err)
+	stor := tr.InstrumentStorage(backend)
+
+	ctx, parent := tr.tracer.Start(context.Background(), "request")
+	_, err = stor.FindByShortKey(ctx, "000000001")
+	assert.Error(t, err)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	//	storage calls are children of the span of ctx, errors are recorded
+	find := spans[0]
+	assert.Equal(t, "Storager.FindByShortKey", find.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), find.Parent().SpanID())
+	assert.Equal(t, codes.Error, find.Status().Code)
+	assert.Contains(t, find.Attributes(), shortKeyKey.String("000000001"))
+
+	//	optional methods of the storage are still found
+	assert.Equal(t, backend, stor.Unwrap())
+}
+
+func TestTracing_New(t *testing.T) {
+
+	fileName := filepath.Join(t.TempDir(), "spans.json")
+
+	tr, err := New("test", ExporterFile, fileName)
+	require.NoError(t, err)
+
+	_, span := tr.tracer.Start(context.Background(), "request")
+	span.End()
+	require.NoError(t, tr.Shutdown(context.Background()))
+
+	//	spans are written to the file on shutdown
+	data, err := os.ReadFile(fileName)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"request"`)
+	assert.Contains(t, string(data), span.SpanContext().TraceID().String())
+
+	_, err = New("test", "jaeger", "")
+	assert.Error(t, err)
+
+	_, err = New("test", ExporterFile, "")
+	assert.Error(t, err)
+}
+
+func TestStorager(t *testing.T) {
+
+	tr, _ := newRecordedTracing()
+	storagetest.Run(t, func(t *testing.T) aliasmaker.Storager {
+		stor, err := memstor.NewStorage()
+		require.NoError(t, err)
+		return tr.InstrumentStorage(stor)
+	})
+}